@@ -0,0 +1,111 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: user/v1/oidc.proto
+
+package userv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	OIDCClientService_RegisterOIDCClient_FullMethodName = "/user.v1.OIDCClientService/RegisterOIDCClient"
+)
+
+// OIDCClientServiceClient is the client API for OIDCClientService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OIDCClientServiceClient interface {
+	// RegisterOIDCClient 注册应用，client_secret 只在这里返回一次
+	RegisterOIDCClient(ctx context.Context, in *RegisterOIDCClientRequest, opts ...grpc.CallOption) (*RegisterOIDCClientResponse, error)
+}
+
+type oIDCClientServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOIDCClientServiceClient(cc grpc.ClientConnInterface) OIDCClientServiceClient {
+	return &oIDCClientServiceClient{cc}
+}
+
+func (c *oIDCClientServiceClient) RegisterOIDCClient(ctx context.Context, in *RegisterOIDCClientRequest, opts ...grpc.CallOption) (*RegisterOIDCClientResponse, error) {
+	out := new(RegisterOIDCClientResponse)
+	err := c.cc.Invoke(ctx, OIDCClientService_RegisterOIDCClient_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OIDCClientServiceServer is the server API for OIDCClientService service.
+// All implementations must embed UnimplementedOIDCClientServiceServer
+// for forward compatibility
+type OIDCClientServiceServer interface {
+	// RegisterOIDCClient 注册应用，client_secret 只在这里返回一次
+	RegisterOIDCClient(context.Context, *RegisterOIDCClientRequest) (*RegisterOIDCClientResponse, error)
+	mustEmbedUnimplementedOIDCClientServiceServer()
+}
+
+// UnimplementedOIDCClientServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedOIDCClientServiceServer struct {
+}
+
+func (UnimplementedOIDCClientServiceServer) RegisterOIDCClient(context.Context, *RegisterOIDCClientRequest) (*RegisterOIDCClientResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterOIDCClient not implemented")
+}
+func (UnimplementedOIDCClientServiceServer) mustEmbedUnimplementedOIDCClientServiceServer() {}
+
+// UnsafeOIDCClientServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OIDCClientServiceServer will
+// result in compilation errors.
+type UnsafeOIDCClientServiceServer interface {
+	mustEmbedUnimplementedOIDCClientServiceServer()
+}
+
+func RegisterOIDCClientServiceServer(s grpc.ServiceRegistrar, srv OIDCClientServiceServer) {
+	s.RegisterService(&OIDCClientService_ServiceDesc, srv)
+}
+
+func _OIDCClientService_RegisterOIDCClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterOIDCClientRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OIDCClientServiceServer).RegisterOIDCClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OIDCClientService_RegisterOIDCClient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OIDCClientServiceServer).RegisterOIDCClient(ctx, req.(*RegisterOIDCClientRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OIDCClientService_ServiceDesc is the grpc.ServiceDesc for OIDCClientService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OIDCClientService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "user.v1.OIDCClientService",
+	HandlerType: (*OIDCClientServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RegisterOIDCClient",
+			Handler:    _OIDCClientService_RegisterOIDCClient_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "user/v1/oidc.proto",
+}
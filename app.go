@@ -0,0 +1,21 @@
+package main
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/go-pkg/customserver"
+	"github.com/dadaxiaoxiao/user/internal/job"
+	"github.com/dadaxiaoxiao/user/pkg/registry"
+)
+
+// App 在 customserver.App 的基础上加上服务注册
+type App struct {
+	*customserver.App
+	Registry registry.Registry
+	// 需要注册的实例
+	Instances []registry.ServiceInstance
+	// DeletionJob 删除注销用户的定时任务
+	DeletionJob *job.AccountDeletionJob
+	// ExportJob 执行个人数据导出的任务
+	ExportJob *job.DataExportJob
+	Logger    accesslog.Logger
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// 个人数据导出的状态
+const (
+	DataExportPending   = "pending"
+	DataExportRunning   = "running"
+	DataExportSucceeded = "succeeded"
+	DataExportFailed    = "failed"
+)
+
+// DataExport 一次个人数据导出，异步生成压缩包，前端轮询状态，成功之后拿下载链接
+type DataExport struct {
+	Id     string
+	Uid    int64
+	Status string
+	// Size 压缩包的字节数
+	Size  int64
+	Ctime time.Time
+	Utime time.Time
+}
+
+// Finished 是否已经结束，结束之后可以重新导出
+func (e DataExport) Finished() bool {
+	return e.Status == DataExportSucceeded || e.Status == DataExportFailed
+}
@@ -0,0 +1,72 @@
+package domain
+
+import "time"
+
+// 第三方登录的提供方
+const (
+	ProviderWechat   = "wechat"
+	ProviderGithub   = "github"
+	ProviderDingTalk = "dingtalk"
+	// ProviderWechatMini 微信小程序，openid 和网站应用的不一样，unionid 一样
+	ProviderWechatMini = "wechat_mini"
+)
+
+// WechatProviders 同一个微信开放平台下的提供方，可以按 unionid 找到同一个用户
+var WechatProviders = []string{ProviderWechat, ProviderWechatMini}
+
+// UserIdentity 绑定在用户上的第三方账号，(Provider, Subject) 全局唯一
+type UserIdentity struct {
+	Id       int64
+	Uid      int64
+	Provider string
+	// Subject 第三方账号在提供方下的唯一 id，比如微信的 openid、GitHub 的 user id
+	Subject string
+	// UnionId 微信、钉钉同一个开放平台下的统一 id，其它提供方为空
+	UnionId  string
+	Nickname string
+	Avatar   string
+	Email    string
+	Ctime    time.Time
+}
+
+// Bindings 用户可以用来登录的方式
+type Bindings struct {
+	Phone      string
+	Email      string
+	Identities []UserIdentity
+}
+
+// Count 登录方式的数量，手机号可以短信登录，邮箱可以找回密码
+func (b Bindings) Count() int {
+	cnt := len(b.Identities)
+	if b.Phone != "" {
+		cnt++
+	}
+	if b.Email != "" {
+		cnt++
+	}
+	return cnt
+}
+
+// 发起第三方授权的目的
+const (
+	OAuth2IntentLogin = "login"
+	OAuth2IntentBind  = "bind"
+	// OAuth2IntentTicket 登录成功之后跳回前端，前端用一次性票据换取 token
+	OAuth2IntentTicket = "ticket"
+)
+
+// OAuth2State 发起第三方授权的时候记录下来，回调的时候根据 state 取回
+type OAuth2State struct {
+	Provider string
+	Intent   string
+	// Uid 和 Ssid 只有绑定和票据的时候才有，回调的时候没有 token
+	Uid  int64
+	Ssid string
+	// CodeVerifier 提供方支持 PKCE 的时候才有
+	CodeVerifier string
+	// RedirectURI 成功之后跳转回去的地址，已经校验过白名单，为空的时候直接返回 JSON
+	RedirectURI string
+	// Nonce 同时写在发起授权的浏览器的 cookie 里面，回调的时候必须一致，防止把别人的授权结果塞给当前用户
+	Nonce string
+}
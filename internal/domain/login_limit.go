@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// LoginLimitRule 密码登录失败之后的限制规则
+type LoginLimitRule struct {
+	// FreeAttempts 前面几次失败不限制
+	FreeAttempts int
+	// BaseDelay 超过之后，每多失败一次等待时间翻倍
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Window 失败次数的统计窗口，窗口内没有失败就重新计数
+	Window time.Duration
+	// LockThreshold 失败次数达到之后锁定，0 代表不锁定
+	LockThreshold int
+	LockDuration  time.Duration
+}
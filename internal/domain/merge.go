@@ -0,0 +1,20 @@
+package domain
+
+// MergePolicy 合并用户的时候，两边都有值的资料字段怎么处理
+type MergePolicy string
+
+const (
+	// MergeKeepTarget 保留 target 的值，target 为空的时候才用 source 的值
+	MergeKeepTarget MergePolicy = "target"
+	// MergePreferSource source 有值就用 source 的值
+	MergePreferSource MergePolicy = "source"
+)
+
+// MergeRules 资料字段的合并规则，零值等同于 MergeKeepTarget
+// 邮箱、手机号、微信这些登录方式不受规则影响，两边不一致的时候拒绝合并
+type MergeRules struct {
+	Nickname MergePolicy
+	Birthday MergePolicy
+	AboutMe  MergePolicy
+	Avatar   MergePolicy
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// OAuthClient 接入单点登录的应用，也就是 OIDC 里面的 RP
+type OAuthClient struct {
+	Id       int64
+	ClientId string
+	// SecretHash bcrypt 之后的 client_secret，公开客户端没有
+	SecretHash   string
+	Name         string
+	RedirectURIs []string
+	// Public 公开客户端（SPA、App），不能保存 secret，必须使用 PKCE
+	Public bool
+	Ctime  time.Time
+}
+
+// AllowRedirect 回调地址必须完全匹配
+func (c OAuthClient) AllowRedirect(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// AuthCode 授权码，只能使用一次
+type AuthCode struct {
+	ClientId    string
+	Uid         int64
+	RedirectURI string
+	Scopes      []string
+	Nonce       string
+	// PKCE
+	CodeChallenge       string
+	CodeChallengeMethod string
+	AuthTime            time.Time
+}
+
+// OAuthConsent 用户同意授权给某个应用的范围
+type OAuthConsent struct {
+	Uid      int64
+	ClientId string
+	Scopes   []string
+}
+
+// Covers 是否已经同意了 scopes 里面的全部权限
+func (c OAuthConsent) Covers(scopes []string) bool {
+	for _, s := range scopes {
+		if !slices.Contains(c.Scopes, s) {
+			return false
+		}
+	}
+	return true
+}
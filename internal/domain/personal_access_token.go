@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"net/http"
+	"slices"
+	"time"
+)
+
+// 个人访问令牌的权限
+const (
+	// PATScopeRead 只能调用 GET 接口
+	PATScopeRead = "read"
+	// PATScopeWrite 可以调用所有接口
+	PATScopeWrite = "write"
+)
+
+var PATScopes = []string{PATScopeRead, PATScopeWrite}
+
+// PersonalAccessToken 用户自己创建的访问令牌，给脚本调用接口用，不绑定登录设备
+type PersonalAccessToken struct {
+	Id   int64
+	Uid  int64
+	Name string
+	// TokenHash token 的 SHA-256，token 本身只在创建的时候返回一次
+	TokenHash string
+	// Prefix token 的前几位，方便用户在列表里面认出是哪一个
+	Prefix     string
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	Ctime      time.Time
+}
+
+func (t PersonalAccessToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// Allow 是否可以用来发起 method 请求
+func (t PersonalAccessToken) Allow(method string) bool {
+	if slices.Contains(t.Scopes, PATScopeWrite) {
+		return true
+	}
+	return slices.Contains(t.Scopes, PATScopeRead) &&
+		(method == http.MethodGet || method == http.MethodHead)
+}
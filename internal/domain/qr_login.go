@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// 扫码登录的状态，票据过期之后 Redis 里面就没有了，查询的时候返回 QRLoginExpired
+const (
+	QRLoginPending   = "pending"
+	QRLoginScanned   = "scanned"
+	QRLoginConfirmed = "confirmed"
+	QRLoginExpired   = "expired"
+)
+
+// QRLogin 桌面端发起的扫码登录，Ticket 放在二维码里面，Secret 只有桌面端知道，
+// 轮询的时候两个都要带上，防止别人拍下二维码之后抢先拿到 token
+type QRLogin struct {
+	Ticket string
+	Secret string
+	Status string
+	// Uid 扫码的用户，pending 的时候为 0
+	Uid    int64
+	Device QRLoginDevice
+	Ctime  time.Time
+}
+
+// QRLoginDevice 发起登录的桌面端，扫码之后展示给用户确认
+type QRLoginDevice struct {
+	UserAgent string
+	IP        string
+}
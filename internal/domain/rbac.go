@@ -0,0 +1,44 @@
+package domain
+
+import "slices"
+
+// 权限的编码，写在代码里面，角色只能从这里面选
+const (
+	// PermissionRBACManage 管理角色和给用户分配角色
+	PermissionRBACManage = "rbac:manage"
+	// PermissionUserRead 查询用户信息
+	PermissionUserRead = "user:read"
+	// PermissionUserManage 冻结、解冻、合并用户，强制下线和解锁登录
+	PermissionUserManage = "user:manage"
+	// PermissionOIDCManage 注册接入单点登录的应用
+	PermissionOIDCManage = "oidc:manage"
+)
+
+// Permission 权限，Code 是接口上校验的值，Description 给管理后台展示
+type Permission struct {
+	Code        string
+	Description string
+}
+
+// Permissions 全部的权限
+var Permissions = []Permission{
+	{Code: PermissionRBACManage, Description: "管理角色和用户的角色"},
+	{Code: PermissionUserRead, Description: "查询用户信息"},
+	{Code: PermissionUserManage, Description: "冻结、解冻、合并用户，强制下线和解锁登录"},
+	{Code: PermissionOIDCManage, Description: "注册接入单点登录的应用"},
+}
+
+// ValidPermission 是否为已经定义的权限
+func ValidPermission(code string) bool {
+	return slices.ContainsFunc(Permissions, func(p Permission) bool {
+		return p.Code == code
+	})
+}
+
+// Role 角色，一个角色包含多个权限，一个用户可以有多个角色
+type Role struct {
+	Id          int64
+	Name        string
+	Description string
+	Permissions []string
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// TOTP 两步验证
+type TOTP struct {
+	Uid int64
+	// Secret base32 编码的密钥，明文
+	Secret string
+	// RecoveryCodes 恢复码的 sha256，不保存明文
+	RecoveryCodes []string
+	// LastCounter 最后一次使用的验证码计数器
+	LastCounter int64
+	// EnabledAt 启用时间，零值代表还没有确认
+	EnabledAt time.Time
+}
+
+// Enabled 是否已经启用
+func (t TOTP) Enabled() bool {
+	return !t.EnabledAt.IsZero()
+}
+
+// TwoFactorChallenge 第一步登录通过之后，等待第二步验证的登录
+type TwoFactorChallenge struct {
+	Uid int64
+	// Method 第一步的登录方式，第二步通过之后按这个记录登录态和登录记录
+	Method string
+	// Account 第一步登录的账号，密码登录的时候是邮箱
+	// 第二步验证通过之后才清掉它的密码错误次数
+	Account string
+}
@@ -10,8 +10,44 @@ type User struct {
 	Phone    string
 	Password string
 	AboutMe  string
+	// Avatar 头像地址，第三方登录的时候从第三方同步
+	Avatar   string
 	Ctime    time.Time
 	Birthday time.Time
+	// 邮箱验证时间，零值代表还没有验证
+	VerifiedAt time.Time
 	// 如果将来接入 DingDingInfo，里面有同名字段 UnionID，所以不使用组合
 	WechatInfo WechatInfo
+	// FrozenAt 被管理员冻结的时间，零值代表没有冻结，冻结之后不能登录
+	FrozenAt     time.Time
+	FrozenReason string
+	// DeleteAt 申请注销之后冷静期结束的时间，零值代表没有申请注销
+	DeleteAt time.Time
+	// ErasedAt 注销之后删除个人信息的时间，删除之后只剩下 id
+	ErasedAt time.Time
+}
+
+// Frozen 是否被冻结
+func (u User) Frozen() bool {
+	return !u.FrozenAt.IsZero()
+}
+
+// Erased 是否已经注销并删除了个人信息
+func (u User) Erased() bool {
+	return !u.ErasedAt.IsZero()
+}
+
+// UserQuery 管理后台查询用户，条件之间是 AND，零值代表不限制
+// 按 id 递增的游标分页
+type UserQuery struct {
+	Email        string
+	Phone        string
+	WechatOpenId string
+	MinId        int64
+	MaxId        int64
+	CtimeStart   time.Time
+	CtimeEnd     time.Time
+	// Cursor 上一页最后一个用户的 id，第一页为 0
+	Cursor int64
+	Limit  int
 }
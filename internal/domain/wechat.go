@@ -1,6 +1,35 @@
 package domain
 
+import "time"
+
 type WechatInfo struct {
 	OpenId  string
 	UnionId string
 }
+
+// WechatToken 微信网页授权的 token，access_token 2 小时过期，refresh_token 30 天过期
+type WechatToken struct {
+	OpenId       string
+	UnionId      string
+	AccessToken  string
+	RefreshToken string
+	Scope        string
+	// ExpiresAt access_token 的过期时间
+	ExpiresAt time.Time
+}
+
+// WechatProfile sns/userinfo 返回的个人信息
+type WechatProfile struct {
+	OpenId   string
+	UnionId  string
+	Nickname string
+	// Avatar 头像地址，用户换了头像之后原来的地址会失效
+	Avatar string
+}
+
+// WechatMiniSession 小程序 jscode2session 的结果，SessionKey 不能返回给前端
+type WechatMiniSession struct {
+	OpenId     string
+	UnionId    string
+	SessionKey string
+}
@@ -12,6 +12,20 @@ const (
 	UserInternalServerError = 501001
 	// UserInvalidOrPassword 用户不存在或者密码错误
 	UserInvalidOrPassword = 401002
+	// UserEmailNotVerified 邮箱还没有验证，不允许登录
+	UserEmailNotVerified = 401003
+	// UserSecondFactorRequired 密码正确，还需要两步验证
+	UserSecondFactorRequired = 401004
+	// UserLoginLocked 密码错误次数太多，账号被临时锁定，Data 里面是剩余的秒数
+	UserLoginLocked = 401005
+	// UserLoginTooFrequent 密码错误之后需要等待一段时间再试，Data 里面是剩余的秒数
+	UserLoginTooFrequent = 401006
+	// UserBindConflict 手机号、邮箱或者第三方账号已经绑定在其它用户上
+	UserBindConflict = 401007
+	// UserUnbindLastMethod 不能解绑最后一种登录方式
+	UserUnbindLastMethod = 401008
+	// UserFrozen 账号已经被管理员冻结
+	UserFrozen = 401009
 )
 
 const (
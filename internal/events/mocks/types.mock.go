@@ -0,0 +1,69 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./types.go -package=evtmocks -destination=mocks/types.mock.go Producer
+//
+
+// Package evtmocks is a generated GoMock package.
+package evtmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	events "github.com/dadaxiaoxiao/user/internal/events"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProducer is a mock of Producer interface.
+type MockProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockProducerMockRecorder
+}
+
+// MockProducerMockRecorder is the mock recorder for MockProducer.
+type MockProducerMockRecorder struct {
+	mock *MockProducer
+}
+
+// NewMockProducer creates a new mock instance.
+func NewMockProducer(ctrl *gomock.Controller) *MockProducer {
+	mock := &MockProducer{ctrl: ctrl}
+	mock.recorder = &MockProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProducer) EXPECT() *MockProducerMockRecorder {
+	return m.recorder
+}
+
+// ProduceUserDeletedEvent mocks base method.
+func (m *MockProducer) ProduceUserDeletedEvent(ctx context.Context, evt events.UserDeletedEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProduceUserDeletedEvent", ctx, evt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProduceUserDeletedEvent indicates an expected call of ProduceUserDeletedEvent.
+func (mr *MockProducerMockRecorder) ProduceUserDeletedEvent(ctx, evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProduceUserDeletedEvent", reflect.TypeOf((*MockProducer)(nil).ProduceUserDeletedEvent), ctx, evt)
+}
+
+// ProduceUserMergedEvent mocks base method.
+func (m *MockProducer) ProduceUserMergedEvent(ctx context.Context, evt events.UserMergedEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProduceUserMergedEvent", ctx, evt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProduceUserMergedEvent indicates an expected call of ProduceUserMergedEvent.
+func (mr *MockProducerMockRecorder) ProduceUserMergedEvent(ctx, evt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProduceUserMergedEvent", reflect.TypeOf((*MockProducer)(nil).ProduceUserMergedEvent), ctx, evt)
+}
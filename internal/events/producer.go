@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamProducer 事件写入 Redis Stream，消费方用消费者组读取
+type RedisStreamProducer struct {
+	client redis.Cmdable
+}
+
+func NewRedisStreamProducer(client redis.Cmdable) Producer {
+	return &RedisStreamProducer{
+		client: client,
+	}
+}
+
+func (p *RedisStreamProducer) ProduceUserMergedEvent(ctx context.Context, evt UserMergedEvent) error {
+	val, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: StreamUserMerged,
+		Values: map[string]any{
+			"data": val,
+		},
+	}).Err()
+}
+
+func (p *RedisStreamProducer) ProduceUserDeletedEvent(ctx context.Context, evt UserDeletedEvent) error {
+	val, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	return p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: StreamUserDeleted,
+		Values: map[string]any{
+			"data": val,
+		},
+	}).Err()
+}
@@ -0,0 +1,32 @@
+package events
+
+import "context"
+
+const (
+	// StreamUserMerged 用户合并事件的 Redis Stream
+	StreamUserMerged = "events:user_merged"
+	// StreamUserDeleted 用户注销事件的 Redis Stream
+	StreamUserDeleted = "events:user_deleted"
+)
+
+// UserMergedEvent 用户 SourceId 已经合并到 TargetId，
+// 下游服务需要把 SourceId 名下的数据迁移到 TargetId。可能重复投递，消费方需要幂等
+type UserMergedEvent struct {
+	SourceId int64 `json:"source_id"`
+	TargetId int64 `json:"target_id"`
+	// 毫秒时间戳
+	Ctime int64 `json:"ctime"`
+}
+
+// UserDeletedEvent 用户注销之后个人信息已经删除，下游服务需要删除 Uid 名下的个人数据。可能重复投递，消费方需要幂等
+type UserDeletedEvent struct {
+	Uid int64 `json:"uid"`
+	// 毫秒时间戳
+	Ctime int64 `json:"ctime"`
+}
+
+//go:generate mockgen.exe -source=./types.go -package=evtmocks -destination=mocks/types.mock.go Producer
+type Producer interface {
+	ProduceUserMergedEvent(ctx context.Context, evt UserMergedEvent) error
+	ProduceUserDeletedEvent(ctx context.Context, evt UserDeletedEvent) error
+}
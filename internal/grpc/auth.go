@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"slices"
+	"strings"
+)
+
+// AuthInterceptorBuilder 管理类的 RPC 需要带上管理员的 access token，并且有对应的权限
+// 和 HTTP 的 RBACMiddlewareBuilder 用同一套权限，没有配置的 RPC 不校验
+type AuthInterceptorBuilder struct {
+	wtHdl myjwt.Handler
+	svc   service.RBACService
+	// rules key 是 RPC 的完整方法名，例如 /user.v1.UserService/MergeUsers
+	rules map[string][]string
+}
+
+// NewAuthInterceptorBuilder 返回实例
+func NewAuthInterceptorBuilder(wtHdl myjwt.Handler, svc service.RBACService) *AuthInterceptorBuilder {
+	return &AuthInterceptorBuilder{
+		wtHdl: wtHdl,
+		svc:   svc,
+		rules: make(map[string][]string),
+	}
+}
+
+// Require 调用 fullMethod 需要 permission，同一个方法配置多次的时候需要全部满足
+func (b *AuthInterceptorBuilder) Require(fullMethod string, permission string) *AuthInterceptorBuilder {
+	b.rules[fullMethod] = append(b.rules[fullMethod], permission)
+	return b
+}
+
+// Build 生成拦截器
+func (b *AuthInterceptorBuilder) Build() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		required := b.rules[info.FullMethod]
+		if len(required) == 0 {
+			return handler(ctx, req)
+		}
+		uid, err := b.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		perms, err := b.svc.Permissions(ctx, uid)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "系统错误")
+		}
+		for _, p := range required {
+			if !slices.Contains(perms, p) {
+				return nil, status.Error(codes.PermissionDenied, "没有权限")
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authenticate 从 metadata 的 authorization: Bearer xxx 里面拿到用户
+// 只接受用户中心登录签发的 access token，第三方应用通过 /oidc/token 拿到的 token 不能调用管理接口
+func (b *AuthInterceptorBuilder) authenticate(ctx context.Context) (int64, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	vals := md.Get("authorization")
+	if len(vals) == 0 {
+		return 0, status.Error(codes.Unauthenticated, "没有登录")
+	}
+	token, ok := strings.CutPrefix(vals[0], "Bearer ")
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, "没有登录")
+	}
+	res, err := b.wtHdl.Introspect(ctx, token)
+	if err != nil {
+		return 0, status.Error(codes.Internal, "系统错误")
+	}
+	if !res.Active || res.Ssid == "" || res.ClientId != "" {
+		return 0, status.Error(codes.Unauthenticated, "没有登录")
+	}
+	return res.Uid, nil
+}
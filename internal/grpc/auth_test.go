@@ -0,0 +1,153 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"github.com/alicebob/miniredis/v2"
+	userv1 "github.com/dadaxiaoxiao/user/api/proto/gen/user/v1"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	svcmocks "github.com/dadaxiaoxiao/user/internal/service/mocks"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestAuthInterceptorBuilder_Build(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ks, err := myjwt.NewKeySet("k1", myjwt.Key{Kid: "k1", Private: priv, Public: pub})
+	require.NoError(t, err)
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	adminToken, err := ks.Sign(myjwt.TypeAccessToken, myjwt.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: exp},
+		Uid:              1,
+		Ssid:             "ssid",
+	})
+	require.NoError(t, err)
+	revokedToken, err := ks.Sign(myjwt.TypeAccessToken, myjwt.UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: exp},
+		Uid:              1,
+		Ssid:             "revoked",
+	})
+	require.NoError(t, err)
+	// 第三方应用通过 /oidc/token 拿到的 token
+	oidcToken, err := ks.Sign(myjwt.TypeAccessToken, jwt.MapClaims{
+		"sub":       "1",
+		"exp":       exp.Unix(),
+		"client_id": "rp",
+		"scope":     "openid",
+	})
+	require.NoError(t, err)
+
+	testCase := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) service.RBACService
+		method string
+		token  string
+
+		wantCode codes.Code
+		// 是否调用到了业务方法
+		wantCalled bool
+	}{
+		{
+			name: "有权限",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				svc := svcmocks.NewMockRBACService(ctrl)
+				svc.EXPECT().Permissions(gomock.Any(), int64(1)).
+					Return([]string{domain.PermissionUserRead, domain.PermissionUserManage}, nil)
+				return svc
+			},
+			method:     userv1.UserService_MergeUsers_FullMethodName,
+			token:      "Bearer " + adminToken,
+			wantCode:   codes.OK,
+			wantCalled: true,
+		},
+		{
+			name: "没有权限",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				svc := svcmocks.NewMockRBACService(ctrl)
+				svc.EXPECT().Permissions(gomock.Any(), int64(1)).
+					Return([]string{domain.PermissionUserRead}, nil)
+				return svc
+			},
+			method:   userv1.UserService_MergeUsers_FullMethodName,
+			token:    "Bearer " + adminToken,
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "没有带 token",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				return svcmocks.NewMockRBACService(ctrl)
+			},
+			method:   userv1.OIDCClientService_RegisterOIDCClient_FullMethodName,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "已经退出登录",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				return svcmocks.NewMockRBACService(ctrl)
+			},
+			method:   userv1.UserService_UnlockLogin_FullMethodName,
+			token:    "Bearer " + revokedToken,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "第三方应用的 token",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				return svcmocks.NewMockRBACService(ctrl)
+			},
+			method:   userv1.UserService_UnlockLogin_FullMethodName,
+			token:    "Bearer " + oidcToken,
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "不需要权限的 RPC",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				return svcmocks.NewMockRBACService(ctrl)
+			},
+			method:     userv1.UserService_Profile_FullMethodName,
+			wantCode:   codes.OK,
+			wantCalled: true,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mr := miniredis.RunT(t)
+			mr.Set("users:ssid:revoked", "")
+			cmd := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer cmd.Close()
+			wtHdl := myjwt.NewRedisJWTHandler(cmd, ks, myjwt.SessionConfig{})
+
+			interceptor := NewAuthInterceptorBuilder(wtHdl, tc.mock(ctrl)).
+				Require(userv1.UserService_UnlockLogin_FullMethodName, domain.PermissionUserManage).
+				Require(userv1.UserService_MergeUsers_FullMethodName, domain.PermissionUserManage).
+				Require(userv1.OIDCClientService_RegisterOIDCClient_FullMethodName, domain.PermissionOIDCManage).
+				Build()
+			ctx := context.Background()
+			if tc.token != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tc.token))
+			}
+			called := false
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method},
+				func(ctx context.Context, req any) (any, error) {
+					called = true
+					return nil, nil
+				})
+			assert.Equal(t, tc.wantCode, status.Code(err))
+			assert.Equal(t, tc.wantCalled, called)
+		})
+	}
+}
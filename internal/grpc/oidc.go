@@ -0,0 +1,44 @@
+package grpc
+
+import (
+	"context"
+	userv1 "github.com/dadaxiaoxiao/user/api/proto/gen/user/v1"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// OIDCClientServiceServer 管理接入单点登录的应用
+type OIDCClientServiceServer struct {
+	userv1.UnimplementedOIDCClientServiceServer
+	svc service.OIDCService
+}
+
+// NewOIDCClientServiceServer 新建 OIDCClientServiceServer
+func NewOIDCClientServiceServer(svc service.OIDCService) *OIDCClientServiceServer {
+	return &OIDCClientServiceServer{
+		svc: svc,
+	}
+}
+
+// Register 注册到 grpc.Server
+func (o *OIDCClientServiceServer) Register(server *grpc.Server) {
+	userv1.RegisterOIDCClientServiceServer(server, o)
+}
+
+// RegisterOIDCClient 注册应用
+func (o *OIDCClientServiceServer) RegisterOIDCClient(ctx context.Context, req *userv1.RegisterOIDCClientRequest) (*userv1.RegisterOIDCClientResponse, error) {
+	client, secret, err := o.svc.RegisterClient(ctx, req.GetName(), req.GetRedirectUris(), req.GetPublic())
+	switch err {
+	case nil:
+		return &userv1.RegisterOIDCClientResponse{
+			ClientId:     client.ClientId,
+			ClientSecret: secret,
+		}, nil
+	case service.ErrOIDCInvalidClientMetadata:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return nil, toStatusErr(err)
+	}
+}
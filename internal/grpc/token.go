@@ -0,0 +1,63 @@
+package grpc
+
+import (
+	"context"
+	userv1 "github.com/dadaxiaoxiao/user/api/proto/gen/user/v1"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// TokenServiceServer 资源服务器校验 token
+type TokenServiceServer struct {
+	userv1.UnimplementedTokenServiceServer
+	oidcSvc service.OIDCService
+	wtHdl   myjwt.Handler
+}
+
+// NewTokenServiceServer 新建 TokenServiceServer
+func NewTokenServiceServer(oidcSvc service.OIDCService, wtHdl myjwt.Handler) *TokenServiceServer {
+	return &TokenServiceServer{
+		oidcSvc: oidcSvc,
+		wtHdl:   wtHdl,
+	}
+}
+
+// Register 注册到 grpc.Server
+func (t *TokenServiceServer) Register(server *grpc.Server) {
+	userv1.RegisterTokenServiceServer(server, t)
+}
+
+// IntrospectToken 和 HTTP 的 /oidc/introspect 共用一份缓存
+func (t *TokenServiceServer) IntrospectToken(ctx context.Context, req *userv1.IntrospectTokenRequest) (*userv1.IntrospectTokenResponse, error) {
+	_, err := t.oidcSvc.AuthenticateClient(ctx, req.GetClientId(), req.GetClientSecret())
+	switch err {
+	case nil:
+	case service.ErrOIDCInvalidClient:
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	default:
+		return nil, toStatusErr(err)
+	}
+	res, err := t.wtHdl.Introspect(ctx, req.GetToken())
+	if err != nil {
+		return nil, toStatusErr(err)
+	}
+	if !res.Active {
+		return &userv1.IntrospectTokenResponse{}, nil
+	}
+	resp := &userv1.IntrospectTokenResponse{
+		Active:   true,
+		Uid:      res.Uid,
+		Ssid:     res.Ssid,
+		Exp:      timestamppb.New(res.ExpiresAt),
+		ClientId: res.ClientId,
+		Scopes:   res.Scopes,
+	}
+	if !res.IssuedAt.IsZero() {
+		resp.Iat = timestamppb.New(res.IssuedAt)
+	}
+	return resp, nil
+}
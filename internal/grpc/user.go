@@ -0,0 +1,226 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	userv1 "github.com/dadaxiaoxiao/user/api/proto/gen/user/v1"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	regexp "github.com/dlclark/regexp2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// 和 HTTP 注册接口的校验规则保持一致
+const (
+	emailRegexPattern    = "^\\w+([-+.]\\w+)*@\\w+([-.]\\w+)*\\.\\w+([-.]\\w+)*$"
+	passwordRegexPattern = `^(?=.*[A-Za-z])(?=.*\d)(?=.*[$@$!%*#?&])[A-Za-z\d$@$!%*#?&]{8,72}$`
+)
+
+// UserServiceServer 用户服务的 gRPC 适配层
+// 只做 DTO 转换和错误码映射，业务逻辑都在 service.UserService 里面
+type UserServiceServer struct {
+	userv1.UnimplementedUserServiceServer
+	svc              service.UserService
+	emailCodeSvc     service.EmailCodeService
+	limitSvc         service.LoginLimitService
+	mergeSvc         service.MergeService
+	wtHdl            myjwt.Handler
+	emailRegexExp    *regexp.Regexp
+	passwordRegexExp *regexp.Regexp
+}
+
+// NewUserServiceServer 新建 UserServiceServer
+func NewUserServiceServer(svc service.UserService, emailCodeSvc service.EmailCodeService,
+	limitSvc service.LoginLimitService, mergeSvc service.MergeService, wtHdl myjwt.Handler) *UserServiceServer {
+	return &UserServiceServer{
+		svc:              svc,
+		emailCodeSvc:     emailCodeSvc,
+		limitSvc:         limitSvc,
+		mergeSvc:         mergeSvc,
+		wtHdl:            wtHdl,
+		emailRegexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
+		passwordRegexExp: regexp.MustCompile(passwordRegexPattern, regexp.None),
+	}
+}
+
+// Register 注册到 grpc.Server
+func (u *UserServiceServer) Register(server *grpc.Server) {
+	userv1.RegisterUserServiceServer(server, u)
+}
+
+// Signup 注册，和 HTTP 接口一样校验邮箱和密码的格式，注册成功之后发送邮箱验证码
+// 账号已经创建了，验证码发送失败不返回错误，通过 verification_sent 告诉调用方
+func (u *UserServiceServer) Signup(ctx context.Context, req *userv1.SignupRequest) (*userv1.SignupResponse, error) {
+	user := u.toDomain(req.GetUser())
+	isEmail, err := u.emailRegexExp.MatchString(user.Email)
+	if err != nil {
+		return nil, toStatusErr(err)
+	}
+	if !isEmail {
+		return nil, status.Error(codes.InvalidArgument, "邮箱不正确")
+	}
+	isPassword, err := u.passwordRegexExp.MatchString(user.Password)
+	if err != nil {
+		return nil, toStatusErr(err)
+	}
+	if !isPassword {
+		return nil, status.Error(codes.InvalidArgument, "密码必须包含数字、特殊字符，并且长度不能小于 8 位")
+	}
+	err = u.svc.Signup(ctx, user)
+	if err != nil {
+		return nil, toStatusErr(err)
+	}
+	// 验证之前不能登录
+	err = u.emailCodeSvc.Send(ctx, service.EmailVerifyBiz, user.Email)
+	return &userv1.SignupResponse{VerificationSent: err == nil}, nil
+}
+
+// FindOrCreate 根据手机号查找或新建
+func (u *UserServiceServer) FindOrCreate(ctx context.Context, req *userv1.FindOrCreateRequest) (*userv1.FindOrCreateResponse, error) {
+	user, err := u.svc.FindOrCreate(ctx, req.GetPhone())
+	if err != nil {
+		return nil, toStatusErr(err)
+	}
+	return &userv1.FindOrCreateResponse{
+		User: u.toDTO(user),
+	}, nil
+}
+
+// Login 邮箱+密码登录，和 HTTP 接口共用密码错误次数的限制
+func (u *UserServiceServer) Login(ctx context.Context, req *userv1.LoginRequest) (*userv1.LoginResponse, error) {
+	_, err := u.limitSvc.Allow(ctx, req.GetEmail(), req.GetIp())
+	if err != nil {
+		return nil, toStatusErr(err)
+	}
+	user, err := u.svc.Login(ctx, req.GetEmail(), req.GetPassword())
+	if err == service.ErrInvalidUserOrPassword {
+		_, ferr := u.limitSvc.Fail(ctx, req.GetEmail(), req.GetIp())
+		if ferr == service.ErrLoginLocked {
+			return nil, toStatusErr(ferr)
+		}
+		return nil, toStatusErr(err)
+	}
+	if err != nil && err != service.ErrEmailNotVerified && err != service.ErrUserFrozen {
+		return nil, toStatusErr(err)
+	}
+	// 密码是对的，清掉失败次数，这里出错不影响登录
+	_ = u.limitSvc.Succeed(ctx, req.GetEmail())
+	if err != nil {
+		return nil, toStatusErr(err)
+	}
+	return &userv1.LoginResponse{
+		User: u.toDTO(user),
+	}, nil
+}
+
+// UpdateNonSensitiveInfo 修改非敏感信息
+func (u *UserServiceServer) UpdateNonSensitiveInfo(ctx context.Context, req *userv1.UpdateNonSensitiveInfoRequest) (*userv1.UpdateNonSensitiveInfoResponse, error) {
+	err := u.svc.UpdateNonSensitiveInfo(ctx, u.toDomain(req.GetUser()))
+	return &userv1.UpdateNonSensitiveInfoResponse{}, toStatusErr(err)
+}
+
+// Profile 查询个人信息
+func (u *UserServiceServer) Profile(ctx context.Context, req *userv1.ProfileRequest) (*userv1.ProfileResponse, error) {
+	user, err := u.svc.Profile(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatusErr(err)
+	}
+	return &userv1.ProfileResponse{
+		User: u.toDTO(user),
+	}, nil
+}
+
+// UnlockLogin 解锁账号
+func (u *UserServiceServer) UnlockLogin(ctx context.Context, req *userv1.UnlockLoginRequest) (*userv1.UnlockLoginResponse, error) {
+	err := u.limitSvc.Unlock(ctx, req.GetEmail())
+	return &userv1.UnlockLoginResponse{}, toStatusErr(err)
+}
+
+// MergeUsers 合并用户，合并提交之后 source 的登录态全部失效，重复合并的时候也会再撤销一次
+func (u *UserServiceServer) MergeUsers(ctx context.Context, req *userv1.MergeUsersRequest) (*userv1.MergeUsersResponse, error) {
+	err := u.mergeSvc.Merge(ctx, req.GetSourceId(), req.GetTargetId())
+	if err == nil {
+		err = u.wtHdl.RevokeSessions(ctx, req.GetSourceId(), "")
+	}
+	return &userv1.MergeUsersResponse{}, toStatusErr(err)
+}
+
+// toStatusErr 把业务错误映射为 gRPC 状态码
+func toStatusErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, service.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrUserDuplicateEmail):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrInvalidUserOrPassword):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, service.ErrEmailNotVerified):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrUserFrozen):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, service.ErrLoginLocked), errors.Is(err, service.ErrLoginTooFrequent):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, service.ErrMergeSelf):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrMergeConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		// 系统错误不把细节暴露给调用方
+		return status.Error(codes.Internal, "系统错误")
+	}
+}
+
+func (u *UserServiceServer) toDomain(user *userv1.User) domain.User {
+	if user == nil {
+		return domain.User{}
+	}
+	res := domain.User{
+		Id:       user.GetId(),
+		Email:    user.GetEmail(),
+		Nickname: user.GetNickname(),
+		Password: user.GetPassword(),
+		Phone:    user.GetPhone(),
+		AboutMe:  user.GetAboutMe(),
+		WechatInfo: domain.WechatInfo{
+			OpenId:  user.GetWechatInfo().GetOpenId(),
+			UnionId: user.GetWechatInfo().GetUnionId(),
+		},
+	}
+	if user.GetBirthday() != nil {
+		res.Birthday = user.GetBirthday().AsTime().In(time.Local)
+	}
+	if user.GetCtime() != nil {
+		res.Ctime = user.GetCtime().AsTime().In(time.Local)
+	}
+	return res
+}
+
+// toDTO 转换为 gRPC 对象，密码不对外返回
+func (u *UserServiceServer) toDTO(user domain.User) *userv1.User {
+	res := &userv1.User{
+		Id:       user.Id,
+		Email:    user.Email,
+		Nickname: user.Nickname,
+		Phone:    user.Phone,
+		AboutMe:  user.AboutMe,
+		Avatar:   user.Avatar,
+		WechatInfo: &userv1.WechatInfo{
+			OpenId:  user.WechatInfo.OpenId,
+			UnionId: user.WechatInfo.UnionId,
+		},
+	}
+	if !user.Birthday.IsZero() {
+		res.Birthday = timestamppb.New(user.Birthday)
+	}
+	if !user.Ctime.IsZero() {
+		res.Ctime = timestamppb.New(user.Ctime)
+	}
+	return res
+}
@@ -0,0 +1,284 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	userv1 "github.com/dadaxiaoxiao/user/api/proto/gen/user/v1"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	svcmocks "github.com/dadaxiaoxiao/user/internal/service/mocks"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"testing"
+	"time"
+)
+
+func TestUserServiceServer_Login(t *testing.T) {
+	now := time.UnixMilli(time.Now().UnixMilli())
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.LoginLimitService)
+		// 输入
+		req *userv1.LoginRequest
+		// 输出
+		wantResp *userv1.LoginResponse
+		wantCode codes.Code
+	}{
+		{
+			name: "登录成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginLimitService) {
+				limitSvc := svcmocks.NewMockLoginLimitService(ctrl)
+				limitSvc.EXPECT().Allow(gomock.Any(), "1426325504@qq.com", "").Return(time.Duration(0), nil)
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().Login(gomock.Any(), "1426325504@qq.com", "hellword@123").
+					Return(domain.User{
+						Id:       1,
+						Email:    "1426325504@qq.com",
+						Password: "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+						Nickname: "yeqin",
+						Ctime:    now,
+					}, nil)
+				limitSvc.EXPECT().Succeed(gomock.Any(), "1426325504@qq.com").Return(nil)
+				return svc, limitSvc
+			},
+			req: &userv1.LoginRequest{
+				Email:    "1426325504@qq.com",
+				Password: "hellword@123",
+			},
+			// 密码不会返回
+			wantResp: &userv1.LoginResponse{
+				User: &userv1.User{
+					Id:         1,
+					Email:      "1426325504@qq.com",
+					Nickname:   "yeqin",
+					Ctime:      timestamppb.New(now),
+					WechatInfo: &userv1.WechatInfo{},
+				},
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name: "用户名或密码不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginLimitService) {
+				limitSvc := svcmocks.NewMockLoginLimitService(ctrl)
+				limitSvc.EXPECT().Allow(gomock.Any(), "1426325504@qq.com", "").Return(time.Duration(0), nil)
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().Login(gomock.Any(), "1426325504@qq.com", "hellword@12").
+					Return(domain.User{}, service.ErrInvalidUserOrPassword)
+				limitSvc.EXPECT().Fail(gomock.Any(), "1426325504@qq.com", "").Return(time.Duration(0), nil)
+				return svc, limitSvc
+			},
+			req: &userv1.LoginRequest{
+				Email:    "1426325504@qq.com",
+				Password: "hellword@12",
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "系统错误",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginLimitService) {
+				limitSvc := svcmocks.NewMockLoginLimitService(ctrl)
+				limitSvc.EXPECT().Allow(gomock.Any(), "1426325504@qq.com", "").Return(time.Duration(0), nil)
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().Login(gomock.Any(), "1426325504@qq.com", "hellword@123").
+					Return(domain.User{}, errors.New("mock db 错误"))
+				return svc, limitSvc
+			},
+			req: &userv1.LoginRequest{
+				Email:    "1426325504@qq.com",
+				Password: "hellword@123",
+			},
+			wantCode: codes.Internal,
+		},
+		{
+			name: "账号被锁定",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.LoginLimitService) {
+				limitSvc := svcmocks.NewMockLoginLimitService(ctrl)
+				limitSvc.EXPECT().Allow(gomock.Any(), "1426325504@qq.com", "10.0.0.1").
+					Return(time.Minute, service.ErrLoginLocked)
+				return svcmocks.NewMockUserService(ctrl), limitSvc
+			},
+			req: &userv1.LoginRequest{
+				Email:    "1426325504@qq.com",
+				Password: "hellword@123",
+				Ip:       "10.0.0.1",
+			},
+			wantCode: codes.ResourceExhausted,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, limitSvc := tc.mock(ctrl)
+			server := NewUserServiceServer(svc, nil, limitSvc, nil, nil)
+			resp, err := server.Login(context.Background(), tc.req)
+			assert.Equal(t, tc.wantCode, status.Code(err))
+			assert.Equal(t, tc.wantResp.String(), resp.String())
+		})
+	}
+}
+
+func TestUserServiceServer_Signup(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService)
+		// 输入
+		req *userv1.SignupRequest
+		// 输出
+		wantResp *userv1.SignupResponse
+		wantCode codes.Code
+	}{
+		{
+			name: "注册成功",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().Signup(gomock.Any(), domain.User{
+					Email:    "1426325504@qq.com",
+					Password: "hellword@123",
+				}).Return(nil)
+				emailCodeSvc := svcmocks.NewMockEmailCodeService(ctrl)
+				emailCodeSvc.EXPECT().Send(gomock.Any(), service.EmailVerifyBiz, "1426325504@qq.com").Return(nil)
+				return svc, emailCodeSvc
+			},
+			req: &userv1.SignupRequest{
+				User: &userv1.User{
+					Email:    "1426325504@qq.com",
+					Password: "hellword@123",
+				},
+			},
+			wantResp: &userv1.SignupResponse{VerificationSent: true},
+			wantCode: codes.OK,
+		},
+		{
+			name: "验证码发送失败",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().Signup(gomock.Any(), gomock.Any()).Return(nil)
+				emailCodeSvc := svcmocks.NewMockEmailCodeService(ctrl)
+				emailCodeSvc.EXPECT().Send(gomock.Any(), service.EmailVerifyBiz, "1426325504@qq.com").
+					Return(errors.New("mock smtp 错误"))
+				return svc, emailCodeSvc
+			},
+			req: &userv1.SignupRequest{
+				User: &userv1.User{
+					Email:    "1426325504@qq.com",
+					Password: "hellword@123",
+				},
+			},
+			wantResp: &userv1.SignupResponse{},
+			wantCode: codes.OK,
+		},
+		{
+			name: "邮箱格式不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService) {
+				return svcmocks.NewMockUserService(ctrl), svcmocks.NewMockEmailCodeService(ctrl)
+			},
+			req: &userv1.SignupRequest{
+				User: &userv1.User{
+					Email:    "1426325504",
+					Password: "hellword@123",
+				},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "密码格式不对",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService) {
+				return svcmocks.NewMockUserService(ctrl), svcmocks.NewMockEmailCodeService(ctrl)
+			},
+			req: &userv1.SignupRequest{
+				User: &userv1.User{
+					Email:    "1426325504@qq.com",
+					Password: "hellword123",
+				},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "邮箱冲突",
+			mock: func(ctrl *gomock.Controller) (service.UserService, service.EmailCodeService) {
+				svc := svcmocks.NewMockUserService(ctrl)
+				svc.EXPECT().Signup(gomock.Any(), gomock.Any()).
+					Return(service.ErrUserDuplicateEmail)
+				return svc, svcmocks.NewMockEmailCodeService(ctrl)
+			},
+			req: &userv1.SignupRequest{
+				User: &userv1.User{
+					Email:    "1426325504@qq.com",
+					Password: "hellword@123",
+				},
+			},
+			wantCode: codes.AlreadyExists,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, emailCodeSvc := tc.mock(ctrl)
+			server := NewUserServiceServer(svc, emailCodeSvc, nil, nil, nil)
+			resp, err := server.Signup(context.Background(), tc.req)
+			assert.Equal(t, tc.wantCode, status.Code(err))
+			assert.Equal(t, tc.wantResp.String(), resp.String())
+		})
+	}
+}
+
+func TestUserServiceServer_Profile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	svc := svcmocks.NewMockUserService(ctrl)
+	svc.EXPECT().Profile(gomock.Any(), int64(12)).
+		Return(domain.User{}, service.ErrUserNotFound)
+	server := NewUserServiceServer(svc, nil, nil, nil, nil)
+	_, err := server.Profile(context.Background(), &userv1.ProfileRequest{Id: 12})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestUserServiceServer_MergeUsers(t *testing.T) {
+	testCase := []struct {
+		name     string
+		mergeErr error
+		// 输出
+		wantCode    codes.Code
+		wantRevoked bool
+	}{
+		{
+			name:        "合并成功，source 的登录态失效",
+			wantCode:    codes.OK,
+			wantRevoked: true,
+		},
+		{
+			name:     "冲突，不撤销登录态",
+			mergeErr: service.ErrMergeConflict,
+			wantCode: codes.FailedPrecondition,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mergeSvc := svcmocks.NewMockMergeService(ctrl)
+			mergeSvc.EXPECT().Merge(gomock.Any(), int64(1), int64(2)).Return(tc.mergeErr)
+			mr := miniredis.RunT(t)
+			mr.SAdd("users:sessions:1", "source_ssid")
+			mr.SAdd("users:sessions:2", "target_ssid")
+			cmd := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer cmd.Close()
+			wtHdl := myjwt.NewRedisJWTHandler(cmd, nil, myjwt.SessionConfig{Sliding: time.Hour})
+
+			server := NewUserServiceServer(nil, nil, nil, mergeSvc, wtHdl)
+			_, err := server.MergeUsers(context.Background(), &userv1.MergeUsersRequest{SourceId: 1, TargetId: 2})
+			assert.Equal(t, tc.wantCode, status.Code(err))
+			assert.Equal(t, tc.wantRevoked, mr.Exists("users:ssid:source_ssid"))
+			assert.False(t, mr.Exists("users:ssid:target_ssid"))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package job
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	rlock "github.com/gotomicro/redis-lock"
+	"time"
+)
+
+const accountDeletionLockKey = "users:job:account_deletion"
+
+// AccountDeletionJob 定时删除冷静期已经结束的用户的个人信息
+// 每个实例都会启动，用分布式锁保证同一时间只有一个实例在删除
+type AccountDeletionJob struct {
+	svc      service.AccountDeletionService
+	client   *rlock.Client
+	log      accesslog.Logger
+	interval time.Duration
+	// batch 每次从数据库查出来的用户数
+	batch int
+}
+
+func NewAccountDeletionJob(svc service.AccountDeletionService, client *rlock.Client, log accesslog.Logger,
+	interval time.Duration, batch int) *AccountDeletionJob {
+	return &AccountDeletionJob{
+		svc:      svc,
+		client:   client,
+		log:      log,
+		interval: interval,
+		batch:    batch,
+	}
+}
+
+// Start 每隔 interval 执行一次，ctx 取消之后返回
+func (j *AccountDeletionJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.Run(ctx); err != nil {
+				j.log.Error("删除注销用户失败", accesslog.Error(err))
+			}
+		}
+	}
+}
+
+// Run 执行一次，没有抢到锁说明其它实例在执行，直接返回
+func (j *AccountDeletionJob) Run(ctx context.Context) error {
+	lock, err := j.client.TryLock(ctx, accountDeletionLockKey, j.interval)
+	if err == rlock.ErrFailedToPreemptLock {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// ctx 可能已经取消了，释放锁用新的 ctx
+		uctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if er := lock.Unlock(uctx); er != nil {
+			j.log.Warn("释放删除注销用户的锁失败", accesslog.Error(er))
+		}
+	}()
+	for ctx.Err() == nil {
+		ids, err := j.svc.EraseExpired(ctx, j.batch)
+		if len(ids) > 0 {
+			j.log.Info("删除注销用户", accesslog.Any("uids", ids))
+		}
+		if err != nil {
+			return err
+		}
+		// 不满一批说明已经删完了，被取消的用户也会让这一批不满，剩下的下一次再删
+		if len(ids) < j.batch {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
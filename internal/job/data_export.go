@@ -0,0 +1,88 @@
+package job
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	"time"
+)
+
+// DataExportJob 执行个人数据导出的队列，并定时删除过期的压缩包
+// 每个实例都会启动，队列保证一个任务只被一个实例取到，不需要加锁
+type DataExportJob struct {
+	svc service.DataExportService
+	log accesslog.Logger
+	// pollInterval 队列为空的时候多久再查一次
+	pollInterval    time.Duration
+	cleanupInterval time.Duration
+	// allowed 压缩包只有当前实例读得到的时候（本地磁盘），有其它实例在运行就返回 false，暂停执行
+	// nil 代表存储是共享的，总是执行
+	allowed func(ctx context.Context) (bool, error)
+	paused  bool
+}
+
+func NewDataExportJob(svc service.DataExportService, log accesslog.Logger,
+	pollInterval time.Duration, cleanupInterval time.Duration,
+	allowed func(ctx context.Context) (bool, error)) *DataExportJob {
+	return &DataExportJob{
+		svc:             svc,
+		log:             log,
+		pollInterval:    pollInterval,
+		cleanupInterval: cleanupInterval,
+		allowed:         allowed,
+	}
+}
+
+// Start 一直执行到 ctx 取消
+func (j *DataExportJob) Start(ctx context.Context) {
+	cleanup := time.NewTicker(j.cleanupInterval)
+	defer cleanup.Stop()
+	for {
+		ran, err := false, error(nil)
+		if j.runnable(ctx) {
+			ran, err = j.svc.RunNext(ctx)
+			if err != nil {
+				j.log.Error("执行个人数据导出失败", accesslog.Error(err))
+			}
+		}
+		// 队列里面还有任务就接着做
+		wait := j.pollInterval
+		if ran && err == nil {
+			wait = 0
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanup.C:
+			if !j.runnable(ctx) {
+				continue
+			}
+			if err = j.svc.Cleanup(ctx); err != nil {
+				j.log.Error("删除过期的导出文件失败", accesslog.Error(err))
+			}
+		case <-time.After(wait):
+		}
+	}
+}
+
+// runnable 每一轮都检查一次，其它实例下线之后自动恢复
+func (j *DataExportJob) runnable(ctx context.Context) bool {
+	if j.allowed == nil {
+		return true
+	}
+	ok, err := j.allowed(ctx)
+	if err != nil {
+		j.log.Error("查询服务实例失败，暂停个人数据导出", accesslog.Error(err))
+		return false
+	}
+	// 只在状态变化的时候记日志
+	if ok == j.paused {
+		j.paused = !ok
+		if j.paused {
+			j.log.Warn("有其它实例在运行，压缩包保存在本地磁盘，暂停个人数据导出")
+		} else {
+			j.log.Info("只剩下当前实例，恢复个人数据导出")
+		}
+	}
+	return ok
+}
@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	svcmocks "github.com/dadaxiaoxiao/user/internal/service/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDataExportJob_Start(t *testing.T) {
+	testCase := []struct {
+		name    string
+		allowed func(ctx context.Context) (bool, error)
+		mock    func(ctrl *gomock.Controller) *svcmocks.MockDataExportService
+	}{
+		{
+			name: "存储是共享的",
+			mock: func(ctrl *gomock.Controller) *svcmocks.MockDataExportService {
+				svc := svcmocks.NewMockDataExportService(ctrl)
+				svc.EXPECT().RunNext(gomock.Any()).Return(false, nil).MinTimes(1)
+				return svc
+			},
+		},
+		{
+			name: "只有当前实例",
+			allowed: func(ctx context.Context) (bool, error) {
+				return true, nil
+			},
+			mock: func(ctrl *gomock.Controller) *svcmocks.MockDataExportService {
+				svc := svcmocks.NewMockDataExportService(ctrl)
+				svc.EXPECT().RunNext(gomock.Any()).Return(false, nil).MinTimes(1)
+				return svc
+			},
+		},
+		{
+			name: "有其它实例，不执行",
+			allowed: func(ctx context.Context) (bool, error) {
+				return false, nil
+			},
+			mock: func(ctrl *gomock.Controller) *svcmocks.MockDataExportService {
+				return svcmocks.NewMockDataExportService(ctrl)
+			},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			var checked atomic.Int64
+			allowed := tc.allowed
+			if allowed != nil {
+				allowed = func(ctx context.Context) (bool, error) {
+					checked.Add(1)
+					return tc.allowed(ctx)
+				}
+			}
+			j := NewDataExportJob(tc.mock(ctrl), accesslog.NewNopLogger(), time.Millisecond*10, time.Hour, allowed)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+			defer cancel()
+			j.Start(ctx)
+			if tc.allowed != nil {
+				// 每一轮都重新检查
+				assert.Greater(t, checked.Load(), int64(1))
+			}
+		})
+	}
+}
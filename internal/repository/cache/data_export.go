@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const (
+	dataExportQueueKey = "users:export:queue"
+	// dataExportProcessingKey 取出来还没有执行完的任务，执行完之后删除
+	dataExportProcessingKey = "users:export:processing"
+	dataExportObjectsKey    = "users:export:objects"
+	// dataExportDequeueTimeout 队列为空的时候最多阻塞多久
+	dataExportDequeueTimeout = time.Second
+)
+
+//go:embed lua/data_export_requeue.lua
+var luaDataExportRequeue string
+
+//go:generate mockgen.exe -source=./data_export.go -package=cachemocks -destination=mocks/data_export.mock.go DataExportCache
+type DataExportCache interface {
+	// Set 保存导出任务，同时记为用户最近一次导出
+	Set(ctx context.Context, e domain.DataExport, expiration time.Duration) error
+	// Get 不存在或者过期返回 ErrKeyNotExist
+	Get(ctx context.Context, id string) (domain.DataExport, error)
+	// GetLatest 用户最近一次导出，没有返回 ErrKeyNotExist
+	GetLatest(ctx context.Context, uid int64) (domain.DataExport, error)
+	Enqueue(ctx context.Context, id string) error
+	// Dequeue 取出任务的同时放到处理中的列表，队列为空返回 ErrKeyNotExist
+	Dequeue(ctx context.Context) (string, error)
+	// Ack 任务执行完，从处理中的列表删除
+	Ack(ctx context.Context, id string) error
+	// Processing 处理中的任务，包括执行的实例已经挂掉的
+	Processing(ctx context.Context) ([]string, error)
+	// Requeue 把处理中的任务放回队列，已经不在处理中的返回 false
+	Requeue(ctx context.Context, id string) (bool, error)
+	// AddObject 记录压缩包什么时候删除
+	AddObject(ctx context.Context, key string, deleteAt time.Time) error
+	// ExpiredObjects 到了删除时间的压缩包
+	ExpiredObjects(ctx context.Context, now time.Time, limit int) ([]string, error)
+	// FindObjects key 以 prefix 开头的压缩包
+	FindObjects(ctx context.Context, prefix string) ([]string, error)
+	RemoveObject(ctx context.Context, key string) error
+}
+
+// RedisDataExportCache 导出任务放在 Hash 里面，队列用 List，压缩包的删除时间用 ZSet
+// 出队的时候用 BLMOVE 放到处理中的列表，执行的实例挂了任务也不会丢
+type RedisDataExportCache struct {
+	client redis.Cmdable
+}
+
+func NewRedisDataExportCache(client redis.Cmdable) DataExportCache {
+	return &RedisDataExportCache{
+		client: client,
+	}
+}
+
+func (cache *RedisDataExportCache) Set(ctx context.Context, e domain.DataExport, expiration time.Duration) error {
+	key := cache.key(e.Id)
+	pipe := cache.client.TxPipeline()
+	pipe.HSet(ctx, key,
+		"uid", e.Uid,
+		"status", e.Status,
+		"size", e.Size,
+		"ctime", e.Ctime.UnixMilli(),
+		"utime", e.Utime.UnixMilli())
+	pipe.Expire(ctx, key, expiration)
+	pipe.Set(ctx, cache.latestKey(e.Uid), e.Id, expiration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (cache *RedisDataExportCache) Get(ctx context.Context, id string) (domain.DataExport, error) {
+	vals, err := cache.client.HGetAll(ctx, cache.key(id)).Result()
+	if err != nil {
+		return domain.DataExport{}, err
+	}
+	if len(vals) == 0 {
+		return domain.DataExport{}, ErrKeyNotExist
+	}
+	uid, _ := strconv.ParseInt(vals["uid"], 10, 64)
+	size, _ := strconv.ParseInt(vals["size"], 10, 64)
+	ctime, _ := strconv.ParseInt(vals["ctime"], 10, 64)
+	utime, _ := strconv.ParseInt(vals["utime"], 10, 64)
+	return domain.DataExport{
+		Id:     id,
+		Uid:    uid,
+		Status: vals["status"],
+		Size:   size,
+		Ctime:  time.UnixMilli(ctime),
+		Utime:  time.UnixMilli(utime),
+	}, nil
+}
+
+func (cache *RedisDataExportCache) GetLatest(ctx context.Context, uid int64) (domain.DataExport, error) {
+	id, err := cache.client.Get(ctx, cache.latestKey(uid)).Result()
+	if err == redis.Nil {
+		return domain.DataExport{}, ErrKeyNotExist
+	}
+	if err != nil {
+		return domain.DataExport{}, err
+	}
+	return cache.Get(ctx, id)
+}
+
+func (cache *RedisDataExportCache) Enqueue(ctx context.Context, id string) error {
+	return cache.client.LPush(ctx, dataExportQueueKey, id).Err()
+}
+
+func (cache *RedisDataExportCache) Dequeue(ctx context.Context) (string, error) {
+	id, err := cache.client.BLMove(ctx, dataExportQueueKey, dataExportProcessingKey,
+		"RIGHT", "LEFT", dataExportDequeueTimeout).Result()
+	if err == redis.Nil {
+		return "", ErrKeyNotExist
+	}
+	return id, err
+}
+
+func (cache *RedisDataExportCache) Ack(ctx context.Context, id string) error {
+	return cache.client.LRem(ctx, dataExportProcessingKey, 1, id).Err()
+}
+
+func (cache *RedisDataExportCache) Processing(ctx context.Context) ([]string, error) {
+	return cache.client.LRange(ctx, dataExportProcessingKey, 0, -1).Result()
+}
+
+func (cache *RedisDataExportCache) Requeue(ctx context.Context, id string) (bool, error) {
+	res, err := cache.client.Eval(ctx, luaDataExportRequeue,
+		[]string{dataExportProcessingKey, dataExportQueueKey}, id).Int()
+	return res == 1, err
+}
+
+func (cache *RedisDataExportCache) AddObject(ctx context.Context, key string, deleteAt time.Time) error {
+	return cache.client.ZAdd(ctx, dataExportObjectsKey, redis.Z{
+		Score:  float64(deleteAt.UnixMilli()),
+		Member: key,
+	}).Err()
+}
+
+func (cache *RedisDataExportCache) ExpiredObjects(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	return cache.client.ZRangeByScore(ctx, dataExportObjectsKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+}
+
+func (cache *RedisDataExportCache) FindObjects(ctx context.Context, prefix string) ([]string, error) {
+	var res []string
+	var cursor uint64
+	for {
+		// ZSCAN 返回的是成员和分数交替的列表
+		vals, next, err := cache.client.ZScan(ctx, dataExportObjectsKey, cursor, prefix+"*", 100).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(vals); i += 2 {
+			res = append(res, vals[i])
+		}
+		if next == 0 {
+			return res, nil
+		}
+		cursor = next
+	}
+}
+
+func (cache *RedisDataExportCache) RemoveObject(ctx context.Context, key string) error {
+	return cache.client.ZRem(ctx, dataExportObjectsKey, key).Err()
+}
+
+func (cache *RedisDataExportCache) key(id string) string {
+	return fmt.Sprintf("users:export:%s", id)
+}
+
+func (cache *RedisDataExportCache) latestKey(uid int64) string {
+	return fmt.Sprintf("users:export:latest:%d", uid)
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// TestRedisDataExportCache_Queue 出队、Ack 和放回队列，Lua 脚本和 BLMOVE 直接用 miniredis 测
+func TestRedisDataExportCache_Queue(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	cache := NewRedisDataExportCache(client)
+	ctx := context.Background()
+
+	require.NoError(t, cache.Enqueue(ctx, "a"))
+	require.NoError(t, cache.Enqueue(ctx, "b"))
+	// 先进先出，取出来的放到处理中的列表
+	id, err := cache.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", id)
+	processing, err := cache.Processing(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, processing)
+
+	// 执行的实例挂了，放回队列之后下一个就取到它
+	ok, err := cache.Requeue(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	// 另外一个实例同时放回
+	ok, err = cache.Requeue(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	id, err = cache.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", id)
+
+	require.NoError(t, cache.Ack(ctx, "a"))
+	processing, err = cache.Processing(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, processing)
+
+	id, err = cache.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "b", id)
+	require.NoError(t, cache.Ack(ctx, "b"))
+
+	// 队列为空，阻塞到超时
+	start := time.Now()
+	_, err = cache.Dequeue(ctx)
+	assert.Equal(t, ErrKeyNotExist, err)
+	assert.GreaterOrEqual(t, time.Since(start), dataExportDequeueTimeout/2)
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed lua/login_fail.lua
+var luaLoginFail string
+
+//go:generate mockgen.exe -source=./login_limit.go -package=cachemocks -destination=mocks/login_limit.mock.go LoginLimitCache
+type LoginLimitCache interface {
+	// Blocked 返回剩余的等待时间，locked 代表是被锁定而不是退避
+	Blocked(ctx context.Context, keys ...string) (wait time.Duration, locked bool, err error)
+	// Fail 记录一次失败，返回下一次可以尝试之前需要等待的时间
+	Fail(ctx context.Context, key string, rule domain.LoginLimitRule) (wait time.Duration, locked bool, err error)
+	// Reset 清掉失败次数和锁定
+	Reset(ctx context.Context, key string) error
+}
+
+// RedisLoginLimitCache 密码登录失败的计数
+type RedisLoginLimitCache struct {
+	client redis.Cmdable
+}
+
+func NewRedisLoginLimitCache(client redis.Cmdable) LoginLimitCache {
+	return &RedisLoginLimitCache{
+		client: client,
+	}
+}
+
+func (cache *RedisLoginLimitCache) Blocked(ctx context.Context, keys ...string) (time.Duration, bool, error) {
+	pipe := cache.client.Pipeline()
+	typs := make([]*redis.StringCmd, 0, len(keys))
+	ttls := make([]*redis.DurationCmd, 0, len(keys))
+	for _, key := range keys {
+		typs = append(typs, pipe.Get(ctx, cache.blockKey(key)))
+		ttls = append(ttls, pipe.PTTL(ctx, cache.blockKey(key)))
+	}
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return 0, false, err
+	}
+	var (
+		wait   time.Duration
+		locked bool
+	)
+	for i := range keys {
+		typ, err := typs[i].Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		ttl := ttls[i].Val()
+		if ttl <= 0 {
+			continue
+		}
+		if typ == "lock" {
+			locked = true
+		}
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait, locked, nil
+}
+
+func (cache *RedisLoginLimitCache) Fail(ctx context.Context, key string, rule domain.LoginLimitRule) (time.Duration, bool, error) {
+	res, err := cache.client.Eval(ctx, luaLoginFail,
+		[]string{cache.cntKey(key), cache.blockKey(key)},
+		rule.FreeAttempts,
+		rule.BaseDelay.Milliseconds(),
+		rule.MaxDelay.Milliseconds(),
+		rule.Window.Milliseconds(),
+		rule.LockThreshold,
+		rule.LockDuration.Milliseconds()).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if res < 0 {
+		return time.Duration(-res) * time.Millisecond, true, nil
+	}
+	return time.Duration(res) * time.Millisecond, false, nil
+}
+
+func (cache *RedisLoginLimitCache) Reset(ctx context.Context, key string) error {
+	return cache.client.Del(ctx, cache.cntKey(key), cache.blockKey(key)).Err()
+}
+
+func (cache *RedisLoginLimitCache) cntKey(key string) string {
+	return fmt.Sprintf("users:login_fail:%s", key)
+}
+
+func (cache *RedisLoginLimitCache) blockKey(key string) string {
+	return fmt.Sprintf("users:login_block:%s", key)
+}
@@ -0,0 +1,12 @@
+-- users:export:processing
+local processing = KEYS[1]
+-- users:export:queue
+local queue = KEYS[2]
+local id = ARGV[1]
+-- 多个实例同时放回，只有从处理中删掉的那个放回队列
+if redis.call("lrem", processing, 1, id) == 0 then
+    return 0
+end
+-- 出队是从右边取的，放回右边下一个就执行
+redis.call("rpush", queue, id)
+return 1
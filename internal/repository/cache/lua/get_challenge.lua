@@ -0,0 +1,16 @@
+-- users:2fa_challenge:xxx
+local key = KEYS[1]
+-- 最多尝试次数
+local maxAttempts = tonumber(ARGV[1])
+local uid = redis.call("hget", key, "uid")
+if uid == false then
+    -- 挑战不存在或者已经过期
+    return -1
+end
+local cnt = redis.call("hincrby", key, "cnt", 1)
+if cnt > maxAttempts then
+    -- 尝试次数太多，挑战作废
+    redis.call("del", key)
+    return -2
+end
+return redis.call("hmget", key, "uid", "method", "account")
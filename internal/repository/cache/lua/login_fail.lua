@@ -0,0 +1,30 @@
+-- users:login_fail:account:xxx
+local cntKey = KEYS[1]
+-- users:login_block:account:xxx
+local blockKey = KEYS[2]
+local freeAttempts = tonumber(ARGV[1])
+local baseDelay = tonumber(ARGV[2])
+local maxDelay = tonumber(ARGV[3])
+local window = tonumber(ARGV[4])
+local lockThreshold = tonumber(ARGV[5])
+local lockDuration = tonumber(ARGV[6])
+
+local cnt = redis.call("incr", cntKey)
+redis.call("pexpire", cntKey, window)
+if lockThreshold > 0 and cnt >= lockThreshold then
+    -- 锁定，锁定结束之后重新计数
+    redis.call("set", blockKey, "lock", "PX", lockDuration)
+    redis.call("del", cntKey)
+    -- 负数代表锁定
+    return -lockDuration
+end
+if cnt <= freeAttempts then
+    return 0
+end
+-- 指数退避
+local delay = baseDelay * 2 ^ (cnt - freeAttempts - 1)
+if delay > maxDelay then
+    delay = maxDelay
+end
+redis.call("set", blockKey, "backoff", "PX", delay)
+return delay
@@ -0,0 +1,32 @@
+-- users:qr_login:xxx
+local key = KEYS[1]
+-- 期望的当前状态
+local from = ARGV[1]
+-- 新的状态，为空的时候删除票据
+local to = ARGV[2]
+local ttl = tonumber(ARGV[3])
+-- 扫码的用户，为 0 的时候不校验
+local uid = ARGV[4]
+local status = redis.call("hget", key, "status")
+if status == false then
+    -- 票据不存在或者已经过期
+    return -1
+end
+if status ~= from then
+    return -2
+end
+local cur = redis.call("hget", key, "uid")
+if uid ~= "0" then
+    if cur ~= "0" and cur ~= uid then
+        -- 不是扫码的用户
+        return -2
+    end
+    cur = uid
+end
+if to == "" then
+    redis.call("del", key)
+    return tonumber(cur)
+end
+redis.call("hset", key, "status", to, "uid", cur)
+redis.call("expire", key, ttl)
+return tonumber(cur)
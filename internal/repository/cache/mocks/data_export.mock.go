@@ -0,0 +1,217 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./data_export.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./data_export.go -package=cachemocks -destination=mocks/data_export.mock.go DataExportCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDataExportCache is a mock of DataExportCache interface.
+type MockDataExportCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockDataExportCacheMockRecorder
+}
+
+// MockDataExportCacheMockRecorder is the mock recorder for MockDataExportCache.
+type MockDataExportCacheMockRecorder struct {
+	mock *MockDataExportCache
+}
+
+// NewMockDataExportCache creates a new mock instance.
+func NewMockDataExportCache(ctrl *gomock.Controller) *MockDataExportCache {
+	mock := &MockDataExportCache{ctrl: ctrl}
+	mock.recorder = &MockDataExportCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataExportCache) EXPECT() *MockDataExportCacheMockRecorder {
+	return m.recorder
+}
+
+// Ack mocks base method.
+func (m *MockDataExportCache) Ack(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ack", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ack indicates an expected call of Ack.
+func (mr *MockDataExportCacheMockRecorder) Ack(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ack", reflect.TypeOf((*MockDataExportCache)(nil).Ack), ctx, id)
+}
+
+// AddObject mocks base method.
+func (m *MockDataExportCache) AddObject(ctx context.Context, key string, deleteAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddObject", ctx, key, deleteAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddObject indicates an expected call of AddObject.
+func (mr *MockDataExportCacheMockRecorder) AddObject(ctx, key, deleteAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddObject", reflect.TypeOf((*MockDataExportCache)(nil).AddObject), ctx, key, deleteAt)
+}
+
+// Dequeue mocks base method.
+func (m *MockDataExportCache) Dequeue(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dequeue", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Dequeue indicates an expected call of Dequeue.
+func (mr *MockDataExportCacheMockRecorder) Dequeue(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dequeue", reflect.TypeOf((*MockDataExportCache)(nil).Dequeue), ctx)
+}
+
+// Enqueue mocks base method.
+func (m *MockDataExportCache) Enqueue(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enqueue", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Enqueue indicates an expected call of Enqueue.
+func (mr *MockDataExportCacheMockRecorder) Enqueue(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockDataExportCache)(nil).Enqueue), ctx, id)
+}
+
+// ExpiredObjects mocks base method.
+func (m *MockDataExportCache) ExpiredObjects(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpiredObjects", ctx, now, limit)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpiredObjects indicates an expected call of ExpiredObjects.
+func (mr *MockDataExportCacheMockRecorder) ExpiredObjects(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpiredObjects", reflect.TypeOf((*MockDataExportCache)(nil).ExpiredObjects), ctx, now, limit)
+}
+
+// FindObjects mocks base method.
+func (m *MockDataExportCache) FindObjects(ctx context.Context, prefix string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindObjects", ctx, prefix)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindObjects indicates an expected call of FindObjects.
+func (mr *MockDataExportCacheMockRecorder) FindObjects(ctx, prefix any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindObjects", reflect.TypeOf((*MockDataExportCache)(nil).FindObjects), ctx, prefix)
+}
+
+// Get mocks base method.
+func (m *MockDataExportCache) Get(ctx context.Context, id string) (domain.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, id)
+	ret0, _ := ret[0].(domain.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockDataExportCacheMockRecorder) Get(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDataExportCache)(nil).Get), ctx, id)
+}
+
+// GetLatest mocks base method.
+func (m *MockDataExportCache) GetLatest(ctx context.Context, uid int64) (domain.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLatest", ctx, uid)
+	ret0, _ := ret[0].(domain.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLatest indicates an expected call of GetLatest.
+func (mr *MockDataExportCacheMockRecorder) GetLatest(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatest", reflect.TypeOf((*MockDataExportCache)(nil).GetLatest), ctx, uid)
+}
+
+// Processing mocks base method.
+func (m *MockDataExportCache) Processing(ctx context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Processing", ctx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Processing indicates an expected call of Processing.
+func (mr *MockDataExportCacheMockRecorder) Processing(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Processing", reflect.TypeOf((*MockDataExportCache)(nil).Processing), ctx)
+}
+
+// RemoveObject mocks base method.
+func (m *MockDataExportCache) RemoveObject(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveObject", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveObject indicates an expected call of RemoveObject.
+func (mr *MockDataExportCacheMockRecorder) RemoveObject(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveObject", reflect.TypeOf((*MockDataExportCache)(nil).RemoveObject), ctx, key)
+}
+
+// Requeue mocks base method.
+func (m *MockDataExportCache) Requeue(ctx context.Context, id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Requeue", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Requeue indicates an expected call of Requeue.
+func (mr *MockDataExportCacheMockRecorder) Requeue(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Requeue", reflect.TypeOf((*MockDataExportCache)(nil).Requeue), ctx, id)
+}
+
+// Set mocks base method.
+func (m *MockDataExportCache) Set(ctx context.Context, e domain.DataExport, expiration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, e, expiration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockDataExportCacheMockRecorder) Set(ctx, e, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockDataExportCache)(nil).Set), ctx, e, expiration)
+}
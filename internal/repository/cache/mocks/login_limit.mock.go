@@ -0,0 +1,93 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./login_limit.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./login_limit.go -package=cachemocks -destination=mocks/login_limit.mock.go LoginLimitCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginLimitCache is a mock of LoginLimitCache interface.
+type MockLoginLimitCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginLimitCacheMockRecorder
+}
+
+// MockLoginLimitCacheMockRecorder is the mock recorder for MockLoginLimitCache.
+type MockLoginLimitCacheMockRecorder struct {
+	mock *MockLoginLimitCache
+}
+
+// NewMockLoginLimitCache creates a new mock instance.
+func NewMockLoginLimitCache(ctrl *gomock.Controller) *MockLoginLimitCache {
+	mock := &MockLoginLimitCache{ctrl: ctrl}
+	mock.recorder = &MockLoginLimitCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginLimitCache) EXPECT() *MockLoginLimitCacheMockRecorder {
+	return m.recorder
+}
+
+// Blocked mocks base method.
+func (m *MockLoginLimitCache) Blocked(ctx context.Context, keys ...string) (time.Duration, bool, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx}
+	for _, a := range keys {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Blocked", varargs...)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Blocked indicates an expected call of Blocked.
+func (mr *MockLoginLimitCacheMockRecorder) Blocked(ctx any, keys ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx}, keys...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Blocked", reflect.TypeOf((*MockLoginLimitCache)(nil).Blocked), varargs...)
+}
+
+// Fail mocks base method.
+func (m *MockLoginLimitCache) Fail(ctx context.Context, key string, rule domain.LoginLimitRule) (time.Duration, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fail", ctx, key, rule)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockLoginLimitCacheMockRecorder) Fail(ctx, key, rule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockLoginLimitCache)(nil).Fail), ctx, key, rule)
+}
+
+// Reset mocks base method.
+func (m *MockLoginLimitCache) Reset(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reset", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockLoginLimitCacheMockRecorder) Reset(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockLoginLimitCache)(nil).Reset), ctx, key)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oauth2_state.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oauth2_state.go -package=cachemocks -destination=mocks/oauth2_state.mock.go OAuth2StateCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuth2StateCache is a mock of OAuth2StateCache interface.
+type MockOAuth2StateCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuth2StateCacheMockRecorder
+}
+
+// MockOAuth2StateCacheMockRecorder is the mock recorder for MockOAuth2StateCache.
+type MockOAuth2StateCacheMockRecorder struct {
+	mock *MockOAuth2StateCache
+}
+
+// NewMockOAuth2StateCache creates a new mock instance.
+func NewMockOAuth2StateCache(ctrl *gomock.Controller) *MockOAuth2StateCache {
+	mock := &MockOAuth2StateCache{ctrl: ctrl}
+	mock.recorder = &MockOAuth2StateCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth2StateCache) EXPECT() *MockOAuth2StateCacheMockRecorder {
+	return m.recorder
+}
+
+// Set mocks base method.
+func (m *MockOAuth2StateCache) Set(ctx context.Context, state string, val domain.OAuth2State) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, state, val)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockOAuth2StateCacheMockRecorder) Set(ctx, state, val any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockOAuth2StateCache)(nil).Set), ctx, state, val)
+}
+
+// Take mocks base method.
+func (m *MockOAuth2StateCache) Take(ctx context.Context, state string) (domain.OAuth2State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Take", ctx, state)
+	ret0, _ := ret[0].(domain.OAuth2State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Take indicates an expected call of Take.
+func (mr *MockOAuth2StateCacheMockRecorder) Take(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Take", reflect.TypeOf((*MockOAuth2StateCache)(nil).Take), ctx, state)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oidc.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oidc.go -package=cachemocks -destination=mocks/oidc.mock.go OIDCCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOIDCCache is a mock of OIDCCache interface.
+type MockOIDCCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCCacheMockRecorder
+}
+
+// MockOIDCCacheMockRecorder is the mock recorder for MockOIDCCache.
+type MockOIDCCacheMockRecorder struct {
+	mock *MockOIDCCache
+}
+
+// NewMockOIDCCache creates a new mock instance.
+func NewMockOIDCCache(ctrl *gomock.Controller) *MockOIDCCache {
+	mock := &MockOIDCCache{ctrl: ctrl}
+	mock.recorder = &MockOIDCCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCCache) EXPECT() *MockOIDCCacheMockRecorder {
+	return m.recorder
+}
+
+// SetCode mocks base method.
+func (m *MockOIDCCache) SetCode(ctx context.Context, code string, val domain.AuthCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetCode", ctx, code, val)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCode indicates an expected call of SetCode.
+func (mr *MockOIDCCacheMockRecorder) SetCode(ctx, code, val any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCode", reflect.TypeOf((*MockOIDCCache)(nil).SetCode), ctx, code, val)
+}
+
+// TakeCode mocks base method.
+func (m *MockOIDCCache) TakeCode(ctx context.Context, code string) (domain.AuthCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeCode", ctx, code)
+	ret0, _ := ret[0].(domain.AuthCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakeCode indicates an expected call of TakeCode.
+func (mr *MockOIDCCacheMockRecorder) TakeCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeCode", reflect.TypeOf((*MockOIDCCache)(nil).TakeCode), ctx, code)
+}
@@ -0,0 +1,69 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./password_reset.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./password_reset.go -package=cachemocks -destination=mocks/password_reset.mock.go PasswordResetCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPasswordResetCache is a mock of PasswordResetCache interface.
+type MockPasswordResetCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordResetCacheMockRecorder
+}
+
+// MockPasswordResetCacheMockRecorder is the mock recorder for MockPasswordResetCache.
+type MockPasswordResetCacheMockRecorder struct {
+	mock *MockPasswordResetCache
+}
+
+// NewMockPasswordResetCache creates a new mock instance.
+func NewMockPasswordResetCache(ctrl *gomock.Controller) *MockPasswordResetCache {
+	mock := &MockPasswordResetCache{ctrl: ctrl}
+	mock.recorder = &MockPasswordResetCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordResetCache) EXPECT() *MockPasswordResetCacheMockRecorder {
+	return m.recorder
+}
+
+// Set mocks base method.
+func (m *MockPasswordResetCache) Set(ctx context.Context, token string, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, token, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockPasswordResetCacheMockRecorder) Set(ctx, token, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockPasswordResetCache)(nil).Set), ctx, token, uid)
+}
+
+// Take mocks base method.
+func (m *MockPasswordResetCache) Take(ctx context.Context, token string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Take", ctx, token)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Take indicates an expected call of Take.
+func (mr *MockPasswordResetCacheMockRecorder) Take(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Take", reflect.TypeOf((*MockPasswordResetCache)(nil).Take), ctx, token)
+}
@@ -0,0 +1,86 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./qr_login.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./qr_login.go -package=cachemocks -destination=mocks/qr_login.mock.go QRLoginCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockQRLoginCache is a mock of QRLoginCache interface.
+type MockQRLoginCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockQRLoginCacheMockRecorder
+}
+
+// MockQRLoginCacheMockRecorder is the mock recorder for MockQRLoginCache.
+type MockQRLoginCacheMockRecorder struct {
+	mock *MockQRLoginCache
+}
+
+// NewMockQRLoginCache creates a new mock instance.
+func NewMockQRLoginCache(ctrl *gomock.Controller) *MockQRLoginCache {
+	mock := &MockQRLoginCache{ctrl: ctrl}
+	mock.recorder = &MockQRLoginCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQRLoginCache) EXPECT() *MockQRLoginCacheMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockQRLoginCache) Get(ctx context.Context, ticket string) (domain.QRLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, ticket)
+	ret0, _ := ret[0].(domain.QRLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockQRLoginCacheMockRecorder) Get(ctx, ticket any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockQRLoginCache)(nil).Get), ctx, ticket)
+}
+
+// Set mocks base method.
+func (m *MockQRLoginCache) Set(ctx context.Context, qr domain.QRLogin, expiration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, qr, expiration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockQRLoginCacheMockRecorder) Set(ctx, qr, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockQRLoginCache)(nil).Set), ctx, qr, expiration)
+}
+
+// Transit mocks base method.
+func (m *MockQRLoginCache) Transit(ctx context.Context, ticket, from, to string, uid int64, expiration time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transit", ctx, ticket, from, to, uid, expiration)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transit indicates an expected call of Transit.
+func (mr *MockQRLoginCacheMockRecorder) Transit(ctx, ticket, from, to, uid, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transit", reflect.TypeOf((*MockQRLoginCache)(nil).Transit), ctx, ticket, from, to, uid, expiration)
+}
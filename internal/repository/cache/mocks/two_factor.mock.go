@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./two_factor.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./two_factor.go -package=cachemocks -destination=mocks/two_factor.mock.go TwoFactorCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTwoFactorCache is a mock of TwoFactorCache interface.
+type MockTwoFactorCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorCacheMockRecorder
+}
+
+// MockTwoFactorCacheMockRecorder is the mock recorder for MockTwoFactorCache.
+type MockTwoFactorCacheMockRecorder struct {
+	mock *MockTwoFactorCache
+}
+
+// NewMockTwoFactorCache creates a new mock instance.
+func NewMockTwoFactorCache(ctrl *gomock.Controller) *MockTwoFactorCache {
+	mock := &MockTwoFactorCache{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactorCache) EXPECT() *MockTwoFactorCacheMockRecorder {
+	return m.recorder
+}
+
+// DelChallenge mocks base method.
+func (m *MockTwoFactorCache) DelChallenge(ctx context.Context, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DelChallenge", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DelChallenge indicates an expected call of DelChallenge.
+func (mr *MockTwoFactorCacheMockRecorder) DelChallenge(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelChallenge", reflect.TypeOf((*MockTwoFactorCache)(nil).DelChallenge), ctx, token)
+}
+
+// GetChallenge mocks base method.
+func (m *MockTwoFactorCache) GetChallenge(ctx context.Context, token string) (domain.TwoFactorChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetChallenge", ctx, token)
+	ret0, _ := ret[0].(domain.TwoFactorChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChallenge indicates an expected call of GetChallenge.
+func (mr *MockTwoFactorCacheMockRecorder) GetChallenge(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChallenge", reflect.TypeOf((*MockTwoFactorCache)(nil).GetChallenge), ctx, token)
+}
+
+// SetChallenge mocks base method.
+func (m *MockTwoFactorCache) SetChallenge(ctx context.Context, token string, c domain.TwoFactorChallenge) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetChallenge", ctx, token, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetChallenge indicates an expected call of SetChallenge.
+func (mr *MockTwoFactorCacheMockRecorder) SetChallenge(ctx, token, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetChallenge", reflect.TypeOf((*MockTwoFactorCache)(nil).SetChallenge), ctx, token, c)
+}
@@ -0,0 +1,83 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./wechat_mini.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./wechat_mini.go -package=cachemocks -destination=mocks/wechat_mini.mock.go WechatMiniSessionCache
+//
+
+// Package cachemocks is a generated GoMock package.
+package cachemocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWechatMiniSessionCache is a mock of WechatMiniSessionCache interface.
+type MockWechatMiniSessionCache struct {
+	ctrl     *gomock.Controller
+	recorder *MockWechatMiniSessionCacheMockRecorder
+}
+
+// MockWechatMiniSessionCacheMockRecorder is the mock recorder for MockWechatMiniSessionCache.
+type MockWechatMiniSessionCacheMockRecorder struct {
+	mock *MockWechatMiniSessionCache
+}
+
+// NewMockWechatMiniSessionCache creates a new mock instance.
+func NewMockWechatMiniSessionCache(ctrl *gomock.Controller) *MockWechatMiniSessionCache {
+	mock := &MockWechatMiniSessionCache{ctrl: ctrl}
+	mock.recorder = &MockWechatMiniSessionCacheMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWechatMiniSessionCache) EXPECT() *MockWechatMiniSessionCacheMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockWechatMiniSessionCache) Delete(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockWechatMiniSessionCacheMockRecorder) Delete(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWechatMiniSessionCache)(nil).Delete), ctx, uid)
+}
+
+// Get mocks base method.
+func (m *MockWechatMiniSessionCache) Get(ctx context.Context, uid int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, uid)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockWechatMiniSessionCacheMockRecorder) Get(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockWechatMiniSessionCache)(nil).Get), ctx, uid)
+}
+
+// Set mocks base method.
+func (m *MockWechatMiniSessionCache) Set(ctx context.Context, uid int64, val string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, uid, val)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockWechatMiniSessionCacheMockRecorder) Set(ctx, uid, val any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockWechatMiniSessionCache)(nil).Set), ctx, uid, val)
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:generate mockgen.exe -source=./oauth2_state.go -package=cachemocks -destination=mocks/oauth2_state.mock.go OAuth2StateCache
+type OAuth2StateCache interface {
+	Set(ctx context.Context, state string, val domain.OAuth2State) error
+	// Take 取出来之后就删除，state 只能用一次
+	Take(ctx context.Context, state string) (domain.OAuth2State, error)
+}
+
+// RedisOAuth2StateCache 第三方授权的 state
+type RedisOAuth2StateCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisOAuth2StateCache(client redis.Cmdable) OAuth2StateCache {
+	return &RedisOAuth2StateCache{
+		client: client,
+		// 你预期中一个用户完成授权的时间
+		expiration: time.Minute * 10,
+	}
+}
+
+func (cache *RedisOAuth2StateCache) Set(ctx context.Context, state string, val domain.OAuth2State) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return cache.client.Set(ctx, cache.key(state), data, cache.expiration).Err()
+}
+
+// Take state 不存在返回 ErrKeyNotExist
+func (cache *RedisOAuth2StateCache) Take(ctx context.Context, state string) (domain.OAuth2State, error) {
+	data, err := cache.client.GetDel(ctx, cache.key(state)).Bytes()
+	if err != nil {
+		return domain.OAuth2State{}, err
+	}
+	var res domain.OAuth2State
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+func (cache *RedisOAuth2StateCache) key(state string) string {
+	return fmt.Sprintf("oauth2:state:%s", state)
+}
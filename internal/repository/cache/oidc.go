@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:generate mockgen.exe -source=./oidc.go -package=cachemocks -destination=mocks/oidc.mock.go OIDCCache
+type OIDCCache interface {
+	SetCode(ctx context.Context, code string, val domain.AuthCode) error
+	// TakeCode 取出来之后就删除，授权码只能用一次
+	TakeCode(ctx context.Context, code string) (domain.AuthCode, error)
+}
+
+// RedisOIDCCache 授权码
+type RedisOIDCCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisOIDCCache(client redis.Cmdable) OIDCCache {
+	return &RedisOIDCCache{
+		client: client,
+		// RFC 6749 建议授权码最长 10 分钟，越短越好
+		expiration: time.Minute,
+	}
+}
+
+func (cache *RedisOIDCCache) SetCode(ctx context.Context, code string, val domain.AuthCode) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return cache.client.Set(ctx, cache.key(code), data, cache.expiration).Err()
+}
+
+// TakeCode 授权码不存在返回 ErrKeyNotExist
+func (cache *RedisOIDCCache) TakeCode(ctx context.Context, code string) (domain.AuthCode, error) {
+	data, err := cache.client.GetDel(ctx, cache.key(code)).Bytes()
+	if err != nil {
+		return domain.AuthCode{}, err
+	}
+	var res domain.AuthCode
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
+
+func (cache *RedisOIDCCache) key(code string) string {
+	return fmt.Sprintf("oidc:code:%s", code)
+}
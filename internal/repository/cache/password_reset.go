@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:generate mockgen.exe -source=./password_reset.go -package=cachemocks -destination=mocks/password_reset.mock.go PasswordResetCache
+type PasswordResetCache interface {
+	Set(ctx context.Context, token string, uid int64) error
+	// Take 取出来之后就删除，保证凭证只能用一次
+	Take(ctx context.Context, token string) (int64, error)
+}
+
+// RedisPasswordResetCache 找回密码的凭证
+type RedisPasswordResetCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisPasswordResetCache(client redis.Cmdable) PasswordResetCache {
+	return &RedisPasswordResetCache{
+		client:     client,
+		expiration: time.Minute * 10,
+	}
+}
+
+// Set 设置凭证，10 分钟内有效
+func (cache *RedisPasswordResetCache) Set(ctx context.Context, token string, uid int64) error {
+	return cache.client.Set(ctx, cache.key(token), uid, cache.expiration).Err()
+}
+
+// Take 取出凭证对应的用户，凭证不存在返回 ErrKeyNotExist
+func (cache *RedisPasswordResetCache) Take(ctx context.Context, token string) (int64, error) {
+	return cache.client.GetDel(ctx, cache.key(token)).Int64()
+}
+
+func (cache *RedisPasswordResetCache) key(token string) string {
+	return fmt.Sprintf("users:reset_pwd:%s", token)
+}
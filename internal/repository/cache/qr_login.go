@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+var ErrQRLoginStatusConflict = errors.New("扫码登录的状态已经变化")
+
+//go:generate mockgen.exe -source=./qr_login.go -package=cachemocks -destination=mocks/qr_login.mock.go QRLoginCache
+type QRLoginCache interface {
+	Set(ctx context.Context, qr domain.QRLogin, expiration time.Duration) error
+	// Get 票据不存在或者过期返回 ErrKeyNotExist
+	Get(ctx context.Context, ticket string) (domain.QRLogin, error)
+	// Transit 状态是 from 的时候改成 to，to 为空的时候删除票据，返回扫码的用户
+	// uid 不为 0 的时候必须和扫码的用户一致，状态不对返回 ErrQRLoginStatusConflict
+	Transit(ctx context.Context, ticket string, from, to string, uid int64, expiration time.Duration) (int64, error)
+}
+
+//go:embed lua/qr_login_transit.lua
+var luaQRLoginTransit string
+
+// RedisQRLoginCache 扫码登录的票据，每个状态的过期时间不一样
+type RedisQRLoginCache struct {
+	client redis.Cmdable
+}
+
+func NewRedisQRLoginCache(client redis.Cmdable) QRLoginCache {
+	return &RedisQRLoginCache{
+		client: client,
+	}
+}
+
+func (cache *RedisQRLoginCache) Set(ctx context.Context, qr domain.QRLogin, expiration time.Duration) error {
+	key := cache.key(qr.Ticket)
+	pipe := cache.client.TxPipeline()
+	pipe.HSet(ctx, key,
+		"secret", qr.Secret,
+		"status", qr.Status,
+		"uid", qr.Uid,
+		"user_agent", qr.Device.UserAgent,
+		"ip", qr.Device.IP,
+		"ctime", qr.Ctime.UnixMilli())
+	pipe.Expire(ctx, key, expiration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (cache *RedisQRLoginCache) Get(ctx context.Context, ticket string) (domain.QRLogin, error) {
+	vals, err := cache.client.HGetAll(ctx, cache.key(ticket)).Result()
+	if err != nil {
+		return domain.QRLogin{}, err
+	}
+	if len(vals) == 0 {
+		return domain.QRLogin{}, ErrKeyNotExist
+	}
+	uid, _ := strconv.ParseInt(vals["uid"], 10, 64)
+	ctime, _ := strconv.ParseInt(vals["ctime"], 10, 64)
+	return domain.QRLogin{
+		Ticket: ticket,
+		Secret: vals["secret"],
+		Status: vals["status"],
+		Uid:    uid,
+		Device: domain.QRLoginDevice{
+			UserAgent: vals["user_agent"],
+			IP:        vals["ip"],
+		},
+		Ctime: time.UnixMilli(ctime),
+	}, nil
+}
+
+func (cache *RedisQRLoginCache) Transit(ctx context.Context, ticket string, from, to string, uid int64, expiration time.Duration) (int64, error) {
+	res, err := cache.client.Eval(ctx, luaQRLoginTransit, []string{cache.key(ticket)},
+		from, to, int64(expiration.Seconds()), uid).Int64()
+	if err != nil {
+		return 0, err
+	}
+	switch res {
+	case -1:
+		return 0, ErrKeyNotExist
+	case -2:
+		return 0, ErrQRLoginStatusConflict
+	default:
+		return res, nil
+	}
+}
+
+func (cache *RedisQRLoginCache) key(ticket string) string {
+	return fmt.Sprintf("users:qr_login:%s", ticket)
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:generate mockgen.exe -source=./rbac.go -package=cachemocks -destination=mocks/rbac.mock.go RBACCache
+type RBACCache interface {
+	// GetPermissions 没有缓存返回 ErrKeyNotExist，没有任何权限的用户也会缓存
+	GetPermissions(ctx context.Context, uid int64) ([]string, error)
+	SetPermissions(ctx context.Context, uid int64, permissions []string) error
+	DelPermissions(ctx context.Context, uids ...int64) error
+}
+
+// RedisRBACCache 缓存用户的权限，每个需要权限的请求都要查
+type RedisRBACCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisRBACCache(client redis.Cmdable) RBACCache {
+	return &RedisRBACCache{
+		client:     client,
+		expiration: time.Minute * 10,
+	}
+}
+
+func (cache *RedisRBACCache) GetPermissions(ctx context.Context, uid int64) ([]string, error) {
+	val, err := cache.client.Get(ctx, cache.key(uid)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	err = json.Unmarshal(val, &res)
+	return res, err
+}
+
+func (cache *RedisRBACCache) SetPermissions(ctx context.Context, uid int64, permissions []string) error {
+	if permissions == nil {
+		permissions = []string{}
+	}
+	val, err := json.Marshal(permissions)
+	if err != nil {
+		return err
+	}
+	return cache.client.Set(ctx, cache.key(uid), val, cache.expiration).Err()
+}
+
+func (cache *RedisRBACCache) DelPermissions(ctx context.Context, uids ...int64) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		keys = append(keys, cache.key(uid))
+	}
+	return cache.client.Del(ctx, keys...).Err()
+}
+
+func (cache *RedisRBACCache) key(uid int64) string {
+	return fmt.Sprintf("users:permissions:%d", uid)
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+var ErrChallengeTooManyAttempts = errors.New("两步验证尝试次数太多")
+
+//go:generate mockgen.exe -source=./two_factor.go -package=cachemocks -destination=mocks/two_factor.mock.go TwoFactorCache
+type TwoFactorCache interface {
+	// SetChallenge 密码校验通过之后，记录等待第二步验证的用户
+	SetChallenge(ctx context.Context, token string, c domain.TwoFactorChallenge) error
+	// GetChallenge 每调用一次算一次尝试，超过次数之后返回 ErrChallengeTooManyAttempts
+	GetChallenge(ctx context.Context, token string) (domain.TwoFactorChallenge, error)
+	DelChallenge(ctx context.Context, token string) error
+}
+
+//go:embed lua/get_challenge.lua
+var luaGetChallenge string
+
+// RedisTwoFactorCache 两步验证的登录挑战
+type RedisTwoFactorCache struct {
+	client      redis.Cmdable
+	expiration  time.Duration
+	maxAttempts int64
+}
+
+func NewRedisTwoFactorCache(client redis.Cmdable) TwoFactorCache {
+	return &RedisTwoFactorCache{
+		client:      client,
+		expiration:  time.Minute * 5,
+		maxAttempts: 5,
+	}
+}
+
+func (cache *RedisTwoFactorCache) SetChallenge(ctx context.Context, token string, c domain.TwoFactorChallenge) error {
+	key := cache.key(token)
+	pipe := cache.client.TxPipeline()
+	pipe.HSet(ctx, key, "uid", c.Uid, "method", c.Method, "account", c.Account, "cnt", 0)
+	pipe.Expire(ctx, key, cache.expiration)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetChallenge 挑战不存在返回 ErrKeyNotExist
+func (cache *RedisTwoFactorCache) GetChallenge(ctx context.Context, token string) (domain.TwoFactorChallenge, error) {
+	res, err := cache.client.Eval(ctx, luaGetChallenge, []string{cache.key(token)}, cache.maxAttempts).Result()
+	if err != nil {
+		return domain.TwoFactorChallenge{}, err
+	}
+	switch val := res.(type) {
+	case int64:
+		if val == -2 {
+			return domain.TwoFactorChallenge{}, ErrChallengeTooManyAttempts
+		}
+		return domain.TwoFactorChallenge{}, ErrKeyNotExist
+	case []any:
+		// uid、method 和 account
+		if len(val) != 3 {
+			return domain.TwoFactorChallenge{}, fmt.Errorf("两步验证挑战的格式不对 %v", val)
+		}
+		uidStr, _ := val[0].(string)
+		uid, err := strconv.ParseInt(uidStr, 10, 64)
+		if err != nil {
+			return domain.TwoFactorChallenge{}, err
+		}
+		method, _ := val[1].(string)
+		account, _ := val[2].(string)
+		return domain.TwoFactorChallenge{Uid: uid, Method: method, Account: account}, nil
+	default:
+		return domain.TwoFactorChallenge{}, fmt.Errorf("两步验证挑战的格式不对 %v", res)
+	}
+}
+
+func (cache *RedisTwoFactorCache) DelChallenge(ctx context.Context, token string) error {
+	return cache.client.Del(ctx, cache.key(token)).Err()
+}
+
+func (cache *RedisTwoFactorCache) key(token string) string {
+	return fmt.Sprintf("users:2fa_challenge:%s", token)
+}
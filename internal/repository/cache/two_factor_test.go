@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// TestRedisTwoFactorCache_GetChallenge 取出挑战的内容，超过次数之后挑战作废
+func TestRedisTwoFactorCache_GetChallenge(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	cache := NewRedisTwoFactorCache(client)
+	ctx := context.Background()
+
+	_, err := cache.GetChallenge(ctx, "challenge")
+	assert.Equal(t, ErrKeyNotExist, err)
+
+	c := domain.TwoFactorChallenge{Uid: 123, Method: "password", Account: "1426325504@qq.com"}
+	require.NoError(t, cache.SetChallenge(ctx, "challenge", c))
+	for i := 0; i < 5; i++ {
+		res, err := cache.GetChallenge(ctx, "challenge")
+		require.NoError(t, err)
+		assert.Equal(t, c, res)
+	}
+	_, err = cache.GetChallenge(ctx, "challenge")
+	assert.Equal(t, ErrChallengeTooManyAttempts, err)
+	assert.False(t, mr.Exists("users:2fa_challenge:challenge"))
+}
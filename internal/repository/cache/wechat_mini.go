@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:generate mockgen.exe -source=./wechat_mini.go -package=cachemocks -destination=mocks/wechat_mini.mock.go WechatMiniSessionCache
+type WechatMiniSessionCache interface {
+	// Set val 是加密之后的 session_key
+	Set(ctx context.Context, uid int64, val string) error
+	// Get 不存在或者过期返回 ErrKeyNotExist
+	Get(ctx context.Context, uid int64) (string, error)
+	Delete(ctx context.Context, uid int64) error
+}
+
+// RedisWechatMiniSessionCache 小程序的 session_key，每次 wx.login 都会刷新
+type RedisWechatMiniSessionCache struct {
+	client     redis.Cmdable
+	expiration time.Duration
+}
+
+func NewRedisWechatMiniSessionCache(client redis.Cmdable) WechatMiniSessionCache {
+	return &RedisWechatMiniSessionCache{
+		client: client,
+		// 微信没有公布 session_key 的有效期，小程序一般三天内会重新登录
+		expiration: time.Hour * 24 * 3,
+	}
+}
+
+func (cache *RedisWechatMiniSessionCache) Set(ctx context.Context, uid int64, val string) error {
+	return cache.client.Set(ctx, cache.key(uid), val, cache.expiration).Err()
+}
+
+func (cache *RedisWechatMiniSessionCache) Get(ctx context.Context, uid int64) (string, error) {
+	val, err := cache.client.Get(ctx, cache.key(uid)).Result()
+	if err == redis.Nil {
+		return "", ErrKeyNotExist
+	}
+	return val, err
+}
+
+func (cache *RedisWechatMiniSessionCache) Delete(ctx context.Context, uid int64) error {
+	return cache.client.Del(ctx, cache.key(uid)).Err()
+}
+
+func (cache *RedisWechatMiniSessionCache) key(uid int64) string {
+	return fmt.Sprintf("wechat:mini:session_key:%d", uid)
+}
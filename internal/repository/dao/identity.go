@@ -0,0 +1,121 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrIdentityNotFound = gorm.ErrRecordNotFound
+	// ErrIdentityDuplicate 第三方账号已经绑定过了
+	ErrIdentityDuplicate = errors.New("第三方账号已经绑定")
+)
+
+//go:generate mockgen.exe -source=./identity.go -package=daomocks -destination=mocks/identity.mock.go IdentityDAO
+type IdentityDAO interface {
+	FindBySubject(ctx context.Context, provider, subject string) (UserIdentity, error)
+	FindByUid(ctx context.Context, uid int64) ([]UserIdentity, error)
+	// FindByUnionId 在 providers 里面按 unionid 查找，找到多个的时候返回最早绑定的
+	FindByUnionId(ctx context.Context, providers []string, unionId string) (UserIdentity, error)
+	Insert(ctx context.Context, i UserIdentity) error
+	// InsertWithUser 在一个事务里面新建用户并绑定第三方账号，返回用户 id
+	InsertWithUser(ctx context.Context, u User, i UserIdentity) (int64, error)
+	// Delete 解绑，没有绑定返回 ErrIdentityNotFound
+	Delete(ctx context.Context, uid int64, provider string) error
+}
+
+type GORMIdentityDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMIdentityDAO(db *gorm.DB) IdentityDAO {
+	return &GORMIdentityDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMIdentityDAO) FindBySubject(ctx context.Context, provider, subject string) (UserIdentity, error) {
+	var i UserIdentity
+	err := dao.db.WithContext(ctx).
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&i).Error
+	return i, err
+}
+
+func (dao *GORMIdentityDAO) FindByUid(ctx context.Context, uid int64) ([]UserIdentity, error) {
+	var res []UserIdentity
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).Order("id").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMIdentityDAO) FindByUnionId(ctx context.Context, providers []string, unionId string) (UserIdentity, error) {
+	var i UserIdentity
+	err := dao.db.WithContext(ctx).
+		Where("provider IN ? AND union_id = ?", providers, unionId).
+		Order("id").First(&i).Error
+	return i, err
+}
+
+func (dao *GORMIdentityDAO) Insert(ctx context.Context, i UserIdentity) error {
+	now := time.Now().UnixMilli()
+	i.Ctime = now
+	i.Utime = now
+	return dao.identityErr(dao.db.WithContext(ctx).Create(&i).Error)
+}
+
+func (dao *GORMIdentityDAO) InsertWithUser(ctx context.Context, u User, i UserIdentity) (int64, error) {
+	now := time.Now().UnixMilli()
+	u.Ctime, u.Utime = now, now
+	i.Ctime, i.Utime = now, now
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&u).Error; err != nil {
+			return err
+		}
+		i.Uid = u.Id
+		return tx.Create(&i).Error
+	})
+	// 微信用户的 openid 在 users 表上也有唯一索引，冲突的时候同样是重复绑定
+	return u.Id, dao.identityErr(err)
+}
+
+func (dao *GORMIdentityDAO) Delete(ctx context.Context, uid int64, provider string) error {
+	res := dao.db.WithContext(ctx).
+		Where("uid = ? AND provider = ?", uid, provider).
+		Delete(&UserIdentity{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (dao *GORMIdentityDAO) identityErr(err error) error {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+		const uniqueConflictsErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictsErrNo {
+			return ErrIdentityDuplicate
+		}
+	}
+	return err
+}
+
+// UserIdentity 用户绑定的第三方账号，表名 user_identities
+// 一个用户在同一个提供方只能绑定一个账号
+type UserIdentity struct {
+	Id       int64          `gorm:"primaryKey,autoIncrement"`
+	Uid      int64          `gorm:"uniqueIndex:uid_provider"`
+	Provider string         `gorm:"type:varchar(32);uniqueIndex:provider_subject;uniqueIndex:uid_provider"`
+	Subject  string         `gorm:"type:varchar(128);uniqueIndex:provider_subject"`
+	UnionId  sql.NullString `gorm:"type:varchar(128);index"`
+	Nickname string         `gorm:"type:varchar(128)"`
+	Avatar   string         `gorm:"type:varchar(512)"`
+	Email    string         `gorm:"type:varchar(128)"`
+	Ctime    int64
+	Utime    int64
+}
@@ -4,5 +4,10 @@ import "gorm.io/gorm"
 
 // InitTable 初始化表
 func InitTable(db *gorm.DB) error {
-	return db.AutoMigrate(&User{})
+	err := db.AutoMigrate(&User{}, &UserTOTP{}, &OAuthClient{}, &OAuthConsent{}, &UserIdentity{}, &WechatToken{}, &PersonalAccessToken{},
+		&Role{}, &RolePermission{}, &UserRole{}, &Migration{}, &UserDeletedOutbox{})
+	if err != nil {
+		return err
+	}
+	return runMigrations(db)
 }
@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Migration 已经执行过的数据迁移，AutoMigrate 只管表结构，回填数据这类只能执行一次的操作记在这里
+type Migration struct {
+	Name  string `gorm:"primaryKey;type:varchar(128)"`
+	Ctime int64
+}
+
+// migration 一次数据迁移，name 不能修改，否则会重新执行
+type migration struct {
+	name string
+	up   func(tx *gorm.DB) error
+}
+
+// migrations 按顺序执行，只能在后面追加
+var migrations = []migration{
+	{
+		// 上线邮箱验证之前的邮箱用户没有 verified_at，不回填的话全部都登录不了
+		name: "backfill_users_verified_at",
+		up: func(tx *gorm.DB) error {
+			return tx.Model(&User{}).
+				Where("email IS NOT NULL AND verified_at IS NULL").
+				Update("verified_at", gorm.Expr("ctime")).Error
+		},
+	},
+}
+
+// runMigrations 执行还没有执行过的数据迁移
+// 迁移和迁移记录在同一个事务里面，多个实例同时启动的时候，插入迁移记录主键冲突的那个实例回滚
+func runMigrations(db *gorm.DB) error {
+	for _, m := range migrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var cnt int64
+			err := tx.Model(&Migration{}).Where("name = ?", m.name).Count(&cnt).Error
+			if err != nil || cnt > 0 {
+				return err
+			}
+			if err = m.up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&Migration{Name: m.name, Ctime: time.Now().UnixMilli()}).Error
+		})
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			// 其它实例已经执行过了
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
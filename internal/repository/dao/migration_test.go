@@ -0,0 +1,98 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gormMysql "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestRunMigrations(t *testing.T) {
+	testCases := []struct {
+		name    string
+		sqlmock func(t *testing.T) (*sql.DB, sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "第一次启动，回填并记录",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `migrations`").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec("UPDATE `users` SET `verified_at`=ctime.*WHERE email IS NOT NULL AND verified_at IS NULL").
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				mock.ExpectExec("INSERT INTO `migrations`").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				return mockDB, mock
+			},
+		},
+		{
+			name: "已经执行过，不会重复回填",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `migrations`").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectCommit()
+				return mockDB, mock
+			},
+		},
+		{
+			name: "其它实例同时执行，回滚",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `migrations`").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec("UPDATE `users`").
+					WillReturnResult(sqlmock.NewResult(0, 10))
+				mock.ExpectExec("INSERT INTO `migrations`").
+					WillReturnError(&mysql.MySQLError{Number: 1062})
+				mock.ExpectRollback()
+				return mockDB, mock
+			},
+		},
+		{
+			name: "回填失败",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `migrations`").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec("UPDATE `users`").
+					WillReturnError(errors.New("数据库错误"))
+				mock.ExpectRollback()
+				return mockDB, mock
+			},
+			wantErr: errors.New("数据库错误"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB, mock := tc.sqlmock(t)
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			err = runMigrations(db)
+			assert.Equal(t, tc.wantErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oidc.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oidc.go -package=daomocks -destination=mocks/oidc.mock.go OIDCDAO
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "github.com/dadaxiaoxiao/user/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOIDCDAO is a mock of OIDCDAO interface.
+type MockOIDCDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCDAOMockRecorder
+}
+
+// MockOIDCDAOMockRecorder is the mock recorder for MockOIDCDAO.
+type MockOIDCDAOMockRecorder struct {
+	mock *MockOIDCDAO
+}
+
+// NewMockOIDCDAO creates a new mock instance.
+func NewMockOIDCDAO(ctrl *gomock.Controller) *MockOIDCDAO {
+	mock := &MockOIDCDAO{ctrl: ctrl}
+	mock.recorder = &MockOIDCDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCDAO) EXPECT() *MockOIDCDAOMockRecorder {
+	return m.recorder
+}
+
+// FindClient mocks base method.
+func (m *MockOIDCDAO) FindClient(ctx context.Context, clientId string) (dao.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindClient", ctx, clientId)
+	ret0, _ := ret[0].(dao.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindClient indicates an expected call of FindClient.
+func (mr *MockOIDCDAOMockRecorder) FindClient(ctx, clientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindClient", reflect.TypeOf((*MockOIDCDAO)(nil).FindClient), ctx, clientId)
+}
+
+// FindConsent mocks base method.
+func (m *MockOIDCDAO) FindConsent(ctx context.Context, uid int64, clientId string) (dao.OAuthConsent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindConsent", ctx, uid, clientId)
+	ret0, _ := ret[0].(dao.OAuthConsent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindConsent indicates an expected call of FindConsent.
+func (mr *MockOIDCDAOMockRecorder) FindConsent(ctx, uid, clientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindConsent", reflect.TypeOf((*MockOIDCDAO)(nil).FindConsent), ctx, uid, clientId)
+}
+
+// InsertClient mocks base method.
+func (m *MockOIDCDAO) InsertClient(ctx context.Context, c dao.OAuthClient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertClient", ctx, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertClient indicates an expected call of InsertClient.
+func (mr *MockOIDCDAOMockRecorder) InsertClient(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertClient", reflect.TypeOf((*MockOIDCDAO)(nil).InsertClient), ctx, c)
+}
+
+// UpsertConsent mocks base method.
+func (m *MockOIDCDAO) UpsertConsent(ctx context.Context, c dao.OAuthConsent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertConsent", ctx, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpsertConsent indicates an expected call of UpsertConsent.
+func (mr *MockOIDCDAOMockRecorder) UpsertConsent(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertConsent", reflect.TypeOf((*MockOIDCDAO)(nil).UpsertConsent), ctx, c)
+}
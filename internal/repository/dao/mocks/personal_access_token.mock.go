@@ -0,0 +1,114 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./personal_access_token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./personal_access_token.go -package=daomocks -destination=mocks/personal_access_token.mock.go PersonalAccessTokenDAO
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "github.com/dadaxiaoxiao/user/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPersonalAccessTokenDAO is a mock of PersonalAccessTokenDAO interface.
+type MockPersonalAccessTokenDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockPersonalAccessTokenDAOMockRecorder
+}
+
+// MockPersonalAccessTokenDAOMockRecorder is the mock recorder for MockPersonalAccessTokenDAO.
+type MockPersonalAccessTokenDAOMockRecorder struct {
+	mock *MockPersonalAccessTokenDAO
+}
+
+// NewMockPersonalAccessTokenDAO creates a new mock instance.
+func NewMockPersonalAccessTokenDAO(ctrl *gomock.Controller) *MockPersonalAccessTokenDAO {
+	mock := &MockPersonalAccessTokenDAO{ctrl: ctrl}
+	mock.recorder = &MockPersonalAccessTokenDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPersonalAccessTokenDAO) EXPECT() *MockPersonalAccessTokenDAOMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockPersonalAccessTokenDAO) Delete(ctx context.Context, uid, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockPersonalAccessTokenDAOMockRecorder) Delete(ctx, uid, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPersonalAccessTokenDAO)(nil).Delete), ctx, uid, id)
+}
+
+// FindByHash mocks base method.
+func (m *MockPersonalAccessTokenDAO) FindByHash(ctx context.Context, hash string) (dao.PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByHash", ctx, hash)
+	ret0, _ := ret[0].(dao.PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByHash indicates an expected call of FindByHash.
+func (mr *MockPersonalAccessTokenDAOMockRecorder) FindByHash(ctx, hash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByHash", reflect.TypeOf((*MockPersonalAccessTokenDAO)(nil).FindByHash), ctx, hash)
+}
+
+// FindByUid mocks base method.
+func (m *MockPersonalAccessTokenDAO) FindByUid(ctx context.Context, uid int64) ([]dao.PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].([]dao.PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockPersonalAccessTokenDAOMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockPersonalAccessTokenDAO)(nil).FindByUid), ctx, uid)
+}
+
+// Insert mocks base method.
+func (m *MockPersonalAccessTokenDAO) Insert(ctx context.Context, t dao.PersonalAccessToken) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, t)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockPersonalAccessTokenDAOMockRecorder) Insert(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockPersonalAccessTokenDAO)(nil).Insert), ctx, t)
+}
+
+// UpdateLastUsed mocks base method.
+func (m *MockPersonalAccessTokenDAO) UpdateLastUsed(ctx context.Context, id, lastUsed int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastUsed", ctx, id, lastUsed)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastUsed indicates an expected call of UpdateLastUsed.
+func (mr *MockPersonalAccessTokenDAOMockRecorder) UpdateLastUsed(ctx, id, lastUsed any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastUsed", reflect.TypeOf((*MockPersonalAccessTokenDAO)(nil).UpdateLastUsed), ctx, id, lastUsed)
+}
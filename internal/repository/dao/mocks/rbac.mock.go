@@ -0,0 +1,159 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./rbac.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./rbac.go -package=daomocks -destination=mocks/rbac.mock.go RBACDAO
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "github.com/dadaxiaoxiao/user/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRBACDAO is a mock of RBACDAO interface.
+type MockRBACDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockRBACDAOMockRecorder
+}
+
+// MockRBACDAOMockRecorder is the mock recorder for MockRBACDAO.
+type MockRBACDAOMockRecorder struct {
+	mock *MockRBACDAO
+}
+
+// NewMockRBACDAO creates a new mock instance.
+func NewMockRBACDAO(ctrl *gomock.Controller) *MockRBACDAO {
+	mock := &MockRBACDAO{ctrl: ctrl}
+	mock.recorder = &MockRBACDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRBACDAO) EXPECT() *MockRBACDAOMockRecorder {
+	return m.recorder
+}
+
+// DeleteRole mocks base method.
+func (m *MockRBACDAO) DeleteRole(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRole", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRole indicates an expected call of DeleteRole.
+func (mr *MockRBACDAOMockRecorder) DeleteRole(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRole", reflect.TypeOf((*MockRBACDAO)(nil).DeleteRole), ctx, id)
+}
+
+// FindPermissions mocks base method.
+func (m *MockRBACDAO) FindPermissions(ctx context.Context, roleIds []int64) ([]dao.RolePermission, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPermissions", ctx, roleIds)
+	ret0, _ := ret[0].([]dao.RolePermission)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPermissions indicates an expected call of FindPermissions.
+func (mr *MockRBACDAOMockRecorder) FindPermissions(ctx, roleIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPermissions", reflect.TypeOf((*MockRBACDAO)(nil).FindPermissions), ctx, roleIds)
+}
+
+// FindPermissionsByUid mocks base method.
+func (m *MockRBACDAO) FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPermissionsByUid", ctx, uid)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPermissionsByUid indicates an expected call of FindPermissionsByUid.
+func (mr *MockRBACDAOMockRecorder) FindPermissionsByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPermissionsByUid", reflect.TypeOf((*MockRBACDAO)(nil).FindPermissionsByUid), ctx, uid)
+}
+
+// FindRoles mocks base method.
+func (m *MockRBACDAO) FindRoles(ctx context.Context) ([]dao.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRoles", ctx)
+	ret0, _ := ret[0].([]dao.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRoles indicates an expected call of FindRoles.
+func (mr *MockRBACDAOMockRecorder) FindRoles(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRoles", reflect.TypeOf((*MockRBACDAO)(nil).FindRoles), ctx)
+}
+
+// FindRolesByUid mocks base method.
+func (m *MockRBACDAO) FindRolesByUid(ctx context.Context, uid int64) ([]dao.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRolesByUid", ctx, uid)
+	ret0, _ := ret[0].([]dao.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRolesByUid indicates an expected call of FindRolesByUid.
+func (mr *MockRBACDAOMockRecorder) FindRolesByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRolesByUid", reflect.TypeOf((*MockRBACDAO)(nil).FindRolesByUid), ctx, uid)
+}
+
+// FindUidsByRole mocks base method.
+func (m *MockRBACDAO) FindUidsByRole(ctx context.Context, roleId int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUidsByRole", ctx, roleId)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUidsByRole indicates an expected call of FindUidsByRole.
+func (mr *MockRBACDAOMockRecorder) FindUidsByRole(ctx, roleId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUidsByRole", reflect.TypeOf((*MockRBACDAO)(nil).FindUidsByRole), ctx, roleId)
+}
+
+// SaveRole mocks base method.
+func (m *MockRBACDAO) SaveRole(ctx context.Context, r dao.Role, permissions []string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRole", ctx, r, permissions)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveRole indicates an expected call of SaveRole.
+func (mr *MockRBACDAOMockRecorder) SaveRole(ctx, r, permissions any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRole", reflect.TypeOf((*MockRBACDAO)(nil).SaveRole), ctx, r, permissions)
+}
+
+// SetUserRoles mocks base method.
+func (m *MockRBACDAO) SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRoles", ctx, uid, roleIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRoles indicates an expected call of SetUserRoles.
+func (mr *MockRBACDAOMockRecorder) SetUserRoles(ctx, uid, roleIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRoles", reflect.TypeOf((*MockRBACDAO)(nil).SetUserRoles), ctx, uid, roleIds)
+}
@@ -3,7 +3,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=./user.go -package=daomocks -destination=mocks/user.mock.go UserDao
+//	mockgen -source=./user.go -package=daomocks -destination=mocks/user.mock.go
 //
 
 // Package daomocks is a generated GoMock package.
@@ -11,6 +11,7 @@ package daomocks
 
 import (
 	context "context"
+	sql "database/sql"
 	reflect "reflect"
 
 	dao "github.com/dadaxiaoxiao/user/internal/repository/dao"
@@ -40,6 +41,48 @@ func (m *MockUserDao) EXPECT() *MockUserDaoMockRecorder {
 	return m.recorder
 }
 
+// DeleteUserDeletedOutbox mocks base method.
+func (m *MockUserDao) DeleteUserDeletedOutbox(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserDeletedOutbox", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserDeletedOutbox indicates an expected call of DeleteUserDeletedOutbox.
+func (mr *MockUserDaoMockRecorder) DeleteUserDeletedOutbox(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserDeletedOutbox", reflect.TypeOf((*MockUserDao)(nil).DeleteUserDeletedOutbox), ctx, uid)
+}
+
+// Erase mocks base method.
+func (m *MockUserDao) Erase(ctx context.Context, id, now int64, wechatProvider string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Erase", ctx, id, now, wechatProvider)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Erase indicates an expected call of Erase.
+func (mr *MockUserDaoMockRecorder) Erase(ctx, id, now, wechatProvider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Erase", reflect.TypeOf((*MockUserDao)(nil).Erase), ctx, id, now, wechatProvider)
+}
+
+// FillProfile mocks base method.
+func (m *MockUserDao) FillProfile(ctx context.Context, id int64, nickname, avatar string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FillProfile", ctx, id, nickname, avatar)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FillProfile indicates an expected call of FillProfile.
+func (mr *MockUserDaoMockRecorder) FillProfile(ctx, id, nickname, avatar any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FillProfile", reflect.TypeOf((*MockUserDao)(nil).FillProfile), ctx, id, nickname, avatar)
+}
+
 // FindByEmail mocks base method.
 func (m *MockUserDao) FindByEmail(ctx context.Context, email string) (dao.User, error) {
 	m.ctrl.T.Helper()
@@ -100,6 +143,51 @@ func (mr *MockUserDaoMockRecorder) FindByWechat(ctx, openID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByWechat", reflect.TypeOf((*MockUserDao)(nil).FindByWechat), ctx, openID)
 }
 
+// FindByWechatUnionId mocks base method.
+func (m *MockUserDao) FindByWechatUnionId(ctx context.Context, unionId string) (dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByWechatUnionId", ctx, unionId)
+	ret0, _ := ret[0].(dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByWechatUnionId indicates an expected call of FindByWechatUnionId.
+func (mr *MockUserDaoMockRecorder) FindByWechatUnionId(ctx, unionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByWechatUnionId", reflect.TypeOf((*MockUserDao)(nil).FindByWechatUnionId), ctx, unionId)
+}
+
+// FindDeletable mocks base method.
+func (m *MockUserDao) FindDeletable(ctx context.Context, now int64, limit int) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeletable", ctx, now, limit)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeletable indicates an expected call of FindDeletable.
+func (mr *MockUserDaoMockRecorder) FindDeletable(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeletable", reflect.TypeOf((*MockUserDao)(nil).FindDeletable), ctx, now, limit)
+}
+
+// FindUserDeletedOutbox mocks base method.
+func (m *MockUserDao) FindUserDeletedOutbox(ctx context.Context, limit int) ([]dao.UserDeletedOutbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserDeletedOutbox", ctx, limit)
+	ret0, _ := ret[0].([]dao.UserDeletedOutbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserDeletedOutbox indicates an expected call of FindUserDeletedOutbox.
+func (mr *MockUserDaoMockRecorder) FindUserDeletedOutbox(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserDeletedOutbox", reflect.TypeOf((*MockUserDao)(nil).FindUserDeletedOutbox), ctx, limit)
+}
+
 // Insert mocks base method.
 func (m *MockUserDao) Insert(ctx context.Context, u dao.User) error {
 	m.ctrl.T.Helper()
@@ -114,6 +202,91 @@ func (mr *MockUserDaoMockRecorder) Insert(ctx, u any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockUserDao)(nil).Insert), ctx, u)
 }
 
+// InsertIdentity mocks base method.
+func (m *MockUserDao) InsertIdentity(ctx context.Context, i dao.UserIdentity, syncWechat bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertIdentity", ctx, i, syncWechat)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertIdentity indicates an expected call of InsertIdentity.
+func (mr *MockUserDaoMockRecorder) InsertIdentity(ctx, i, syncWechat any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertIdentity", reflect.TypeOf((*MockUserDao)(nil).InsertIdentity), ctx, i, syncWechat)
+}
+
+// Merge mocks base method.
+func (m *MockUserDao) Merge(ctx context.Context, sourceId, targetId int64, merge func(dao.User, dao.User) (dao.User, error)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, sourceId, targetId, merge)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockUserDaoMockRecorder) Merge(ctx, sourceId, targetId, merge any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockUserDao)(nil).Merge), ctx, sourceId, targetId, merge)
+}
+
+// Search mocks base method.
+func (m *MockUserDao) Search(ctx context.Context, q dao.UserQuery) ([]dao.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, q)
+	ret0, _ := ret[0].([]dao.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUserDaoMockRecorder) Search(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUserDao)(nil).Search), ctx, q)
+}
+
+// UpdateDeleteAt mocks base method.
+func (m *MockUserDao) UpdateDeleteAt(ctx context.Context, id int64, deleteAt sql.NullInt64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeleteAt", ctx, id, deleteAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDeleteAt indicates an expected call of UpdateDeleteAt.
+func (mr *MockUserDaoMockRecorder) UpdateDeleteAt(ctx, id, deleteAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeleteAt", reflect.TypeOf((*MockUserDao)(nil).UpdateDeleteAt), ctx, id, deleteAt)
+}
+
+// UpdateEmail mocks base method.
+func (m *MockUserDao) UpdateEmail(ctx context.Context, id int64, email sql.NullString, verifiedAt sql.NullInt64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEmail", ctx, id, email, verifiedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEmail indicates an expected call of UpdateEmail.
+func (mr *MockUserDaoMockRecorder) UpdateEmail(ctx, id, email, verifiedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmail", reflect.TypeOf((*MockUserDao)(nil).UpdateEmail), ctx, id, email, verifiedAt)
+}
+
+// UpdateFrozen mocks base method.
+func (m *MockUserDao) UpdateFrozen(ctx context.Context, id int64, frozenAt sql.NullInt64, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFrozen", ctx, id, frozenAt, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFrozen indicates an expected call of UpdateFrozen.
+func (mr *MockUserDaoMockRecorder) UpdateFrozen(ctx, id, frozenAt, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFrozen", reflect.TypeOf((*MockUserDao)(nil).UpdateFrozen), ctx, id, frozenAt, reason)
+}
+
 // UpdateNonZeroFields mocks base method.
 func (m *MockUserDao) UpdateNonZeroFields(ctx context.Context, u dao.User) error {
 	m.ctrl.T.Helper()
@@ -127,3 +300,59 @@ func (mr *MockUserDaoMockRecorder) UpdateNonZeroFields(ctx, u any) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNonZeroFields", reflect.TypeOf((*MockUserDao)(nil).UpdateNonZeroFields), ctx, u)
 }
+
+// UpdatePassword mocks base method.
+func (m *MockUserDao) UpdatePassword(ctx context.Context, id int64, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, id, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserDaoMockRecorder) UpdatePassword(ctx, id, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserDao)(nil).UpdatePassword), ctx, id, password)
+}
+
+// UpdatePhone mocks base method.
+func (m *MockUserDao) UpdatePhone(ctx context.Context, id int64, phone sql.NullString) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePhone", ctx, id, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePhone indicates an expected call of UpdatePhone.
+func (mr *MockUserDaoMockRecorder) UpdatePhone(ctx, id, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePhone", reflect.TypeOf((*MockUserDao)(nil).UpdatePhone), ctx, id, phone)
+}
+
+// UpdateVerifiedAt mocks base method.
+func (m *MockUserDao) UpdateVerifiedAt(ctx context.Context, id, verifiedAt int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateVerifiedAt", ctx, id, verifiedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateVerifiedAt indicates an expected call of UpdateVerifiedAt.
+func (mr *MockUserDaoMockRecorder) UpdateVerifiedAt(ctx, id, verifiedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVerifiedAt", reflect.TypeOf((*MockUserDao)(nil).UpdateVerifiedAt), ctx, id, verifiedAt)
+}
+
+// UpdateWechat mocks base method.
+func (m *MockUserDao) UpdateWechat(ctx context.Context, id int64, openId, unionId sql.NullString) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWechat", ctx, id, openId, unionId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWechat indicates an expected call of UpdateWechat.
+func (mr *MockUserDaoMockRecorder) UpdateWechat(ctx, id, openId, unionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWechat", reflect.TypeOf((*MockUserDao)(nil).UpdateWechat), ctx, id, openId, unionId)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./wechat_token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./wechat_token.go -package=daomocks -destination=mocks/wechat_token.mock.go WechatTokenDAO
+//
+
+// Package daomocks is a generated GoMock package.
+package daomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dao "github.com/dadaxiaoxiao/user/internal/repository/dao"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWechatTokenDAO is a mock of WechatTokenDAO interface.
+type MockWechatTokenDAO struct {
+	ctrl     *gomock.Controller
+	recorder *MockWechatTokenDAOMockRecorder
+}
+
+// MockWechatTokenDAOMockRecorder is the mock recorder for MockWechatTokenDAO.
+type MockWechatTokenDAOMockRecorder struct {
+	mock *MockWechatTokenDAO
+}
+
+// NewMockWechatTokenDAO creates a new mock instance.
+func NewMockWechatTokenDAO(ctrl *gomock.Controller) *MockWechatTokenDAO {
+	mock := &MockWechatTokenDAO{ctrl: ctrl}
+	mock.recorder = &MockWechatTokenDAOMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWechatTokenDAO) EXPECT() *MockWechatTokenDAOMockRecorder {
+	return m.recorder
+}
+
+// FindByOpenId mocks base method.
+func (m *MockWechatTokenDAO) FindByOpenId(ctx context.Context, openId string) (dao.WechatToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByOpenId", ctx, openId)
+	ret0, _ := ret[0].(dao.WechatToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByOpenId indicates an expected call of FindByOpenId.
+func (mr *MockWechatTokenDAOMockRecorder) FindByOpenId(ctx, openId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByOpenId", reflect.TypeOf((*MockWechatTokenDAO)(nil).FindByOpenId), ctx, openId)
+}
+
+// Upsert mocks base method.
+func (m *MockWechatTokenDAO) Upsert(ctx context.Context, t dao.WechatToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upsert", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Upsert indicates an expected call of Upsert.
+func (mr *MockWechatTokenDAOMockRecorder) Upsert(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upsert", reflect.TypeOf((*MockWechatTokenDAO)(nil).Upsert), ctx, t)
+}
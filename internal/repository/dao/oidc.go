@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrOAuthClientNotFound  = gorm.ErrRecordNotFound
+	ErrOAuthConsentNotFound = gorm.ErrRecordNotFound
+)
+
+//go:generate mockgen.exe -source=./oidc.go -package=daomocks -destination=mocks/oidc.mock.go OIDCDAO
+type OIDCDAO interface {
+	InsertClient(ctx context.Context, c OAuthClient) error
+	FindClient(ctx context.Context, clientId string) (OAuthClient, error)
+	FindConsent(ctx context.Context, uid int64, clientId string) (OAuthConsent, error)
+	// UpsertConsent 重复授权的时候覆盖 scopes
+	UpsertConsent(ctx context.Context, c OAuthConsent) error
+}
+
+type GORMOIDCDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMOIDCDAO(db *gorm.DB) OIDCDAO {
+	return &GORMOIDCDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMOIDCDAO) InsertClient(ctx context.Context, c OAuthClient) error {
+	now := time.Now().UnixMilli()
+	c.Ctime = now
+	c.Utime = now
+	return dao.db.WithContext(ctx).Create(&c).Error
+}
+
+func (dao *GORMOIDCDAO) FindClient(ctx context.Context, clientId string) (OAuthClient, error) {
+	var c OAuthClient
+	err := dao.db.WithContext(ctx).Where("client_id = ?", clientId).First(&c).Error
+	return c, err
+}
+
+func (dao *GORMOIDCDAO) FindConsent(ctx context.Context, uid int64, clientId string) (OAuthConsent, error) {
+	var c OAuthConsent
+	err := dao.db.WithContext(ctx).Where("uid = ? AND client_id = ?", uid, clientId).First(&c).Error
+	return c, err
+}
+
+func (dao *GORMOIDCDAO) UpsertConsent(ctx context.Context, c OAuthConsent) error {
+	now := time.Now().UnixMilli()
+	c.Ctime = now
+	c.Utime = now
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"scopes": c.Scopes,
+			"utime":  now,
+		}),
+	}).Create(&c).Error
+}
+
+// OAuthClient 接入单点登录的应用
+type OAuthClient struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	ClientId string `gorm:"type:varchar(64);unique"`
+	// bcrypt 之后的 client_secret，公开客户端为空
+	SecretHash string
+	Name       string `gorm:"type:varchar(128)"`
+	// 回调地址，JSON 数组
+	RedirectURIs string `gorm:"type:varchar(2048)"`
+	Public       bool
+
+	Ctime int64
+	Utime int64
+}
+
+// OAuthConsent 用户的授权记录
+type OAuthConsent struct {
+	Id       int64  `gorm:"primaryKey,autoIncrement"`
+	Uid      int64  `gorm:"uniqueIndex:uid_client"`
+	ClientId string `gorm:"type:varchar(64);uniqueIndex:uid_client"`
+	// 空格分隔，和 OAuth2 的 scope 参数一样
+	Scopes string `gorm:"type:varchar(512)"`
+
+	Ctime int64
+	Utime int64
+}
@@ -0,0 +1,85 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"time"
+)
+
+var ErrPersonalAccessTokenNotFound = gorm.ErrRecordNotFound
+
+//go:generate mockgen.exe -source=./personal_access_token.go -package=daomocks -destination=mocks/personal_access_token.mock.go PersonalAccessTokenDAO
+type PersonalAccessTokenDAO interface {
+	Insert(ctx context.Context, t PersonalAccessToken) (int64, error)
+	FindByUid(ctx context.Context, uid int64) ([]PersonalAccessToken, error)
+	FindByHash(ctx context.Context, hash string) (PersonalAccessToken, error)
+	// Delete 只能删除自己的，没有删除任何数据返回 ErrPersonalAccessTokenNotFound
+	Delete(ctx context.Context, uid int64, id int64) error
+	UpdateLastUsed(ctx context.Context, id int64, lastUsed int64) error
+}
+
+type GORMPersonalAccessTokenDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMPersonalAccessTokenDAO(db *gorm.DB) PersonalAccessTokenDAO {
+	return &GORMPersonalAccessTokenDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMPersonalAccessTokenDAO) Insert(ctx context.Context, t PersonalAccessToken) (int64, error) {
+	now := time.Now().UnixMilli()
+	t.Ctime = now
+	t.Utime = now
+	err := dao.db.WithContext(ctx).Create(&t).Error
+	return t.Id, err
+}
+
+func (dao *GORMPersonalAccessTokenDAO) FindByUid(ctx context.Context, uid int64) ([]PersonalAccessToken, error) {
+	var res []PersonalAccessToken
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).Order("id DESC").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMPersonalAccessTokenDAO) FindByHash(ctx context.Context, hash string) (PersonalAccessToken, error) {
+	var t PersonalAccessToken
+	err := dao.db.WithContext(ctx).Where("token_hash = ?", hash).First(&t).Error
+	return t, err
+}
+
+func (dao *GORMPersonalAccessTokenDAO) Delete(ctx context.Context, uid int64, id int64) error {
+	res := dao.db.WithContext(ctx).Where("id = ? AND uid = ?", id, uid).Delete(&PersonalAccessToken{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrPersonalAccessTokenNotFound
+	}
+	return nil
+}
+
+func (dao *GORMPersonalAccessTokenDAO) UpdateLastUsed(ctx context.Context, id int64, lastUsed int64) error {
+	return dao.db.WithContext(ctx).Model(&PersonalAccessToken{}).Where("id = ?", id).
+		Updates(map[string]any{
+			"last_used_at": lastUsed,
+			"utime":        time.Now().UnixMilli(),
+		}).Error
+}
+
+// PersonalAccessToken 个人访问令牌
+type PersonalAccessToken struct {
+	Id   int64  `gorm:"primaryKey,autoIncrement"`
+	Uid  int64  `gorm:"index"`
+	Name string `gorm:"type:varchar(64)"`
+	// SHA-256 的十六进制
+	TokenHash string `gorm:"type:varchar(64);unique"`
+	Prefix    string `gorm:"type:varchar(16)"`
+	// 空格分隔
+	Scopes     string `gorm:"type:varchar(128)"`
+	ExpiresAt  int64
+	LastUsedAt int64
+
+	Ctime int64
+	Utime int64
+}
@@ -0,0 +1,193 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrRoleNotFound = gorm.ErrRecordNotFound
+	// ErrRoleDuplicate 角色名字重复
+	ErrRoleDuplicate = errors.New("角色已经存在")
+)
+
+//go:generate mockgen.exe -source=./rbac.go -package=daomocks -destination=mocks/rbac.mock.go RBACDAO
+type RBACDAO interface {
+	// SaveRole id 为 0 的时候新建，否则更新，权限整体替换
+	SaveRole(ctx context.Context, r Role, permissions []string) (int64, error)
+	// DeleteRole 同时删除角色的权限和用户的这个角色
+	DeleteRole(ctx context.Context, id int64) error
+	FindRoles(ctx context.Context) ([]Role, error)
+	FindRolesByUid(ctx context.Context, uid int64) ([]Role, error)
+	FindPermissions(ctx context.Context, roleIds []int64) ([]RolePermission, error)
+	FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error)
+	FindUidsByRole(ctx context.Context, roleId int64) ([]int64, error)
+	// SetUserRoles 整体替换用户的角色，roleIds 里面有不存在的角色返回 ErrRoleNotFound
+	SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error
+}
+
+type GORMRBACDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMRBACDAO(db *gorm.DB) RBACDAO {
+	return &GORMRBACDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMRBACDAO) SaveRole(ctx context.Context, r Role, permissions []string) (int64, error) {
+	now := time.Now().UnixMilli()
+	err := dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		r.Utime = now
+		if r.Id == 0 {
+			r.Ctime = now
+			if err := tx.Create(&r).Error; err != nil {
+				return err
+			}
+		} else {
+			res := tx.Model(&Role{}).Where("id = ?", r.Id).Updates(map[string]any{
+				"name":        r.Name,
+				"description": r.Description,
+				"utime":       now,
+			})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrRoleNotFound
+			}
+			if err := tx.Where("role_id = ?", r.Id).Delete(&RolePermission{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(permissions) == 0 {
+			return nil
+		}
+		rps := make([]RolePermission, 0, len(permissions))
+		for _, p := range permissions {
+			rps = append(rps, RolePermission{RoleId: r.Id, Permission: p, Ctime: now, Utime: now})
+		}
+		return tx.Create(&rps).Error
+	})
+	return r.Id, dao.roleErr(err)
+}
+
+func (dao *GORMRBACDAO) DeleteRole(ctx context.Context, id int64) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ?", id).Delete(&Role{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRoleNotFound
+		}
+		if err := tx.Where("role_id = ?", id).Delete(&RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("role_id = ?", id).Delete(&UserRole{}).Error
+	})
+}
+
+func (dao *GORMRBACDAO) FindRoles(ctx context.Context) ([]Role, error) {
+	var res []Role
+	err := dao.db.WithContext(ctx).Order("id").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMRBACDAO) FindRolesByUid(ctx context.Context, uid int64) ([]Role, error) {
+	var res []Role
+	err := dao.db.WithContext(ctx).
+		Where("id IN (?)", dao.db.Model(&UserRole{}).Select("role_id").Where("uid = ?", uid)).
+		Order("id").Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMRBACDAO) FindPermissions(ctx context.Context, roleIds []int64) ([]RolePermission, error) {
+	var res []RolePermission
+	err := dao.db.WithContext(ctx).Where("role_id IN ?", roleIds).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMRBACDAO) FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error) {
+	var res []string
+	err := dao.db.WithContext(ctx).Model(&RolePermission{}).Distinct("permission").
+		Where("role_id IN (?)", dao.db.Model(&UserRole{}).Select("role_id").Where("uid = ?", uid)).
+		Pluck("permission", &res).Error
+	return res, err
+}
+
+func (dao *GORMRBACDAO) FindUidsByRole(ctx context.Context, roleId int64) ([]int64, error) {
+	var res []int64
+	err := dao.db.WithContext(ctx).Model(&UserRole{}).Where("role_id = ?", roleId).Pluck("uid", &res).Error
+	return res, err
+}
+
+func (dao *GORMRBACDAO) SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(roleIds) > 0 {
+			var cnt int64
+			if err := tx.Model(&Role{}).Where("id IN ?", roleIds).Count(&cnt).Error; err != nil {
+				return err
+			}
+			if int(cnt) != len(roleIds) {
+				return ErrRoleNotFound
+			}
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&UserRole{}).Error; err != nil {
+			return err
+		}
+		if len(roleIds) == 0 {
+			return nil
+		}
+		urs := make([]UserRole, 0, len(roleIds))
+		for _, id := range roleIds {
+			urs = append(urs, UserRole{Uid: uid, RoleId: id, Ctime: now, Utime: now})
+		}
+		return tx.Create(&urs).Error
+	})
+}
+
+func (dao *GORMRBACDAO) roleErr(err error) error {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+		const uniqueConflictsErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictsErrNo {
+			return ErrRoleDuplicate
+		}
+	}
+	return err
+}
+
+// Role 角色
+type Role struct {
+	Id          int64  `gorm:"primaryKey,autoIncrement"`
+	Name        string `gorm:"type:varchar(64);unique"`
+	Description string `gorm:"type:varchar(256)"`
+
+	Ctime int64
+	Utime int64
+}
+
+// RolePermission 角色拥有的权限，权限的定义在 domain.Permissions
+type RolePermission struct {
+	Id         int64  `gorm:"primaryKey,autoIncrement"`
+	RoleId     int64  `gorm:"uniqueIndex:role_permission"`
+	Permission string `gorm:"type:varchar(64);uniqueIndex:role_permission"`
+
+	Ctime int64
+	Utime int64
+}
+
+// UserRole 用户拥有的角色
+type UserRole struct {
+	Id     int64 `gorm:"primaryKey,autoIncrement"`
+	Uid    int64 `gorm:"uniqueIndex:uid_role"`
+	RoleId int64 `gorm:"uniqueIndex:uid_role;index"`
+
+	Ctime int64
+	Utime int64
+}
@@ -0,0 +1,124 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var (
+	ErrTOTPNotFound = gorm.ErrRecordNotFound
+	// ErrTOTPConflict 并发修改，或者验证码已经用过了
+	ErrTOTPConflict = errors.New("两步验证数据已经被修改")
+)
+
+//go:generate mockgen.exe -source=./totp.go -package=daomocks -destination=mocks/totp.mock.go TOTPDAO
+type TOTPDAO interface {
+	// Upsert 重新绑定，会覆盖掉还没有启用的密钥
+	Upsert(ctx context.Context, t UserTOTP) error
+	FindByUid(ctx context.Context, uid int64) (UserTOTP, error)
+	Enable(ctx context.Context, uid int64, recoveryCodes string) error
+	// UpdateLastCounter 只允许计数器变大，否则返回 ErrTOTPConflict
+	UpdateLastCounter(ctx context.Context, uid int64, counter int64) error
+	// UpdateRecoveryCodes 只有 old 没有被修改过才会更新，否则返回 ErrTOTPConflict
+	UpdateRecoveryCodes(ctx context.Context, uid int64, old string, recoveryCodes string) error
+	Delete(ctx context.Context, uid int64) error
+}
+
+type GORMTOTPDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMTOTPDAO(db *gorm.DB) TOTPDAO {
+	return &GORMTOTPDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMTOTPDAO) Upsert(ctx context.Context, t UserTOTP) error {
+	now := time.Now().UnixMilli()
+	t.Ctime = now
+	t.Utime = now
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"secret":         t.Secret,
+			"recovery_codes": "",
+			"last_counter":   0,
+			"enabled_at":     nil,
+			"utime":          now,
+		}),
+	}).Create(&t).Error
+}
+
+func (dao *GORMTOTPDAO) FindByUid(ctx context.Context, uid int64) (UserTOTP, error) {
+	var t UserTOTP
+	err := dao.db.WithContext(ctx).Where("uid = ?", uid).First(&t).Error
+	return t, err
+}
+
+func (dao *GORMTOTPDAO) Enable(ctx context.Context, uid int64, recoveryCodes string) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("uid = ?", uid).
+		Updates(map[string]any{
+			"recovery_codes": recoveryCodes,
+			"enabled_at":     now,
+			"utime":          now,
+		}).Error
+}
+
+func (dao *GORMTOTPDAO) UpdateLastCounter(ctx context.Context, uid int64, counter int64) error {
+	res := dao.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("uid = ? AND last_counter < ?", uid, counter).
+		Updates(map[string]any{
+			"last_counter": counter,
+			"utime":        time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTOTPConflict
+	}
+	return nil
+}
+
+func (dao *GORMTOTPDAO) UpdateRecoveryCodes(ctx context.Context, uid int64, old string, recoveryCodes string) error {
+	res := dao.db.WithContext(ctx).Model(&UserTOTP{}).
+		Where("uid = ? AND recovery_codes = ?", uid, old).
+		Updates(map[string]any{
+			"recovery_codes": recoveryCodes,
+			"utime":          time.Now().UnixMilli(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTOTPConflict
+	}
+	return nil
+}
+
+func (dao *GORMTOTPDAO) Delete(ctx context.Context, uid int64) error {
+	return dao.db.WithContext(ctx).Where("uid = ?", uid).Delete(&UserTOTP{}).Error
+}
+
+// UserTOTP 两步验证
+type UserTOTP struct {
+	Id  int64 `gorm:"primaryKey,autoIncrement"`
+	Uid int64 `gorm:"unique"`
+	// 加密之后的密钥
+	Secret string `gorm:"type:varchar(256)"`
+	// 恢复码的 sha256，JSON 数组
+	RecoveryCodes string `gorm:"type:varchar(1024)"`
+	// 最后一次使用的验证码计数器，同一个验证码不能用两次
+	LastCounter int64
+	// 启用时间，NULL 代表绑定了但是还没有确认
+	EnabledAt sql.NullInt64
+
+	Ctime int64
+	Utime int64
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"time"
 )
 
@@ -17,6 +18,40 @@ type UserDao interface {
 	FindById(ctx context.Context, id int64) (User, error)
 	UpdateNonZeroFields(ctx context.Context, u User) error
 	FindByWechat(ctx context.Context, openID string) (User, error)
+	// FindByWechatUnionId 以前的微信用户只在 users 表里面记录了 unionid
+	FindByWechatUnionId(ctx context.Context, unionId string) (User, error)
+	UpdateVerifiedAt(ctx context.Context, id int64, verifiedAt int64) error
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	// UpdatePhone phone 无效的时候清空，被其它用户占用返回 ErrUserDuplicatePhone
+	UpdatePhone(ctx context.Context, id int64, phone sql.NullString) error
+	// UpdateEmail email 无效的时候清空，被其它用户占用返回 ErrUserDuplicateEmail
+	UpdateEmail(ctx context.Context, id int64, email sql.NullString, verifiedAt sql.NullInt64) error
+	// UpdateWechat openId 无效的时候清空，被其它用户占用返回 ErrUserDuplicateWechat
+	UpdateWechat(ctx context.Context, id int64, openId sql.NullString, unionId sql.NullString) error
+	// InsertIdentity 绑定第三方账号，微信同时写 users 表里面的 openid，两者在一个事务里面
+	// 第三方账号已经绑定过返回 ErrIdentityDuplicate，openid 被其它用户占用返回 ErrUserDuplicateWechat
+	InsertIdentity(ctx context.Context, i UserIdentity, syncWechat bool) error
+	// FillProfile 只填充还是空的昵称和头像
+	FillProfile(ctx context.Context, id int64, nickname string, avatar string) error
+	// UpdateFrozen frozenAt 无效的时候解冻
+	UpdateFrozen(ctx context.Context, id int64, frozenAt sql.NullInt64, reason string) error
+	// Search 不包括已经合并的用户
+	Search(ctx context.Context, q UserQuery) ([]User, error)
+	// UpdateDeleteAt 申请注销，deleteAt 无效的时候取消注销
+	UpdateDeleteAt(ctx context.Context, id int64, deleteAt sql.NullInt64) error
+	// FindDeletable 冷静期已经结束、还没有删除数据的用户 id
+	FindDeletable(ctx context.Context, now int64, limit int) ([]int64, error)
+	// Erase 删除用户的个人信息并释放有唯一索引的字段，只保留 id 作为墓碑
+	// 冷静期还没有结束或者已经取消注销返回 ErrUserNotDeletable
+	// wechatProvider 是保存了微信 token 的第三方账号提供方，按它的 subject（openid）删除 token
+	Erase(ctx context.Context, id int64, now int64, wechatProvider string) error
+	// FindUserDeletedOutbox 还没有发送出去的注销事件，按删除的时间排序
+	FindUserDeletedOutbox(ctx context.Context, limit int) ([]UserDeletedOutbox, error)
+	// DeleteUserDeletedOutbox 事件发送成功之后删除
+	DeleteUserDeletedOutbox(ctx context.Context, uid int64) error
+	// Merge 在一个事务里面把 sourceId 合并到 targetId，merge 根据两个用户算出合并之后的 target
+	// source 变成指向 target 的墓碑，已经合并过返回 ErrUserAlreadyMerged
+	Merge(ctx context.Context, sourceId int64, targetId int64, merge func(source User, target User) (User, error)) error
 }
 
 type GORMUserDAO struct {
@@ -24,8 +59,16 @@ type GORMUserDAO struct {
 }
 
 var (
-	ErrUserDuplicateEmail = errors.New("邮箱冲突")
-	ErrUserNotFound       = gorm.ErrRecordNotFound
+	ErrUserDuplicateEmail  = errors.New("邮箱冲突")
+	ErrUserDuplicatePhone  = errors.New("手机号冲突")
+	ErrUserDuplicateWechat = errors.New("微信冲突")
+	ErrUserNotFound        = gorm.ErrRecordNotFound
+	// ErrUserAlreadyMerged source 已经合并到 target 了，重复合并
+	ErrUserAlreadyMerged = errors.New("已经合并过")
+	// ErrUserMergeConflict source 已经合并到其它用户，或者 target 已经是墓碑，或者第三方账号冲突
+	ErrUserMergeConflict = errors.New("合并冲突")
+	// ErrUserNotDeletable 用户没有申请注销，或者冷静期还没有结束
+	ErrUserNotDeletable = errors.New("用户不能删除")
 )
 
 // NewGORMUserDAO 获取 结构实例
@@ -73,6 +116,13 @@ func (dao *GORMUserDAO) FindByWechat(ctx context.Context, openID string) (User,
 	return u, err
 }
 
+// FindByWechatUnionId 根据微信 unionid 查找，unionid 没有唯一索引，返回最早注册的
+func (dao *GORMUserDAO) FindByWechatUnionId(ctx context.Context, unionId string) (User, error) {
+	var u User
+	err := dao.db.WithContext(ctx).Where("wechat_union_id = ?", unionId).Order("id").First(&u).Error
+	return u, err
+}
+
 // FindById  根据id 查询用户信息
 func (dao *GORMUserDAO) FindById(ctx context.Context, id int64) (User, error) {
 	var u User
@@ -92,6 +142,402 @@ func (dao *GORMUserDAO) UpdateNonZeroFields(ctx context.Context, u User) error {
 	return dao.db.Updates(&u).Error
 }
 
+// UpdateVerifiedAt 标记邮箱已经验证
+func (dao *GORMUserDAO) UpdateVerifiedAt(ctx context.Context, id int64, verifiedAt int64) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"verified_at": verifiedAt,
+			"utime":       now,
+		}).Error
+}
+
+// UpdatePassword 只修改密码
+func (dao *GORMUserDAO) UpdatePassword(ctx context.Context, id int64, password string) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"password": password,
+			"utime":    now,
+		}).Error
+}
+
+// UpdatePhone 修改手机号
+func (dao *GORMUserDAO) UpdatePhone(ctx context.Context, id int64, phone sql.NullString) error {
+	return dao.updateUnique(ctx, id, map[string]any{
+		"phone": phone,
+	}, ErrUserDuplicatePhone)
+}
+
+// UpdateEmail 修改邮箱，同时修改验证时间
+func (dao *GORMUserDAO) UpdateEmail(ctx context.Context, id int64, email sql.NullString, verifiedAt sql.NullInt64) error {
+	return dao.updateUnique(ctx, id, map[string]any{
+		"email":       email,
+		"verified_at": verifiedAt,
+	}, ErrUserDuplicateEmail)
+}
+
+// UpdateWechat 修改微信信息
+func (dao *GORMUserDAO) UpdateWechat(ctx context.Context, id int64, openId sql.NullString, unionId sql.NullString) error {
+	return dao.updateUnique(ctx, id, map[string]any{
+		"wechat_open_id":  openId,
+		"wechat_union_id": unionId,
+	}, ErrUserDuplicateWechat)
+}
+
+// InsertIdentity 绑定第三方账号
+func (dao *GORMUserDAO) InsertIdentity(ctx context.Context, i UserIdentity, syncWechat bool) error {
+	now := time.Now().UnixMilli()
+	i.Ctime, i.Utime = now, now
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&i).Error
+		if isDuplicate(err) {
+			return ErrIdentityDuplicate
+		}
+		if err != nil || !syncWechat {
+			return err
+		}
+		// 保持 users 表里面的微信信息一致，gRPC 接口还在返回
+		err = tx.Model(&User{}).Where("id = ?", i.Uid).
+			Updates(map[string]any{
+				"wechat_open_id":  sql.NullString{String: i.Subject, Valid: true},
+				"wechat_union_id": i.UnionId,
+				"utime":           now,
+			}).Error
+		if isDuplicate(err) {
+			return ErrUserDuplicateWechat
+		}
+		return err
+	})
+}
+
+// FillProfile 第三方登录的时候补上昵称和头像，用户自己设置过的不覆盖
+func (dao *GORMUserDAO) FillProfile(ctx context.Context, id int64, nickname string, avatar string) error {
+	now := time.Now().UnixMilli()
+	fields := map[string]any{
+		"utime": now,
+	}
+	if nickname != "" {
+		fields["nickname"] = gorm.Expr("CASE WHEN nickname IS NULL OR nickname = '' THEN ? ELSE nickname END", nickname)
+	}
+	if avatar != "" {
+		fields["avatar"] = gorm.Expr("CASE WHEN avatar IS NULL OR avatar = '' THEN ? ELSE avatar END", avatar)
+	}
+	return dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", id).
+		Updates(fields).Error
+}
+
+// UpdateFrozen 冻结或者解冻
+func (dao *GORMUserDAO) UpdateFrozen(ctx context.Context, id int64, frozenAt sql.NullInt64, reason string) error {
+	now := time.Now().UnixMilli()
+	res := dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"frozen_at":     frozenAt,
+			"frozen_reason": reason,
+			"utime":         now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// Search 管理后台查询，按照 id 递增
+func (dao *GORMUserDAO) Search(ctx context.Context, q UserQuery) ([]User, error) {
+	db := dao.db.WithContext(ctx).Where("merged_into IS NULL AND id > ?", q.Cursor)
+	if q.Email != "" {
+		db = db.Where("email = ?", q.Email)
+	}
+	if q.Phone != "" {
+		db = db.Where("phone = ?", q.Phone)
+	}
+	if q.WechatOpenId != "" {
+		// 小程序的 openid 只在 user_identities 里面
+		db = db.Where("(wechat_open_id = ? OR id IN (?))", q.WechatOpenId,
+			dao.db.Model(&UserIdentity{}).Select("uid").
+				Where("provider IN ? AND subject = ?", q.WechatProviders, q.WechatOpenId))
+	}
+	if q.MinId > 0 {
+		db = db.Where("id >= ?", q.MinId)
+	}
+	if q.MaxId > 0 {
+		db = db.Where("id <= ?", q.MaxId)
+	}
+	if q.CtimeStart > 0 {
+		db = db.Where("ctime >= ?", q.CtimeStart)
+	}
+	if q.CtimeEnd > 0 {
+		db = db.Where("ctime < ?", q.CtimeEnd)
+	}
+	var res []User
+	err := db.Order("id").Limit(q.Limit).Find(&res).Error
+	return res, err
+}
+
+// UpdateDeleteAt 申请或者取消注销，已经删除的用户不能再修改
+func (dao *GORMUserDAO) UpdateDeleteAt(ctx context.Context, id int64, deleteAt sql.NullInt64) error {
+	now := time.Now().UnixMilli()
+	res := dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND erased_at IS NULL", id).
+		Updates(map[string]any{
+			"delete_at": deleteAt,
+			"utime":     now,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// FindDeletable 按冷静期结束的时间排序
+func (dao *GORMUserDAO) FindDeletable(ctx context.Context, now int64, limit int) ([]int64, error) {
+	var ids []int64
+	err := dao.db.WithContext(ctx).Model(&User{}).
+		Where("delete_at <= ? AND erased_at IS NULL", now).
+		Order("delete_at").Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Erase 在一个事务里面清空个人信息和第三方账号
+// 合并留下的墓碑（merged_into 指向这个用户）一起清空，和用户关联的表按 uid 删除
+func (dao *GORMUserDAO) Erase(ctx context.Context, id int64, now int64, wechatProvider string) error {
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 带上冷静期的条件，和登录取消注销并发的时候以数据库为准
+		var u User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND delete_at <= ? AND erased_at IS NULL", id, now).
+			First(&u).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotDeletable
+		}
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&User{}).Where("id = ?", id).
+			Updates(map[string]any{
+				"email":           sql.NullString{},
+				"phone":           sql.NullString{},
+				"password":        "",
+				"nickname":        sql.NullString{},
+				"birthday":        sql.NullInt64{},
+				"about_me":        sql.NullString{},
+				"avatar":          sql.NullString{},
+				"wechat_open_id":  sql.NullString{},
+				"wechat_union_id": sql.NullString{},
+				"verified_at":     sql.NullInt64{},
+				"frozen_reason":   "",
+				"delete_at":       sql.NullInt64{},
+				"erased_at":       now,
+				"utime":           now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		// 墓碑的邮箱、手机号和微信在合并的时候已经转走了，这里清掉剩下的资料
+		var uids []int64
+		err = tx.Model(&User{}).Where("merged_into = ?", id).Pluck("id", &uids).Error
+		if err != nil {
+			return err
+		}
+		if len(uids) > 0 {
+			err = tx.Model(&User{}).Where("id IN ?", uids).
+				Updates(map[string]any{
+					"password":      "",
+					"nickname":      sql.NullString{},
+					"birthday":      sql.NullInt64{},
+					"about_me":      sql.NullString{},
+					"avatar":        sql.NullString{},
+					"frozen_reason": "",
+					"utime":         now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		uids = append(uids, id)
+		// 微信 token 按 openid 保存
+		var openIds []string
+		err = tx.Model(&UserIdentity{}).
+			Where("uid IN ? AND provider = ?", uids, wechatProvider).
+			Pluck("subject", &openIds).Error
+		if err != nil {
+			return err
+		}
+		if u.WechatOpenId.Valid {
+			openIds = append(openIds, u.WechatOpenId.String)
+		}
+		if len(openIds) > 0 {
+			err = tx.Where("open_id IN ?", openIds).Delete(&WechatToken{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		// 第三方账号里面有 openid、昵称和邮箱，删掉之后同一个第三方账号可以重新注册
+		for _, m := range []any{&UserIdentity{}, &UserTOTP{}, &OAuthConsent{}, &PersonalAccessToken{}, &UserRole{}} {
+			if err = tx.Where("uid IN ?", uids).Delete(m).Error; err != nil {
+				return err
+			}
+		}
+		// 和删除数据一起提交，事件发送失败下一次还能找到
+		return tx.Create(&UserDeletedOutbox{Uid: id, Ctime: now}).Error
+	})
+}
+
+func (dao *GORMUserDAO) FindUserDeletedOutbox(ctx context.Context, limit int) ([]UserDeletedOutbox, error) {
+	var res []UserDeletedOutbox
+	err := dao.db.WithContext(ctx).Order("ctime").Limit(limit).Find(&res).Error
+	return res, err
+}
+
+func (dao *GORMUserDAO) DeleteUserDeletedOutbox(ctx context.Context, uid int64) error {
+	return dao.db.WithContext(ctx).Where("uid = ?", uid).Delete(&UserDeletedOutbox{}).Error
+}
+
+// Merge 合并用户
+func (dao *GORMUserDAO) Merge(ctx context.Context, sourceId int64, targetId int64,
+	merge func(source User, target User) (User, error)) error {
+	now := time.Now().UnixMilli()
+	return dao.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 按照 id 的顺序加锁，避免两个方向同时合并的时候死锁
+		var us []User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []int64{sourceId, targetId}).
+			Order("id").Find(&us).Error
+		if err != nil {
+			return err
+		}
+		if len(us) != 2 {
+			return ErrUserNotFound
+		}
+		source, target := us[0], us[1]
+		if source.Id != sourceId {
+			source, target = target, source
+		}
+		switch {
+		case source.MergedInto.Valid && source.MergedInto.Int64 == targetId:
+			return ErrUserAlreadyMerged
+		case source.MergedInto.Valid, target.MergedInto.Valid:
+			return ErrUserMergeConflict
+		}
+
+		merged, err := merge(source, target)
+		if err != nil {
+			return err
+		}
+
+		// 先清掉 source 上有唯一索引的字段，再写到 target 上
+		err = tx.Model(&User{}).Where("id = ?", sourceId).
+			Updates(map[string]any{
+				"email":           sql.NullString{},
+				"phone":           sql.NullString{},
+				"wechat_open_id":  sql.NullString{},
+				"wechat_union_id": sql.NullString{},
+				"verified_at":     sql.NullInt64{},
+				"merged_into":     targetId,
+				"utime":           now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&User{}).Where("id = ?", targetId).
+			Updates(map[string]any{
+				"email":           merged.Email,
+				"phone":           merged.Phone,
+				"password":        merged.Password,
+				"nickname":        merged.Nickname,
+				"birthday":        merged.Birthday,
+				"about_me":        merged.AboutMe,
+				"avatar":          merged.Avatar,
+				"wechat_open_id":  merged.WechatOpenId,
+				"wechat_union_id": merged.WechatUnionID,
+				"verified_at":     merged.VerifiedAt,
+				"utime":           now,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		// 一个用户在同一个提供方只能绑定一个账号，冲突说明两边绑定了同一个平台的不同账号
+		err = tx.Model(&UserIdentity{}).Where("uid = ?", sourceId).
+			Updates(map[string]any{
+				"uid":   targetId,
+				"utime": now,
+			}).Error
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+			const uniqueConflictsErrNo uint16 = 1062
+			if mysqlErr.Number == uniqueConflictsErrNo {
+				return ErrUserMergeConflict
+			}
+		}
+		if err != nil {
+			return err
+		}
+		// target 没有密码的时候用了 source 的密码，source 开启的两步验证要跟着密码一起转过去，
+		// 不然 source 的密码在 target 上只要一步就能登录。
+		// 没有密码不能开启两步验证，target 上最多只有一个还没有确认的，直接覆盖
+		if target.Password == "" && merged.Password != "" && merged.Password == source.Password {
+			err = tx.Where("uid = ?", targetId).Delete(&UserTOTP{}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&UserTOTP{}).Where("uid = ?", sourceId).
+				Updates(map[string]any{
+					"uid":   targetId,
+					"utime": now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+		// 个人访问令牌是 source 自己创建的，不能变成 target 的，直接作废
+		err = tx.Where("uid = ?", sourceId).Delete(&PersonalAccessToken{}).Error
+		if err != nil {
+			return err
+		}
+		// 以前合并到 source 的墓碑直接指向 target，查找的时候只需要跳一次
+		return tx.Model(&User{}).Where("merged_into = ?", sourceId).
+			Updates(map[string]any{
+				"merged_into": targetId,
+				"utime":       now,
+			}).Error
+	})
+}
+
+// isDuplicate 是否为唯一索引冲突
+func isDuplicate(err error) bool {
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+		const uniqueConflictsErrNo uint16 = 1062
+		return mysqlErr.Number == uniqueConflictsErrNo
+	}
+	return false
+}
+
+// updateUnique 修改有唯一索引的字段，冲突的时候返回 duplicateErr
+func (dao *GORMUserDAO) updateUnique(ctx context.Context, id int64, fields map[string]any, duplicateErr error) error {
+	fields["utime"] = time.Now().UnixMilli()
+	err := dao.db.WithContext(ctx).Model(&User{}).
+		Where("id = ?", id).
+		Updates(fields).Error
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok {
+		const uniqueConflictsErrNo uint16 = 1062
+		if mysqlErr.Number == uniqueConflictsErrNo {
+			return duplicateErr
+		}
+	}
+	return err
+}
+
 // User 数据库层次上的 用户表
 type User struct {
 	// 用户Id
@@ -109,13 +555,49 @@ type User struct {
 	Birthday sql.NullInt64 `gorm:"colum:birthday"`
 	// 个人简介
 	AboutMe sql.NullString `gorm:"colum:about_me;type:varchar(1024)"`
+	// 头像地址
+	Avatar sql.NullString `gorm:"column:avatar;type:varchar(512)"`
 	// 微信Openid ,app 应用下唯一id
 	WechatOpenId sql.NullString `gorm:"colum:wechat_openId;unique"`
 	// 微信unionid
 	WechatUnionID sql.NullString `gorm:"colum:wechat_unionID"`
+	// 邮箱验证时间，NULL 代表邮箱还没有验证
+	// 上线之前的邮箱用户在 migrations 里面回填
+	VerifiedAt sql.NullInt64 `gorm:"column:verified_at"`
+	// 合并之后 source 变成墓碑，指向合并到的用户，按 id 查找的时候跳转过去
+	MergedInto sql.NullInt64 `gorm:"column:merged_into;index"`
+	// 冻结时间，NULL 代表没有冻结
+	FrozenAt     sql.NullInt64 `gorm:"column:frozen_at"`
+	FrozenReason string        `gorm:"column:frozen_reason;type:varchar(256)"`
+	// 申请注销之后冷静期结束的时间，NULL 代表没有申请注销
+	DeleteAt sql.NullInt64 `gorm:"column:delete_at;index"`
+	// 删除个人信息的时间，NULL 代表没有删除
+	ErasedAt sql.NullInt64 `gorm:"column:erased_at"`
 
 	// 创建时间
 	Ctime int64
 	// 更新时间
 	Utime int64
 }
+
+// UserQuery 管理后台查询用户的条件，时间是毫秒
+type UserQuery struct {
+	Email        string
+	Phone        string
+	WechatOpenId string
+	// WechatProviders 按 openid 查找 user_identities 的时候限定提供方
+	WechatProviders []string
+	MinId           int64
+	MaxId           int64
+	CtimeStart      int64
+	CtimeEnd        int64
+	Cursor          int64
+	Limit           int
+}
+
+// UserDeletedOutbox 还没有发送的 UserDeleted 事件，一个用户只会删除一次
+type UserDeletedOutbox struct {
+	Uid int64 `gorm:"primaryKey;autoIncrement:false"`
+	// Ctime 删除个人信息的时间，毫秒
+	Ctime int64 `gorm:"index"`
+}
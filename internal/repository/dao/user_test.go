@@ -93,3 +93,202 @@ func TestGORMUserDAO_Insert(t *testing.T) {
 		})
 	}
 }
+
+func TestGORMUserDAO_Erase(t *testing.T) {
+	testCase := []struct {
+		name    string
+		sqlmock func(t *testing.T) (*sql.DB, sqlmock.Sqlmock)
+
+		wantErr error
+	}{
+		{
+			name: "连同墓碑和关联的表一起删除，写入发件箱",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users` WHERE .*delete_at <= \\?.* FOR UPDATE").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "wechat_open_id"}).AddRow(1, "web_openid"))
+				mock.ExpectExec("UPDATE `users` SET .*`erased_at`=.* WHERE id = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT `id` FROM `users` WHERE merged_into = \\?").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectExec("UPDATE `users` SET .*`nickname`=.* WHERE id IN \\(\\?\\)").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT `subject` FROM `user_identities` WHERE uid IN \\(\\?,\\?\\) AND provider = \\?").
+					WithArgs(2, 1, "wechat").
+					WillReturnRows(sqlmock.NewRows([]string{"subject"}).AddRow("merged_openid"))
+				mock.ExpectExec("DELETE FROM `wechat_tokens` WHERE open_id IN \\(\\?,\\?\\)").
+					WithArgs("merged_openid", "web_openid").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				for _, table := range []string{"user_identities", "user_totps", "o_auth_consents",
+					"personal_access_tokens", "user_roles"} {
+					mock.ExpectExec("DELETE FROM `"+table+"` WHERE uid IN \\(\\?,\\?\\)").
+						WithArgs(2, 1).
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectExec("INSERT INTO `user_deleted_outboxes`").
+					WithArgs(1, 1700000000000).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				return mockDB, mock
+			},
+		},
+		{
+			name: "登录取消了注销",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users`").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+				return mockDB, mock
+			},
+			wantErr: ErrUserNotDeletable,
+		},
+		{
+			name: "删除关联的表失败，回滚",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users`").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("UPDATE `users`").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("SELECT `id` FROM `users`").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery("SELECT `subject` FROM `user_identities`").
+					WillReturnRows(sqlmock.NewRows([]string{"subject"}))
+				mock.ExpectExec("DELETE FROM `user_identities`").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `user_totps`").
+					WillReturnError(errors.New("数据库错误"))
+				mock.ExpectRollback()
+				return mockDB, mock
+			},
+			wantErr: errors.New("数据库错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB, mock := tc.sqlmock(t)
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			d := NewGORMUserDAO(db)
+			err = d.Erase(context.Background(), 1, 1700000000000, "wechat")
+			assert.Equal(t, tc.wantErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGORMUserDAO_Merge(t *testing.T) {
+	testCase := []struct {
+		name    string
+		sqlmock func(t *testing.T) (*sql.DB, sqlmock.Sqlmock)
+
+		wantErr error
+	}{
+		{
+			name: "登录方式转到 target，source 的个人访问令牌作废",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users` WHERE id IN \\(\\?,\\?\\) ORDER BY id FOR UPDATE").
+					WithArgs(1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).
+						AddRow(1, "source_hash").AddRow(2, "target_hash"))
+				mock.ExpectExec("UPDATE `users` SET .*`merged_into`=.* WHERE id = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `users` SET .*`password`=.* WHERE id = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `user_identities` SET .* WHERE uid = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `personal_access_tokens` WHERE uid = \\?").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE `users` SET .* WHERE merged_into = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+				return mockDB, mock
+			},
+		},
+		{
+			name: "target 用了 source 的密码，两步验证一起转过去",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users` WHERE id IN \\(\\?,\\?\\) ORDER BY id FOR UPDATE").
+					WithArgs(1, 2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).
+						AddRow(1, "source_hash").AddRow(2, ""))
+				mock.ExpectExec("UPDATE `users` SET .*`merged_into`=.* WHERE id = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `users` SET .*`password`=.* WHERE id = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE `user_identities` SET .* WHERE uid = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `user_totps` WHERE uid = \\?").
+					WithArgs(2).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE `user_totps` SET `uid`=\\?,`utime`=\\? WHERE uid = \\?").
+					WithArgs(2, sqlmock.AnyArg(), 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM `personal_access_tokens` WHERE uid = \\?").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE `users` SET .* WHERE merged_into = \\?").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+				return mockDB, mock
+			},
+		},
+		{
+			name: "已经合并过了",
+			sqlmock: func(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+				mockDB, mock, err := sqlmock.New()
+				require.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `users`").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "merged_into"}).
+						AddRow(1, 2).AddRow(2, nil))
+				mock.ExpectRollback()
+				return mockDB, mock
+			},
+			wantErr: ErrUserAlreadyMerged,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			sqlDB, mock := tc.sqlmock(t)
+			db, err := gorm.Open(gormMysql.New(gormMysql.Config{
+				Conn:                      sqlDB,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{
+				DisableAutomaticPing:   true,
+				SkipDefaultTransaction: true,
+			})
+			require.NoError(t, err)
+			d := NewGORMUserDAO(db)
+			err = d.Merge(context.Background(), 1, 2, func(source User, target User) (User, error) {
+				if target.Password == "" {
+					target.Password = source.Password
+				}
+				return target, nil
+			})
+			assert.Equal(t, tc.wantErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
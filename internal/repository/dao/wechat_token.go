@@ -0,0 +1,62 @@
+package dao
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+var ErrWechatTokenNotFound = gorm.ErrRecordNotFound
+
+//go:generate mockgen.exe -source=./wechat_token.go -package=daomocks -destination=mocks/wechat_token.mock.go WechatTokenDAO
+type WechatTokenDAO interface {
+	// Upsert 每次授权或者刷新之后覆盖
+	Upsert(ctx context.Context, t WechatToken) error
+	FindByOpenId(ctx context.Context, openId string) (WechatToken, error)
+}
+
+type GORMWechatTokenDAO struct {
+	db *gorm.DB
+}
+
+func NewGORMWechatTokenDAO(db *gorm.DB) WechatTokenDAO {
+	return &GORMWechatTokenDAO{
+		db: db,
+	}
+}
+
+func (dao *GORMWechatTokenDAO) Upsert(ctx context.Context, t WechatToken) error {
+	now := time.Now().UnixMilli()
+	t.Ctime = now
+	t.Utime = now
+	return dao.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"access_token":  t.AccessToken,
+			"refresh_token": t.RefreshToken,
+			"scope":         t.Scope,
+			"expires_at":    t.ExpiresAt,
+			"utime":         now,
+		}),
+	}).Create(&t).Error
+}
+
+func (dao *GORMWechatTokenDAO) FindByOpenId(ctx context.Context, openId string) (WechatToken, error) {
+	var t WechatToken
+	err := dao.db.WithContext(ctx).Where("open_id = ?", openId).First(&t).Error
+	return t, err
+}
+
+// WechatToken 微信网页授权的 token，按 openid 保存，用来以后刷新昵称和头像
+type WechatToken struct {
+	Id     int64  `gorm:"primaryKey,autoIncrement"`
+	OpenId string `gorm:"type:varchar(128);uniqueIndex"`
+	// AccessToken 和 RefreshToken 都是加密之后的
+	AccessToken  string `gorm:"type:varchar(1024)"`
+	RefreshToken string `gorm:"type:varchar(1024)"`
+	Scope        string `gorm:"type:varchar(128)"`
+	// ExpiresAt access_token 的过期时间，毫秒
+	ExpiresAt int64
+	Ctime     int64
+	Utime     int64
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+	"time"
+)
+
+var ErrDataExportNotFound = cache.ErrKeyNotExist
+
+//go:generate mockgen.exe -source=./data_export.go -package=repomocks -destination=mocks/data_export.mock.go DataExportRepository
+type DataExportRepository interface {
+	// Save 新建或者更新导出任务，expiration 之后自动删除
+	Save(ctx context.Context, e domain.DataExport, expiration time.Duration) error
+	// FindById 不存在或者过期返回 ErrDataExportNotFound
+	FindById(ctx context.Context, id string) (domain.DataExport, error)
+	// FindLatest 用户最近一次导出，没有返回 ErrDataExportNotFound
+	FindLatest(ctx context.Context, uid int64) (domain.DataExport, error)
+	Enqueue(ctx context.Context, id string) error
+	// Dequeue 多个实例同时取也不会取到同一个，队列为空返回 ErrDataExportNotFound
+	// 取出来的任务在 Ack 之前一直记在处理中的列表里面
+	Dequeue(ctx context.Context) (string, error)
+	// Ack 任务执行完
+	Ack(ctx context.Context, id string) error
+	// Processing 取出来还没有 Ack 的任务
+	Processing(ctx context.Context) ([]string, error)
+	// Requeue 把没有 Ack 的任务放回队列，多个实例同时放回只有一个返回 true
+	Requeue(ctx context.Context, id string) (bool, error)
+	// AddObject 记录压缩包的删除时间
+	AddObject(ctx context.Context, key string, deleteAt time.Time) error
+	ExpiredObjects(ctx context.Context, now time.Time, limit int) ([]string, error)
+	// FindObjects key 以 prefix 开头的压缩包，还没有到删除时间的也会返回
+	FindObjects(ctx context.Context, prefix string) ([]string, error)
+	RemoveObject(ctx context.Context, key string) error
+}
+
+type CachedDataExportRepository struct {
+	cache cache.DataExportCache
+}
+
+func NewCachedDataExportRepository(cache cache.DataExportCache) DataExportRepository {
+	return &CachedDataExportRepository{
+		cache: cache,
+	}
+}
+
+func (r *CachedDataExportRepository) Save(ctx context.Context, e domain.DataExport, expiration time.Duration) error {
+	return r.cache.Set(ctx, e, expiration)
+}
+
+func (r *CachedDataExportRepository) FindById(ctx context.Context, id string) (domain.DataExport, error) {
+	return r.cache.Get(ctx, id)
+}
+
+func (r *CachedDataExportRepository) FindLatest(ctx context.Context, uid int64) (domain.DataExport, error) {
+	return r.cache.GetLatest(ctx, uid)
+}
+
+func (r *CachedDataExportRepository) Enqueue(ctx context.Context, id string) error {
+	return r.cache.Enqueue(ctx, id)
+}
+
+func (r *CachedDataExportRepository) Dequeue(ctx context.Context) (string, error) {
+	return r.cache.Dequeue(ctx)
+}
+
+func (r *CachedDataExportRepository) Ack(ctx context.Context, id string) error {
+	return r.cache.Ack(ctx, id)
+}
+
+func (r *CachedDataExportRepository) Processing(ctx context.Context) ([]string, error) {
+	return r.cache.Processing(ctx)
+}
+
+func (r *CachedDataExportRepository) Requeue(ctx context.Context, id string) (bool, error) {
+	return r.cache.Requeue(ctx, id)
+}
+
+func (r *CachedDataExportRepository) AddObject(ctx context.Context, key string, deleteAt time.Time) error {
+	return r.cache.AddObject(ctx, key, deleteAt)
+}
+
+func (r *CachedDataExportRepository) ExpiredObjects(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	return r.cache.ExpiredObjects(ctx, now, limit)
+}
+
+func (r *CachedDataExportRepository) FindObjects(ctx context.Context, prefix string) ([]string, error) {
+	return r.cache.FindObjects(ctx, prefix)
+}
+
+func (r *CachedDataExportRepository) RemoveObject(ctx context.Context, key string) error {
+	return r.cache.RemoveObject(ctx, key)
+}
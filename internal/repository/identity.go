@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/dao"
+	"time"
+)
+
+var (
+	ErrIdentityNotFound  = dao.ErrIdentityNotFound
+	ErrIdentityDuplicate = dao.ErrIdentityDuplicate
+)
+
+//go:generate mockgen.exe -source=./identity.go -package=repomocks -destination=mocks/identity.mock.go IdentityRepository
+type IdentityRepository interface {
+	FindBySubject(ctx context.Context, provider, subject string) (domain.UserIdentity, error)
+	FindByUid(ctx context.Context, uid int64) ([]domain.UserIdentity, error)
+	// FindByUnionId 在 providers 里面按 unionid 查找，没有找到返回 ErrIdentityNotFound
+	FindByUnionId(ctx context.Context, providers []string, unionId string) (domain.UserIdentity, error)
+	// Create 绑定到已有的用户，已经绑定过返回 ErrIdentityDuplicate
+	Create(ctx context.Context, i domain.UserIdentity) error
+	// CreateWithUser 新建用户并绑定，返回用户 id
+	CreateWithUser(ctx context.Context, u domain.User, i domain.UserIdentity) (int64, error)
+	// Delete 解绑，没有绑定返回 ErrIdentityNotFound
+	Delete(ctx context.Context, uid int64, provider string) error
+}
+
+type CachedIdentityRepository struct {
+	dao dao.IdentityDAO
+}
+
+func NewCachedIdentityRepository(dao dao.IdentityDAO) IdentityRepository {
+	return &CachedIdentityRepository{
+		dao: dao,
+	}
+}
+
+func (r *CachedIdentityRepository) FindBySubject(ctx context.Context, provider, subject string) (domain.UserIdentity, error) {
+	i, err := r.dao.FindBySubject(ctx, provider, subject)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	return r.toDomain(i), nil
+}
+
+func (r *CachedIdentityRepository) FindByUnionId(ctx context.Context, providers []string, unionId string) (domain.UserIdentity, error) {
+	i, err := r.dao.FindByUnionId(ctx, providers, unionId)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	return r.toDomain(i), nil
+}
+
+func (r *CachedIdentityRepository) FindByUid(ctx context.Context, uid int64) ([]domain.UserIdentity, error) {
+	identities, err := r.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.UserIdentity, 0, len(identities))
+	for _, i := range identities {
+		res = append(res, r.toDomain(i))
+	}
+	return res, nil
+}
+
+func (r *CachedIdentityRepository) Create(ctx context.Context, i domain.UserIdentity) error {
+	return r.dao.Insert(ctx, r.toEntity(i))
+}
+
+func (r *CachedIdentityRepository) CreateWithUser(ctx context.Context, u domain.User, i domain.UserIdentity) (int64, error) {
+	// 第三方登录新建的用户只有昵称、头像和微信信息
+	return r.dao.InsertWithUser(ctx, dao.User{
+		Nickname: sql.NullString{
+			String: u.Nickname,
+			Valid:  u.Nickname != "",
+		},
+		Avatar: sql.NullString{
+			String: u.Avatar,
+			Valid:  u.Avatar != "",
+		},
+		WechatOpenId: sql.NullString{
+			String: u.WechatInfo.OpenId,
+			Valid:  u.WechatInfo.OpenId != "",
+		},
+		WechatUnionID: sql.NullString{
+			String: u.WechatInfo.UnionId,
+			Valid:  u.WechatInfo.UnionId != "",
+		},
+	}, r.toEntity(i))
+}
+
+func (r *CachedIdentityRepository) Delete(ctx context.Context, uid int64, provider string) error {
+	return r.dao.Delete(ctx, uid, provider)
+}
+
+func (r *CachedIdentityRepository) toEntity(i domain.UserIdentity) dao.UserIdentity {
+	return dao.UserIdentity{
+		Id:       i.Id,
+		Uid:      i.Uid,
+		Provider: i.Provider,
+		Subject:  i.Subject,
+		UnionId: sql.NullString{
+			String: i.UnionId,
+			Valid:  i.UnionId != "",
+		},
+		Nickname: i.Nickname,
+		Avatar:   i.Avatar,
+		Email:    i.Email,
+	}
+}
+
+func (r *CachedIdentityRepository) toDomain(i dao.UserIdentity) domain.UserIdentity {
+	return domain.UserIdentity{
+		Id:       i.Id,
+		Uid:      i.Uid,
+		Provider: i.Provider,
+		Subject:  i.Subject,
+		UnionId:  i.UnionId.String,
+		Nickname: i.Nickname,
+		Avatar:   i.Avatar,
+		Email:    i.Email,
+		Ctime:    time.UnixMilli(i.Ctime),
+	}
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+	"time"
+)
+
+//go:generate mockgen.exe -source=./login_limit.go -package=repomocks -destination=mocks/login_limit.mock.go LoginLimitRepository
+type LoginLimitRepository interface {
+	// Blocked 账号或者 IP 任意一个被限制都算，返回最长的等待时间
+	Blocked(ctx context.Context, account string, ip string) (wait time.Duration, locked bool, err error)
+	FailAccount(ctx context.Context, account string, rule domain.LoginLimitRule) (wait time.Duration, locked bool, err error)
+	FailIP(ctx context.Context, ip string, rule domain.LoginLimitRule) (wait time.Duration, locked bool, err error)
+	ResetAccount(ctx context.Context, account string) error
+}
+
+type CachedLoginLimitRepository struct {
+	cache cache.LoginLimitCache
+}
+
+func NewCachedLoginLimitRepository(cache cache.LoginLimitCache) LoginLimitRepository {
+	return &CachedLoginLimitRepository{
+		cache: cache,
+	}
+}
+
+func (r *CachedLoginLimitRepository) Blocked(ctx context.Context, account string, ip string) (time.Duration, bool, error) {
+	keys := []string{r.accountKey(account)}
+	if ip != "" {
+		keys = append(keys, r.ipKey(ip))
+	}
+	return r.cache.Blocked(ctx, keys...)
+}
+
+func (r *CachedLoginLimitRepository) FailAccount(ctx context.Context, account string, rule domain.LoginLimitRule) (time.Duration, bool, error) {
+	return r.cache.Fail(ctx, r.accountKey(account), rule)
+}
+
+func (r *CachedLoginLimitRepository) FailIP(ctx context.Context, ip string, rule domain.LoginLimitRule) (time.Duration, bool, error) {
+	return r.cache.Fail(ctx, r.ipKey(ip), rule)
+}
+
+func (r *CachedLoginLimitRepository) ResetAccount(ctx context.Context, account string) error {
+	return r.cache.Reset(ctx, r.accountKey(account))
+}
+
+func (r *CachedLoginLimitRepository) accountKey(account string) string {
+	return "account:" + account
+}
+
+func (r *CachedLoginLimitRepository) ipKey(ip string) string {
+	return "ip:" + ip
+}
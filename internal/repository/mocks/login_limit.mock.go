@@ -0,0 +1,104 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./login_limit.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./login_limit.go -package=repomocks -destination=mocks/login_limit.mock.go LoginLimitRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginLimitRepository is a mock of LoginLimitRepository interface.
+type MockLoginLimitRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginLimitRepositoryMockRecorder
+}
+
+// MockLoginLimitRepositoryMockRecorder is the mock recorder for MockLoginLimitRepository.
+type MockLoginLimitRepositoryMockRecorder struct {
+	mock *MockLoginLimitRepository
+}
+
+// NewMockLoginLimitRepository creates a new mock instance.
+func NewMockLoginLimitRepository(ctrl *gomock.Controller) *MockLoginLimitRepository {
+	mock := &MockLoginLimitRepository{ctrl: ctrl}
+	mock.recorder = &MockLoginLimitRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginLimitRepository) EXPECT() *MockLoginLimitRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Blocked mocks base method.
+func (m *MockLoginLimitRepository) Blocked(ctx context.Context, account, ip string) (time.Duration, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Blocked", ctx, account, ip)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Blocked indicates an expected call of Blocked.
+func (mr *MockLoginLimitRepositoryMockRecorder) Blocked(ctx, account, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Blocked", reflect.TypeOf((*MockLoginLimitRepository)(nil).Blocked), ctx, account, ip)
+}
+
+// FailAccount mocks base method.
+func (m *MockLoginLimitRepository) FailAccount(ctx context.Context, account string, rule domain.LoginLimitRule) (time.Duration, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailAccount", ctx, account, rule)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FailAccount indicates an expected call of FailAccount.
+func (mr *MockLoginLimitRepositoryMockRecorder) FailAccount(ctx, account, rule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailAccount", reflect.TypeOf((*MockLoginLimitRepository)(nil).FailAccount), ctx, account, rule)
+}
+
+// FailIP mocks base method.
+func (m *MockLoginLimitRepository) FailIP(ctx context.Context, ip string, rule domain.LoginLimitRule) (time.Duration, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailIP", ctx, ip, rule)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FailIP indicates an expected call of FailIP.
+func (mr *MockLoginLimitRepositoryMockRecorder) FailIP(ctx, ip, rule any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailIP", reflect.TypeOf((*MockLoginLimitRepository)(nil).FailIP), ctx, ip, rule)
+}
+
+// ResetAccount mocks base method.
+func (m *MockLoginLimitRepository) ResetAccount(ctx context.Context, account string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetAccount", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetAccount indicates an expected call of ResetAccount.
+func (mr *MockLoginLimitRepositoryMockRecorder) ResetAccount(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetAccount", reflect.TypeOf((*MockLoginLimitRepository)(nil).ResetAccount), ctx, account)
+}
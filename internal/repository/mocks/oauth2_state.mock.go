@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oauth2_state.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oauth2_state.go -package=repomocks -destination=mocks/oauth2_state.mock.go OAuth2StateRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuth2StateRepository is a mock of OAuth2StateRepository interface.
+type MockOAuth2StateRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuth2StateRepositoryMockRecorder
+}
+
+// MockOAuth2StateRepositoryMockRecorder is the mock recorder for MockOAuth2StateRepository.
+type MockOAuth2StateRepositoryMockRecorder struct {
+	mock *MockOAuth2StateRepository
+}
+
+// NewMockOAuth2StateRepository creates a new mock instance.
+func NewMockOAuth2StateRepository(ctrl *gomock.Controller) *MockOAuth2StateRepository {
+	mock := &MockOAuth2StateRepository{ctrl: ctrl}
+	mock.recorder = &MockOAuth2StateRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuth2StateRepository) EXPECT() *MockOAuth2StateRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Store mocks base method.
+func (m *MockOAuth2StateRepository) Store(ctx context.Context, state string, val domain.OAuth2State) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Store", ctx, state, val)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Store indicates an expected call of Store.
+func (mr *MockOAuth2StateRepositoryMockRecorder) Store(ctx, state, val any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Store", reflect.TypeOf((*MockOAuth2StateRepository)(nil).Store), ctx, state, val)
+}
+
+// Take mocks base method.
+func (m *MockOAuth2StateRepository) Take(ctx context.Context, state string) (domain.OAuth2State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Take", ctx, state)
+	ret0, _ := ret[0].(domain.OAuth2State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Take indicates an expected call of Take.
+func (mr *MockOAuth2StateRepositoryMockRecorder) Take(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Take", reflect.TypeOf((*MockOAuth2StateRepository)(nil).Take), ctx, state)
+}
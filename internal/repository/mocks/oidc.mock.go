@@ -0,0 +1,128 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oidc.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oidc.go -package=repomocks -destination=mocks/oidc.mock.go OIDCRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOIDCRepository is a mock of OIDCRepository interface.
+type MockOIDCRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCRepositoryMockRecorder
+}
+
+// MockOIDCRepositoryMockRecorder is the mock recorder for MockOIDCRepository.
+type MockOIDCRepositoryMockRecorder struct {
+	mock *MockOIDCRepository
+}
+
+// NewMockOIDCRepository creates a new mock instance.
+func NewMockOIDCRepository(ctrl *gomock.Controller) *MockOIDCRepository {
+	mock := &MockOIDCRepository{ctrl: ctrl}
+	mock.recorder = &MockOIDCRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCRepository) EXPECT() *MockOIDCRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateClient mocks base method.
+func (m *MockOIDCRepository) CreateClient(ctx context.Context, c domain.OAuthClient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClient", ctx, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateClient indicates an expected call of CreateClient.
+func (mr *MockOIDCRepositoryMockRecorder) CreateClient(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClient", reflect.TypeOf((*MockOIDCRepository)(nil).CreateClient), ctx, c)
+}
+
+// FindClient mocks base method.
+func (m *MockOIDCRepository) FindClient(ctx context.Context, clientId string) (domain.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindClient", ctx, clientId)
+	ret0, _ := ret[0].(domain.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindClient indicates an expected call of FindClient.
+func (mr *MockOIDCRepositoryMockRecorder) FindClient(ctx, clientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindClient", reflect.TypeOf((*MockOIDCRepository)(nil).FindClient), ctx, clientId)
+}
+
+// FindConsent mocks base method.
+func (m *MockOIDCRepository) FindConsent(ctx context.Context, uid int64, clientId string) (domain.OAuthConsent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindConsent", ctx, uid, clientId)
+	ret0, _ := ret[0].(domain.OAuthConsent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindConsent indicates an expected call of FindConsent.
+func (mr *MockOIDCRepositoryMockRecorder) FindConsent(ctx, uid, clientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindConsent", reflect.TypeOf((*MockOIDCRepository)(nil).FindConsent), ctx, uid, clientId)
+}
+
+// SaveConsent mocks base method.
+func (m *MockOIDCRepository) SaveConsent(ctx context.Context, c domain.OAuthConsent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveConsent", ctx, c)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveConsent indicates an expected call of SaveConsent.
+func (mr *MockOIDCRepositoryMockRecorder) SaveConsent(ctx, c any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveConsent", reflect.TypeOf((*MockOIDCRepository)(nil).SaveConsent), ctx, c)
+}
+
+// StoreCode mocks base method.
+func (m *MockOIDCRepository) StoreCode(ctx context.Context, code string, val domain.AuthCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreCode", ctx, code, val)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreCode indicates an expected call of StoreCode.
+func (mr *MockOIDCRepositoryMockRecorder) StoreCode(ctx, code, val any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreCode", reflect.TypeOf((*MockOIDCRepository)(nil).StoreCode), ctx, code, val)
+}
+
+// TakeCode mocks base method.
+func (m *MockOIDCRepository) TakeCode(ctx context.Context, code string) (domain.AuthCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeCode", ctx, code)
+	ret0, _ := ret[0].(domain.AuthCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakeCode indicates an expected call of TakeCode.
+func (mr *MockOIDCRepositoryMockRecorder) TakeCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeCode", reflect.TypeOf((*MockOIDCRepository)(nil).TakeCode), ctx, code)
+}
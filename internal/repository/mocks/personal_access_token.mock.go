@@ -0,0 +1,115 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./personal_access_token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./personal_access_token.go -package=repomocks -destination=mocks/personal_access_token.mock.go PersonalAccessTokenRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPersonalAccessTokenRepository is a mock of PersonalAccessTokenRepository interface.
+type MockPersonalAccessTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockPersonalAccessTokenRepositoryMockRecorder
+}
+
+// MockPersonalAccessTokenRepositoryMockRecorder is the mock recorder for MockPersonalAccessTokenRepository.
+type MockPersonalAccessTokenRepositoryMockRecorder struct {
+	mock *MockPersonalAccessTokenRepository
+}
+
+// NewMockPersonalAccessTokenRepository creates a new mock instance.
+func NewMockPersonalAccessTokenRepository(ctrl *gomock.Controller) *MockPersonalAccessTokenRepository {
+	mock := &MockPersonalAccessTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockPersonalAccessTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPersonalAccessTokenRepository) EXPECT() *MockPersonalAccessTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockPersonalAccessTokenRepository) Create(ctx context.Context, t domain.PersonalAccessToken) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, t)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) Create(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).Create), ctx, t)
+}
+
+// Delete mocks base method.
+func (m *MockPersonalAccessTokenRepository) Delete(ctx context.Context, uid, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, uid, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) Delete(ctx, uid, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).Delete), ctx, uid, id)
+}
+
+// FindByHash mocks base method.
+func (m *MockPersonalAccessTokenRepository) FindByHash(ctx context.Context, hash string) (domain.PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByHash", ctx, hash)
+	ret0, _ := ret[0].(domain.PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByHash indicates an expected call of FindByHash.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) FindByHash(ctx, hash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByHash", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).FindByHash), ctx, hash)
+}
+
+// FindByUid mocks base method.
+func (m *MockPersonalAccessTokenRepository) FindByUid(ctx context.Context, uid int64) ([]domain.PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByUid", ctx, uid)
+	ret0, _ := ret[0].([]domain.PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByUid indicates an expected call of FindByUid.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) FindByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByUid", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).FindByUid), ctx, uid)
+}
+
+// UpdateLastUsed mocks base method.
+func (m *MockPersonalAccessTokenRepository) UpdateLastUsed(ctx context.Context, id int64, lastUsed time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLastUsed", ctx, id, lastUsed)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLastUsed indicates an expected call of UpdateLastUsed.
+func (mr *MockPersonalAccessTokenRepositoryMockRecorder) UpdateLastUsed(ctx, id, lastUsed any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLastUsed", reflect.TypeOf((*MockPersonalAccessTokenRepository)(nil).UpdateLastUsed), ctx, id, lastUsed)
+}
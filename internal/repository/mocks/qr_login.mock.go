@@ -0,0 +1,86 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./qr_login.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./qr_login.go -package=repomocks -destination=mocks/qr_login.mock.go QRLoginRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockQRLoginRepository is a mock of QRLoginRepository interface.
+type MockQRLoginRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockQRLoginRepositoryMockRecorder
+}
+
+// MockQRLoginRepositoryMockRecorder is the mock recorder for MockQRLoginRepository.
+type MockQRLoginRepositoryMockRecorder struct {
+	mock *MockQRLoginRepository
+}
+
+// NewMockQRLoginRepository creates a new mock instance.
+func NewMockQRLoginRepository(ctrl *gomock.Controller) *MockQRLoginRepository {
+	mock := &MockQRLoginRepository{ctrl: ctrl}
+	mock.recorder = &MockQRLoginRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQRLoginRepository) EXPECT() *MockQRLoginRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockQRLoginRepository) Create(ctx context.Context, qr domain.QRLogin, expiration time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, qr, expiration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockQRLoginRepositoryMockRecorder) Create(ctx, qr, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockQRLoginRepository)(nil).Create), ctx, qr, expiration)
+}
+
+// FindByTicket mocks base method.
+func (m *MockQRLoginRepository) FindByTicket(ctx context.Context, ticket string) (domain.QRLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByTicket", ctx, ticket)
+	ret0, _ := ret[0].(domain.QRLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByTicket indicates an expected call of FindByTicket.
+func (mr *MockQRLoginRepositoryMockRecorder) FindByTicket(ctx, ticket any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByTicket", reflect.TypeOf((*MockQRLoginRepository)(nil).FindByTicket), ctx, ticket)
+}
+
+// Transit mocks base method.
+func (m *MockQRLoginRepository) Transit(ctx context.Context, ticket, from, to string, uid int64, expiration time.Duration) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transit", ctx, ticket, from, to, uid, expiration)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transit indicates an expected call of Transit.
+func (mr *MockQRLoginRepositoryMockRecorder) Transit(ctx, ticket, from, to, uid, expiration any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transit", reflect.TypeOf((*MockQRLoginRepository)(nil).Transit), ctx, ticket, from, to, uid, expiration)
+}
@@ -0,0 +1,129 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./rbac.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./rbac.go -package=repomocks -destination=mocks/rbac.mock.go RBACRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRBACRepository is a mock of RBACRepository interface.
+type MockRBACRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRBACRepositoryMockRecorder
+}
+
+// MockRBACRepositoryMockRecorder is the mock recorder for MockRBACRepository.
+type MockRBACRepositoryMockRecorder struct {
+	mock *MockRBACRepository
+}
+
+// NewMockRBACRepository creates a new mock instance.
+func NewMockRBACRepository(ctrl *gomock.Controller) *MockRBACRepository {
+	mock := &MockRBACRepository{ctrl: ctrl}
+	mock.recorder = &MockRBACRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRBACRepository) EXPECT() *MockRBACRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteRole mocks base method.
+func (m *MockRBACRepository) DeleteRole(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRole", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRole indicates an expected call of DeleteRole.
+func (mr *MockRBACRepositoryMockRecorder) DeleteRole(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRole", reflect.TypeOf((*MockRBACRepository)(nil).DeleteRole), ctx, id)
+}
+
+// FindPermissionsByUid mocks base method.
+func (m *MockRBACRepository) FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindPermissionsByUid", ctx, uid)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindPermissionsByUid indicates an expected call of FindPermissionsByUid.
+func (mr *MockRBACRepositoryMockRecorder) FindPermissionsByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindPermissionsByUid", reflect.TypeOf((*MockRBACRepository)(nil).FindPermissionsByUid), ctx, uid)
+}
+
+// FindRoles mocks base method.
+func (m *MockRBACRepository) FindRoles(ctx context.Context) ([]domain.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRoles", ctx)
+	ret0, _ := ret[0].([]domain.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRoles indicates an expected call of FindRoles.
+func (mr *MockRBACRepositoryMockRecorder) FindRoles(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRoles", reflect.TypeOf((*MockRBACRepository)(nil).FindRoles), ctx)
+}
+
+// FindRolesByUid mocks base method.
+func (m *MockRBACRepository) FindRolesByUid(ctx context.Context, uid int64) ([]domain.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRolesByUid", ctx, uid)
+	ret0, _ := ret[0].([]domain.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRolesByUid indicates an expected call of FindRolesByUid.
+func (mr *MockRBACRepositoryMockRecorder) FindRolesByUid(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRolesByUid", reflect.TypeOf((*MockRBACRepository)(nil).FindRolesByUid), ctx, uid)
+}
+
+// SaveRole mocks base method.
+func (m *MockRBACRepository) SaveRole(ctx context.Context, r domain.Role) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRole", ctx, r)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveRole indicates an expected call of SaveRole.
+func (mr *MockRBACRepositoryMockRecorder) SaveRole(ctx, r any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRole", reflect.TypeOf((*MockRBACRepository)(nil).SaveRole), ctx, r)
+}
+
+// SetUserRoles mocks base method.
+func (m *MockRBACRepository) SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRoles", ctx, uid, roleIds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRoles indicates an expected call of SetUserRoles.
+func (mr *MockRBACRepositoryMockRecorder) SetUserRoles(ctx, uid, roleIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRoles", reflect.TypeOf((*MockRBACRepository)(nil).SetUserRoles), ctx, uid, roleIds)
+}
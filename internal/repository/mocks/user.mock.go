@@ -3,7 +3,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=./user.go -package=repomocks -destination=mocks/user.mock.go UserRepository
+//	mockgen -source=./user.go -package=repomocks -destination=mocks/user.mock.go
 //
 
 // Package repomocks is a generated GoMock package.
@@ -12,6 +12,7 @@ package repomocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/dadaxiaoxiao/user/internal/domain"
 	gomock "go.uber.org/mock/gomock"
@@ -40,6 +41,20 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// BindIdentity mocks base method.
+func (m *MockUserRepository) BindIdentity(ctx context.Context, i domain.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindIdentity", ctx, i)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindIdentity indicates an expected call of BindIdentity.
+func (mr *MockUserRepositoryMockRecorder) BindIdentity(ctx, i any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindIdentity", reflect.TypeOf((*MockUserRepository)(nil).BindIdentity), ctx, i)
+}
+
 // Create mocks base method.
 func (m *MockUserRepository) Create(ctx context.Context, user domain.User) error {
 	m.ctrl.T.Helper()
@@ -54,6 +69,34 @@ func (mr *MockUserRepositoryMockRecorder) Create(ctx, user any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUserRepository)(nil).Create), ctx, user)
 }
 
+// Erase mocks base method.
+func (m *MockUserRepository) Erase(ctx context.Context, id int64, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Erase", ctx, id, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Erase indicates an expected call of Erase.
+func (mr *MockUserRepositoryMockRecorder) Erase(ctx, id, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Erase", reflect.TypeOf((*MockUserRepository)(nil).Erase), ctx, id, now)
+}
+
+// FillProfile mocks base method.
+func (m *MockUserRepository) FillProfile(ctx context.Context, id int64, nickname, avatar string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FillProfile", ctx, id, nickname, avatar)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FillProfile indicates an expected call of FillProfile.
+func (mr *MockUserRepositoryMockRecorder) FillProfile(ctx, id, nickname, avatar any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FillProfile", reflect.TypeOf((*MockUserRepository)(nil).FillProfile), ctx, id, nickname, avatar)
+}
+
 // FindByEmail mocks base method.
 func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (domain.User, error) {
 	m.ctrl.T.Helper()
@@ -114,6 +157,108 @@ func (mr *MockUserRepositoryMockRecorder) FindByWechat(ctx, openID any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByWechat", reflect.TypeOf((*MockUserRepository)(nil).FindByWechat), ctx, openID)
 }
 
+// FindByWechatUnionId mocks base method.
+func (m *MockUserRepository) FindByWechatUnionId(ctx context.Context, unionId string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByWechatUnionId", ctx, unionId)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByWechatUnionId indicates an expected call of FindByWechatUnionId.
+func (mr *MockUserRepositoryMockRecorder) FindByWechatUnionId(ctx, unionId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByWechatUnionId", reflect.TypeOf((*MockUserRepository)(nil).FindByWechatUnionId), ctx, unionId)
+}
+
+// FindDeletable mocks base method.
+func (m *MockUserRepository) FindDeletable(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeletable", ctx, now, limit)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindDeletable indicates an expected call of FindDeletable.
+func (mr *MockUserRepositoryMockRecorder) FindDeletable(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeletable", reflect.TypeOf((*MockUserRepository)(nil).FindDeletable), ctx, now, limit)
+}
+
+// FindUnsentDeleted mocks base method.
+func (m *MockUserRepository) FindUnsentDeleted(ctx context.Context, limit int) ([]domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUnsentDeleted", ctx, limit)
+	ret0, _ := ret[0].([]domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUnsentDeleted indicates an expected call of FindUnsentDeleted.
+func (mr *MockUserRepositoryMockRecorder) FindUnsentDeleted(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUnsentDeleted", reflect.TypeOf((*MockUserRepository)(nil).FindUnsentDeleted), ctx, limit)
+}
+
+// MarkDeletedSent mocks base method.
+func (m *MockUserRepository) MarkDeletedSent(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkDeletedSent", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkDeletedSent indicates an expected call of MarkDeletedSent.
+func (mr *MockUserRepositoryMockRecorder) MarkDeletedSent(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDeletedSent", reflect.TypeOf((*MockUserRepository)(nil).MarkDeletedSent), ctx, id)
+}
+
+// MarkEmailVerified mocks base method.
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id int64, verifiedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailVerified", ctx, id, verifiedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailVerified indicates an expected call of MarkEmailVerified.
+func (mr *MockUserRepositoryMockRecorder) MarkEmailVerified(ctx, id, verifiedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailVerified", reflect.TypeOf((*MockUserRepository)(nil).MarkEmailVerified), ctx, id, verifiedAt)
+}
+
+// Merge mocks base method.
+func (m *MockUserRepository) Merge(ctx context.Context, sourceId, targetId int64, merge func(domain.User, domain.User) (domain.User, error)) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, sourceId, targetId, merge)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockUserRepositoryMockRecorder) Merge(ctx, sourceId, targetId, merge any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockUserRepository)(nil).Merge), ctx, sourceId, targetId, merge)
+}
+
+// Search mocks base method.
+func (m *MockUserRepository) Search(ctx context.Context, q domain.UserQuery) ([]domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, q)
+	ret0, _ := ret[0].([]domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUserRepositoryMockRecorder) Search(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUserRepository)(nil).Search), ctx, q)
+}
+
 // Update mocks base method.
 func (m *MockUserRepository) Update(ctx context.Context, user domain.User) error {
 	m.ctrl.T.Helper()
@@ -127,3 +272,87 @@ func (mr *MockUserRepositoryMockRecorder) Update(ctx, user any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUserRepository)(nil).Update), ctx, user)
 }
+
+// UpdateDeleteAt mocks base method.
+func (m *MockUserRepository) UpdateDeleteAt(ctx context.Context, id int64, deleteAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDeleteAt", ctx, id, deleteAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDeleteAt indicates an expected call of UpdateDeleteAt.
+func (mr *MockUserRepositoryMockRecorder) UpdateDeleteAt(ctx, id, deleteAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDeleteAt", reflect.TypeOf((*MockUserRepository)(nil).UpdateDeleteAt), ctx, id, deleteAt)
+}
+
+// UpdateEmail mocks base method.
+func (m *MockUserRepository) UpdateEmail(ctx context.Context, id int64, email string, verifiedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEmail", ctx, id, email, verifiedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEmail indicates an expected call of UpdateEmail.
+func (mr *MockUserRepositoryMockRecorder) UpdateEmail(ctx, id, email, verifiedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmail", reflect.TypeOf((*MockUserRepository)(nil).UpdateEmail), ctx, id, email, verifiedAt)
+}
+
+// UpdateFrozen mocks base method.
+func (m *MockUserRepository) UpdateFrozen(ctx context.Context, id int64, frozenAt time.Time, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateFrozen", ctx, id, frozenAt, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateFrozen indicates an expected call of UpdateFrozen.
+func (mr *MockUserRepositoryMockRecorder) UpdateFrozen(ctx, id, frozenAt, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateFrozen", reflect.TypeOf((*MockUserRepository)(nil).UpdateFrozen), ctx, id, frozenAt, reason)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", ctx, id, password)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserRepositoryMockRecorder) UpdatePassword(ctx, id, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserRepository)(nil).UpdatePassword), ctx, id, password)
+}
+
+// UpdatePhone mocks base method.
+func (m *MockUserRepository) UpdatePhone(ctx context.Context, id int64, phone string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePhone", ctx, id, phone)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePhone indicates an expected call of UpdatePhone.
+func (mr *MockUserRepositoryMockRecorder) UpdatePhone(ctx, id, phone any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePhone", reflect.TypeOf((*MockUserRepository)(nil).UpdatePhone), ctx, id, phone)
+}
+
+// UpdateWechat mocks base method.
+func (m *MockUserRepository) UpdateWechat(ctx context.Context, id int64, info domain.WechatInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWechat", ctx, id, info)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWechat indicates an expected call of UpdateWechat.
+func (mr *MockUserRepositoryMockRecorder) UpdateWechat(ctx, id, info any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWechat", reflect.TypeOf((*MockUserRepository)(nil).UpdateWechat), ctx, id, info)
+}
@@ -0,0 +1,83 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./wechat_mini.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./wechat_mini.go -package=repomocks -destination=mocks/wechat_mini.mock.go WechatMiniSessionRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWechatMiniSessionRepository is a mock of WechatMiniSessionRepository interface.
+type MockWechatMiniSessionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWechatMiniSessionRepositoryMockRecorder
+}
+
+// MockWechatMiniSessionRepositoryMockRecorder is the mock recorder for MockWechatMiniSessionRepository.
+type MockWechatMiniSessionRepositoryMockRecorder struct {
+	mock *MockWechatMiniSessionRepository
+}
+
+// NewMockWechatMiniSessionRepository creates a new mock instance.
+func NewMockWechatMiniSessionRepository(ctrl *gomock.Controller) *MockWechatMiniSessionRepository {
+	mock := &MockWechatMiniSessionRepository{ctrl: ctrl}
+	mock.recorder = &MockWechatMiniSessionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWechatMiniSessionRepository) EXPECT() *MockWechatMiniSessionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteSessionKey mocks base method.
+func (m *MockWechatMiniSessionRepository) DeleteSessionKey(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSessionKey", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSessionKey indicates an expected call of DeleteSessionKey.
+func (mr *MockWechatMiniSessionRepositoryMockRecorder) DeleteSessionKey(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSessionKey", reflect.TypeOf((*MockWechatMiniSessionRepository)(nil).DeleteSessionKey), ctx, uid)
+}
+
+// FindSessionKey mocks base method.
+func (m *MockWechatMiniSessionRepository) FindSessionKey(ctx context.Context, uid int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSessionKey", ctx, uid)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSessionKey indicates an expected call of FindSessionKey.
+func (mr *MockWechatMiniSessionRepositoryMockRecorder) FindSessionKey(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSessionKey", reflect.TypeOf((*MockWechatMiniSessionRepository)(nil).FindSessionKey), ctx, uid)
+}
+
+// SaveSessionKey mocks base method.
+func (m *MockWechatMiniSessionRepository) SaveSessionKey(ctx context.Context, uid int64, sessionKey string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveSessionKey", ctx, uid, sessionKey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveSessionKey indicates an expected call of SaveSessionKey.
+func (mr *MockWechatMiniSessionRepositoryMockRecorder) SaveSessionKey(ctx, uid, sessionKey any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSessionKey", reflect.TypeOf((*MockWechatMiniSessionRepository)(nil).SaveSessionKey), ctx, uid, sessionKey)
+}
@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./wechat_token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./wechat_token.go -package=repomocks -destination=mocks/wechat_token.mock.go WechatTokenRepository
+//
+
+// Package repomocks is a generated GoMock package.
+package repomocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWechatTokenRepository is a mock of WechatTokenRepository interface.
+type MockWechatTokenRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWechatTokenRepositoryMockRecorder
+}
+
+// MockWechatTokenRepositoryMockRecorder is the mock recorder for MockWechatTokenRepository.
+type MockWechatTokenRepositoryMockRecorder struct {
+	mock *MockWechatTokenRepository
+}
+
+// NewMockWechatTokenRepository creates a new mock instance.
+func NewMockWechatTokenRepository(ctrl *gomock.Controller) *MockWechatTokenRepository {
+	mock := &MockWechatTokenRepository{ctrl: ctrl}
+	mock.recorder = &MockWechatTokenRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWechatTokenRepository) EXPECT() *MockWechatTokenRepositoryMockRecorder {
+	return m.recorder
+}
+
+// FindByOpenId mocks base method.
+func (m *MockWechatTokenRepository) FindByOpenId(ctx context.Context, openId string) (domain.WechatToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByOpenId", ctx, openId)
+	ret0, _ := ret[0].(domain.WechatToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindByOpenId indicates an expected call of FindByOpenId.
+func (mr *MockWechatTokenRepositoryMockRecorder) FindByOpenId(ctx, openId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByOpenId", reflect.TypeOf((*MockWechatTokenRepository)(nil).FindByOpenId), ctx, openId)
+}
+
+// Save mocks base method.
+func (m *MockWechatTokenRepository) Save(ctx context.Context, t domain.WechatToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockWechatTokenRepositoryMockRecorder) Save(ctx, t any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockWechatTokenRepository)(nil).Save), ctx, t)
+}
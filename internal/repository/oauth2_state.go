@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+)
+
+var ErrOAuth2StateNotFound = cache.ErrKeyNotExist
+
+//go:generate mockgen.exe -source=./oauth2_state.go -package=repomocks -destination=mocks/oauth2_state.mock.go OAuth2StateRepository
+type OAuth2StateRepository interface {
+	Store(ctx context.Context, state string, val domain.OAuth2State) error
+	// Take state 不存在、过期或者已经用过了返回 ErrOAuth2StateNotFound
+	Take(ctx context.Context, state string) (domain.OAuth2State, error)
+}
+
+type CachedOAuth2StateRepository struct {
+	cache cache.OAuth2StateCache
+}
+
+func NewCachedOAuth2StateRepository(cache cache.OAuth2StateCache) OAuth2StateRepository {
+	return &CachedOAuth2StateRepository{
+		cache: cache,
+	}
+}
+
+func (r *CachedOAuth2StateRepository) Store(ctx context.Context, state string, val domain.OAuth2State) error {
+	return r.cache.Set(ctx, state, val)
+}
+
+func (r *CachedOAuth2StateRepository) Take(ctx context.Context, state string) (domain.OAuth2State, error) {
+	return r.cache.Take(ctx, state)
+}
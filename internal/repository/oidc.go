@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+	"github.com/dadaxiaoxiao/user/internal/repository/dao"
+	"strings"
+	"time"
+)
+
+var (
+	ErrOAuthClientNotFound  = dao.ErrOAuthClientNotFound
+	ErrOAuthConsentNotFound = dao.ErrOAuthConsentNotFound
+	ErrAuthCodeNotFound     = cache.ErrKeyNotExist
+)
+
+//go:generate mockgen.exe -source=./oidc.go -package=repomocks -destination=mocks/oidc.mock.go OIDCRepository
+type OIDCRepository interface {
+	CreateClient(ctx context.Context, c domain.OAuthClient) error
+	FindClient(ctx context.Context, clientId string) (domain.OAuthClient, error)
+	FindConsent(ctx context.Context, uid int64, clientId string) (domain.OAuthConsent, error)
+	SaveConsent(ctx context.Context, c domain.OAuthConsent) error
+	StoreCode(ctx context.Context, code string, val domain.AuthCode) error
+	// TakeCode 授权码不存在或者已经用过了返回 ErrAuthCodeNotFound
+	TakeCode(ctx context.Context, code string) (domain.AuthCode, error)
+}
+
+// CachedOIDCRepository 应用和授权记录在数据库，授权码在缓存
+type CachedOIDCRepository struct {
+	dao   dao.OIDCDAO
+	cache cache.OIDCCache
+}
+
+func NewCachedOIDCRepository(dao dao.OIDCDAO, cache cache.OIDCCache) OIDCRepository {
+	return &CachedOIDCRepository{
+		dao:   dao,
+		cache: cache,
+	}
+}
+
+func (r *CachedOIDCRepository) CreateClient(ctx context.Context, c domain.OAuthClient) error {
+	uris, err := json.Marshal(c.RedirectURIs)
+	if err != nil {
+		return err
+	}
+	return r.dao.InsertClient(ctx, dao.OAuthClient{
+		ClientId:     c.ClientId,
+		SecretHash:   c.SecretHash,
+		Name:         c.Name,
+		RedirectURIs: string(uris),
+		Public:       c.Public,
+	})
+}
+
+func (r *CachedOIDCRepository) FindClient(ctx context.Context, clientId string) (domain.OAuthClient, error) {
+	c, err := r.dao.FindClient(ctx, clientId)
+	if err != nil {
+		return domain.OAuthClient{}, err
+	}
+	var uris []string
+	err = json.Unmarshal([]byte(c.RedirectURIs), &uris)
+	if err != nil {
+		return domain.OAuthClient{}, err
+	}
+	return domain.OAuthClient{
+		Id:           c.Id,
+		ClientId:     c.ClientId,
+		SecretHash:   c.SecretHash,
+		Name:         c.Name,
+		RedirectURIs: uris,
+		Public:       c.Public,
+		Ctime:        time.UnixMilli(c.Ctime),
+	}, nil
+}
+
+func (r *CachedOIDCRepository) FindConsent(ctx context.Context, uid int64, clientId string) (domain.OAuthConsent, error) {
+	c, err := r.dao.FindConsent(ctx, uid, clientId)
+	if err != nil {
+		return domain.OAuthConsent{}, err
+	}
+	return domain.OAuthConsent{
+		Uid:      c.Uid,
+		ClientId: c.ClientId,
+		Scopes:   strings.Fields(c.Scopes),
+	}, nil
+}
+
+func (r *CachedOIDCRepository) SaveConsent(ctx context.Context, c domain.OAuthConsent) error {
+	return r.dao.UpsertConsent(ctx, dao.OAuthConsent{
+		Uid:      c.Uid,
+		ClientId: c.ClientId,
+		Scopes:   strings.Join(c.Scopes, " "),
+	})
+}
+
+func (r *CachedOIDCRepository) StoreCode(ctx context.Context, code string, val domain.AuthCode) error {
+	return r.cache.SetCode(ctx, code, val)
+}
+
+func (r *CachedOIDCRepository) TakeCode(ctx context.Context, code string) (domain.AuthCode, error) {
+	return r.cache.TakeCode(ctx, code)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+)
+
+var ErrResetTokenNotFound = cache.ErrKeyNotExist
+
+//go:generate mockgen.exe -source=./password_reset.go -package=repomocks -destination=mocks/password_reset.mock.go PasswordResetRepository
+type PasswordResetRepository interface {
+	Store(ctx context.Context, token string, uid int64) error
+	// Consume 使用凭证，凭证不存在或者已经用过了返回 ErrResetTokenNotFound
+	Consume(ctx context.Context, token string) (int64, error)
+}
+
+type CachedPasswordResetRepository struct {
+	cache cache.PasswordResetCache
+}
+
+func NewCachedPasswordResetRepository(cache cache.PasswordResetCache) PasswordResetRepository {
+	return &CachedPasswordResetRepository{
+		cache: cache,
+	}
+}
+
+func (r *CachedPasswordResetRepository) Store(ctx context.Context, token string, uid int64) error {
+	return r.cache.Set(ctx, token, uid)
+}
+
+func (r *CachedPasswordResetRepository) Consume(ctx context.Context, token string) (int64, error) {
+	return r.cache.Take(ctx, token)
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/dao"
+	"strings"
+	"time"
+)
+
+var ErrPersonalAccessTokenNotFound = dao.ErrPersonalAccessTokenNotFound
+
+//go:generate mockgen.exe -source=./personal_access_token.go -package=repomocks -destination=mocks/personal_access_token.mock.go PersonalAccessTokenRepository
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, t domain.PersonalAccessToken) (int64, error)
+	FindByUid(ctx context.Context, uid int64) ([]domain.PersonalAccessToken, error)
+	// FindByHash 没有找到返回 ErrPersonalAccessTokenNotFound
+	FindByHash(ctx context.Context, hash string) (domain.PersonalAccessToken, error)
+	// Delete 不是该用户的令牌返回 ErrPersonalAccessTokenNotFound
+	Delete(ctx context.Context, uid int64, id int64) error
+	UpdateLastUsed(ctx context.Context, id int64, lastUsed time.Time) error
+}
+
+type CachedPersonalAccessTokenRepository struct {
+	dao dao.PersonalAccessTokenDAO
+}
+
+func NewCachedPersonalAccessTokenRepository(dao dao.PersonalAccessTokenDAO) PersonalAccessTokenRepository {
+	return &CachedPersonalAccessTokenRepository{
+		dao: dao,
+	}
+}
+
+func (r *CachedPersonalAccessTokenRepository) Create(ctx context.Context, t domain.PersonalAccessToken) (int64, error) {
+	return r.dao.Insert(ctx, dao.PersonalAccessToken{
+		Uid:       t.Uid,
+		Name:      t.Name,
+		TokenHash: t.TokenHash,
+		Prefix:    t.Prefix,
+		Scopes:    strings.Join(t.Scopes, " "),
+		ExpiresAt: t.ExpiresAt.UnixMilli(),
+	})
+}
+
+func (r *CachedPersonalAccessTokenRepository) FindByUid(ctx context.Context, uid int64) ([]domain.PersonalAccessToken, error) {
+	tokens, err := r.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.PersonalAccessToken, 0, len(tokens))
+	for _, t := range tokens {
+		res = append(res, r.toDomain(t))
+	}
+	return res, nil
+}
+
+func (r *CachedPersonalAccessTokenRepository) FindByHash(ctx context.Context, hash string) (domain.PersonalAccessToken, error) {
+	t, err := r.dao.FindByHash(ctx, hash)
+	if err != nil {
+		return domain.PersonalAccessToken{}, err
+	}
+	return r.toDomain(t), nil
+}
+
+func (r *CachedPersonalAccessTokenRepository) Delete(ctx context.Context, uid int64, id int64) error {
+	return r.dao.Delete(ctx, uid, id)
+}
+
+func (r *CachedPersonalAccessTokenRepository) UpdateLastUsed(ctx context.Context, id int64, lastUsed time.Time) error {
+	return r.dao.UpdateLastUsed(ctx, id, lastUsed.UnixMilli())
+}
+
+func (r *CachedPersonalAccessTokenRepository) toDomain(t dao.PersonalAccessToken) domain.PersonalAccessToken {
+	res := domain.PersonalAccessToken{
+		Id:        t.Id,
+		Uid:       t.Uid,
+		Name:      t.Name,
+		TokenHash: t.TokenHash,
+		Prefix:    t.Prefix,
+		Scopes:    strings.Fields(t.Scopes),
+		ExpiresAt: time.UnixMilli(t.ExpiresAt),
+		Ctime:     time.UnixMilli(t.Ctime),
+	}
+	// 0 表示从来没有用过
+	if t.LastUsedAt > 0 {
+		res.LastUsedAt = time.UnixMilli(t.LastUsedAt)
+	}
+	return res
+}
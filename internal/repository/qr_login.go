@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+	"time"
+)
+
+var (
+	ErrQRLoginNotFound       = cache.ErrKeyNotExist
+	ErrQRLoginStatusConflict = cache.ErrQRLoginStatusConflict
+)
+
+//go:generate mockgen.exe -source=./qr_login.go -package=repomocks -destination=mocks/qr_login.mock.go QRLoginRepository
+type QRLoginRepository interface {
+	Create(ctx context.Context, qr domain.QRLogin, expiration time.Duration) error
+	// FindByTicket 票据不存在或者过期返回 ErrQRLoginNotFound
+	FindByTicket(ctx context.Context, ticket string) (domain.QRLogin, error)
+	// Transit 状态是 from 的时候改成 to，to 为空的时候删除票据，返回扫码的用户
+	// 状态不对或者不是扫码的用户返回 ErrQRLoginStatusConflict
+	Transit(ctx context.Context, ticket string, from, to string, uid int64, expiration time.Duration) (int64, error)
+}
+
+type CachedQRLoginRepository struct {
+	cache cache.QRLoginCache
+}
+
+func NewCachedQRLoginRepository(cache cache.QRLoginCache) QRLoginRepository {
+	return &CachedQRLoginRepository{
+		cache: cache,
+	}
+}
+
+func (r *CachedQRLoginRepository) Create(ctx context.Context, qr domain.QRLogin, expiration time.Duration) error {
+	return r.cache.Set(ctx, qr, expiration)
+}
+
+func (r *CachedQRLoginRepository) FindByTicket(ctx context.Context, ticket string) (domain.QRLogin, error) {
+	return r.cache.Get(ctx, ticket)
+}
+
+func (r *CachedQRLoginRepository) Transit(ctx context.Context, ticket string, from, to string, uid int64, expiration time.Duration) (int64, error) {
+	return r.cache.Transit(ctx, ticket, from, to, uid, expiration)
+}
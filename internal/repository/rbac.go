@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+	"github.com/dadaxiaoxiao/user/internal/repository/dao"
+)
+
+var (
+	ErrRoleNotFound  = dao.ErrRoleNotFound
+	ErrRoleDuplicate = dao.ErrRoleDuplicate
+)
+
+//go:generate mockgen.exe -source=./rbac.go -package=repomocks -destination=mocks/rbac.mock.go RBACRepository
+type RBACRepository interface {
+	// SaveRole Id 为 0 的时候新建，返回角色 id
+	SaveRole(ctx context.Context, r domain.Role) (int64, error)
+	DeleteRole(ctx context.Context, id int64) error
+	FindRoles(ctx context.Context) ([]domain.Role, error)
+	FindRolesByUid(ctx context.Context, uid int64) ([]domain.Role, error)
+	// FindPermissionsByUid 用户所有角色的权限，去重之后的结果
+	FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error)
+	SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error
+}
+
+// CachedRBACRepository 只缓存用户的权限，角色的修改都要清掉相关用户的缓存
+type CachedRBACRepository struct {
+	dao   dao.RBACDAO
+	cache cache.RBACCache
+}
+
+func NewCachedRBACRepository(dao dao.RBACDAO, cache cache.RBACCache) RBACRepository {
+	return &CachedRBACRepository{
+		dao:   dao,
+		cache: cache,
+	}
+}
+
+func (r *CachedRBACRepository) SaveRole(ctx context.Context, role domain.Role) (int64, error) {
+	id, err := r.dao.SaveRole(ctx, dao.Role{
+		Id:          role.Id,
+		Name:        role.Name,
+		Description: role.Description,
+	}, role.Permissions)
+	if err != nil {
+		return 0, err
+	}
+	if role.Id > 0 {
+		return id, r.delRoleCache(ctx, id)
+	}
+	return id, nil
+}
+
+func (r *CachedRBACRepository) DeleteRole(ctx context.Context, id int64) error {
+	// 先查出来，删除之后就查不到了
+	uids, err := r.dao.FindUidsByRole(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = r.dao.DeleteRole(ctx, id); err != nil {
+		return err
+	}
+	return r.cache.DelPermissions(ctx, uids...)
+}
+
+func (r *CachedRBACRepository) FindRoles(ctx context.Context) ([]domain.Role, error) {
+	roles, err := r.dao.FindRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.toDomain(ctx, roles)
+}
+
+func (r *CachedRBACRepository) FindRolesByUid(ctx context.Context, uid int64) ([]domain.Role, error) {
+	roles, err := r.dao.FindRolesByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return r.toDomain(ctx, roles)
+}
+
+func (r *CachedRBACRepository) FindPermissionsByUid(ctx context.Context, uid int64) ([]string, error) {
+	res, err := r.cache.GetPermissions(ctx, uid)
+	if err == nil {
+		return res, nil
+	}
+	res, err = r.dao.FindPermissionsByUid(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	// 缓存失败不影响结果
+	_ = r.cache.SetPermissions(ctx, uid, res)
+	return res, nil
+}
+
+func (r *CachedRBACRepository) SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error {
+	if err := r.dao.SetUserRoles(ctx, uid, roleIds); err != nil {
+		return err
+	}
+	return r.cache.DelPermissions(ctx, uid)
+}
+
+func (r *CachedRBACRepository) delRoleCache(ctx context.Context, roleId int64) error {
+	uids, err := r.dao.FindUidsByRole(ctx, roleId)
+	if err != nil {
+		return err
+	}
+	return r.cache.DelPermissions(ctx, uids...)
+}
+
+func (r *CachedRBACRepository) toDomain(ctx context.Context, roles []dao.Role) ([]domain.Role, error) {
+	if len(roles) == 0 {
+		return []domain.Role{}, nil
+	}
+	ids := make([]int64, 0, len(roles))
+	for _, role := range roles {
+		ids = append(ids, role.Id)
+	}
+	rps, err := r.dao.FindPermissions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	perms := make(map[int64][]string, len(roles))
+	for _, rp := range rps {
+		perms[rp.RoleId] = append(perms[rp.RoleId], rp.Permission)
+	}
+	res := make([]domain.Role, 0, len(roles))
+	for _, role := range roles {
+		res = append(res, domain.Role{
+			Id:          role.Id,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: perms[role.Id],
+		})
+	}
+	return res, nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+	"github.com/dadaxiaoxiao/user/internal/repository/dao"
+	"github.com/dadaxiaoxiao/user/pkg/cryptox"
+	"time"
+)
+
+var (
+	ErrTOTPNotFound             = dao.ErrTOTPNotFound
+	ErrTOTPConflict             = dao.ErrTOTPConflict
+	ErrChallengeNotFound        = cache.ErrKeyNotExist
+	ErrChallengeTooManyAttempts = cache.ErrChallengeTooManyAttempts
+)
+
+//go:generate mockgen.exe -source=./totp.go -package=repomocks -destination=mocks/totp.mock.go TOTPRepository
+type TOTPRepository interface {
+	// Save 保存还没有启用的密钥
+	Save(ctx context.Context, t domain.TOTP) error
+	FindByUid(ctx context.Context, uid int64) (domain.TOTP, error)
+	Enable(ctx context.Context, uid int64, recoveryCodes []string) error
+	// UseCounter 标记验证码已经使用，重复使用返回 ErrTOTPConflict
+	UseCounter(ctx context.Context, uid int64, counter int64) error
+	// UpdateRecoveryCodes 乐观锁，old 被别人改过了返回 ErrTOTPConflict
+	UpdateRecoveryCodes(ctx context.Context, uid int64, old []string, recoveryCodes []string) error
+	Delete(ctx context.Context, uid int64) error
+
+	SetChallenge(ctx context.Context, token string, c domain.TwoFactorChallenge) error
+	// GetChallenge 挑战不存在返回 ErrChallengeNotFound
+	GetChallenge(ctx context.Context, token string) (domain.TwoFactorChallenge, error)
+	DelChallenge(ctx context.Context, token string) error
+}
+
+// CachedTOTPRepository 密钥加密之后存到数据库，登录挑战放在缓存
+type CachedTOTPRepository struct {
+	dao   dao.TOTPDAO
+	cache cache.TwoFactorCache
+	enc   cryptox.Encrypter
+}
+
+func NewCachedTOTPRepository(dao dao.TOTPDAO, cache cache.TwoFactorCache, enc cryptox.Encrypter) TOTPRepository {
+	return &CachedTOTPRepository{
+		dao:   dao,
+		cache: cache,
+		enc:   enc,
+	}
+}
+
+func (r *CachedTOTPRepository) Save(ctx context.Context, t domain.TOTP) error {
+	secret, err := r.enc.Encrypt([]byte(t.Secret))
+	if err != nil {
+		return err
+	}
+	return r.dao.Upsert(ctx, dao.UserTOTP{
+		Uid:    t.Uid,
+		Secret: secret,
+	})
+}
+
+func (r *CachedTOTPRepository) FindByUid(ctx context.Context, uid int64) (domain.TOTP, error) {
+	t, err := r.dao.FindByUid(ctx, uid)
+	if err != nil {
+		return domain.TOTP{}, err
+	}
+	return r.entityToDomain(t)
+}
+
+func (r *CachedTOTPRepository) Enable(ctx context.Context, uid int64, recoveryCodes []string) error {
+	codes, err := json.Marshal(recoveryCodes)
+	if err != nil {
+		return err
+	}
+	return r.dao.Enable(ctx, uid, string(codes))
+}
+
+func (r *CachedTOTPRepository) UseCounter(ctx context.Context, uid int64, counter int64) error {
+	return r.dao.UpdateLastCounter(ctx, uid, counter)
+}
+
+func (r *CachedTOTPRepository) UpdateRecoveryCodes(ctx context.Context, uid int64, old []string, recoveryCodes []string) error {
+	oldVal, err := json.Marshal(old)
+	if err != nil {
+		return err
+	}
+	val, err := json.Marshal(recoveryCodes)
+	if err != nil {
+		return err
+	}
+	return r.dao.UpdateRecoveryCodes(ctx, uid, string(oldVal), string(val))
+}
+
+func (r *CachedTOTPRepository) Delete(ctx context.Context, uid int64) error {
+	return r.dao.Delete(ctx, uid)
+}
+
+func (r *CachedTOTPRepository) SetChallenge(ctx context.Context, token string, c domain.TwoFactorChallenge) error {
+	return r.cache.SetChallenge(ctx, token, c)
+}
+
+func (r *CachedTOTPRepository) GetChallenge(ctx context.Context, token string) (domain.TwoFactorChallenge, error) {
+	return r.cache.GetChallenge(ctx, token)
+}
+
+func (r *CachedTOTPRepository) DelChallenge(ctx context.Context, token string) error {
+	return r.cache.DelChallenge(ctx, token)
+}
+
+func (r *CachedTOTPRepository) entityToDomain(t dao.UserTOTP) (domain.TOTP, error) {
+	secret, err := r.enc.Decrypt(t.Secret)
+	if err != nil {
+		return domain.TOTP{}, err
+	}
+	var codes []string
+	if t.RecoveryCodes != "" {
+		err = json.Unmarshal([]byte(t.RecoveryCodes), &codes)
+		if err != nil {
+			return domain.TOTP{}, err
+		}
+	}
+	return domain.TOTP{
+		Uid:           t.Uid,
+		Secret:        string(secret),
+		RecoveryCodes: codes,
+		LastCounter:   t.LastCounter,
+		EnabledAt:     r.toTime(t.EnabledAt),
+	}, nil
+}
+
+func (r *CachedTOTPRepository) toTime(val sql.NullInt64) time.Time {
+	if !val.Valid {
+		return time.Time{}
+	}
+	return time.UnixMilli(val.Int64)
+}
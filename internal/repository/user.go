@@ -10,8 +10,13 @@ import (
 )
 
 var (
-	ErrUserDuplicateEmail = dao.ErrUserDuplicateEmail
-	ErrUserNotFound       = dao.ErrUserNotFound
+	ErrUserDuplicateEmail  = dao.ErrUserDuplicateEmail
+	ErrUserDuplicatePhone  = dao.ErrUserDuplicatePhone
+	ErrUserDuplicateWechat = dao.ErrUserDuplicateWechat
+	ErrUserNotFound        = dao.ErrUserNotFound
+	ErrUserAlreadyMerged   = dao.ErrUserAlreadyMerged
+	ErrUserNotDeletable    = dao.ErrUserNotDeletable
+	ErrUserMergeConflict   = dao.ErrUserMergeConflict
 )
 
 //go:generate mockgen.exe -source=./user.go -package=repomocks -destination=mocks/user.mock.go UserRepository
@@ -22,6 +27,35 @@ type UserRepository interface {
 	FindById(ctx context.Context, id int64) (domain.User, error)
 	Update(ctx context.Context, user domain.User) error
 	FindByWechat(ctx context.Context, openID string) (domain.User, error)
+	FindByWechatUnionId(ctx context.Context, unionId string) (domain.User, error)
+	MarkEmailVerified(ctx context.Context, id int64, verifiedAt time.Time) error
+	// UpdatePassword 只修改密码，password 是加密之后的
+	UpdatePassword(ctx context.Context, id int64, password string) error
+	// UpdatePhone phone 为空的时候解绑
+	UpdatePhone(ctx context.Context, id int64, phone string) error
+	// UpdateEmail email 为空的时候解绑，verifiedAt 为零值代表还没有验证
+	UpdateEmail(ctx context.Context, id int64, email string, verifiedAt time.Time) error
+	// UpdateWechat info.OpenId 为空的时候解绑
+	UpdateWechat(ctx context.Context, id int64, info domain.WechatInfo) error
+	// BindIdentity 绑定第三方账号，微信在同一个事务里面同步 users 表里面的 openid
+	// 已经绑定过返回 ErrIdentityDuplicate，openid 被其它用户占用返回 ErrUserDuplicateWechat
+	BindIdentity(ctx context.Context, i domain.UserIdentity) error
+	// FillProfile 只填充还是空的昵称和头像
+	FillProfile(ctx context.Context, id int64, nickname string, avatar string) error
+	// UpdateFrozen frozenAt 为零值的时候解冻，用户不存在返回 ErrUserNotFound
+	UpdateFrozen(ctx context.Context, id int64, frozenAt time.Time, reason string) error
+	Search(ctx context.Context, q domain.UserQuery) ([]domain.User, error)
+	// UpdateDeleteAt deleteAt 为零值的时候取消注销，用户不存在或者已经删除返回 ErrUserNotFound
+	UpdateDeleteAt(ctx context.Context, id int64, deleteAt time.Time) error
+	FindDeletable(ctx context.Context, now time.Time, limit int) ([]int64, error)
+	// Erase 删除个人信息，冷静期没有结束或者已经取消注销返回 ErrUserNotDeletable
+	Erase(ctx context.Context, id int64, now time.Time) error
+	// FindUnsentDeleted 已经删除个人信息、还没有发送注销事件的用户，只有 Id 和 ErasedAt
+	FindUnsentDeleted(ctx context.Context, limit int) ([]domain.User, error)
+	// MarkDeletedSent 注销事件发送成功
+	MarkDeletedSent(ctx context.Context, id int64) error
+	// Merge 把 sourceId 合并到 targetId，merge 算出合并之后的 target，已经合并过返回 ErrUserAlreadyMerged
+	Merge(ctx context.Context, sourceId int64, targetId int64, merge func(source domain.User, target domain.User) (domain.User, error)) error
 }
 
 type CachedUserRepository struct {
@@ -68,6 +102,14 @@ func (r *CachedUserRepository) FindByWechat(ctx context.Context, openID string)
 	return r.entityToDomain(u), err
 }
 
+func (r *CachedUserRepository) FindByWechatUnionId(ctx context.Context, unionId string) (domain.User, error) {
+	u, err := r.dao.FindByWechatUnionId(ctx, unionId)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return r.entityToDomain(u), nil
+}
+
 // FindById 根据id 查询用户信息
 func (r *CachedUserRepository) FindById(ctx context.Context, id int64) (domain.User, error) {
 	// 获取缓存
@@ -82,6 +124,13 @@ func (r *CachedUserRepository) FindById(ctx context.Context, id int64) (domain.U
 	if err != nil {
 		return domain.User{}, err
 	}
+	if user.MergedInto.Valid {
+		// 已经合并的用户，返回合并到的用户
+		user, err = r.dao.FindById(ctx, user.MergedInto.Int64)
+		if err != nil {
+			return domain.User{}, err
+		}
+	}
 	u = r.entityToDomain(user)
 	// 写入缓存
 	//go func() {
@@ -108,6 +157,201 @@ func (r *CachedUserRepository) Update(ctx context.Context, user domain.User) err
 	return r.cache.Delete(ctx, user.Id)
 }
 
+// MarkEmailVerified 标记邮箱已经验证
+func (r *CachedUserRepository) MarkEmailVerified(ctx context.Context, id int64, verifiedAt time.Time) error {
+	err := r.dao.UpdateVerifiedAt(ctx, id, verifiedAt.UnixMilli())
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+// UpdatePassword 修改密码
+func (r *CachedUserRepository) UpdatePassword(ctx context.Context, id int64, password string) error {
+	err := r.dao.UpdatePassword(ctx, id, password)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+// UpdatePhone 绑定或者解绑手机号
+func (r *CachedUserRepository) UpdatePhone(ctx context.Context, id int64, phone string) error {
+	err := r.dao.UpdatePhone(ctx, id, sql.NullString{
+		String: phone,
+		Valid:  phone != "",
+	})
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+// UpdateEmail 绑定或者解绑邮箱
+func (r *CachedUserRepository) UpdateEmail(ctx context.Context, id int64, email string, verifiedAt time.Time) error {
+	err := r.dao.UpdateEmail(ctx, id, sql.NullString{
+		String: email,
+		Valid:  email != "",
+	}, sql.NullInt64{
+		Int64: verifiedAt.UnixMilli(),
+		Valid: !verifiedAt.IsZero(),
+	})
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+// UpdateWechat 绑定或者解绑微信
+func (r *CachedUserRepository) UpdateWechat(ctx context.Context, id int64, info domain.WechatInfo) error {
+	err := r.dao.UpdateWechat(ctx, id, sql.NullString{
+		String: info.OpenId,
+		Valid:  info.OpenId != "",
+	}, sql.NullString{
+		String: info.UnionId,
+		Valid:  info.UnionId != "",
+	})
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+// BindIdentity 绑定第三方账号，微信修改了 users 表，需要删缓存
+func (r *CachedUserRepository) BindIdentity(ctx context.Context, i domain.UserIdentity) error {
+	syncWechat := i.Provider == domain.ProviderWechat
+	err := r.dao.InsertIdentity(ctx, dao.UserIdentity{
+		Uid:      i.Uid,
+		Provider: i.Provider,
+		Subject:  i.Subject,
+		UnionId: sql.NullString{
+			String: i.UnionId,
+			Valid:  i.UnionId != "",
+		},
+		Nickname: i.Nickname,
+		Avatar:   i.Avatar,
+		Email:    i.Email,
+	}, syncWechat)
+	if err != nil || !syncWechat {
+		return err
+	}
+	return r.cache.Delete(ctx, i.Uid)
+}
+
+// FillProfile 补上昵称和头像
+func (r *CachedUserRepository) FillProfile(ctx context.Context, id int64, nickname string, avatar string) error {
+	err := r.dao.FillProfile(ctx, id, nickname, avatar)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+// Merge 合并用户，合并之后两个用户的缓存都失效
+func (r *CachedUserRepository) Merge(ctx context.Context, sourceId int64, targetId int64,
+	merge func(source domain.User, target domain.User) (domain.User, error)) error {
+	err := r.dao.Merge(ctx, sourceId, targetId, func(source dao.User, target dao.User) (dao.User, error) {
+		u, err := merge(r.entityToDomain(source), r.entityToDomain(target))
+		if err != nil {
+			return dao.User{}, err
+		}
+		return r.domainToEntity(u), nil
+	})
+	if err != nil && err != ErrUserAlreadyMerged {
+		return err
+	}
+	// 重复合并的时候也删一次，上一次可能删除缓存失败了
+	if er := r.cache.Delete(ctx, sourceId); er != nil {
+		return er
+	}
+	if er := r.cache.Delete(ctx, targetId); er != nil {
+		return er
+	}
+	return err
+}
+
+func (r *CachedUserRepository) UpdateFrozen(ctx context.Context, id int64, frozenAt time.Time, reason string) error {
+	err := r.dao.UpdateFrozen(ctx, id, sql.NullInt64{
+		Int64: frozenAt.UnixMilli(),
+		Valid: !frozenAt.IsZero(),
+	}, reason)
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+func (r *CachedUserRepository) UpdateDeleteAt(ctx context.Context, id int64, deleteAt time.Time) error {
+	err := r.dao.UpdateDeleteAt(ctx, id, sql.NullInt64{
+		Int64: deleteAt.UnixMilli(),
+		Valid: !deleteAt.IsZero(),
+	})
+	if err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+func (r *CachedUserRepository) FindDeletable(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	return r.dao.FindDeletable(ctx, now.UnixMilli(), limit)
+}
+
+func (r *CachedUserRepository) Erase(ctx context.Context, id int64, now time.Time) error {
+	err := r.dao.Erase(ctx, id, now.UnixMilli(), domain.ProviderWechat)
+	if err != nil {
+		return err
+	}
+	// 缓存里面还有个人信息，一定要删掉
+	return r.cache.Delete(ctx, id)
+}
+
+func (r *CachedUserRepository) FindUnsentDeleted(ctx context.Context, limit int) ([]domain.User, error) {
+	outbox, err := r.dao.FindUserDeletedOutbox(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.User, 0, len(outbox))
+	for _, o := range outbox {
+		res = append(res, domain.User{
+			Id:       o.Uid,
+			ErasedAt: time.UnixMilli(o.Ctime),
+		})
+	}
+	return res, nil
+}
+
+func (r *CachedUserRepository) MarkDeletedSent(ctx context.Context, id int64) error {
+	return r.dao.DeleteUserDeletedOutbox(ctx, id)
+}
+
+func (r *CachedUserRepository) Search(ctx context.Context, q domain.UserQuery) ([]domain.User, error) {
+	dq := dao.UserQuery{
+		Email:           q.Email,
+		Phone:           q.Phone,
+		WechatOpenId:    q.WechatOpenId,
+		WechatProviders: domain.WechatProviders,
+		MinId:           q.MinId,
+		MaxId:           q.MaxId,
+		Cursor:          q.Cursor,
+		Limit:           q.Limit,
+	}
+	if !q.CtimeStart.IsZero() {
+		dq.CtimeStart = q.CtimeStart.UnixMilli()
+	}
+	if !q.CtimeEnd.IsZero() {
+		dq.CtimeEnd = q.CtimeEnd.UnixMilli()
+	}
+	users, err := r.dao.Search(ctx, dq)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]domain.User, 0, len(users))
+	for _, u := range users {
+		res = append(res, r.entityToDomain(u))
+	}
+	return res, nil
+}
+
 func (r *CachedUserRepository) domainToEntity(u domain.User) dao.User {
 	return dao.User{
 		Id: u.Id,
@@ -126,12 +370,16 @@ func (r *CachedUserRepository) domainToEntity(u domain.User) dao.User {
 		},
 		Birthday: sql.NullInt64{
 			Int64: u.Birthday.UnixMilli(),
-			Valid: u.Birthday.IsZero(),
+			Valid: !u.Birthday.IsZero(),
 		},
 		AboutMe: sql.NullString{
 			String: u.AboutMe,
 			Valid:  u.AboutMe != "",
 		},
+		Avatar: sql.NullString{
+			String: u.Avatar,
+			Valid:  u.Avatar != "",
+		},
 		WechatOpenId: sql.NullString{
 			String: u.WechatInfo.OpenId,
 			Valid:  u.WechatInfo.OpenId != "",
@@ -140,6 +388,10 @@ func (r *CachedUserRepository) domainToEntity(u domain.User) dao.User {
 			String: u.WechatInfo.UnionId,
 			Valid:  u.WechatInfo.UnionId != "",
 		},
+		VerifiedAt: sql.NullInt64{
+			Int64: u.VerifiedAt.UnixMilli(),
+			Valid: !u.VerifiedAt.IsZero(),
+		},
 		Ctime: u.Ctime.UnixMilli(),
 	}
 }
@@ -149,6 +401,22 @@ func (r *CachedUserRepository) entityToDomain(u dao.User) domain.User {
 	if u.Birthday.Valid {
 		birthday = time.UnixMilli(u.Birthday.Int64)
 	}
+	var verifiedAt time.Time
+	if u.VerifiedAt.Valid {
+		verifiedAt = time.UnixMilli(u.VerifiedAt.Int64)
+	}
+	var frozenAt time.Time
+	if u.FrozenAt.Valid {
+		frozenAt = time.UnixMilli(u.FrozenAt.Int64)
+	}
+	var deleteAt time.Time
+	if u.DeleteAt.Valid {
+		deleteAt = time.UnixMilli(u.DeleteAt.Int64)
+	}
+	var erasedAt time.Time
+	if u.ErasedAt.Valid {
+		erasedAt = time.UnixMilli(u.ErasedAt.Int64)
+	}
 	return domain.User{
 		Id:       u.Id,
 		Email:    u.Email.String,
@@ -156,11 +424,17 @@ func (r *CachedUserRepository) entityToDomain(u dao.User) domain.User {
 		Password: u.Password,
 		Nickname: u.Nickname.String,
 		AboutMe:  u.AboutMe.String,
+		Avatar:   u.Avatar.String,
 		WechatInfo: domain.WechatInfo{
 			OpenId:  u.WechatOpenId.String,
 			UnionId: u.WechatUnionID.String,
 		},
-		Birthday: birthday,
-		Ctime:    time.UnixMilli(u.Ctime),
+		Birthday:     birthday,
+		VerifiedAt:   verifiedAt,
+		FrozenAt:     frozenAt,
+		FrozenReason: u.FrozenReason,
+		DeleteAt:     deleteAt,
+		ErasedAt:     erasedAt,
+		Ctime:        time.UnixMilli(u.Ctime),
 	}
 }
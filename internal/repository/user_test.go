@@ -143,6 +143,38 @@ func TestCachedUserRepository_FindById(t *testing.T) {
 			wantUser: domain.User{},
 			wantErr:  errors.New("db 异常"),
 		},
+		{
+			name: "已经合并的用户，返回合并到的用户",
+			mock: func(ctrl *gomock.Controller) (dao.UserDao, cache.UserCache) {
+				c := cachemocks.NewMockUserCache(ctrl)
+				c.EXPECT().Get(gomock.Any(), int64(12)).
+					Return(domain.User{}, cache.ErrKeyNotExist)
+				d := daomocks.NewMockUserDao(ctrl)
+				d.EXPECT().FindById(gomock.Any(), int64(12)).Return(dao.User{
+					Id:         12,
+					MergedInto: sql.NullInt64{Int64: 13, Valid: true},
+					Ctime:      now.UnixMilli(),
+				}, nil)
+				d.EXPECT().FindById(gomock.Any(), int64(13)).Return(dao.User{
+					Id:    13,
+					Phone: sql.NullString{String: "15212345678", Valid: true},
+					Ctime: now.UnixMilli(),
+				}, nil)
+				c.EXPECT().Set(gomock.Any(), domain.User{
+					Id:    13,
+					Phone: "15212345678",
+					Ctime: now,
+				}).Return(nil)
+				return d, c
+			},
+			ctx: context.Background(),
+			id:  12,
+			wantUser: domain.User{
+				Id:    13,
+				Phone: "15212345678",
+				Ctime: now,
+			},
+		},
 	}
 
 	for _, tc := range testCase {
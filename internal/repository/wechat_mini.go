@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+	"github.com/dadaxiaoxiao/user/pkg/cryptox"
+)
+
+var ErrWechatMiniSessionNotFound = cache.ErrKeyNotExist
+
+//go:generate mockgen.exe -source=./wechat_mini.go -package=repomocks -destination=mocks/wechat_mini.mock.go WechatMiniSessionRepository
+type WechatMiniSessionRepository interface {
+	SaveSessionKey(ctx context.Context, uid int64, sessionKey string) error
+	// FindSessionKey 过期或者没有登录过返回 ErrWechatMiniSessionNotFound
+	FindSessionKey(ctx context.Context, uid int64) (string, error)
+	// DeleteSessionKey 注销账号的时候删除
+	DeleteSessionKey(ctx context.Context, uid int64) error
+}
+
+// EncryptedWechatMiniSessionRepository session_key 加密之后放在 Redis 里面
+type EncryptedWechatMiniSessionRepository struct {
+	cache cache.WechatMiniSessionCache
+	enc   cryptox.Encrypter
+}
+
+func NewEncryptedWechatMiniSessionRepository(cache cache.WechatMiniSessionCache, enc cryptox.Encrypter) WechatMiniSessionRepository {
+	return &EncryptedWechatMiniSessionRepository{
+		cache: cache,
+		enc:   enc,
+	}
+}
+
+func (r *EncryptedWechatMiniSessionRepository) SaveSessionKey(ctx context.Context, uid int64, sessionKey string) error {
+	val, err := r.enc.Encrypt([]byte(sessionKey))
+	if err != nil {
+		return err
+	}
+	return r.cache.Set(ctx, uid, val)
+}
+
+func (r *EncryptedWechatMiniSessionRepository) FindSessionKey(ctx context.Context, uid int64) (string, error) {
+	val, err := r.cache.Get(ctx, uid)
+	if err != nil {
+		return "", err
+	}
+	sessionKey, err := r.enc.Decrypt(val)
+	if err != nil {
+		return "", err
+	}
+	return string(sessionKey), nil
+}
+
+func (r *EncryptedWechatMiniSessionRepository) DeleteSessionKey(ctx context.Context, uid int64) error {
+	return r.cache.Delete(ctx, uid)
+}
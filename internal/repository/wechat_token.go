@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/dao"
+	"github.com/dadaxiaoxiao/user/pkg/cryptox"
+	"time"
+)
+
+var ErrWechatTokenNotFound = dao.ErrWechatTokenNotFound
+
+//go:generate mockgen.exe -source=./wechat_token.go -package=repomocks -destination=mocks/wechat_token.mock.go WechatTokenRepository
+type WechatTokenRepository interface {
+	Save(ctx context.Context, t domain.WechatToken) error
+	// FindByOpenId 没有保存过返回 ErrWechatTokenNotFound
+	FindByOpenId(ctx context.Context, openId string) (domain.WechatToken, error)
+}
+
+// EncryptedWechatTokenRepository token 加密之后存到数据库
+type EncryptedWechatTokenRepository struct {
+	dao dao.WechatTokenDAO
+	enc cryptox.Encrypter
+}
+
+func NewEncryptedWechatTokenRepository(dao dao.WechatTokenDAO, enc cryptox.Encrypter) WechatTokenRepository {
+	return &EncryptedWechatTokenRepository{
+		dao: dao,
+		enc: enc,
+	}
+}
+
+func (r *EncryptedWechatTokenRepository) Save(ctx context.Context, t domain.WechatToken) error {
+	accessToken, err := r.enc.Encrypt([]byte(t.AccessToken))
+	if err != nil {
+		return err
+	}
+	refreshToken, err := r.enc.Encrypt([]byte(t.RefreshToken))
+	if err != nil {
+		return err
+	}
+	return r.dao.Upsert(ctx, dao.WechatToken{
+		OpenId:       t.OpenId,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scope:        t.Scope,
+		ExpiresAt:    t.ExpiresAt.UnixMilli(),
+	})
+}
+
+func (r *EncryptedWechatTokenRepository) FindByOpenId(ctx context.Context, openId string) (domain.WechatToken, error) {
+	t, err := r.dao.FindByOpenId(ctx, openId)
+	if err != nil {
+		return domain.WechatToken{}, err
+	}
+	accessToken, err := r.enc.Decrypt(t.AccessToken)
+	if err != nil {
+		return domain.WechatToken{}, err
+	}
+	refreshToken, err := r.enc.Decrypt(t.RefreshToken)
+	if err != nil {
+		return domain.WechatToken{}, err
+	}
+	return domain.WechatToken{
+		OpenId:       t.OpenId,
+		AccessToken:  string(accessToken),
+		RefreshToken: string(refreshToken),
+		Scope:        t.Scope,
+		ExpiresAt:    time.UnixMilli(t.ExpiresAt),
+	}, nil
+}
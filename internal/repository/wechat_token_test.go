@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/dao"
+	daomocks "github.com/dadaxiaoxiao/user/internal/repository/dao/mocks"
+	"github.com/dadaxiaoxiao/user/pkg/cryptox"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestEncryptedWechatTokenRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	enc, err := cryptox.NewAESGCM([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	var saved dao.WechatToken
+	d := daomocks.NewMockWechatTokenDAO(ctrl)
+	d.EXPECT().Upsert(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, t dao.WechatToken) error {
+		saved = t
+		return nil
+	})
+	d.EXPECT().FindByOpenId(gomock.Any(), "openid").DoAndReturn(func(ctx context.Context, openId string) (dao.WechatToken, error) {
+		return saved, nil
+	})
+
+	token := domain.WechatToken{
+		OpenId:       "openid",
+		AccessToken:  "token",
+		RefreshToken: "refresh",
+		Scope:        "snsapi_login",
+		ExpiresAt:    time.UnixMilli(time.Now().Add(time.Hour).UnixMilli()),
+	}
+	repo := NewEncryptedWechatTokenRepository(d, enc)
+	require.NoError(t, repo.Save(context.Background(), token))
+	// 数据库里面不能是明文
+	assert.NotEqual(t, "token", saved.AccessToken)
+	assert.NotEqual(t, "refresh", saved.RefreshToken)
+
+	got, err := repo.FindByOpenId(context.Background(), "openid")
+	require.NoError(t, err)
+	assert.Equal(t, token, got)
+}
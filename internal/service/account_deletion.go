@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/events"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"time"
+)
+
+// AccountDeletionService 注销账号，申请之后有一段冷静期，冷静期内登录会取消注销，
+// 冷静期结束之后由定时任务删除个人信息
+//
+//go:generate mockgen.exe -source=./account_deletion.go -package=svcmocks -destination=mocks/account_deletion.mock.go AccountDeletionService
+type AccountDeletionService interface {
+	// Request 申请注销，返回删除个人信息的时间，重复申请返回第一次申请的时间
+	Request(ctx context.Context, uid int64) (time.Time, error)
+	// Cancel 冷静期内登录成功（包括两步验证）之后取消注销，没有申请注销的时候什么也不做
+	Cancel(ctx context.Context, uid int64) error
+	// EraseExpired 删除冷静期已经结束的用户的个人信息并发送 UserDeleted 事件，返回删除的用户 id
+	// 事件先写入发件箱，之前发送失败的也会在这里重新发送
+	EraseExpired(ctx context.Context, limit int) ([]int64, error)
+}
+
+// AccountEraser 删除用户表以外的个人数据，比如导出的压缩包和登录记录，由 ioc 注入
+type AccountEraser func(ctx context.Context, uid int64) error
+
+type accountDeletionService struct {
+	repo repository.UserRepository
+	// sessionRepo 小程序的 session_key 按 uid 放在 Redis 里面，不在用户表
+	sessionRepo repository.WechatMiniSessionRepository
+	producer    events.Producer
+	erasers     []AccountEraser
+	// coolingOff 冷静期
+	coolingOff time.Duration
+	log        accesslog.Logger
+	now        func() time.Time
+}
+
+func NewAccountDeletionService(repo repository.UserRepository, sessionRepo repository.WechatMiniSessionRepository,
+	producer events.Producer, erasers []AccountEraser, coolingOff time.Duration, log accesslog.Logger) AccountDeletionService {
+	return &accountDeletionService{
+		repo:        repo,
+		sessionRepo: sessionRepo,
+		producer:    producer,
+		erasers:     erasers,
+		coolingOff:  coolingOff,
+		log:         log,
+		now:         time.Now,
+	}
+}
+
+func (svc *accountDeletionService) Request(ctx context.Context, uid int64) (time.Time, error) {
+	u, err := svc.repo.FindById(ctx, uid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !u.DeleteAt.IsZero() {
+		return u.DeleteAt, nil
+	}
+	deleteAt := svc.now().Add(svc.coolingOff)
+	return deleteAt, svc.repo.UpdateDeleteAt(ctx, u.Id, deleteAt)
+}
+
+func (svc *accountDeletionService) Cancel(ctx context.Context, uid int64) error {
+	u, err := svc.repo.FindById(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if u.DeleteAt.IsZero() {
+		return nil
+	}
+	return svc.repo.UpdateDeleteAt(ctx, u.Id, time.Time{})
+}
+
+func (svc *accountDeletionService) EraseExpired(ctx context.Context, limit int) ([]int64, error) {
+	now := svc.now()
+	ids, err := svc.repo.FindDeletable(ctx, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	erased := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		// 先删其它数据，失败的话用户还在 FindDeletable 里面，下一次重试
+		// 冷静期已经结束了，这时候登录取消注销最多丢掉登录记录和导出的压缩包
+		for _, erase := range svc.erasers {
+			if err = erase(ctx, id); err != nil {
+				return erased, err
+			}
+		}
+		err = svc.repo.Erase(ctx, id, now)
+		if err == repository.ErrUserNotDeletable {
+			// 查出来之后用户登录取消了注销
+			continue
+		}
+		if err != nil {
+			return erased, err
+		}
+		erased = append(erased, id)
+		// session_key 三天之后也会过期，删除失败不影响注销
+		err = svc.sessionRepo.DeleteSessionKey(ctx, id)
+		if err != nil {
+			svc.log.Error("删除小程序 session_key 失败", accesslog.Int64("uid", id), accesslog.Error(err))
+		}
+	}
+	svc.sendDeleted(ctx, limit)
+	return erased, nil
+}
+
+// sendDeleted 发送发件箱里面的注销事件，包括之前发送失败的，发送成功之后才从发件箱删除
+func (svc *accountDeletionService) sendDeleted(ctx context.Context, limit int) {
+	us, err := svc.repo.FindUnsentDeleted(ctx, limit)
+	if err != nil {
+		svc.log.Error("查询待发送的用户注销事件失败", accesslog.Error(err))
+		return
+	}
+	for _, u := range us {
+		err = svc.producer.ProduceUserDeletedEvent(ctx, events.UserDeletedEvent{
+			Uid:   u.Id,
+			Ctime: u.ErasedAt.UnixMilli(),
+		})
+		if err != nil {
+			// 剩下的留在发件箱里面，下一次定时任务再发
+			svc.log.Error("发送用户注销事件失败", accesslog.Int64("uid", u.Id), accesslog.Error(err))
+			return
+		}
+		// 删除失败下一次会重复发送，消费方是幂等的
+		err = svc.repo.MarkDeletedSent(ctx, u.Id)
+		if err != nil {
+			svc.log.Error("删除已经发送的用户注销事件失败", accesslog.Int64("uid", u.Id), accesslog.Error(err))
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/events"
+	evtmocks "github.com/dadaxiaoxiao/user/internal/events/mocks"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func Test_accountDeletionService_Request(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	coolingOff := time.Hour * 24 * 15
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+		uid  int64
+
+		wantDeleteAt time.Time
+		wantErr      error
+	}{
+		{
+			name: "申请成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				repo.EXPECT().UpdateDeleteAt(gomock.Any(), int64(1), now.Add(coolingOff)).Return(nil)
+				return repo
+			},
+			uid:          1,
+			wantDeleteAt: now.Add(coolingOff),
+		},
+		{
+			name: "重复申请不延长冷静期",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, DeleteAt: now.Add(time.Hour)}, nil)
+				return repo
+			},
+			uid:          1,
+			wantDeleteAt: now.Add(time.Hour),
+		},
+		{
+			name: "用户不存在",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{}, repository.ErrUserNotFound)
+				return repo
+			},
+			uid:     1,
+			wantErr: ErrUserNotFound,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewAccountDeletionService(tc.mock(ctrl), nil, nil, nil, coolingOff, accesslog.NewNopLogger()).(*accountDeletionService)
+			svc.now = func() time.Time {
+				return now
+			}
+			deleteAt, err := svc.Request(context.Background(), tc.uid)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantDeleteAt, deleteAt)
+		})
+	}
+}
+
+func Test_accountDeletionService_Cancel(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+		uid  int64
+
+		wantErr error
+	}{
+		{
+			name: "冷静期内取消注销",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, DeleteAt: time.UnixMilli(1700000000000)}, nil)
+				repo.EXPECT().UpdateDeleteAt(gomock.Any(), int64(1), time.Time{}).Return(nil)
+				return repo
+			},
+			uid: 1,
+		},
+		{
+			name: "没有申请注销",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				return repo
+			},
+			uid: 1,
+		},
+		{
+			name: "查询失败",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{}, errors.New("db 错误"))
+				return repo
+			},
+			uid:     1,
+			wantErr: errors.New("db 错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewAccountDeletionService(tc.mock(ctrl), nil, nil, nil, time.Hour, accesslog.NewNopLogger())
+			err := svc.Cancel(context.Background(), tc.uid)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func Test_accountDeletionService_EraseExpired(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.WechatMiniSessionRepository, events.Producer)
+
+		// eraseErr 删除用户表以外的数据的结果
+		eraseErr error
+
+		wantIds    []int64
+		wantErased []int64
+		wantErr    error
+	}{
+		{
+			name: "删除并发送事件",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.WechatMiniSessionRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				repo.EXPECT().FindDeletable(gomock.Any(), now, 10).Return([]int64{1, 2}, nil)
+				repo.EXPECT().Erase(gomock.Any(), int64(1), now).Return(nil)
+				repo.EXPECT().Erase(gomock.Any(), int64(2), now).Return(nil)
+				sessionRepo.EXPECT().DeleteSessionKey(gomock.Any(), int64(1)).Return(nil)
+				// session_key 删除失败不影响删除的结果
+				sessionRepo.EXPECT().DeleteSessionKey(gomock.Any(), int64(2)).Return(errors.New("redis 错误"))
+				repo.EXPECT().FindUnsentDeleted(gomock.Any(), 10).Return([]domain.User{
+					{Id: 1, ErasedAt: now},
+					{Id: 2, ErasedAt: now},
+				}, nil)
+				producer.EXPECT().ProduceUserDeletedEvent(gomock.Any(), events.UserDeletedEvent{
+					Uid: 1, Ctime: now.UnixMilli(),
+				}).Return(nil)
+				repo.EXPECT().MarkDeletedSent(gomock.Any(), int64(1)).Return(nil)
+				producer.EXPECT().ProduceUserDeletedEvent(gomock.Any(), events.UserDeletedEvent{
+					Uid: 2, Ctime: now.UnixMilli(),
+				}).Return(nil)
+				repo.EXPECT().MarkDeletedSent(gomock.Any(), int64(2)).Return(nil)
+				return repo, sessionRepo, producer
+			},
+			wantIds:    []int64{1, 2},
+			wantErased: []int64{1, 2},
+		},
+		{
+			name: "事件发送失败，留在发件箱里面",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.WechatMiniSessionRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				repo.EXPECT().FindDeletable(gomock.Any(), now, 10).Return([]int64{2}, nil)
+				repo.EXPECT().Erase(gomock.Any(), int64(2), now).Return(nil)
+				sessionRepo.EXPECT().DeleteSessionKey(gomock.Any(), int64(2)).Return(nil)
+				repo.EXPECT().FindUnsentDeleted(gomock.Any(), 10).Return([]domain.User{
+					{Id: 2, ErasedAt: now},
+				}, nil)
+				producer.EXPECT().ProduceUserDeletedEvent(gomock.Any(), events.UserDeletedEvent{
+					Uid: 2, Ctime: now.UnixMilli(),
+				}).Return(errors.New("redis 错误"))
+				return repo, sessionRepo, producer
+			},
+			wantIds:    []int64{2},
+			wantErased: []int64{2},
+		},
+		{
+			name: "没有要删除的用户，补发之前失败的事件",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.WechatMiniSessionRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				erasedAt := now.Add(-time.Hour)
+				repo.EXPECT().FindDeletable(gomock.Any(), now, 10).Return(nil, nil)
+				repo.EXPECT().FindUnsentDeleted(gomock.Any(), 10).Return([]domain.User{
+					{Id: 2, ErasedAt: erasedAt},
+				}, nil)
+				producer.EXPECT().ProduceUserDeletedEvent(gomock.Any(), events.UserDeletedEvent{
+					Uid: 2, Ctime: erasedAt.UnixMilli(),
+				}).Return(nil)
+				repo.EXPECT().MarkDeletedSent(gomock.Any(), int64(2)).Return(nil)
+				return repo, sessionRepo, producer
+			},
+			wantIds: []int64{},
+		},
+		{
+			name: "查出来之后登录取消了注销",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.WechatMiniSessionRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				producer := evtmocks.NewMockProducer(ctrl)
+				repo.EXPECT().FindDeletable(gomock.Any(), now, 10).Return([]int64{1, 2}, nil)
+				repo.EXPECT().Erase(gomock.Any(), int64(1), now).Return(repository.ErrUserNotDeletable)
+				repo.EXPECT().Erase(gomock.Any(), int64(2), now).Return(nil)
+				sessionRepo.EXPECT().DeleteSessionKey(gomock.Any(), int64(2)).Return(nil)
+				repo.EXPECT().FindUnsentDeleted(gomock.Any(), 10).Return([]domain.User{
+					{Id: 2, ErasedAt: now},
+				}, nil)
+				producer.EXPECT().ProduceUserDeletedEvent(gomock.Any(), events.UserDeletedEvent{
+					Uid: 2, Ctime: now.UnixMilli(),
+				}).Return(nil)
+				repo.EXPECT().MarkDeletedSent(gomock.Any(), int64(2)).Return(nil)
+				return repo, sessionRepo, producer
+			},
+			wantIds: []int64{2},
+			// 用户表以外的数据在取消之前已经删掉了
+			wantErased: []int64{1, 2},
+		},
+		{
+			name: "删除其它数据失败，不删除用户表，下一次重试",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.WechatMiniSessionRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				repo.EXPECT().FindDeletable(gomock.Any(), now, 10).Return([]int64{1, 2}, nil)
+				return repo, sessionRepo, evtmocks.NewMockProducer(ctrl)
+			},
+			eraseErr:   errors.New("redis 错误"),
+			wantIds:    []int64{},
+			wantErased: []int64{1},
+			wantErr:    errors.New("redis 错误"),
+		},
+		{
+			name: "删除失败",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.WechatMiniSessionRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				repo.EXPECT().FindDeletable(gomock.Any(), now, 10).Return([]int64{1, 2}, nil)
+				repo.EXPECT().Erase(gomock.Any(), int64(1), now).Return(errors.New("db 错误"))
+				return repo, sessionRepo, evtmocks.NewMockProducer(ctrl)
+			},
+			wantIds:    []int64{},
+			wantErased: []int64{1},
+			wantErr:    errors.New("db 错误"),
+		},
+		{
+			name: "查询失败",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.WechatMiniSessionRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				repo.EXPECT().FindDeletable(gomock.Any(), now, 10).Return(nil, errors.New("db 错误"))
+				return repo, sessionRepo, evtmocks.NewMockProducer(ctrl)
+			},
+			wantErr: errors.New("db 错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, sessionRepo, producer := tc.mock(ctrl)
+			var erased []int64
+			eraser := func(ctx context.Context, uid int64) error {
+				erased = append(erased, uid)
+				return tc.eraseErr
+			}
+			svc := NewAccountDeletionService(repo, sessionRepo, producer, []AccountEraser{eraser},
+				time.Hour, accesslog.NewNopLogger()).(*accountDeletionService)
+			svc.now = func() time.Time {
+				return now
+			}
+			ids, err := svc.EraseExpired(context.Background(), 10)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantIds, ids)
+			assert.Equal(t, tc.wantErased, erased)
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"slices"
+	"time"
+)
+
+var (
+	ErrBindCodeInvalid = errors.New("验证码错误")
+	ErrBindTypeInvalid = errors.New("不支持的绑定方式")
+	// ErrBindConflict 手机号、邮箱或者第三方账号已经绑定在其它用户上
+	ErrBindConflict = errors.New("已经绑定了其它账号")
+	// ErrBindProviderBound 同一个平台只能绑定一个账号
+	ErrBindProviderBound = errors.New("已经绑定了该平台的其它账号，请先解绑")
+	ErrUnbindNotBound    = errors.New("没有绑定该登录方式")
+	// ErrUnbindLastMethod 解绑之后就没办法登录了
+	ErrUnbindLastMethod = errors.New("至少需要保留一种登录方式")
+)
+
+const (
+	BindByPhone = "phone"
+	BindByEmail = "email"
+
+	bindBiz = "bind"
+)
+
+// BindingService 已经登录的用户绑定和解绑手机号、邮箱和第三方账号
+//
+//go:generate mockgen.exe -source=./binding.go -package=svcmocks -destination=mocks/binding.mock.go BindingService
+type BindingService interface {
+	// Bindings 已经绑定的登录方式
+	Bindings(ctx context.Context, uid int64) (domain.Bindings, error)
+	// SendCode 向要绑定的手机号或者邮箱发送验证码，typ 是 BindByPhone 或者 BindByEmail
+	SendCode(ctx context.Context, typ string, target string) error
+	// BindWithCode 验证码校验通过之后绑定，已经绑定过的会被替换
+	BindWithCode(ctx context.Context, uid int64, typ string, target string, code string) error
+	// BindIdentity 绑定第三方账号
+	BindIdentity(ctx context.Context, uid int64, identity domain.UserIdentity) error
+	// Unbind method 是 BindByPhone、BindByEmail 或者第三方提供方的名字
+	Unbind(ctx context.Context, uid int64, method string) error
+}
+
+type bindingService struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	codeSvc      CodeService
+	emailCodeSvc EmailCodeService
+}
+
+func NewBindingService(userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository,
+	codeSvc CodeService,
+	emailCodeSvc EmailCodeService) BindingService {
+	return &bindingService{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		codeSvc:      codeSvc,
+		emailCodeSvc: emailCodeSvc,
+	}
+}
+
+func (svc *bindingService) Bindings(ctx context.Context, uid int64) (domain.Bindings, error) {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return domain.Bindings{}, err
+	}
+	identities, err := svc.identityRepo.FindByUid(ctx, uid)
+	if err != nil {
+		return domain.Bindings{}, err
+	}
+	if u.WechatInfo.OpenId != "" && !slices.ContainsFunc(identities, func(i domain.UserIdentity) bool {
+		return i.Provider == domain.ProviderWechat
+	}) {
+		// 以前的微信用户还没有补上绑定关系
+		identities = append(identities, domain.UserIdentity{
+			Uid:      uid,
+			Provider: domain.ProviderWechat,
+			Subject:  u.WechatInfo.OpenId,
+			UnionId:  u.WechatInfo.UnionId,
+		})
+	}
+	return domain.Bindings{
+		Phone:      u.Phone,
+		Email:      u.Email,
+		Identities: identities,
+	}, nil
+}
+
+func (svc *bindingService) SendCode(ctx context.Context, typ string, target string) error {
+	switch typ {
+	case BindByPhone:
+		return svc.codeSvc.Send(ctx, bindBiz, target)
+	case BindByEmail:
+		return svc.emailCodeSvc.Send(ctx, bindBiz, target)
+	default:
+		return ErrBindTypeInvalid
+	}
+}
+
+func (svc *bindingService) BindWithCode(ctx context.Context, uid int64, typ string, target string, code string) error {
+	var (
+		ok  bool
+		err error
+	)
+	switch typ {
+	case BindByPhone:
+		ok, err = svc.codeSvc.Verify(ctx, bindBiz, target, code)
+	case BindByEmail:
+		ok, err = svc.emailCodeSvc.Verify(ctx, bindBiz, target, code)
+	default:
+		return ErrBindTypeInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBindCodeInvalid
+	}
+
+	if typ == BindByPhone {
+		err = svc.userRepo.UpdatePhone(ctx, uid, target)
+	} else {
+		// 验证码就是发到这个邮箱的，所以直接标记为已经验证
+		err = svc.userRepo.UpdateEmail(ctx, uid, target, time.Now())
+	}
+	if err == repository.ErrUserDuplicatePhone || err == repository.ErrUserDuplicateEmail {
+		return ErrBindConflict
+	}
+	return err
+}
+
+func (svc *bindingService) BindIdentity(ctx context.Context, uid int64, identity domain.UserIdentity) error {
+	owner, err := svc.identityRepo.FindBySubject(ctx, identity.Provider, identity.Subject)
+	switch {
+	case err == nil && owner.Uid == uid:
+		// 重复绑定
+		return nil
+	case err == nil:
+		return ErrBindConflict
+	case err != repository.ErrIdentityNotFound:
+		return err
+	}
+
+	if identity.Provider == domain.ProviderWechat {
+		// 以前的微信用户只在 users 表里面有 openid
+		u, err := svc.userRepo.FindByWechat(ctx, identity.Subject)
+		if err == nil && u.Id != uid {
+			return ErrBindConflict
+		}
+		if err != nil && err != repository.ErrUserNotFound {
+			return err
+		}
+	}
+
+	identity.Uid = uid
+	// 绑定关系和 users 表里面的微信信息在一个事务里面写
+	err = svc.userRepo.BindIdentity(ctx, identity)
+	if err == repository.ErrIdentityDuplicate {
+		// 要么是被其它用户抢先绑定了，要么是自己已经绑定了该平台的其它账号
+		owner, ferr := svc.identityRepo.FindBySubject(ctx, identity.Provider, identity.Subject)
+		if ferr == nil && owner.Uid == uid {
+			return nil
+		}
+		if ferr == nil {
+			return ErrBindConflict
+		}
+		return ErrBindProviderBound
+	}
+	if err == repository.ErrUserDuplicateWechat {
+		return ErrBindConflict
+	}
+	return err
+}
+
+func (svc *bindingService) Unbind(ctx context.Context, uid int64, method string) error {
+	b, err := svc.Bindings(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if !svc.bound(b, method) {
+		return ErrUnbindNotBound
+	}
+	if b.Count() <= 1 {
+		return ErrUnbindLastMethod
+	}
+
+	switch method {
+	case BindByPhone:
+		return svc.userRepo.UpdatePhone(ctx, uid, "")
+	case BindByEmail:
+		return svc.userRepo.UpdateEmail(ctx, uid, "", time.Time{})
+	}
+	err = svc.identityRepo.Delete(ctx, uid, method)
+	switch {
+	case err == repository.ErrIdentityNotFound && method == domain.ProviderWechat:
+		// 以前的微信用户只需要清掉 users 表里面的 openid
+	case err == repository.ErrIdentityNotFound:
+		return ErrUnbindNotBound
+	case err != nil:
+		return err
+	}
+	if method == domain.ProviderWechat {
+		// 不清掉的话，下次微信登录会按照 openid 重新绑定回来
+		return svc.userRepo.UpdateWechat(ctx, uid, domain.WechatInfo{})
+	}
+	return nil
+}
+
+func (svc *bindingService) bound(b domain.Bindings, method string) bool {
+	switch method {
+	case BindByPhone:
+		return b.Phone != ""
+	case BindByEmail:
+		return b.Email != ""
+	}
+	for _, i := range b.Identities {
+		if i.Provider == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func Test_bindingService_BindIdentity(t *testing.T) {
+	github := domain.UserIdentity{Provider: domain.ProviderGithub, Subject: "583231"}
+	wechat := domain.UserIdentity{Provider: domain.ProviderWechat, Subject: "openid", UnionId: "unionid"}
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository)
+		// 输入
+		uid      int64
+		identity domain.UserIdentity
+		// 输出
+		wantErr error
+	}{
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				bound := github
+				bound.Uid = 1
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().BindIdentity(gomock.Any(), bound).Return(nil)
+				return userRepo, repo
+			},
+			uid:      1,
+			identity: github,
+		},
+		{
+			name: "已经绑定了自己，重复绑定",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{Uid: 1}, nil)
+				return repomocks.NewMockUserRepository(ctrl), repo
+			},
+			uid:      1,
+			identity: github,
+		},
+		{
+			name: "已经绑定了其它用户",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{Uid: 2}, nil)
+				return repomocks.NewMockUserRepository(ctrl), repo
+			},
+			uid:      1,
+			identity: github,
+			wantErr:  ErrBindConflict,
+		},
+		{
+			name: "已经绑定了该平台的其它账号",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().BindIdentity(gomock.Any(), gomock.Any()).Return(repository.ErrIdentityDuplicate)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				return userRepo, repo
+			},
+			uid:      1,
+			identity: github,
+			wantErr:  ErrBindProviderBound,
+		},
+		{
+			name: "以前的微信用户已经占用了 openid",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechat, "openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").Return(domain.User{Id: 2}, nil)
+				return userRepo, repo
+			},
+			uid:      1,
+			identity: wechat,
+			wantErr:  ErrBindConflict,
+		},
+		{
+			name: "绑定微信，绑定关系和 users 表一起写",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechat, "openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				bound := wechat
+				bound.Uid = 1
+				userRepo.EXPECT().BindIdentity(gomock.Any(), bound).Return(nil)
+				return userRepo, repo
+			},
+			uid:      1,
+			identity: wechat,
+		},
+		{
+			name: "绑定微信的时候 openid 被其它用户抢先占用",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechat, "openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				userRepo.EXPECT().BindIdentity(gomock.Any(), gomock.Any()).Return(repository.ErrUserDuplicateWechat)
+				return userRepo, repo
+			},
+			uid:      1,
+			identity: wechat,
+			wantErr:  ErrBindConflict,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, repo := tc.mock(ctrl)
+			svc := NewBindingService(userRepo, repo, nil, nil)
+			err := svc.BindIdentity(context.Background(), tc.uid, tc.identity)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func Test_bindingService_Unbind(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository)
+		// 输入
+		method string
+		// 输出
+		wantErr error
+	}{
+		{
+			name: "解绑手机号",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15212345678", Email: "123@qq.com"}, nil)
+				userRepo.EXPECT().UpdatePhone(gomock.Any(), int64(1), "").Return(nil)
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).Return(nil, nil)
+				return userRepo, repo
+			},
+			method: BindByPhone,
+		},
+		{
+			name: "最后一种登录方式",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1}, nil)
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).Return([]domain.UserIdentity{
+					{Uid: 1, Provider: domain.ProviderGithub, Subject: "583231"},
+				}, nil)
+				return userRepo, repo
+			},
+			method:  domain.ProviderGithub,
+			wantErr: ErrUnbindLastMethod,
+		},
+		{
+			name: "没有绑定",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "15212345678"}, nil)
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).Return(nil, nil)
+				return userRepo, repo
+			},
+			method:  BindByEmail,
+			wantErr: ErrUnbindNotBound,
+		},
+		{
+			name: "以前的微信用户，清掉 openid",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.IdentityRepository) {
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
+					Id:         1,
+					Phone:      "15212345678",
+					WechatInfo: domain.WechatInfo{OpenId: "openid"},
+				}, nil)
+				userRepo.EXPECT().UpdateWechat(gomock.Any(), int64(1), domain.WechatInfo{}).Return(nil)
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).Return(nil, nil)
+				repo.EXPECT().Delete(gomock.Any(), int64(1), domain.ProviderWechat).
+					Return(repository.ErrIdentityNotFound)
+				return userRepo, repo
+			},
+			method: domain.ProviderWechat,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, repo := tc.mock(ctrl)
+			svc := NewBindingService(userRepo, repo, nil, nil)
+			err := svc.Unbind(context.Background(), 1, tc.method)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+// 验证码只是换了 biz，这里只关心绑定冲突
+func Test_bindingService_BindWithCode_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().UpdateEmail(gomock.Any(), int64(1), "123@qq.com", gomock.AssignableToTypeOf(time.Time{})).
+		Return(repository.ErrUserDuplicateEmail)
+	codeRepo := repomocks.NewMockCodeRepository(ctrl)
+	codeRepo.EXPECT().Verify(gomock.Any(), bindBiz, "123@qq.com", "123456").Return(true, nil)
+	svc := NewBindingService(userRepo, nil, nil, NewEmailCodeService(codeRepo, nil))
+	err := svc.BindWithCode(context.Background(), 1, BindByEmail, "123@qq.com", "123456")
+	assert.Equal(t, ErrBindConflict, err)
+}
@@ -39,7 +39,7 @@ func (svc *SMSCodeService) Send(ctx context.Context,
 	biz string,
 	phone string) error {
 	// 随机生成验证码
-	code := svc.generateCode()
+	code := generateCode()
 	// 验证码写入缓存
 	// codeRepository
 	err := svc.repo.Store(ctx, biz, phone, code)
@@ -61,7 +61,8 @@ func (svc *SMSCodeService) Verify(ctx context.Context,
 	return svc.repo.Verify(ctx, biz, phone, inputCode)
 }
 
-func (svc *SMSCodeService) generateCode() string {
+// generateCode 生成 6 位数字验证码，不足 6 位前面补 0
+func generateCode() string {
 	num := rand.Intn(1000000)
-	return fmt.Sprintf("%6d", num)
+	return fmt.Sprintf("%06d", num)
 }
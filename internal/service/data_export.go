@@ -0,0 +1,402 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/pkg/blobstore"
+	"github.com/google/uuid"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDataExportNotFound = repository.ErrDataExportNotFound
+	// ErrDataExportLinkInvalid 下载链接签名不对、已经过期，或者压缩包已经删除
+	ErrDataExportLinkInvalid = errors.New("下载链接无效或者已经过期")
+)
+
+const (
+	// dataExportTimeout 超过这个时间还没有结束的任务，认为执行的实例已经挂了，允许重新导出
+	dataExportTimeout = time.Minute * 10
+	// dataExportCleanupBatch 每次清理的压缩包数量
+	dataExportCleanupBatch = 100
+)
+
+// DataExportSource 导出压缩包里面的一个文件的数据，会被序列化成 JSON
+// 登录设备这些不在 service 里面的数据由 ioc 注入
+type DataExportSource func(ctx context.Context, uid int64) (any, error)
+
+// DataExportService 导出个人数据，异步生成压缩包，通过有时效的签名链接下载
+//
+//go:generate mockgen.exe -source=./data_export.go -package=svcmocks -destination=mocks/data_export.mock.go DataExportService
+type DataExportService interface {
+	// Create 创建导出任务，已经有进行中的任务的时候返回那个任务
+	Create(ctx context.Context, uid int64) (domain.DataExport, error)
+	// Find 查询导出任务，不存在、已经过期或者不是这个用户的返回 ErrDataExportNotFound
+	Find(ctx context.Context, uid int64, id string) (domain.DataExport, error)
+	// DownloadURL 导出成功的任务的下载链接和链接的过期时间
+	DownloadURL(e domain.DataExport) (string, time.Time)
+	// Open 校验下载链接里面的 token，返回压缩包，调用方负责关闭
+	Open(ctx context.Context, token string) (domain.DataExport, io.ReadCloser, error)
+	// RunNext 执行队列里面的下一个任务，队列为空的时候返回 false
+	RunNext(ctx context.Context) (bool, error)
+	// Cleanup 删除已经过了保留时间的压缩包，把执行超时（实例挂了）的任务放回队列
+	Cleanup(ctx context.Context) error
+	// Erase 注销账号的时候删除用户所有的压缩包，不管有没有过保留时间
+	Erase(ctx context.Context, uid int64) error
+}
+
+type dataExportService struct {
+	repo         repository.DataExportRepository
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	store        blobstore.Store
+	// sources 压缩包里面除了资料和第三方账号以外的文件，key 是文件名
+	sources map[string]DataExportSource
+	// retention 压缩包保留的时间，linkTTL 下载链接的有效期
+	retention time.Duration
+	linkTTL   time.Duration
+	// signKey 下载链接签名的密钥，downloadURL 下载接口的地址
+	signKey     []byte
+	downloadURL string
+	log         accesslog.Logger
+	now         func() time.Time
+}
+
+func NewDataExportService(repo repository.DataExportRepository, userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository, store blobstore.Store, sources map[string]DataExportSource,
+	retention time.Duration, linkTTL time.Duration, signKey []byte, downloadURL string,
+	log accesslog.Logger) DataExportService {
+	return &dataExportService{
+		repo:         repo,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		store:        store,
+		sources:      sources,
+		retention:    retention,
+		linkTTL:      linkTTL,
+		signKey:      signKey,
+		downloadURL:  downloadURL,
+		log:          log,
+		now:          time.Now,
+	}
+}
+
+func (svc *dataExportService) Create(ctx context.Context, uid int64) (domain.DataExport, error) {
+	now := svc.now()
+	latest, err := svc.repo.FindLatest(ctx, uid)
+	if err == nil && !latest.Finished() && now.Sub(latest.Utime) < dataExportTimeout {
+		return latest, nil
+	}
+	if err != nil && err != repository.ErrDataExportNotFound {
+		return domain.DataExport{}, err
+	}
+	e := domain.DataExport{
+		Id:     uuid.New().String(),
+		Uid:    uid,
+		Status: domain.DataExportPending,
+		Ctime:  now,
+		Utime:  now,
+	}
+	if err = svc.repo.Save(ctx, e, svc.retention); err != nil {
+		return domain.DataExport{}, err
+	}
+	return e, svc.repo.Enqueue(ctx, e.Id)
+}
+
+func (svc *dataExportService) Find(ctx context.Context, uid int64, id string) (domain.DataExport, error) {
+	e, err := svc.repo.FindById(ctx, id)
+	if err != nil {
+		return domain.DataExport{}, err
+	}
+	if e.Uid != uid {
+		return domain.DataExport{}, ErrDataExportNotFound
+	}
+	return e, nil
+}
+
+func (svc *dataExportService) DownloadURL(e domain.DataExport) (string, time.Time) {
+	expiresAt := svc.now().Add(svc.linkTTL)
+	// 链接不能比压缩包活得久
+	if deleteAt := e.Utime.Add(svc.retention); expiresAt.After(deleteAt) {
+		expiresAt = deleteAt
+	}
+	payload := e.Id + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	token := payload + "." + base64.RawURLEncoding.EncodeToString(svc.mac(payload))
+	return svc.downloadURL + "?token=" + url.QueryEscape(token), time.Unix(expiresAt.Unix(), 0)
+}
+
+func (svc *dataExportService) Open(ctx context.Context, token string) (domain.DataExport, io.ReadCloser, error) {
+	// token 的格式是 id.过期时间.base64(hmac)
+	idx := strings.LastIndexByte(token, '.')
+	if idx < 0 {
+		return domain.DataExport{}, nil, ErrDataExportLinkInvalid
+	}
+	payload := token[:idx]
+	sig, err := base64.RawURLEncoding.DecodeString(token[idx+1:])
+	if err != nil || !hmac.Equal(sig, svc.mac(payload)) {
+		return domain.DataExport{}, nil, ErrDataExportLinkInvalid
+	}
+	id, expires, _ := strings.Cut(payload, ".")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || svc.now().Unix() > expiresAt {
+		return domain.DataExport{}, nil, ErrDataExportLinkInvalid
+	}
+
+	e, err := svc.repo.FindById(ctx, id)
+	if err == repository.ErrDataExportNotFound || (err == nil && e.Status != domain.DataExportSucceeded) {
+		return domain.DataExport{}, nil, ErrDataExportLinkInvalid
+	}
+	if err != nil {
+		return domain.DataExport{}, nil, err
+	}
+	r, err := svc.store.Get(ctx, svc.objectKey(e))
+	if err == blobstore.ErrNotFound {
+		return domain.DataExport{}, nil, ErrDataExportLinkInvalid
+	}
+	if err != nil {
+		return domain.DataExport{}, nil, err
+	}
+	return e, r, nil
+}
+
+func (svc *dataExportService) RunNext(ctx context.Context) (bool, error) {
+	id, err := svc.repo.Dequeue(ctx)
+	if err == repository.ErrDataExportNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	e, err := svc.repo.FindById(ctx, id)
+	if err == repository.ErrDataExportNotFound {
+		// 排队太久，任务已经过期了
+		return true, svc.repo.Ack(ctx, id)
+	}
+	if err != nil {
+		return true, err
+	}
+
+	e.Status = domain.DataExportRunning
+	e.Utime = svc.now()
+	if err = svc.repo.Save(ctx, e, svc.retention); err != nil {
+		return true, err
+	}
+	size, err := svc.export(ctx, e)
+	if err != nil {
+		svc.log.Error("导出个人数据失败", accesslog.Int64("uid", e.Uid),
+			accesslog.String("id", e.Id), accesslog.Error(err))
+		e.Status = domain.DataExportFailed
+	} else {
+		e.Status = domain.DataExportSucceeded
+		e.Size = size
+	}
+	e.Utime = svc.now()
+	if err = svc.repo.Save(ctx, e, svc.retention); err != nil {
+		// 不 Ack，超时之后重新执行
+		return true, err
+	}
+	return true, svc.repo.Ack(ctx, id)
+}
+
+// export 生成压缩包并保存，每个数据一个 JSON 文件
+func (svc *dataExportService) export(ctx context.Context, e domain.DataExport) (int64, error) {
+	files, err := svc.collect(ctx, e.Uid)
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			return 0, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(files[name]); err != nil {
+			return 0, err
+		}
+	}
+	if err = zw.Close(); err != nil {
+		return 0, err
+	}
+
+	key := svc.objectKey(e)
+	// 先记下删除时间再写文件，写文件之后挂了也不会留下删不掉的压缩包
+	if err = svc.repo.AddObject(ctx, key, svc.now().Add(svc.retention)); err != nil {
+		return 0, err
+	}
+	size := int64(buf.Len())
+	return size, svc.store.Put(ctx, key, &buf)
+}
+
+type exportProfile struct {
+	Id           int64  `json:"id"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Nickname     string `json:"nickname"`
+	Birthday     string `json:"birthday"`
+	AboutMe      string `json:"aboutMe"`
+	Avatar       string `json:"avatar"`
+	WechatOpenId string `json:"wechatOpenId"`
+	// 只说明有没有设置密码，不导出密码的哈希
+	HasPassword bool   `json:"hasPassword"`
+	VerifiedAt  string `json:"verifiedAt"`
+	Ctime       string `json:"ctime"`
+}
+
+type exportIdentity struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+	UnionId  string `json:"unionId"`
+	Nickname string `json:"nickname"`
+	Avatar   string `json:"avatar"`
+	Email    string `json:"email"`
+	Ctime    string `json:"ctime"`
+}
+
+func (svc *dataExportService) collect(ctx context.Context, uid int64) (map[string]any, error) {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := svc.identityRepo.FindByUid(ctx, u.Id)
+	if err != nil {
+		return nil, err
+	}
+	profile := exportProfile{
+		Id:           u.Id,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		Nickname:     u.Nickname,
+		AboutMe:      u.AboutMe,
+		Avatar:       u.Avatar,
+		WechatOpenId: u.WechatInfo.OpenId,
+		HasPassword:  u.Password != "",
+		Ctime:        u.Ctime.Format(time.DateTime),
+	}
+	if !u.Birthday.IsZero() {
+		profile.Birthday = u.Birthday.Format(time.DateOnly)
+	}
+	if !u.VerifiedAt.IsZero() {
+		profile.VerifiedAt = u.VerifiedAt.Format(time.DateTime)
+	}
+	ids := make([]exportIdentity, 0, len(identities))
+	for _, i := range identities {
+		ids = append(ids, exportIdentity{
+			Provider: i.Provider,
+			Subject:  i.Subject,
+			UnionId:  i.UnionId,
+			Nickname: i.Nickname,
+			Avatar:   i.Avatar,
+			Email:    i.Email,
+			Ctime:    i.Ctime.Format(time.DateTime),
+		})
+	}
+	files := map[string]any{
+		"profile.json":    profile,
+		"identities.json": ids,
+	}
+	for name, source := range svc.sources {
+		data, err := source(ctx, u.Id)
+		if err != nil {
+			return nil, fmt.Errorf("导出 %s: %w", name, err)
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+func (svc *dataExportService) Cleanup(ctx context.Context) error {
+	if err := svc.requeueStale(ctx); err != nil {
+		return err
+	}
+	keys, err := svc.repo.ExpiredObjects(ctx, svc.now(), dataExportCleanupBatch)
+	if err != nil {
+		return err
+	}
+	return svc.deleteObjects(ctx, keys)
+}
+
+// requeueStale 取出来之后超过 dataExportTimeout 还没有 Ack 的任务，执行的实例已经挂了，放回队列
+func (svc *dataExportService) requeueStale(ctx context.Context) error {
+	ids, err := svc.repo.Processing(ctx)
+	if err != nil {
+		return err
+	}
+	now := svc.now()
+	for _, id := range ids {
+		e, err := svc.repo.FindById(ctx, id)
+		switch {
+		case err == repository.ErrDataExportNotFound || (err == nil && e.Finished()):
+			// 任务已经过期，或者执行完了但是 Ack 失败
+			err = svc.repo.Ack(ctx, id)
+		case err == nil && now.Sub(e.Utime) >= dataExportTimeout:
+			var ok bool
+			ok, err = svc.repo.Requeue(ctx, id)
+			if ok {
+				svc.log.Warn("个人数据导出超时，重新放回队列", accesslog.Int64("uid", e.Uid), accesslog.String("id", id))
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *dataExportService) Erase(ctx context.Context, uid int64) error {
+	keys, err := svc.repo.FindObjects(ctx, svc.objectPrefix(uid))
+	if err != nil {
+		return err
+	}
+	return svc.deleteObjects(ctx, keys)
+}
+
+// deleteObjects 先删文件再删记录，中间失败了下一次还能找到
+func (svc *dataExportService) deleteObjects(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := svc.store.Delete(ctx, key); err != nil {
+			return err
+		}
+		if err := svc.repo.RemoveObject(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *dataExportService) objectKey(e domain.DataExport) string {
+	return fmt.Sprintf("%s%s.zip", svc.objectPrefix(e.Uid), e.Id)
+}
+
+func (svc *dataExportService) objectPrefix(uid int64) string {
+	return fmt.Sprintf("exports/%d/", uid)
+}
+
+func (svc *dataExportService) mac(payload string) []byte {
+	h := hmac.New(sha256.New, svc.signKey)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/dadaxiaoxiao/user/pkg/blobstore"
+	"github.com/dadaxiaoxiao/user/pkg/blobstore/local"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_dataExportService_Create(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.DataExportRepository
+
+		wantId  string
+		wantErr error
+	}{
+		{
+			name: "已经有进行中的任务",
+			mock: func(ctrl *gomock.Controller) repository.DataExportRepository {
+				repo := repomocks.NewMockDataExportRepository(ctrl)
+				repo.EXPECT().FindLatest(gomock.Any(), int64(1)).Return(domain.DataExport{
+					Id: "abc", Uid: 1, Status: domain.DataExportRunning, Utime: now.Add(-time.Minute),
+				}, nil)
+				return repo
+			},
+			wantId: "abc",
+		},
+		{
+			name: "进行中的任务超时了，重新导出",
+			mock: func(ctrl *gomock.Controller) repository.DataExportRepository {
+				repo := repomocks.NewMockDataExportRepository(ctrl)
+				repo.EXPECT().FindLatest(gomock.Any(), int64(1)).Return(domain.DataExport{
+					Id: "abc", Uid: 1, Status: domain.DataExportRunning, Utime: now.Add(-time.Hour),
+				}, nil)
+				repo.EXPECT().Save(gomock.Any(), gomock.Any(), time.Hour).Return(nil)
+				repo.EXPECT().Enqueue(gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+		},
+		{
+			name: "查询失败",
+			mock: func(ctrl *gomock.Controller) repository.DataExportRepository {
+				repo := repomocks.NewMockDataExportRepository(ctrl)
+				repo.EXPECT().FindLatest(gomock.Any(), int64(1)).Return(domain.DataExport{}, errors.New("redis 错误"))
+				return repo
+			},
+			wantErr: errors.New("redis 错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewDataExportService(tc.mock(ctrl), nil, nil, nil, nil, time.Hour, time.Minute,
+				[]byte("key"), "http://localhost/download", accesslog.NewNopLogger()).(*dataExportService)
+			svc.now = func() time.Time {
+				return now
+			}
+			e, err := svc.Create(context.Background(), 1)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			if tc.wantId != "" {
+				assert.Equal(t, tc.wantId, e.Id)
+			} else {
+				assert.NotEmpty(t, e.Id)
+				assert.Equal(t, domain.DataExportPending, e.Status)
+			}
+		})
+	}
+}
+
+// Test_dataExportService_RunNext 导出之后用下载链接把压缩包读出来
+func Test_dataExportService_RunNext(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	e := domain.DataExport{Id: "abc", Uid: 1, Status: domain.DataExportPending, Ctime: now, Utime: now}
+	repo := repomocks.NewMockDataExportRepository(ctrl)
+	repo.EXPECT().Dequeue(gomock.Any()).Return("abc", nil)
+	repo.EXPECT().FindById(gomock.Any(), "abc").Return(e, nil)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any(), time.Hour).Return(nil).Times(2)
+	repo.EXPECT().AddObject(gomock.Any(), "exports/1/abc.zip", now.Add(time.Hour)).Return(nil)
+	repo.EXPECT().Ack(gomock.Any(), "abc").Return(nil)
+	userRepo := repomocks.NewMockUserRepository(ctrl)
+	userRepo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{
+		Id: 1, Email: "a@qq.com", Password: "$2a$10$hash", Ctime: now,
+	}, nil)
+	identityRepo := repomocks.NewMockIdentityRepository(ctrl)
+	identityRepo.EXPECT().FindByUid(gomock.Any(), int64(1)).Return([]domain.UserIdentity{
+		{Provider: domain.ProviderGithub, Subject: "123", Ctime: now},
+	}, nil)
+	sources := map[string]DataExportSource{
+		"sessions.json": func(ctx context.Context, uid int64) (any, error) {
+			return []string{"chrome"}, nil
+		},
+	}
+
+	store := local.NewStore(t.TempDir())
+	svc := NewDataExportService(repo, userRepo, identityRepo, store, sources, time.Hour, time.Minute,
+		[]byte("key"), "http://localhost/download", accesslog.NewNopLogger()).(*dataExportService)
+	svc.now = func() time.Time {
+		return now
+	}
+	ran, err := svc.RunNext(context.Background())
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	e.Status = domain.DataExportSucceeded
+	link, expiresAt := svc.DownloadURL(e)
+	assert.Equal(t, time.Unix(now.Add(time.Minute).Unix(), 0), expiresAt)
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+
+	repo.EXPECT().FindById(gomock.Any(), "abc").Return(e, nil)
+	_, r, err := svc.Open(context.Background(), token)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		fr, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(fr)
+		require.NoError(t, err)
+		files[f.Name] = string(content)
+	}
+	assert.Len(t, files, 3)
+	assert.Contains(t, files["profile.json"], `"email": "a@qq.com"`)
+	// 不导出密码的哈希
+	assert.NotContains(t, files["profile.json"], "$2a$10$hash")
+	assert.Contains(t, files["identities.json"], `"subject": "123"`)
+	assert.Contains(t, files["sessions.json"], "chrome")
+
+	// 改了 id 签名就对不上
+	_, _, err = svc.Open(context.Background(), strings.Replace(token, "abc", "abd", 1))
+	assert.Equal(t, ErrDataExportLinkInvalid, err)
+	// 过期
+	svc.now = func() time.Time {
+		return now.Add(time.Hour)
+	}
+	_, _, err = svc.Open(context.Background(), token)
+	assert.Equal(t, ErrDataExportLinkInvalid, err)
+}
+
+func Test_dataExportService_Cleanup(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repomocks.NewMockDataExportRepository(ctrl)
+	repo.EXPECT().Processing(gomock.Any()).Return([]string{"stale", "running", "done", "expired"}, nil)
+	// 执行的实例挂了，放回队列
+	repo.EXPECT().FindById(gomock.Any(), "stale").Return(domain.DataExport{
+		Id: "stale", Uid: 1, Status: domain.DataExportRunning, Utime: now.Add(-dataExportTimeout),
+	}, nil)
+	repo.EXPECT().Requeue(gomock.Any(), "stale").Return(true, nil)
+	// 还在执行
+	repo.EXPECT().FindById(gomock.Any(), "running").Return(domain.DataExport{
+		Id: "running", Uid: 2, Status: domain.DataExportRunning, Utime: now.Add(-time.Minute),
+	}, nil)
+	// 执行完了但是 Ack 失败
+	repo.EXPECT().FindById(gomock.Any(), "done").Return(domain.DataExport{
+		Id: "done", Uid: 3, Status: domain.DataExportSucceeded, Utime: now.Add(-time.Hour),
+	}, nil)
+	repo.EXPECT().Ack(gomock.Any(), "done").Return(nil)
+	repo.EXPECT().FindById(gomock.Any(), "expired").Return(domain.DataExport{}, repository.ErrDataExportNotFound)
+	repo.EXPECT().Ack(gomock.Any(), "expired").Return(nil)
+	repo.EXPECT().ExpiredObjects(gomock.Any(), now, dataExportCleanupBatch).Return(nil, nil)
+
+	svc := NewDataExportService(repo, nil, nil, local.NewStore(t.TempDir()), nil, time.Hour, time.Minute,
+		[]byte("key"), "http://localhost/download", accesslog.NewNopLogger()).(*dataExportService)
+	svc.now = func() time.Time {
+		return now
+	}
+	require.NoError(t, svc.Cleanup(context.Background()))
+}
+
+func Test_dataExportService_Erase(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := local.NewStore(t.TempDir())
+	keys := []string{"exports/1/abc.zip", "exports/1/abd.zip"}
+	for _, key := range keys {
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("zip")))
+	}
+	// 别人的压缩包不受影响
+	require.NoError(t, store.Put(context.Background(), "exports/10/abe.zip", strings.NewReader("zip")))
+
+	repo := repomocks.NewMockDataExportRepository(ctrl)
+	repo.EXPECT().FindObjects(gomock.Any(), "exports/1/").Return(keys, nil)
+	repo.EXPECT().RemoveObject(gomock.Any(), "exports/1/abc.zip").Return(nil)
+	repo.EXPECT().RemoveObject(gomock.Any(), "exports/1/abd.zip").Return(nil)
+
+	svc := NewDataExportService(repo, nil, nil, store, nil, time.Hour, time.Minute,
+		[]byte("key"), "http://localhost/download", accesslog.NewNopLogger())
+	require.NoError(t, svc.Erase(context.Background(), 1))
+	for _, key := range keys {
+		_, err := store.Get(context.Background(), key)
+		assert.Equal(t, blobstore.ErrNotFound, err)
+	}
+	r, err := store.Get(context.Background(), "exports/10/abe.zip")
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/service/email"
+	"strings"
+	"sync"
+)
+
+// Service 本地邮件服务，只把邮件记在内存里面，用于开发和测试
+// 邮件里面有验证码和登录链接，不能在线上环境使用
+type Service struct {
+	lock  sync.Mutex
+	mails []Mail
+	// log 不为空的时候把邮件记到 debug 日志，方便本地开发拿到验证码
+	log accesslog.Logger
+}
+
+type Mail struct {
+	Subject string
+	Content string
+	To      []string
+}
+
+type Option func(s *Service)
+
+// WithLogger 把发送的邮件记到 debug 日志
+func WithLogger(l accesslog.Logger) Option {
+	return func(s *Service) {
+		s.log = l
+	}
+}
+
+func NewService(opts ...Option) *Service {
+	s := &Service{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+var _ email.Service = &Service{}
+
+// Send 发送邮件
+func (s *Service) Send(ctx context.Context, subject string, content string, to ...string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.mails = append(s.mails, Mail{
+		Subject: subject,
+		Content: content,
+		To:      to,
+	})
+	if s.log != nil {
+		s.log.Debug("本地邮件服务", accesslog.String("subject", subject),
+			accesslog.String("content", content), accesslog.String("to", strings.Join(to, ",")))
+	}
+	return nil
+}
+
+// Mails 已经发送的邮件
+func (s *Service) Mails() []Mail {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	res := make([]Mail, len(s.mails))
+	copy(res, s.mails)
+	return res
+}
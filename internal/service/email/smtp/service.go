@@ -0,0 +1,67 @@
+package smtp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/service/email"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Service struct {
+	// smtp 服务器地址，net/smtp 使用 STARTTLS，一般是 587 端口
+	host string
+	port int
+	// 登录用户名，一般就是发件邮箱
+	username string
+	password string
+	// 发件人，例如 "用户中心 <noreply@your-company.com>"
+	from string
+	// 发送邮件的实现，测试的时候可以替换
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewService(host string, port int, username string, password string, from string) email.Service {
+	return &Service{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+		sendMail: smtp.SendMail,
+	}
+}
+
+// Send 发送纯文本邮件
+// net/smtp 不支持 ctx，超时由调用方控制不了，这里只在发送前检查一下 ctx
+func (s *Service) Send(ctx context.Context, subject string, content string, to ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	auth := smtp.PlainAuth("", s.username, s.password, s.host)
+	err := s.sendMail(addr, auth, s.username, to, s.message(subject, content, to))
+	if err != nil {
+		return fmt.Errorf("发送邮件失败 %w", err)
+	}
+	return nil
+}
+
+// message 组装邮件，标题可能有中文，需要编码
+func (s *Service) message(subject string, content string, to []string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + s.from + "\r\n")
+	buf.WriteString("To: " + strings.Join(to, ",") + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\r\n")
+	buf.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(content)
+	return buf.Bytes()
+}
@@ -0,0 +1,34 @@
+package smtp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestService_Send(t *testing.T) {
+	var (
+		gotAddr string
+		gotFrom string
+		gotTo   []string
+		gotMsg  string
+	)
+	svc := NewService("smtp.your-company.com", 587, "noreply@your-company.com", "123456",
+		"用户中心 <noreply@your-company.com>").(*Service)
+	svc.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, string(msg)
+		return nil
+	}
+
+	err := svc.Send(context.Background(), "邮箱验证码", "您的验证码是 123456", "1426325504@qq.com")
+	require.NoError(t, err)
+	assert.Equal(t, "smtp.your-company.com:587", gotAddr)
+	assert.Equal(t, "noreply@your-company.com", gotFrom)
+	assert.Equal(t, []string{"1426325504@qq.com"}, gotTo)
+	// 中文标题需要编码
+	assert.True(t, strings.Contains(gotMsg, "Subject: =?UTF-8?q?"))
+	assert.True(t, strings.HasSuffix(gotMsg, "\r\n\r\n您的验证码是 123456"))
+}
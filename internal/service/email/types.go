@@ -0,0 +1,11 @@
+package email
+
+import "context"
+
+// Service 发送邮件的抽象
+// 屏蔽不同发送方式之间的区别
+//
+//go:generate mockgen.exe -source=./types.go  -package=emailmocks -destination=mocks/svc.mock.go
+type Service interface {
+	Send(ctx context.Context, subject string, content string, to ...string) error
+}
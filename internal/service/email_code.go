@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service/email"
+)
+
+// EmailCodeService 邮箱验证码
+// 和短信验证码共用 CodeRepository，一样是一分钟只能发送一次，最多验证三次
+//
+//go:generate mockgen.exe -source=./email_code.go -package=svcmocks -destination=mocks/email_code.mock.go EmailCodeService
+type EmailCodeService interface {
+	Send(ctx context.Context, biz string, email string) error
+	Verify(ctx context.Context, biz string, email string, inputCode string) (bool, error)
+}
+
+const emailCodeSubject = "邮箱验证码"
+
+// EmailVerifyBiz 注册之后验证邮箱的验证码 biz，HTTP 和 gRPC 注册共用
+const EmailVerifyBiz = "email_verify"
+
+type emailCodeService struct {
+	repo     repository.CodeRepository
+	emailSvc email.Service
+}
+
+// NewEmailCodeService 新建 EmailCodeService 实例
+func NewEmailCodeService(repo repository.CodeRepository, emailSvc email.Service) EmailCodeService {
+	return &emailCodeService{
+		repo:     repo,
+		emailSvc: emailSvc,
+	}
+}
+
+// Send 生成一个随机验证码，并发送到邮箱
+func (svc *emailCodeService) Send(ctx context.Context, biz string, email string) error {
+	code := generateCode()
+	err := svc.repo.Store(ctx, biz, email, code)
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("您的验证码是 %s，10 分钟内有效。如果不是您本人操作，请忽略这封邮件。", code)
+	return svc.emailSvc.Send(ctx, emailCodeSubject, content, email)
+}
+
+// Verify 验证验证码
+func (svc *emailCodeService) Verify(ctx context.Context, biz string, email string, inputCode string) (bool, error) {
+	return svc.repo.Verify(ctx, biz, email, inputCode)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/dadaxiaoxiao/user/internal/service/email/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"strings"
+	"testing"
+)
+
+func Test_emailCodeService_Send(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.CodeRepository
+		// 输入
+		email string
+		// 输出
+		wantErr   error
+		wantMails int
+	}{
+		{
+			name: "发送成功",
+			mock: func(ctrl *gomock.Controller) repository.CodeRepository {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "email_verify", "1426325504@qq.com", gomock.Any()).
+					Return(nil)
+				return repo
+			},
+			email:     "1426325504@qq.com",
+			wantMails: 1,
+		},
+		{
+			name: "发送太频繁",
+			mock: func(ctrl *gomock.Controller) repository.CodeRepository {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "email_verify", "1426325504@qq.com", gomock.Any()).
+					Return(ErrCodeSendTooMany)
+				return repo
+			},
+			email:     "1426325504@qq.com",
+			wantErr:   ErrCodeSendTooMany,
+			wantMails: 0,
+		},
+		{
+			name: "缓存错误",
+			mock: func(ctrl *gomock.Controller) repository.CodeRepository {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Store(gomock.Any(), "email_verify", "1426325504@qq.com", gomock.Any()).
+					Return(errors.New("mock redis 错误"))
+				return repo
+			},
+			email:     "1426325504@qq.com",
+			wantErr:   errors.New("mock redis 错误"),
+			wantMails: 0,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			emailSvc := memory.NewService()
+			svc := NewEmailCodeService(tc.mock(ctrl), emailSvc)
+			err := svc.Send(context.Background(), "email_verify", tc.email)
+			assert.Equal(t, tc.wantErr, err)
+			mails := emailSvc.Mails()
+			require.Len(t, mails, tc.wantMails)
+			if tc.wantMails > 0 {
+				assert.Equal(t, []string{tc.email}, mails[0].To)
+			}
+		})
+	}
+}
+
+func Test_emailCodeService_SendStoresSameCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var stored string
+	repo := repomocks.NewMockCodeRepository(ctrl)
+	repo.EXPECT().Store(gomock.Any(), "email_verify", "1426325504@qq.com", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, biz, email, code string) error {
+			stored = code
+			return nil
+		})
+	emailSvc := memory.NewService()
+	svc := NewEmailCodeService(repo, emailSvc)
+	err := svc.Send(context.Background(), "email_verify", "1426325504@qq.com")
+	require.NoError(t, err)
+	// 6 位数字，并且邮件里面就是存进去的验证码
+	assert.Len(t, stored, 6)
+	assert.True(t, strings.Contains(emailSvc.Mails()[0].Content, stored))
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/google/uuid"
+	"slices"
+)
+
+var ErrOAuth2StateInvalid = errors.New("state 无效或者已经过期")
+
+// IdentityService 第三方账号登录
+//
+//go:generate mockgen.exe -source=./identity.go -package=svcmocks -destination=mocks/identity.mock.go IdentityService
+type IdentityService interface {
+	// FindOrCreateByIdentity 第三方账号已经绑定过就返回对应的用户，否则新建一个用户并绑定
+	// 用户被冻结返回 ErrUserFrozen
+	FindOrCreateByIdentity(ctx context.Context, identity domain.UserIdentity) (domain.User, error)
+	// CreateState 发起授权之前记录下来，返回的 state 以 Intent 开头
+	CreateState(ctx context.Context, st domain.OAuth2State) (string, error)
+	// TakeState 回调的时候取回，只能用一次，失效返回 ErrOAuth2StateInvalid
+	TakeState(ctx context.Context, state string) (domain.OAuth2State, error)
+}
+
+type identityService struct {
+	repo      repository.IdentityRepository
+	stateRepo repository.OAuth2StateRepository
+	userRepo  repository.UserRepository
+	log       accesslog.Logger
+}
+
+func NewIdentityService(repo repository.IdentityRepository, stateRepo repository.OAuth2StateRepository,
+	userRepo repository.UserRepository, log accesslog.Logger) IdentityService {
+	return &identityService{
+		repo:      repo,
+		stateRepo: stateRepo,
+		userRepo:  userRepo,
+		log:       log,
+	}
+}
+
+func (svc *identityService) CreateState(ctx context.Context, st domain.OAuth2State) (string, error) {
+	// 微信限制 state 最长 128 字节，所以只放随机串，其它信息放在服务端
+	state := st.Intent + "_" + uuid.New().String()
+	return state, svc.stateRepo.Store(ctx, state, st)
+}
+
+func (svc *identityService) TakeState(ctx context.Context, state string) (domain.OAuth2State, error) {
+	st, err := svc.stateRepo.Take(ctx, state)
+	if err == repository.ErrOAuth2StateNotFound {
+		return domain.OAuth2State{}, ErrOAuth2StateInvalid
+	}
+	return st, err
+}
+
+func (svc *identityService) FindOrCreateByIdentity(ctx context.Context, identity domain.UserIdentity) (domain.User, error) {
+	u, err := svc.findOrCreateByIdentity(ctx, identity)
+	return checkLogin(ctx, svc.userRepo, u, err)
+}
+
+func (svc *identityService) findOrCreateByIdentity(ctx context.Context, identity domain.UserIdentity) (domain.User, error) {
+	i, err := svc.repo.FindBySubject(ctx, identity.Provider, identity.Subject)
+	switch err {
+	case nil:
+		return svc.userRepo.FindById(ctx, i.Uid)
+	case repository.ErrIdentityNotFound:
+	default:
+		return domain.User{}, err
+	}
+
+	if identity.Provider == domain.ProviderWechat {
+		// 以前的微信用户只在 users 表里面记录了 openid，第一次登录的时候补上绑定关系
+		u, err := svc.userRepo.FindByWechat(ctx, identity.Subject)
+		if err == nil {
+			identity.Uid = u.Id
+			err = svc.repo.Create(ctx, identity)
+			if err != nil && err != repository.ErrIdentityDuplicate {
+				return domain.User{}, err
+			}
+			if (u.Nickname == "" && identity.Nickname != "") || (u.Avatar == "" && identity.Avatar != "") {
+				// 以前的微信用户没有同步过个人信息
+				err = svc.userRepo.FillProfile(ctx, u.Id, identity.Nickname, identity.Avatar)
+				if err != nil {
+					svc.log.Error("补充微信个人信息失败", accesslog.Int64("uid", u.Id), accesslog.Error(err))
+				}
+			}
+			return u, nil
+		}
+		if err != repository.ErrUserNotFound {
+			return domain.User{}, err
+		}
+	}
+
+	if identity.UnionId != "" && slices.Contains(domain.WechatProviders, identity.Provider) {
+		// 网站应用和小程序的 openid 不一样，按 unionid 找到同一个用户
+		u, err := svc.findByWechatUnionId(ctx, identity)
+		switch err {
+		case nil:
+			return u, nil
+		case repository.ErrIdentityNotFound:
+		default:
+			return domain.User{}, err
+		}
+	}
+
+	svc.log.Info("第三方账号未注册，注册新用户",
+		accesslog.String("provider", identity.Provider),
+		accesslog.String("subject", identity.Subject))
+	u := domain.User{
+		Nickname: identity.Nickname,
+		Avatar:   identity.Avatar,
+	}
+	if identity.Provider == domain.ProviderWechat {
+		u.WechatInfo = domain.WechatInfo{
+			OpenId:  identity.Subject,
+			UnionId: identity.UnionId,
+		}
+	}
+	uid, err := svc.repo.CreateWithUser(ctx, u, identity)
+	switch err {
+	case nil:
+		return svc.userRepo.FindById(ctx, uid)
+	case repository.ErrIdentityDuplicate:
+		// 并发登录，另外一个请求已经注册了
+		i, err = svc.repo.FindBySubject(ctx, identity.Provider, identity.Subject)
+		if err != nil {
+			return domain.User{}, err
+		}
+		return svc.userRepo.FindById(ctx, i.Uid)
+	default:
+		return domain.User{}, err
+	}
+}
+
+// findByWechatUnionId 找到 unionid 相同的用户之后补上这个 openid 的绑定关系，
+// 没有找到返回 ErrIdentityNotFound
+func (svc *identityService) findByWechatUnionId(ctx context.Context, identity domain.UserIdentity) (domain.User, error) {
+	var uid int64
+	i, err := svc.repo.FindByUnionId(ctx, domain.WechatProviders, identity.UnionId)
+	switch err {
+	case nil:
+		uid = i.Uid
+	case repository.ErrIdentityNotFound:
+		// 以前的微信用户只在 users 表里面记录了 unionid
+		u, err := svc.userRepo.FindByWechatUnionId(ctx, identity.UnionId)
+		if err == repository.ErrUserNotFound {
+			return domain.User{}, repository.ErrIdentityNotFound
+		}
+		if err != nil {
+			return domain.User{}, err
+		}
+		uid = u.Id
+	default:
+		return domain.User{}, err
+	}
+	identity.Uid = uid
+	err = svc.repo.Create(ctx, identity)
+	if err != nil && err != repository.ErrIdentityDuplicate {
+		return domain.User{}, err
+	}
+	return svc.userRepo.FindById(ctx, uid)
+}
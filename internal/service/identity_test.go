@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func Test_identityService_FindOrCreateByIdentity(t *testing.T) {
+	github := domain.UserIdentity{Provider: domain.ProviderGithub, Subject: "583231", Nickname: "octocat"}
+	wechat := domain.UserIdentity{Provider: domain.ProviderWechat, Subject: "openid", UnionId: "unionid"}
+	mini := domain.UserIdentity{Provider: domain.ProviderWechatMini, Subject: "mini_openid", UnionId: "unionid"}
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository)
+		// 输入
+		identity domain.UserIdentity
+		// 输出
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "已经绑定过",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{Uid: 1, Provider: domain.ProviderGithub, Subject: "583231"}, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(1)).Return(domain.User{Id: 1}, nil)
+				return repo, userRepo
+			},
+			identity: github,
+			wantUser: domain.User{Id: 1},
+		},
+		{
+			name: "没有绑定过，新建用户",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				repo.EXPECT().CreateWithUser(gomock.Any(), domain.User{Nickname: "octocat"}, github).
+					Return(int64(2), nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.User{Id: 2, Nickname: "octocat"}, nil)
+				return repo, userRepo
+			},
+			identity: github,
+			wantUser: domain.User{Id: 2, Nickname: "octocat"},
+		},
+		{
+			name: "并发登录，另外一个请求已经新建了用户",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				repo.EXPECT().CreateWithUser(gomock.Any(), gomock.Any(), github).
+					Return(int64(0), repository.ErrIdentityDuplicate)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderGithub, "583231").
+					Return(domain.UserIdentity{Uid: 3}, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(3)).Return(domain.User{Id: 3}, nil)
+				return repo, userRepo
+			},
+			identity: github,
+			wantUser: domain.User{Id: 3},
+		},
+		{
+			name: "以前的微信用户，补上绑定关系",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechat, "openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").Return(domain.User{Id: 4}, nil)
+				linked := wechat
+				linked.Uid = 4
+				repo.EXPECT().Create(gomock.Any(), linked).Return(nil)
+				return repo, userRepo
+			},
+			identity: wechat,
+			wantUser: domain.User{Id: 4},
+		},
+		{
+			name: "以前的微信用户没有昵称和头像，补上",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechat, "openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").Return(domain.User{Id: 4}, nil)
+				userRepo.EXPECT().FillProfile(gomock.Any(), int64(4), "叶钦", "https://thirdwx.qlogo.cn/avatar").Return(nil)
+				return repo, userRepo
+			},
+			identity: domain.UserIdentity{
+				Provider: domain.ProviderWechat,
+				Subject:  "openid",
+				Nickname: "叶钦",
+				Avatar:   "https://thirdwx.qlogo.cn/avatar",
+			},
+			wantUser: domain.User{Id: 4},
+		},
+		{
+			name: "新的微信用户，同时记录 openid",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechat, "openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechat(gomock.Any(), "openid").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().FindByUnionId(gomock.Any(), domain.WechatProviders, "unionid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo.EXPECT().FindByWechatUnionId(gomock.Any(), "unionid").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().CreateWithUser(gomock.Any(), domain.User{
+					WechatInfo: domain.WechatInfo{OpenId: "openid", UnionId: "unionid"},
+				}, wechat).Return(int64(5), nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(5)).Return(domain.User{Id: 5}, nil)
+				return repo, userRepo
+			},
+			identity: wechat,
+			wantUser: domain.User{Id: 5},
+		},
+		{
+			name: "小程序第一次登录，按 unionid 找到网站应用注册的用户",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechatMini, "mini_openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				repo.EXPECT().FindByUnionId(gomock.Any(), domain.WechatProviders, "unionid").
+					Return(domain.UserIdentity{Uid: 6, Provider: domain.ProviderWechat, Subject: "openid", UnionId: "unionid"}, nil)
+				repo.EXPECT().Create(gomock.Any(), domain.UserIdentity{
+					Uid:      6,
+					Provider: domain.ProviderWechatMini,
+					Subject:  "mini_openid",
+					UnionId:  "unionid",
+				}).Return(nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(6)).Return(domain.User{Id: 6}, nil)
+				return repo, userRepo
+			},
+			identity: mini,
+			wantUser: domain.User{Id: 6},
+		},
+		{
+			name: "小程序第一次登录，按 unionid 找到以前的微信用户",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechatMini, "mini_openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				repo.EXPECT().FindByUnionId(gomock.Any(), domain.WechatProviders, "unionid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechatUnionId(gomock.Any(), "unionid").Return(domain.User{Id: 7}, nil)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(7)).Return(domain.User{Id: 7}, nil)
+				return repo, userRepo
+			},
+			identity: mini,
+			wantUser: domain.User{Id: 7},
+		},
+		{
+			name: "小程序新用户，不记录网站应用的 openid",
+			mock: func(ctrl *gomock.Controller) (repository.IdentityRepository, repository.UserRepository) {
+				repo := repomocks.NewMockIdentityRepository(ctrl)
+				repo.EXPECT().FindBySubject(gomock.Any(), domain.ProviderWechatMini, "mini_openid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				repo.EXPECT().FindByUnionId(gomock.Any(), domain.WechatProviders, "unionid").
+					Return(domain.UserIdentity{}, repository.ErrIdentityNotFound)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByWechatUnionId(gomock.Any(), "unionid").
+					Return(domain.User{}, repository.ErrUserNotFound)
+				repo.EXPECT().CreateWithUser(gomock.Any(), domain.User{}, mini).Return(int64(8), nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(8)).Return(domain.User{Id: 8}, nil)
+				return repo, userRepo
+			},
+			identity: mini,
+			wantUser: domain.User{Id: 8},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo := tc.mock(ctrl)
+			svc := NewIdentityService(repo, nil, userRepo, accesslog.NewNopLogger())
+			u, err := svc.FindOrCreateByIdentity(context.Background(), tc.identity)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	ErrLoginLocked      = errors.New("密码错误次数太多，账号已经被临时锁定")
+	ErrLoginTooFrequent = errors.New("登录太频繁，请稍后再试")
+)
+
+// LoginLimitService 密码登录失败之后的限制
+// 按账号和 IP 分别统计失败次数，超过之后指数退避，账号失败次数太多会临时锁定
+//
+//go:generate mockgen.exe -source=./login_limit.go -package=svcmocks -destination=mocks/login_limit.mock.go LoginLimitService
+type LoginLimitService interface {
+	// Allow 是否允许尝试登录，不允许的时候返回剩余等待时间和
+	// ErrLoginLocked 或者 ErrLoginTooFrequent
+	Allow(ctx context.Context, account string, ip string) (time.Duration, error)
+	// Fail 记录一次密码错误，返回的等待时间和错误含义同 Allow
+	Fail(ctx context.Context, account string, ip string) (time.Duration, error)
+	// Succeed 登录成功，清掉账号的失败次数。IP 的不清，避免用一个正确的账号给 IP 洗白
+	Succeed(ctx context.Context, account string) error
+	// Unlock 管理员解锁
+	Unlock(ctx context.Context, account string) error
+}
+
+type loginLimitService struct {
+	repo        repository.LoginLimitRepository
+	accountRule domain.LoginLimitRule
+	ipRule      domain.LoginLimitRule
+}
+
+func NewLoginLimitService(repo repository.LoginLimitRepository, accountRule domain.LoginLimitRule, ipRule domain.LoginLimitRule) LoginLimitService {
+	return &loginLimitService{
+		repo:        repo,
+		accountRule: accountRule,
+		ipRule:      ipRule,
+	}
+}
+
+func (svc *loginLimitService) Allow(ctx context.Context, account string, ip string) (time.Duration, error) {
+	wait, locked, err := svc.repo.Blocked(ctx, svc.normalize(account), ip)
+	if err != nil {
+		return 0, err
+	}
+	return wait, svc.toErr(wait, locked)
+}
+
+func (svc *loginLimitService) Fail(ctx context.Context, account string, ip string) (time.Duration, error) {
+	wait, locked, err := svc.repo.FailAccount(ctx, svc.normalize(account), svc.accountRule)
+	if err != nil {
+		return 0, err
+	}
+	if ip != "" {
+		ipWait, ipLocked, err := svc.repo.FailIP(ctx, ip, svc.ipRule)
+		if err != nil {
+			return 0, err
+		}
+		if ipWait > wait {
+			wait = ipWait
+		}
+		locked = locked || ipLocked
+	}
+	return wait, svc.toErr(wait, locked)
+}
+
+func (svc *loginLimitService) Succeed(ctx context.Context, account string) error {
+	return svc.repo.ResetAccount(ctx, svc.normalize(account))
+}
+
+func (svc *loginLimitService) Unlock(ctx context.Context, account string) error {
+	return svc.repo.ResetAccount(ctx, svc.normalize(account))
+}
+
+func (svc *loginLimitService) toErr(wait time.Duration, locked bool) error {
+	switch {
+	case wait <= 0:
+		return nil
+	case locked:
+		return ErrLoginLocked
+	default:
+		return ErrLoginTooFrequent
+	}
+}
+
+// normalize 邮箱不区分大小写，避免换个大小写绕过限制
+func (svc *loginLimitService) normalize(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
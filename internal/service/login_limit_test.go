@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func Test_loginLimitService_Fail(t *testing.T) {
+	accountRule := domain.LoginLimitRule{FreeAttempts: 3, LockThreshold: 10}
+	ipRule := domain.LoginLimitRule{FreeAttempts: 20}
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.LoginLimitRepository
+		// 输入
+		ip string
+		// 输出
+		wantWait time.Duration
+		wantErr  error
+	}{
+		{
+			name: "还没有超过次数",
+			mock: func(ctrl *gomock.Controller) repository.LoginLimitRepository {
+				repo := repomocks.NewMockLoginLimitRepository(ctrl)
+				repo.EXPECT().FailAccount(gomock.Any(), "1426325504@qq.com", accountRule).
+					Return(time.Duration(0), false, nil)
+				repo.EXPECT().FailIP(gomock.Any(), "10.0.0.1", ipRule).
+					Return(time.Duration(0), false, nil)
+				return repo
+			},
+			ip: "10.0.0.1",
+		},
+		{
+			name: "账号退避，取最长的等待时间",
+			mock: func(ctrl *gomock.Controller) repository.LoginLimitRepository {
+				repo := repomocks.NewMockLoginLimitRepository(ctrl)
+				repo.EXPECT().FailAccount(gomock.Any(), "1426325504@qq.com", accountRule).
+					Return(time.Second*2, false, nil)
+				repo.EXPECT().FailIP(gomock.Any(), "10.0.0.1", ipRule).
+					Return(time.Second, false, nil)
+				return repo
+			},
+			ip:       "10.0.0.1",
+			wantWait: time.Second * 2,
+			wantErr:  ErrLoginTooFrequent,
+		},
+		{
+			name: "账号锁定",
+			mock: func(ctrl *gomock.Controller) repository.LoginLimitRepository {
+				repo := repomocks.NewMockLoginLimitRepository(ctrl)
+				repo.EXPECT().FailAccount(gomock.Any(), "1426325504@qq.com", accountRule).
+					Return(time.Minute*30, true, nil)
+				return repo
+			},
+			// 没有 IP 的时候只按账号统计
+			ip:       "",
+			wantWait: time.Minute * 30,
+			wantErr:  ErrLoginLocked,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewLoginLimitService(tc.mock(ctrl), accountRule, ipRule)
+			// 邮箱不区分大小写
+			wait, err := svc.Fail(context.Background(), " 1426325504@QQ.com", tc.ip)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantWait, wait)
+		})
+	}
+}
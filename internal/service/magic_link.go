@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service/email"
+	"github.com/google/uuid"
+	"net/url"
+	"strings"
+)
+
+// ErrMagicLinkInvalid 链接被篡改、已经用过或者已经过期
+var ErrMagicLinkInvalid = errors.New("登录链接无效或者已经过期")
+
+const (
+	loginEmailBiz      = "login_email"
+	magicLinkSubject   = "登录链接"
+	magicLinkParamName = "token"
+)
+
+// MagicLinkService 邮箱免密登录
+// 链接里面的随机数和验证码一样保存在 CodeRepository，一分钟只能发送一次，10 分钟有效，只能用一次
+// 链接再用 HMAC 签名，防止篡改邮箱
+//
+//go:generate mockgen.exe -source=./magic_link.go -package=svcmocks -destination=mocks/magic_link.mock.go MagicLinkService
+type MagicLinkService interface {
+	// Send 向邮箱发送登录链接，发送太频繁返回 ErrCodeSendTooMany
+	Send(ctx context.Context, email string) error
+	// Login 校验链接里面的 token，没有注册过的邮箱新建用户
+	// 第一次通过链接验证邮箱的时候会清掉注册时设置的密码，开启了两步验证的话调用方还要走两步验证
+	Login(ctx context.Context, token string) (domain.User, error)
+}
+
+type magicLinkService struct {
+	repo     repository.CodeRepository
+	emailSvc email.Service
+	userSvc  UserService
+	// signKey 签名的密钥
+	signKey []byte
+	// loginURL 前端的登录页面，拿到 token 之后调用 /users/login_email
+	loginURL string
+}
+
+func NewMagicLinkService(repo repository.CodeRepository, emailSvc email.Service, userSvc UserService,
+	signKey []byte, loginURL string) MagicLinkService {
+	return &magicLinkService{
+		repo:     repo,
+		emailSvc: emailSvc,
+		userSvc:  userSvc,
+		signKey:  signKey,
+		loginURL: loginURL,
+	}
+}
+
+func (svc *magicLinkService) Send(ctx context.Context, email string) error {
+	nonce := uuid.New().String()
+	err := svc.repo.Store(ctx, loginEmailBiz, email, nonce)
+	if err != nil {
+		return err
+	}
+	link, err := svc.link(svc.sign(email, nonce))
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("点击下面的链接登录，10 分钟内有效，只能使用一次。如果不是您本人操作，请忽略这封邮件。\n%s", link)
+	return svc.emailSvc.Send(ctx, magicLinkSubject, content, email)
+}
+
+func (svc *magicLinkService) Login(ctx context.Context, token string) (domain.User, error) {
+	email, nonce, ok := svc.verify(token)
+	if !ok {
+		return domain.User{}, ErrMagicLinkInvalid
+	}
+	ok, err := svc.repo.Verify(ctx, loginEmailBiz, email, nonce)
+	if err == repository.ErrCodeVerifyTooManyTimes {
+		// 已经用过了
+		return domain.User{}, ErrMagicLinkInvalid
+	}
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !ok {
+		// 重新发送过，旧的链接作废
+		return domain.User{}, ErrMagicLinkInvalid
+	}
+	u, err := svc.userSvc.FindOrCreateByEmail(ctx, email)
+	if err != nil {
+		return domain.User{}, err
+	}
+	// 能收到邮件，说明邮箱是本人的
+	if u.VerifiedAt.IsZero() {
+		err = svc.userSvc.VerifyEmailByLink(ctx, email)
+	}
+	return u, err
+}
+
+// sign token 的格式是 base64(email).nonce.base64(hmac)
+func (svc *magicLinkService) sign(email string, nonce string) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(email)) + "." + nonce
+	return payload + "." + base64.RawURLEncoding.EncodeToString(svc.mac(payload))
+}
+
+func (svc *magicLinkService) verify(token string) (string, string, bool) {
+	idx := strings.LastIndex(token, ".")
+	if idx < 0 {
+		return "", "", false
+	}
+	payload := token[:idx]
+	sig, err := base64.RawURLEncoding.DecodeString(token[idx+1:])
+	if err != nil || !hmac.Equal(sig, svc.mac(payload)) {
+		return "", "", false
+	}
+	encodedEmail, nonce, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", "", false
+	}
+	email, err := base64.RawURLEncoding.DecodeString(encodedEmail)
+	if err != nil {
+		return "", "", false
+	}
+	return string(email), nonce, true
+}
+
+func (svc *magicLinkService) mac(payload string) []byte {
+	h := hmac.New(sha256.New, svc.signKey)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+func (svc *magicLinkService) link(token string) (string, error) {
+	u, err := url.Parse(svc.loginURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set(magicLinkParamName, token)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
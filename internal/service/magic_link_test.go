@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/dadaxiaoxiao/user/internal/service/email/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_magicLinkService_Send(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var nonce string
+	repo := repomocks.NewMockCodeRepository(ctrl)
+	repo.EXPECT().Store(gomock.Any(), "login_email", "1426325504@qq.com", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, biz, email, code string) error {
+			nonce = code
+			return nil
+		})
+	emailSvc := memory.NewService()
+	svc := NewMagicLinkService(repo, emailSvc, nil, []byte("sign_key"), "https://qinyeyiyi.cn/login/email").(*magicLinkService)
+	err := svc.Send(context.Background(), "1426325504@qq.com")
+	require.NoError(t, err)
+
+	// 邮件里面的链接带着签名之后的 token，可以还原出邮箱和随机数
+	mails := emailSvc.Mails()
+	require.Len(t, mails, 1)
+	lines := strings.Split(mails[0].Content, "\n")
+	link, err := url.Parse(lines[len(lines)-1])
+	require.NoError(t, err)
+	assert.Equal(t, "qinyeyiyi.cn", link.Host)
+	email, gotNonce, ok := svc.verify(link.Query().Get("token"))
+	assert.True(t, ok)
+	assert.Equal(t, "1426325504@qq.com", email)
+	assert.Equal(t, nonce, gotNonce)
+}
+
+func Test_magicLinkService_Login(t *testing.T) {
+	signer := &magicLinkService{signKey: []byte("sign_key")}
+	token := signer.sign("1426325504@qq.com", "nonce")
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.CodeRepository, repository.UserRepository)
+		// 输入
+		token string
+		// 输出
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "新用户，顺便验证邮箱",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, repository.UserRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Verify(gomock.Any(), "login_email", "1426325504@qq.com", "nonce").Return(true, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				gomock.InOrder(
+					userRepo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
+						Return(domain.User{}, repository.ErrUserNotFound),
+					userRepo.EXPECT().Create(gomock.Any(), domain.User{Email: "1426325504@qq.com"}).Return(nil),
+					userRepo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
+						Return(domain.User{Id: 1, Email: "1426325504@qq.com"}, nil).Times(2),
+				)
+				userRepo.EXPECT().MarkEmailVerified(gomock.Any(), int64(1), gomock.Any()).Return(nil)
+				return repo, userRepo
+			},
+			token:    token,
+			wantUser: domain.User{Id: 1, Email: "1426325504@qq.com"},
+		},
+		{
+			name: "别人抢先注册过但是没有验证，清掉别人设置的密码",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, repository.UserRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Verify(gomock.Any(), "login_email", "1426325504@qq.com", "nonce").Return(true, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
+					Return(domain.User{Id: 1, Email: "1426325504@qq.com", Password: "attacker"}, nil).Times(2)
+				gomock.InOrder(
+					userRepo.EXPECT().UpdatePassword(gomock.Any(), int64(1), "").Return(nil),
+					userRepo.EXPECT().MarkEmailVerified(gomock.Any(), int64(1), gomock.Any()).Return(nil),
+				)
+				return repo, userRepo
+			},
+			token:    token,
+			wantUser: domain.User{Id: 1, Email: "1426325504@qq.com", Password: "attacker"},
+		},
+		{
+			name: "邮箱已经验证过",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, repository.UserRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Verify(gomock.Any(), "login_email", "1426325504@qq.com", "nonce").Return(true, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
+					Return(domain.User{Id: 1, VerifiedAt: time.UnixMilli(100)}, nil)
+				return repo, userRepo
+			},
+			token:    token,
+			wantUser: domain.User{Id: 1, VerifiedAt: time.UnixMilli(100)},
+		},
+		{
+			name: "链接已经用过了",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, repository.UserRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Verify(gomock.Any(), "login_email", "1426325504@qq.com", "nonce").
+					Return(false, repository.ErrCodeVerifyTooManyTimes)
+				return repo, repomocks.NewMockUserRepository(ctrl)
+			},
+			token:   token,
+			wantErr: ErrMagicLinkInvalid,
+		},
+		{
+			name: "重新发送过，旧的链接作废",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, repository.UserRepository) {
+				repo := repomocks.NewMockCodeRepository(ctrl)
+				repo.EXPECT().Verify(gomock.Any(), "login_email", "1426325504@qq.com", "nonce").Return(false, nil)
+				return repo, repomocks.NewMockUserRepository(ctrl)
+			},
+			token:   token,
+			wantErr: ErrMagicLinkInvalid,
+		},
+		{
+			name: "篡改了邮箱",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, repository.UserRepository) {
+				return repomocks.NewMockCodeRepository(ctrl), repomocks.NewMockUserRepository(ctrl)
+			},
+			token:   "YXR0YWNrZXJAcXEuY29t" + token[strings.Index(token, "."):],
+			wantErr: ErrMagicLinkInvalid,
+		},
+		{
+			name: "别的密钥签名的",
+			mock: func(ctrl *gomock.Controller) (repository.CodeRepository, repository.UserRepository) {
+				return repomocks.NewMockCodeRepository(ctrl), repomocks.NewMockUserRepository(ctrl)
+			},
+			token:   (&magicLinkService{signKey: []byte("other")}).sign("1426325504@qq.com", "nonce"),
+			wantErr: ErrMagicLinkInvalid,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo := tc.mock(ctrl)
+			userSvc := NewUserService(userRepo, accesslog.NewNopLogger())
+			svc := NewMagicLinkService(repo, memory.NewService(), userSvc, []byte("sign_key"), "https://qinyeyiyi.cn/login/email")
+			u, err := svc.Login(context.Background(), tc.token)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
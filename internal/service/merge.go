@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/events"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"time"
+)
+
+var (
+	ErrMergeSelf = errors.New("不能合并到自己")
+	// ErrMergeConflict 两个用户的登录方式冲突，或者其中一个已经合并到其它用户
+	ErrMergeConflict = errors.New("两个用户有冲突，不能合并")
+)
+
+// MergeService 把分别注册的两个用户合并成一个
+//
+//go:generate mockgen.exe -source=./merge.go -package=svcmocks -destination=mocks/merge.mock.go MergeService
+type MergeService interface {
+	// Merge 把 sourceId 合并到 targetId，source 变成墓碑，按 id 查找的时候返回 target
+	// 重复合并直接返回成功，并且再发送一次事件
+	Merge(ctx context.Context, sourceId int64, targetId int64) error
+}
+
+type mergeService struct {
+	repo     repository.UserRepository
+	producer events.Producer
+	rules    domain.MergeRules
+}
+
+func NewMergeService(repo repository.UserRepository, producer events.Producer, rules domain.MergeRules) MergeService {
+	return &mergeService{
+		repo:     repo,
+		producer: producer,
+		rules:    rules,
+	}
+}
+
+func (svc *mergeService) Merge(ctx context.Context, sourceId int64, targetId int64) error {
+	if sourceId == targetId {
+		return ErrMergeSelf
+	}
+	err := svc.repo.Merge(ctx, sourceId, targetId, svc.merge)
+	switch err {
+	case nil, repository.ErrUserAlreadyMerged:
+	case repository.ErrUserMergeConflict:
+		return ErrMergeConflict
+	default:
+		return err
+	}
+	// 事务提交之后才发送，发送失败的话调用方重试合并会再发送一次
+	return svc.producer.ProduceUserMergedEvent(ctx, events.UserMergedEvent{
+		SourceId: sourceId,
+		TargetId: targetId,
+		Ctime:    time.Now().UnixMilli(),
+	})
+}
+
+// merge 算出合并之后的 target，在事务里面执行
+func (svc *mergeService) merge(source domain.User, target domain.User) (domain.User, error) {
+	var err error
+	if target.Email, err = mergeIdentity(source.Email, target.Email); err != nil {
+		return domain.User{}, err
+	}
+	if target.Email == source.Email && target.VerifiedAt.IsZero() {
+		target.VerifiedAt = source.VerifiedAt
+	}
+	if target.Phone, err = mergeIdentity(source.Phone, target.Phone); err != nil {
+		return domain.User{}, err
+	}
+	if target.WechatInfo.OpenId, err = mergeIdentity(source.WechatInfo.OpenId, target.WechatInfo.OpenId); err != nil {
+		return domain.User{}, err
+	}
+	if target.WechatInfo.UnionId, err = mergeIdentity(source.WechatInfo.UnionId, target.WechatInfo.UnionId); err != nil {
+		return domain.User{}, err
+	}
+	// 用了 source 的密码的时候，source 的两步验证在同一个事务里面一起转过去
+	if target.Password == "" {
+		target.Password = source.Password
+	}
+
+	target.Nickname = mergeField(svc.rules.Nickname, source.Nickname, target.Nickname)
+	target.AboutMe = mergeField(svc.rules.AboutMe, source.AboutMe, target.AboutMe)
+	target.Avatar = mergeField(svc.rules.Avatar, source.Avatar, target.Avatar)
+	if !source.Birthday.IsZero() &&
+		(target.Birthday.IsZero() || svc.rules.Birthday == domain.MergePreferSource) {
+		target.Birthday = source.Birthday
+	}
+	return target, nil
+}
+
+// mergeIdentity 登录方式只能一边有，或者两边一样
+func mergeIdentity(source string, target string) (string, error) {
+	if source == "" || source == target {
+		return target, nil
+	}
+	if target != "" {
+		return "", repository.ErrUserMergeConflict
+	}
+	return source, nil
+}
+
+func mergeField(policy domain.MergePolicy, source string, target string) string {
+	if source != "" && (target == "" || policy == domain.MergePreferSource) {
+		return source
+	}
+	return target
+}
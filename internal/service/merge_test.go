@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/events"
+	evtmocks "github.com/dadaxiaoxiao/user/internal/events/mocks"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func Test_mergeService_Merge(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, events.Producer)
+		// 输入
+		sourceId int64
+		targetId int64
+		// 输出
+		wantErr error
+	}{
+		{
+			name: "合并成功，发送事件",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().Merge(gomock.Any(), int64(1), int64(2), gomock.Any()).Return(nil)
+				producer := evtmocks.NewMockProducer(ctrl)
+				producer.EXPECT().ProduceUserMergedEvent(gomock.Any(), gomock.Cond(func(x any) bool {
+					evt := x.(events.UserMergedEvent)
+					return evt.SourceId == 1 && evt.TargetId == 2
+				})).Return(nil)
+				return repo, producer
+			},
+			sourceId: 1,
+			targetId: 2,
+		},
+		{
+			name: "重复合并，再发送一次事件",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().Merge(gomock.Any(), int64(1), int64(2), gomock.Any()).
+					Return(repository.ErrUserAlreadyMerged)
+				producer := evtmocks.NewMockProducer(ctrl)
+				producer.EXPECT().ProduceUserMergedEvent(gomock.Any(), gomock.Any()).Return(nil)
+				return repo, producer
+			},
+			sourceId: 1,
+			targetId: 2,
+		},
+		{
+			name: "冲突，不发送事件",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, events.Producer) {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().Merge(gomock.Any(), int64(1), int64(2), gomock.Any()).
+					Return(repository.ErrUserMergeConflict)
+				return repo, evtmocks.NewMockProducer(ctrl)
+			},
+			sourceId: 1,
+			targetId: 2,
+			wantErr:  ErrMergeConflict,
+		},
+		{
+			name: "合并到自己",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, events.Producer) {
+				return repomocks.NewMockUserRepository(ctrl), evtmocks.NewMockProducer(ctrl)
+			},
+			sourceId: 1,
+			targetId: 1,
+			wantErr:  ErrMergeSelf,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, producer := tc.mock(ctrl)
+			svc := NewMergeService(repo, producer, domain.MergeRules{})
+			err := svc.Merge(context.Background(), tc.sourceId, tc.targetId)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func Test_mergeService_merge(t *testing.T) {
+	birthday := time.Date(1990, 1, 1, 0, 0, 0, 0, time.Local)
+	verifiedAt := time.UnixMilli(1700000000000)
+	testCase := []struct {
+		name  string
+		rules domain.MergeRules
+		// 输入
+		source domain.User
+		target domain.User
+		// 输出
+		wantUser domain.User
+		wantErr  error
+	}{
+		{
+			name: "微信用户合并到手机号用户",
+			source: domain.User{
+				Id:         1,
+				Nickname:   "微信昵称",
+				WechatInfo: domain.WechatInfo{OpenId: "openid", UnionId: "unionid"},
+			},
+			target: domain.User{
+				Id:    2,
+				Phone: "15212345678",
+			},
+			wantUser: domain.User{
+				Id:         2,
+				Phone:      "15212345678",
+				Nickname:   "微信昵称",
+				WechatInfo: domain.WechatInfo{OpenId: "openid", UnionId: "unionid"},
+			},
+		},
+		{
+			name: "邮箱跟着验证时间一起合并",
+			source: domain.User{
+				Id:         1,
+				Email:      "123@qq.com",
+				Password:   "hash",
+				VerifiedAt: verifiedAt,
+			},
+			target: domain.User{Id: 2},
+			wantUser: domain.User{
+				Id:         2,
+				Email:      "123@qq.com",
+				Password:   "hash",
+				VerifiedAt: verifiedAt,
+			},
+		},
+		{
+			name:  "按照规则使用 source 的资料",
+			rules: domain.MergeRules{Nickname: domain.MergePreferSource, Birthday: domain.MergePreferSource},
+			source: domain.User{
+				Id:       1,
+				Nickname: "source",
+				AboutMe:  "source",
+				Birthday: birthday,
+			},
+			target: domain.User{
+				Id:       2,
+				Nickname: "target",
+				AboutMe:  "target",
+				Birthday: birthday.AddDate(1, 0, 0),
+			},
+			wantUser: domain.User{
+				Id:       2,
+				Nickname: "source",
+				AboutMe:  "target",
+				Birthday: birthday,
+			},
+		},
+		{
+			name:    "两边绑定了不同的手机号",
+			source:  domain.User{Id: 1, Phone: "15212345678"},
+			target:  domain.User{Id: 2, Phone: "15212345679"},
+			wantErr: repository.ErrUserMergeConflict,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &mergeService{rules: tc.rules}
+			u, err := svc.merge(tc.source, tc.target)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./account_deletion.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./account_deletion.go -package=svcmocks -destination=mocks/account_deletion.mock.go AccountDeletionService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAccountDeletionService is a mock of AccountDeletionService interface.
+type MockAccountDeletionService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountDeletionServiceMockRecorder
+}
+
+// MockAccountDeletionServiceMockRecorder is the mock recorder for MockAccountDeletionService.
+type MockAccountDeletionServiceMockRecorder struct {
+	mock *MockAccountDeletionService
+}
+
+// NewMockAccountDeletionService creates a new mock instance.
+func NewMockAccountDeletionService(ctrl *gomock.Controller) *MockAccountDeletionService {
+	mock := &MockAccountDeletionService{ctrl: ctrl}
+	mock.recorder = &MockAccountDeletionServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountDeletionService) EXPECT() *MockAccountDeletionServiceMockRecorder {
+	return m.recorder
+}
+
+// Cancel mocks base method.
+func (m *MockAccountDeletionService) Cancel(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockAccountDeletionServiceMockRecorder) Cancel(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockAccountDeletionService)(nil).Cancel), ctx, uid)
+}
+
+// EraseExpired mocks base method.
+func (m *MockAccountDeletionService) EraseExpired(ctx context.Context, limit int) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EraseExpired", ctx, limit)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EraseExpired indicates an expected call of EraseExpired.
+func (mr *MockAccountDeletionServiceMockRecorder) EraseExpired(ctx, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EraseExpired", reflect.TypeOf((*MockAccountDeletionService)(nil).EraseExpired), ctx, limit)
+}
+
+// Request mocks base method.
+func (m *MockAccountDeletionService) Request(ctx context.Context, uid int64) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Request", ctx, uid)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Request indicates an expected call of Request.
+func (mr *MockAccountDeletionServiceMockRecorder) Request(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Request", reflect.TypeOf((*MockAccountDeletionService)(nil).Request), ctx, uid)
+}
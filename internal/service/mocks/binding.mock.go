@@ -0,0 +1,112 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./binding.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./binding.go -package=svcmocks -destination=mocks/binding.mock.go BindingService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockBindingService is a mock of BindingService interface.
+type MockBindingService struct {
+	ctrl     *gomock.Controller
+	recorder *MockBindingServiceMockRecorder
+}
+
+// MockBindingServiceMockRecorder is the mock recorder for MockBindingService.
+type MockBindingServiceMockRecorder struct {
+	mock *MockBindingService
+}
+
+// NewMockBindingService creates a new mock instance.
+func NewMockBindingService(ctrl *gomock.Controller) *MockBindingService {
+	mock := &MockBindingService{ctrl: ctrl}
+	mock.recorder = &MockBindingServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBindingService) EXPECT() *MockBindingServiceMockRecorder {
+	return m.recorder
+}
+
+// BindIdentity mocks base method.
+func (m *MockBindingService) BindIdentity(ctx context.Context, uid int64, identity domain.UserIdentity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindIdentity", ctx, uid, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindIdentity indicates an expected call of BindIdentity.
+func (mr *MockBindingServiceMockRecorder) BindIdentity(ctx, uid, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindIdentity", reflect.TypeOf((*MockBindingService)(nil).BindIdentity), ctx, uid, identity)
+}
+
+// BindWithCode mocks base method.
+func (m *MockBindingService) BindWithCode(ctx context.Context, uid int64, typ, target, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindWithCode", ctx, uid, typ, target, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BindWithCode indicates an expected call of BindWithCode.
+func (mr *MockBindingServiceMockRecorder) BindWithCode(ctx, uid, typ, target, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindWithCode", reflect.TypeOf((*MockBindingService)(nil).BindWithCode), ctx, uid, typ, target, code)
+}
+
+// Bindings mocks base method.
+func (m *MockBindingService) Bindings(ctx context.Context, uid int64) (domain.Bindings, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bindings", ctx, uid)
+	ret0, _ := ret[0].(domain.Bindings)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Bindings indicates an expected call of Bindings.
+func (mr *MockBindingServiceMockRecorder) Bindings(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bindings", reflect.TypeOf((*MockBindingService)(nil).Bindings), ctx, uid)
+}
+
+// SendCode mocks base method.
+func (m *MockBindingService) SendCode(ctx context.Context, typ, target string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendCode", ctx, typ, target)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendCode indicates an expected call of SendCode.
+func (mr *MockBindingServiceMockRecorder) SendCode(ctx, typ, target any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendCode", reflect.TypeOf((*MockBindingService)(nil).SendCode), ctx, typ, target)
+}
+
+// Unbind mocks base method.
+func (m *MockBindingService) Unbind(ctx context.Context, uid int64, method string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unbind", ctx, uid, method)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unbind indicates an expected call of Unbind.
+func (mr *MockBindingServiceMockRecorder) Unbind(ctx, uid, method any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unbind", reflect.TypeOf((*MockBindingService)(nil).Unbind), ctx, uid, method)
+}
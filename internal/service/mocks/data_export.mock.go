@@ -0,0 +1,147 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./data_export.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./data_export.go -package=svcmocks -destination=mocks/data_export.mock.go DataExportService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	io "io"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDataExportService is a mock of DataExportService interface.
+type MockDataExportService struct {
+	ctrl     *gomock.Controller
+	recorder *MockDataExportServiceMockRecorder
+}
+
+// MockDataExportServiceMockRecorder is the mock recorder for MockDataExportService.
+type MockDataExportServiceMockRecorder struct {
+	mock *MockDataExportService
+}
+
+// NewMockDataExportService creates a new mock instance.
+func NewMockDataExportService(ctrl *gomock.Controller) *MockDataExportService {
+	mock := &MockDataExportService{ctrl: ctrl}
+	mock.recorder = &MockDataExportServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDataExportService) EXPECT() *MockDataExportServiceMockRecorder {
+	return m.recorder
+}
+
+// Cleanup mocks base method.
+func (m *MockDataExportService) Cleanup(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cleanup", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Cleanup indicates an expected call of Cleanup.
+func (mr *MockDataExportServiceMockRecorder) Cleanup(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cleanup", reflect.TypeOf((*MockDataExportService)(nil).Cleanup), ctx)
+}
+
+// Create mocks base method.
+func (m *MockDataExportService) Create(ctx context.Context, uid int64) (domain.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, uid)
+	ret0, _ := ret[0].(domain.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockDataExportServiceMockRecorder) Create(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockDataExportService)(nil).Create), ctx, uid)
+}
+
+// DownloadURL mocks base method.
+func (m *MockDataExportService) DownloadURL(e domain.DataExport) (string, time.Time) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DownloadURL", e)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(time.Time)
+	return ret0, ret1
+}
+
+// DownloadURL indicates an expected call of DownloadURL.
+func (mr *MockDataExportServiceMockRecorder) DownloadURL(e any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadURL", reflect.TypeOf((*MockDataExportService)(nil).DownloadURL), e)
+}
+
+// Erase mocks base method.
+func (m *MockDataExportService) Erase(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Erase", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Erase indicates an expected call of Erase.
+func (mr *MockDataExportServiceMockRecorder) Erase(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Erase", reflect.TypeOf((*MockDataExportService)(nil).Erase), ctx, uid)
+}
+
+// Find mocks base method.
+func (m *MockDataExportService) Find(ctx context.Context, uid int64, id string) (domain.DataExport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Find", ctx, uid, id)
+	ret0, _ := ret[0].(domain.DataExport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Find indicates an expected call of Find.
+func (mr *MockDataExportServiceMockRecorder) Find(ctx, uid, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockDataExportService)(nil).Find), ctx, uid, id)
+}
+
+// Open mocks base method.
+func (m *MockDataExportService) Open(ctx context.Context, token string) (domain.DataExport, io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Open", ctx, token)
+	ret0, _ := ret[0].(domain.DataExport)
+	ret1, _ := ret[1].(io.ReadCloser)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Open indicates an expected call of Open.
+func (mr *MockDataExportServiceMockRecorder) Open(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockDataExportService)(nil).Open), ctx, token)
+}
+
+// RunNext mocks base method.
+func (m *MockDataExportService) RunNext(ctx context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunNext", ctx)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunNext indicates an expected call of RunNext.
+func (mr *MockDataExportServiceMockRecorder) RunNext(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunNext", reflect.TypeOf((*MockDataExportService)(nil).RunNext), ctx)
+}
@@ -0,0 +1,86 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./identity.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./identity.go -package=svcmocks -destination=mocks/identity.mock.go IdentityService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIdentityService is a mock of IdentityService interface.
+type MockIdentityService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdentityServiceMockRecorder
+}
+
+// MockIdentityServiceMockRecorder is the mock recorder for MockIdentityService.
+type MockIdentityServiceMockRecorder struct {
+	mock *MockIdentityService
+}
+
+// NewMockIdentityService creates a new mock instance.
+func NewMockIdentityService(ctrl *gomock.Controller) *MockIdentityService {
+	mock := &MockIdentityService{ctrl: ctrl}
+	mock.recorder = &MockIdentityServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdentityService) EXPECT() *MockIdentityServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateState mocks base method.
+func (m *MockIdentityService) CreateState(ctx context.Context, st domain.OAuth2State) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateState", ctx, st)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateState indicates an expected call of CreateState.
+func (mr *MockIdentityServiceMockRecorder) CreateState(ctx, st any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateState", reflect.TypeOf((*MockIdentityService)(nil).CreateState), ctx, st)
+}
+
+// FindOrCreateByIdentity mocks base method.
+func (m *MockIdentityService) FindOrCreateByIdentity(ctx context.Context, identity domain.UserIdentity) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindOrCreateByIdentity", ctx, identity)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindOrCreateByIdentity indicates an expected call of FindOrCreateByIdentity.
+func (mr *MockIdentityServiceMockRecorder) FindOrCreateByIdentity(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOrCreateByIdentity", reflect.TypeOf((*MockIdentityService)(nil).FindOrCreateByIdentity), ctx, identity)
+}
+
+// TakeState mocks base method.
+func (m *MockIdentityService) TakeState(ctx context.Context, state string) (domain.OAuth2State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeState", ctx, state)
+	ret0, _ := ret[0].(domain.OAuth2State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakeState indicates an expected call of TakeState.
+func (mr *MockIdentityServiceMockRecorder) TakeState(ctx, state any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeState", reflect.TypeOf((*MockIdentityService)(nil).TakeState), ctx, state)
+}
@@ -0,0 +1,99 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./login_limit.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./login_limit.go -package=svcmocks -destination=mocks/login_limit.mock.go LoginLimitService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginLimitService is a mock of LoginLimitService interface.
+type MockLoginLimitService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginLimitServiceMockRecorder
+}
+
+// MockLoginLimitServiceMockRecorder is the mock recorder for MockLoginLimitService.
+type MockLoginLimitServiceMockRecorder struct {
+	mock *MockLoginLimitService
+}
+
+// NewMockLoginLimitService creates a new mock instance.
+func NewMockLoginLimitService(ctrl *gomock.Controller) *MockLoginLimitService {
+	mock := &MockLoginLimitService{ctrl: ctrl}
+	mock.recorder = &MockLoginLimitServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginLimitService) EXPECT() *MockLoginLimitServiceMockRecorder {
+	return m.recorder
+}
+
+// Allow mocks base method.
+func (m *MockLoginLimitService) Allow(ctx context.Context, account, ip string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allow", ctx, account, ip)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Allow indicates an expected call of Allow.
+func (mr *MockLoginLimitServiceMockRecorder) Allow(ctx, account, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allow", reflect.TypeOf((*MockLoginLimitService)(nil).Allow), ctx, account, ip)
+}
+
+// Fail mocks base method.
+func (m *MockLoginLimitService) Fail(ctx context.Context, account, ip string) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fail", ctx, account, ip)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockLoginLimitServiceMockRecorder) Fail(ctx, account, ip any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockLoginLimitService)(nil).Fail), ctx, account, ip)
+}
+
+// Succeed mocks base method.
+func (m *MockLoginLimitService) Succeed(ctx context.Context, account string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Succeed", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Succeed indicates an expected call of Succeed.
+func (mr *MockLoginLimitServiceMockRecorder) Succeed(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Succeed", reflect.TypeOf((*MockLoginLimitService)(nil).Succeed), ctx, account)
+}
+
+// Unlock mocks base method.
+func (m *MockLoginLimitService) Unlock(ctx context.Context, account string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, account)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockLoginLimitServiceMockRecorder) Unlock(ctx, account any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockLoginLimitService)(nil).Unlock), ctx, account)
+}
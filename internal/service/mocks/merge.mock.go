@@ -0,0 +1,54 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./merge.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./merge.go -package=svcmocks -destination=mocks/merge.mock.go MergeService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMergeService is a mock of MergeService interface.
+type MockMergeService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMergeServiceMockRecorder
+}
+
+// MockMergeServiceMockRecorder is the mock recorder for MockMergeService.
+type MockMergeServiceMockRecorder struct {
+	mock *MockMergeService
+}
+
+// NewMockMergeService creates a new mock instance.
+func NewMockMergeService(ctrl *gomock.Controller) *MockMergeService {
+	mock := &MockMergeService{ctrl: ctrl}
+	mock.recorder = &MockMergeServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMergeService) EXPECT() *MockMergeServiceMockRecorder {
+	return m.recorder
+}
+
+// Merge mocks base method.
+func (m *MockMergeService) Merge(ctx context.Context, sourceId, targetId int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", ctx, sourceId, targetId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockMergeServiceMockRecorder) Merge(ctx, sourceId, targetId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockMergeService)(nil).Merge), ctx, sourceId, targetId)
+}
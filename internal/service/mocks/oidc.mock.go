@@ -0,0 +1,119 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./oidc.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./oidc.go -package=svcmocks -destination=mocks/oidc.mock.go OIDCService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	service "github.com/dadaxiaoxiao/user/internal/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOIDCService is a mock of OIDCService interface.
+type MockOIDCService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCServiceMockRecorder
+}
+
+// MockOIDCServiceMockRecorder is the mock recorder for MockOIDCService.
+type MockOIDCServiceMockRecorder struct {
+	mock *MockOIDCService
+}
+
+// NewMockOIDCService creates a new mock instance.
+func NewMockOIDCService(ctrl *gomock.Controller) *MockOIDCService {
+	mock := &MockOIDCService{ctrl: ctrl}
+	mock.recorder = &MockOIDCServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCService) EXPECT() *MockOIDCServiceMockRecorder {
+	return m.recorder
+}
+
+// AuthenticateClient mocks base method.
+func (m *MockOIDCService) AuthenticateClient(ctx context.Context, clientId, clientSecret string) (domain.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthenticateClient", ctx, clientId, clientSecret)
+	ret0, _ := ret[0].(domain.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuthenticateClient indicates an expected call of AuthenticateClient.
+func (mr *MockOIDCServiceMockRecorder) AuthenticateClient(ctx, clientId, clientSecret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthenticateClient", reflect.TypeOf((*MockOIDCService)(nil).AuthenticateClient), ctx, clientId, clientSecret)
+}
+
+// Authorize mocks base method.
+func (m *MockOIDCService) Authorize(ctx context.Context, uid int64, req service.AuthorizeRequest, approve bool) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authorize", ctx, uid, req, approve)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authorize indicates an expected call of Authorize.
+func (mr *MockOIDCServiceMockRecorder) Authorize(ctx, uid, req, approve any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authorize", reflect.TypeOf((*MockOIDCService)(nil).Authorize), ctx, uid, req, approve)
+}
+
+// Exchange mocks base method.
+func (m *MockOIDCService) Exchange(ctx context.Context, clientId, clientSecret, code, redirectURI, codeVerifier string) (domain.AuthCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exchange", ctx, clientId, clientSecret, code, redirectURI, codeVerifier)
+	ret0, _ := ret[0].(domain.AuthCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exchange indicates an expected call of Exchange.
+func (mr *MockOIDCServiceMockRecorder) Exchange(ctx, clientId, clientSecret, code, redirectURI, codeVerifier any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exchange", reflect.TypeOf((*MockOIDCService)(nil).Exchange), ctx, clientId, clientSecret, code, redirectURI, codeVerifier)
+}
+
+// RegisterClient mocks base method.
+func (m *MockOIDCService) RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (domain.OAuthClient, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterClient", ctx, name, redirectURIs, public)
+	ret0, _ := ret[0].(domain.OAuthClient)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// RegisterClient indicates an expected call of RegisterClient.
+func (mr *MockOIDCServiceMockRecorder) RegisterClient(ctx, name, redirectURIs, public any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterClient", reflect.TypeOf((*MockOIDCService)(nil).RegisterClient), ctx, name, redirectURIs, public)
+}
+
+// ValidateAuthorize mocks base method.
+func (m *MockOIDCService) ValidateAuthorize(ctx context.Context, req service.AuthorizeRequest) (domain.OAuthClient, []string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateAuthorize", ctx, req)
+	ret0, _ := ret[0].(domain.OAuthClient)
+	ret1, _ := ret[1].([]string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ValidateAuthorize indicates an expected call of ValidateAuthorize.
+func (mr *MockOIDCServiceMockRecorder) ValidateAuthorize(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAuthorize", reflect.TypeOf((*MockOIDCService)(nil).ValidateAuthorize), ctx, req)
+}
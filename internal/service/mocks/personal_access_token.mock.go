@@ -0,0 +1,102 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./personal_access_token.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./personal_access_token.go -package=svcmocks -destination=mocks/personal_access_token.mock.go PersonalAccessTokenService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPersonalAccessTokenService is a mock of PersonalAccessTokenService interface.
+type MockPersonalAccessTokenService struct {
+	ctrl     *gomock.Controller
+	recorder *MockPersonalAccessTokenServiceMockRecorder
+}
+
+// MockPersonalAccessTokenServiceMockRecorder is the mock recorder for MockPersonalAccessTokenService.
+type MockPersonalAccessTokenServiceMockRecorder struct {
+	mock *MockPersonalAccessTokenService
+}
+
+// NewMockPersonalAccessTokenService creates a new mock instance.
+func NewMockPersonalAccessTokenService(ctrl *gomock.Controller) *MockPersonalAccessTokenService {
+	mock := &MockPersonalAccessTokenService{ctrl: ctrl}
+	mock.recorder = &MockPersonalAccessTokenServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPersonalAccessTokenService) EXPECT() *MockPersonalAccessTokenServiceMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockPersonalAccessTokenService) Create(ctx context.Context, uid int64, name string, scopes []string, ttl time.Duration) (domain.PersonalAccessToken, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, uid, name, scopes, ttl)
+	ret0, _ := ret[0].(domain.PersonalAccessToken)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockPersonalAccessTokenServiceMockRecorder) Create(ctx, uid, name, scopes, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPersonalAccessTokenService)(nil).Create), ctx, uid, name, scopes, ttl)
+}
+
+// List mocks base method.
+func (m *MockPersonalAccessTokenService) List(ctx context.Context, uid int64) ([]domain.PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, uid)
+	ret0, _ := ret[0].([]domain.PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockPersonalAccessTokenServiceMockRecorder) List(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockPersonalAccessTokenService)(nil).List), ctx, uid)
+}
+
+// Revoke mocks base method.
+func (m *MockPersonalAccessTokenService) Revoke(ctx context.Context, uid, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, uid, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockPersonalAccessTokenServiceMockRecorder) Revoke(ctx, uid, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockPersonalAccessTokenService)(nil).Revoke), ctx, uid, id)
+}
+
+// Verify mocks base method.
+func (m *MockPersonalAccessTokenService) Verify(ctx context.Context, token string) (domain.PersonalAccessToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", ctx, token)
+	ret0, _ := ret[0].(domain.PersonalAccessToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockPersonalAccessTokenServiceMockRecorder) Verify(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockPersonalAccessTokenService)(nil).Verify), ctx, token)
+}
@@ -0,0 +1,114 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./qr_login.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./qr_login.go -package=svcmocks -destination=mocks/qr_login.mock.go QRLoginService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockQRLoginService is a mock of QRLoginService interface.
+type MockQRLoginService struct {
+	ctrl     *gomock.Controller
+	recorder *MockQRLoginServiceMockRecorder
+}
+
+// MockQRLoginServiceMockRecorder is the mock recorder for MockQRLoginService.
+type MockQRLoginServiceMockRecorder struct {
+	mock *MockQRLoginService
+}
+
+// NewMockQRLoginService creates a new mock instance.
+func NewMockQRLoginService(ctrl *gomock.Controller) *MockQRLoginService {
+	mock := &MockQRLoginService{ctrl: ctrl}
+	mock.recorder = &MockQRLoginServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQRLoginService) EXPECT() *MockQRLoginServiceMockRecorder {
+	return m.recorder
+}
+
+// Cancel mocks base method.
+func (m *MockQRLoginService) Cancel(ctx context.Context, ticket string, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", ctx, ticket, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockQRLoginServiceMockRecorder) Cancel(ctx, ticket, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockQRLoginService)(nil).Cancel), ctx, ticket, uid)
+}
+
+// Confirm mocks base method.
+func (m *MockQRLoginService) Confirm(ctx context.Context, ticket string, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Confirm", ctx, ticket, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Confirm indicates an expected call of Confirm.
+func (mr *MockQRLoginServiceMockRecorder) Confirm(ctx, ticket, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Confirm", reflect.TypeOf((*MockQRLoginService)(nil).Confirm), ctx, ticket, uid)
+}
+
+// Create mocks base method.
+func (m *MockQRLoginService) Create(ctx context.Context, device domain.QRLoginDevice) (domain.QRLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, device)
+	ret0, _ := ret[0].(domain.QRLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockQRLoginServiceMockRecorder) Create(ctx, device any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockQRLoginService)(nil).Create), ctx, device)
+}
+
+// Poll mocks base method.
+func (m *MockQRLoginService) Poll(ctx context.Context, ticket, secret, last string) (domain.QRLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Poll", ctx, ticket, secret, last)
+	ret0, _ := ret[0].(domain.QRLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Poll indicates an expected call of Poll.
+func (mr *MockQRLoginServiceMockRecorder) Poll(ctx, ticket, secret, last any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Poll", reflect.TypeOf((*MockQRLoginService)(nil).Poll), ctx, ticket, secret, last)
+}
+
+// Scan mocks base method.
+func (m *MockQRLoginService) Scan(ctx context.Context, ticket string, uid int64) (domain.QRLoginDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Scan", ctx, ticket, uid)
+	ret0, _ := ret[0].(domain.QRLoginDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Scan indicates an expected call of Scan.
+func (mr *MockQRLoginServiceMockRecorder) Scan(ctx, ticket, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Scan", reflect.TypeOf((*MockQRLoginService)(nil).Scan), ctx, ticket, uid)
+}
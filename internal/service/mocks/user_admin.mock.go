@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./user_admin.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./user_admin.go -package=svcmocks -destination=mocks/user_admin.mock.go UserAdminService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserAdminService is a mock of UserAdminService interface.
+type MockUserAdminService struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserAdminServiceMockRecorder
+}
+
+// MockUserAdminServiceMockRecorder is the mock recorder for MockUserAdminService.
+type MockUserAdminServiceMockRecorder struct {
+	mock *MockUserAdminService
+}
+
+// NewMockUserAdminService creates a new mock instance.
+func NewMockUserAdminService(ctrl *gomock.Controller) *MockUserAdminService {
+	mock := &MockUserAdminService{ctrl: ctrl}
+	mock.recorder = &MockUserAdminServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserAdminService) EXPECT() *MockUserAdminServiceMockRecorder {
+	return m.recorder
+}
+
+// Detail mocks base method.
+func (m *MockUserAdminService) Detail(ctx context.Context, uid int64) (domain.User, []domain.UserIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Detail", ctx, uid)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].([]domain.UserIdentity)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Detail indicates an expected call of Detail.
+func (mr *MockUserAdminServiceMockRecorder) Detail(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Detail", reflect.TypeOf((*MockUserAdminService)(nil).Detail), ctx, uid)
+}
+
+// Freeze mocks base method.
+func (m *MockUserAdminService) Freeze(ctx context.Context, uid int64, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Freeze", ctx, uid, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Freeze indicates an expected call of Freeze.
+func (mr *MockUserAdminServiceMockRecorder) Freeze(ctx, uid, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Freeze", reflect.TypeOf((*MockUserAdminService)(nil).Freeze), ctx, uid, reason)
+}
+
+// Search mocks base method.
+func (m *MockUserAdminService) Search(ctx context.Context, q domain.UserQuery) ([]domain.User, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, q)
+	ret0, _ := ret[0].([]domain.User)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUserAdminServiceMockRecorder) Search(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUserAdminService)(nil).Search), ctx, q)
+}
+
+// Unfreeze mocks base method.
+func (m *MockUserAdminService) Unfreeze(ctx context.Context, uid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unfreeze", ctx, uid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unfreeze indicates an expected call of Unfreeze.
+func (mr *MockUserAdminServiceMockRecorder) Unfreeze(ctx, uid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unfreeze", reflect.TypeOf((*MockUserAdminService)(nil).Unfreeze), ctx, uid)
+}
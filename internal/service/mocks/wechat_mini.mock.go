@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./wechat_mini.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./wechat_mini.go -package=svcmocks -destination=mocks/wechat_mini.mock.go WechatMiniService
+//
+
+// Package svcmocks is a generated GoMock package.
+package svcmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockWechatMiniService is a mock of WechatMiniService interface.
+type MockWechatMiniService struct {
+	ctrl     *gomock.Controller
+	recorder *MockWechatMiniServiceMockRecorder
+}
+
+// MockWechatMiniServiceMockRecorder is the mock recorder for MockWechatMiniService.
+type MockWechatMiniServiceMockRecorder struct {
+	mock *MockWechatMiniService
+}
+
+// NewMockWechatMiniService creates a new mock instance.
+func NewMockWechatMiniService(ctrl *gomock.Controller) *MockWechatMiniService {
+	mock := &MockWechatMiniService{ctrl: ctrl}
+	mock.recorder = &MockWechatMiniServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWechatMiniService) EXPECT() *MockWechatMiniServiceMockRecorder {
+	return m.recorder
+}
+
+// BindPhone mocks base method.
+func (m *MockWechatMiniService) BindPhone(ctx context.Context, uid int64, encryptedData, iv string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BindPhone", ctx, uid, encryptedData, iv)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BindPhone indicates an expected call of BindPhone.
+func (mr *MockWechatMiniServiceMockRecorder) BindPhone(ctx, uid, encryptedData, iv any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BindPhone", reflect.TypeOf((*MockWechatMiniService)(nil).BindPhone), ctx, uid, encryptedData, iv)
+}
+
+// Login mocks base method.
+func (m *MockWechatMiniService) Login(ctx context.Context, jsCode string) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", ctx, jsCode)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockWechatMiniServiceMockRecorder) Login(ctx, jsCode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockWechatMiniService)(nil).Login), ctx, jsCode)
+}
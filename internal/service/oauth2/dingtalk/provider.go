@@ -0,0 +1,122 @@
+package dingtalk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2"
+	"net/http"
+	"net/url"
+)
+
+// Provider 钉钉扫码登录第三方网站
+// https://open.dingtalk.com/document/orgapp/tutorial-obtaining-user-personal-information
+type Provider struct {
+	clientId     string
+	clientSecret string
+	redirectURI  string
+	client       *http.Client
+	// 测试的时候替换成 httptest 的地址
+	loginBase string
+	apiBase   string
+}
+
+func NewProvider(clientId, clientSecret, redirectURI string) oauth2.Provider {
+	return &Provider{
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		redirectURI:  redirectURI,
+		client:       http.DefaultClient,
+		loginBase:    "https://login.dingtalk.com",
+		apiBase:      "https://api.dingtalk.com",
+	}
+}
+
+func (p *Provider) Name() string {
+	return domain.ProviderDingTalk
+}
+
+func (p *Provider) AuthURL(ctx context.Context, state string) (string, error) {
+	params := url.Values{
+		"client_id":     {p.clientId},
+		"redirect_uri":  {p.redirectURI},
+		"response_type": {"code"},
+		"scope":         {"openid"},
+		"state":         {state},
+		"prompt":        {"consent"},
+	}
+	return p.loginBase + "/oauth2/auth?" + params.Encode(), nil
+}
+
+func (p *Provider) VerifyCode(ctx context.Context, code string) (domain.UserIdentity, error) {
+	var token struct {
+		AccessToken string `json:"accessToken"`
+	}
+	err := p.call(ctx, http.MethodPost, "/v1.0/oauth2/userAccessToken", "", map[string]string{
+		"clientId":     p.clientId,
+		"clientSecret": p.clientSecret,
+		"code":         code,
+		"grantType":    "authorization_code",
+	}, &token)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+
+	var user struct {
+		Nick      string `json:"nick"`
+		AvatarUrl string `json:"avatarUrl"`
+		Email     string `json:"email"`
+		OpenId    string `json:"openId"`
+		UnionId   string `json:"unionId"`
+	}
+	err = p.call(ctx, http.MethodGet, "/v1.0/contact/users/me", token.AccessToken, nil, &user)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	if user.UnionId == "" {
+		return domain.UserIdentity{}, fmt.Errorf("钉钉返回的 unionId 为空")
+	}
+	return domain.UserIdentity{
+		Provider: domain.ProviderDingTalk,
+		// openId 只在当前应用下唯一，unionId 在整个开发者账号下唯一
+		Subject:  user.UnionId,
+		UnionId:  user.UnionId,
+		Nickname: user.Nick,
+		Avatar:   user.AvatarUrl,
+		Email:    user.Email,
+	}, nil
+}
+
+// call 调用钉钉的新版接口，出错的时候状态码不是 200，响应里面有 code 和 message
+func (p *Provider) call(ctx context.Context, method, path, accessToken string, body any, val any) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.apiBase+path, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("x-acs-dingtalk-access-token", accessToken)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var res struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		return fmt.Errorf("钉钉返回错误响应，状态码 %d，错误码 %s，错误信息 %s", resp.StatusCode, res.Code, res.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(val)
+}
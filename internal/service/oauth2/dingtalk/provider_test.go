@@ -0,0 +1,77 @@
+package dingtalk
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProvider_VerifyCode(t *testing.T) {
+	testCase := []struct {
+		name      string
+		tokenCode int
+		tokenResp string
+		userResp  string
+		// 输出
+		wantIdentity domain.UserIdentity
+		wantErr      bool
+	}{
+		{
+			name:      "换取成功",
+			tokenCode: http.StatusOK,
+			tokenResp: `{"accessToken":"token","refreshToken":"refresh","expireIn":7200}`,
+			userResp:  `{"nick":"叶钦","email":"1426325504@qq.com","openId":"openid","unionId":"unionid"}`,
+			wantIdentity: domain.UserIdentity{
+				Provider: domain.ProviderDingTalk,
+				Subject:  "unionid",
+				UnionId:  "unionid",
+				Nickname: "叶钦",
+				Email:    "1426325504@qq.com",
+			},
+		},
+		{
+			name:      "code 无效",
+			tokenCode: http.StatusBadRequest,
+			tokenResp: `{"code":"invalidParameter.authCode.notFound","message":"不合法的临时授权码"}`,
+			wantErr:   true,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/v1.0/oauth2/userAccessToken", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				var body map[string]string
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, map[string]string{
+					"clientId":     "client",
+					"clientSecret": "secret",
+					"code":         "code",
+					"grantType":    "authorization_code",
+				}, body)
+				w.WriteHeader(tc.tokenCode)
+				_, _ = w.Write([]byte(tc.tokenResp))
+			})
+			mux.HandleFunc("/v1.0/contact/users/me", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "token", r.Header.Get("x-acs-dingtalk-access-token"))
+				_, _ = w.Write([]byte(tc.userResp))
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+			p := NewProvider("client", "secret", "https://qinyeyiyi.cn/oauth2/dingtalk/callback").(*Provider)
+			p.apiBase = srv.URL
+			identity, err := p.VerifyCode(context.Background(), "code")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantIdentity, identity)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Provider GitHub OAuth App 登录，支持 PKCE
+// https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps
+type Provider struct {
+	clientId     string
+	clientSecret string
+	redirectURI  string
+	client       *http.Client
+	// 测试的时候替换成 httptest 的地址
+	webBase string
+	apiBase string
+}
+
+func NewProvider(clientId, clientSecret, redirectURI string) oauth2.PKCEProvider {
+	return &Provider{
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		redirectURI:  redirectURI,
+		client:       http.DefaultClient,
+		webBase:      "https://github.com",
+		apiBase:      "https://api.github.com",
+	}
+}
+
+func (p *Provider) Name() string {
+	return domain.ProviderGithub
+}
+
+func (p *Provider) AuthURL(ctx context.Context, state string) (string, error) {
+	return p.AuthURLWithPKCE(ctx, state, "")
+}
+
+func (p *Provider) AuthURLWithPKCE(ctx context.Context, state string, challenge string) (string, error) {
+	params := url.Values{
+		"client_id":    {p.clientId},
+		"redirect_uri": {p.redirectURI},
+		"scope":        {"read:user user:email"},
+		"state":        {state},
+	}
+	if challenge != "" {
+		params.Set("code_challenge", challenge)
+		params.Set("code_challenge_method", "S256")
+	}
+	return p.webBase + "/login/oauth/authorize?" + params.Encode(), nil
+}
+
+func (p *Provider) VerifyCode(ctx context.Context, code string) (domain.UserIdentity, error) {
+	return p.VerifyCodeWithPKCE(ctx, code, "")
+}
+
+func (p *Provider) VerifyCodeWithPKCE(ctx context.Context, code string, verifier string) (domain.UserIdentity, error) {
+	token, err := p.accessToken(ctx, code, verifier)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiBase+"/user", nil)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return domain.UserIdentity{}, fmt.Errorf("GitHub 查询用户信息失败，状态码 %d", resp.StatusCode)
+	}
+	var user struct {
+		Id        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarUrl string `json:"avatar_url"`
+		Email     string `json:"email"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return domain.UserIdentity{}, err
+	}
+	if user.Id == 0 {
+		return domain.UserIdentity{}, fmt.Errorf("GitHub 返回的用户 id 为空")
+	}
+	nickname := user.Name
+	if nickname == "" {
+		nickname = user.Login
+	}
+	return domain.UserIdentity{
+		Provider: domain.ProviderGithub,
+		// login 可以修改，只有 id 是稳定的
+		Subject:  strconv.FormatInt(user.Id, 10),
+		Nickname: nickname,
+		Avatar:   user.AvatarUrl,
+		Email:    user.Email,
+	}, nil
+}
+
+func (p *Provider) accessToken(ctx context.Context, code string, verifier string) (string, error) {
+	form := url.Values{
+		"client_id":     {p.clientId},
+		"client_secret": {p.clientSecret},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURI},
+	}
+	if verifier != "" {
+		form.Set("code_verifier", verifier)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.webBase+"/login/oauth/access_token",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// 默认返回的是 form 格式
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	// 出错的时候状态码也是 200
+	var res struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	if res.Error != "" || res.AccessToken == "" {
+		return "", fmt.Errorf("GitHub 返回错误响应，错误码 %s，错误信息 %s", res.Error, res.ErrorDescription)
+	}
+	return res.AccessToken, nil
+}
@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestProvider_VerifyCode(t *testing.T) {
+	testCase := []struct {
+		name      string
+		tokenResp string
+		userResp  string
+		// 输出
+		wantIdentity domain.UserIdentity
+		wantErr      bool
+	}{
+		{
+			name:      "换取成功",
+			tokenResp: `{"access_token":"gho_token","token_type":"bearer","scope":"read:user,user:email"}`,
+			userResp:  `{"id":583231,"login":"octocat","name":"The Octocat","email":"octocat@github.com"}`,
+			wantIdentity: domain.UserIdentity{
+				Provider: domain.ProviderGithub,
+				Subject:  "583231",
+				Nickname: "The Octocat",
+				Email:    "octocat@github.com",
+			},
+		},
+		{
+			name:      "没有设置名字，使用 login",
+			tokenResp: `{"access_token":"gho_token"}`,
+			userResp:  `{"id":583231,"login":"octocat"}`,
+			wantIdentity: domain.UserIdentity{
+				Provider: domain.ProviderGithub,
+				Subject:  "583231",
+				Nickname: "octocat",
+			},
+		},
+		{
+			name:      "code 无效",
+			tokenResp: `{"error":"bad_verification_code","error_description":"The code passed is incorrect or expired."}`,
+			wantErr:   true,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Accept"))
+				require.NoError(t, r.ParseForm())
+				assert.Equal(t, "client", r.PostForm.Get("client_id"))
+				assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+				assert.Equal(t, "code", r.PostForm.Get("code"))
+				_, _ = w.Write([]byte(tc.tokenResp))
+			})
+			mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer gho_token", r.Header.Get("Authorization"))
+				_, _ = w.Write([]byte(tc.userResp))
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+			p := NewProvider("client", "secret", "https://qinyeyiyi.cn/oauth2/github/callback").(*Provider)
+			p.webBase, p.apiBase = srv.URL, srv.URL
+			identity, err := p.VerifyCode(context.Background(), "code")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantIdentity, identity)
+		})
+	}
+}
+
+func TestProvider_AuthURL(t *testing.T) {
+	p := NewProvider("client", "secret", "https://qinyeyiyi.cn/oauth2/github/callback")
+	u, err := p.AuthURL(context.Background(), "state")
+	require.NoError(t, err)
+	parsed, err := url.Parse(u)
+	require.NoError(t, err)
+	assert.Equal(t, "github.com", parsed.Host)
+	assert.Equal(t, "client", parsed.Query().Get("client_id"))
+	assert.Equal(t, "state", parsed.Query().Get("state"))
+	assert.Equal(t, "https://qinyeyiyi.cn/oauth2/github/callback", parsed.Query().Get("redirect_uri"))
+}
+
+func TestProvider_PKCE(t *testing.T) {
+	p := NewProvider("client", "secret", "https://qinyeyiyi.cn/oauth2/github/callback").(*Provider)
+	u, err := p.AuthURLWithPKCE(context.Background(), "state", "challenge")
+	require.NoError(t, err)
+	parsed, err := url.Parse(u)
+	require.NoError(t, err)
+	assert.Equal(t, "challenge", parsed.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "verifier", r.PostForm.Get("code_verifier"))
+		_, _ = w.Write([]byte(`{"access_token":"gho_token"}`))
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":583231,"login":"octocat"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	p.webBase, p.apiBase = srv.URL, srv.URL
+	_, err = p.VerifyCodeWithPKCE(context.Background(), "code", "verifier")
+	require.NoError(t, err)
+}
@@ -0,0 +1,50 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+)
+
+// PKCEProvider 支持 PKCE 的提供方，只支持 S256
+// https://datatracker.ietf.org/doc/html/rfc7636
+type PKCEProvider interface {
+	Provider
+	// AuthURLWithPKCE 授权地址带上 code_challenge
+	AuthURLWithPKCE(ctx context.Context, state string, challenge string) (string, error)
+	// VerifyCodeWithPKCE 换取 token 的时候带上 code_verifier
+	VerifyCodeWithPKCE(ctx context.Context, code string, verifier string) (domain.UserIdentity, error)
+}
+
+// NewCodeVerifier 生成 PKCE 的 code_verifier，43 个字符
+func NewCodeVerifier() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallenge S256 方式计算 code_challenge
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthURL verifier 不为空并且提供方支持 PKCE 的时候带上 code_challenge
+func AuthURL(ctx context.Context, p Provider, state string, verifier string) (string, error) {
+	if pp, ok := p.(PKCEProvider); ok && verifier != "" {
+		return pp.AuthURLWithPKCE(ctx, state, CodeChallenge(verifier))
+	}
+	return p.AuthURL(ctx, state)
+}
+
+// VerifyCode verifier 不为空并且提供方支持 PKCE 的时候带上 code_verifier
+func VerifyCode(ctx context.Context, p Provider, code string, verifier string) (domain.UserIdentity, error) {
+	if pp, ok := p.(PKCEProvider); ok && verifier != "" {
+		return pp.VerifyCodeWithPKCE(ctx, code, verifier)
+	}
+	return p.VerifyCode(ctx, code)
+}
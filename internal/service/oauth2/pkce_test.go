@@ -0,0 +1,17 @@
+package oauth2
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 附录 B 的例子
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+	verifier, err := NewCodeVerifier()
+	require.NoError(t, err)
+	assert.Len(t, verifier, 43)
+}
@@ -0,0 +1,50 @@
+package oauth2
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"sort"
+)
+
+// Provider 第三方登录的提供方
+//
+//go:generate mockgen.exe -source=./provider.go -package=oauth2mocks -destination=mocks/provider.mock.go Provider
+type Provider interface {
+	// Name 提供方的名字，也就是路由 /oauth2/:provider 里面的 provider
+	Name() string
+	// AuthURL 跳转到第三方授权页面的地址
+	AuthURL(ctx context.Context, state string) (string, error)
+	// VerifyCode 用回调里面的 code 换取第三方账号信息
+	VerifyCode(ctx context.Context, code string) (domain.UserIdentity, error)
+}
+
+// Registry 已经启用的提供方
+type Registry struct {
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{
+		providers: make(map[string]Provider, len(providers)),
+	}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// Get 没有启用的提供方返回 false
+func (r *Registry) Get(name string) (Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Names 已经启用的提供方，按名字排序
+func (r *Registry) Names() []string {
+	res := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,137 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"net/http"
+	"net/url"
+)
+
+var ErrMiniDecrypt = errors.New("小程序加密数据解密失败")
+
+// MiniService 微信小程序登录，和网站应用是不同的 appid
+//
+//go:generate mockgen.exe -source=./mini.go -package=wechatmocks -destination=mocks/mini.mock.go MiniService
+type MiniService interface {
+	// Code2Session 用 wx.login 拿到的 code 换取 openid 和 session_key
+	Code2Session(ctx context.Context, jsCode string) (domain.WechatMiniSession, error)
+	// DecryptPhone 解密 getPhoneNumber 拿到的加密数据，返回手机号
+	DecryptPhone(sessionKey, encryptedData, iv string) (string, error)
+}
+
+type miniService struct {
+	appId     string
+	appSecret string
+	client    *http.Client
+	// 测试的时候替换成 httptest 的地址
+	apiBase string
+}
+
+func NewMiniService(appId string, appSecret string) MiniService {
+	return &miniService{
+		appId:     appId,
+		appSecret: appSecret,
+		client:    http.DefaultClient,
+		apiBase:   "https://api.weixin.qq.com",
+	}
+}
+
+func (s *miniService) Code2Session(ctx context.Context, jsCode string) (domain.WechatMiniSession, error) {
+	const targetPattern = "%s/sns/jscode2session?appid=%s&secret=%s&js_code=%s&grant_type=authorization_code"
+	target := fmt.Sprintf(targetPattern, s.apiBase, s.appId, s.appSecret, url.QueryEscape(jsCode))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return domain.WechatMiniSession{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return domain.WechatMiniSession{}, err
+	}
+	defer resp.Body.Close()
+	var res MiniSessionResult
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return domain.WechatMiniSession{}, err
+	}
+	if res.ErrCode != 0 {
+		return domain.WechatMiniSession{}, fmt.Errorf("微信返回错误响应，错误码%d,错误信息%s", res.ErrCode, res.ErrMsg)
+	}
+	return domain.WechatMiniSession{
+		OpenId:     res.OpenId,
+		UnionId:    res.UnionId,
+		SessionKey: res.SessionKey,
+	}, nil
+}
+
+// DecryptPhone AES-128-CBC，密钥是 session_key，数据、密钥和 iv 都是 base64 编码
+// 解密之后还要校验 watermark 里面的 appid，防止拿别的小程序的数据来绑定
+func (s *miniService) DecryptPhone(sessionKey, encryptedData, iv string) (string, error) {
+	plain, err := decrypt(sessionKey, encryptedData, iv)
+	if err != nil {
+		return "", err
+	}
+	var res MiniPhoneResult
+	if err = json.Unmarshal(plain, &res); err != nil {
+		return "", ErrMiniDecrypt
+	}
+	if res.Watermark.AppId != s.appId {
+		return "", ErrMiniDecrypt
+	}
+	if res.PurePhoneNumber != "" {
+		return res.PurePhoneNumber, nil
+	}
+	return res.PhoneNumber, nil
+}
+
+func decrypt(sessionKey, encryptedData, iv string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil {
+		return nil, ErrMiniDecrypt
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil || len(ivBytes) != aes.BlockSize {
+		return nil, ErrMiniDecrypt
+	}
+	data, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, ErrMiniDecrypt
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, ErrMiniDecrypt
+	}
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plain, data)
+	// PKCS#7 去掉填充
+	n := int(plain[len(plain)-1])
+	if n == 0 || n > aes.BlockSize || !bytes.Equal(plain[len(plain)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, ErrMiniDecrypt
+	}
+	return plain[:len(plain)-n], nil
+}
+
+type MiniSessionResult struct {
+	// 错误返回
+	ErrCode int64  `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+
+	OpenId     string `json:"openid"`
+	UnionId    string `json:"unionid"`
+	SessionKey string `json:"session_key"`
+}
+
+type MiniPhoneResult struct {
+	PhoneNumber     string `json:"phoneNumber"`
+	PurePhoneNumber string `json:"purePhoneNumber"`
+	CountryCode     string `json:"countryCode"`
+	Watermark       struct {
+		AppId     string `json:"appid"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"watermark"`
+}
@@ -0,0 +1,120 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiniService_Code2Session(t *testing.T) {
+	testCase := []struct {
+		name string
+		resp string
+		// 输出
+		wantSession domain.WechatMiniSession
+		wantErr     bool
+	}{
+		{
+			name: "换取成功",
+			resp: `{"openid":"mini_openid","session_key":"c2Vzc2lvbmtleQ==","unionid":"unionid"}`,
+			wantSession: domain.WechatMiniSession{
+				OpenId:     "mini_openid",
+				UnionId:    "unionid",
+				SessionKey: "c2Vzc2lvbmtleQ==",
+			},
+		},
+		{
+			name:    "code 无效",
+			resp:    `{"errcode":40029,"errmsg":"invalid code"}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/sns/jscode2session", r.URL.Path)
+				assert.Equal(t, "appid", r.URL.Query().Get("appid"))
+				assert.Equal(t, "secret", r.URL.Query().Get("secret"))
+				assert.Equal(t, "jscode", r.URL.Query().Get("js_code"))
+				assert.Equal(t, "authorization_code", r.URL.Query().Get("grant_type"))
+				_, _ = w.Write([]byte(tc.resp))
+			}))
+			defer srv.Close()
+			svc := NewMiniService("appid", "secret").(*miniService)
+			svc.apiBase = srv.URL
+			session, err := svc.Code2Session(context.Background(), "jscode")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantSession, session)
+		})
+	}
+}
+
+func TestMiniService_DecryptPhone(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	iv := []byte("fedcba9876543210")
+	sessionKey := base64.StdEncoding.EncodeToString(key)
+	ivStr := base64.StdEncoding.EncodeToString(iv)
+	testCase := []struct {
+		name       string
+		sessionKey string
+		data       string
+		// 输出
+		wantPhone string
+		wantErr   error
+	}{
+		{
+			name:       "解密成功",
+			sessionKey: sessionKey,
+			data:       encryptForTest(t, key, iv, `{"phoneNumber":"+86 13800138000","purePhoneNumber":"13800138000","countryCode":"86","watermark":{"appid":"appid","timestamp":1700000000}}`),
+			wantPhone:  "13800138000",
+		},
+		{
+			name:       "别的小程序的数据",
+			sessionKey: sessionKey,
+			data:       encryptForTest(t, key, iv, `{"purePhoneNumber":"13800138000","watermark":{"appid":"other"}}`),
+			wantErr:    ErrMiniDecrypt,
+		},
+		{
+			name:       "session_key 不对",
+			sessionKey: base64.StdEncoding.EncodeToString([]byte("abcdef0123456789")),
+			data:       encryptForTest(t, key, iv, `{"purePhoneNumber":"13800138000","watermark":{"appid":"appid"}}`),
+			wantErr:    ErrMiniDecrypt,
+		},
+		{
+			name:       "数据不是 base64",
+			sessionKey: sessionKey,
+			data:       "not base64!",
+			wantErr:    ErrMiniDecrypt,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := NewMiniService("appid", "secret")
+			phone, err := svc.DecryptPhone(tc.sessionKey, tc.data, ivStr)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantPhone, phone)
+		})
+	}
+}
+
+// encryptForTest 按照微信的方式加密，AES-128-CBC + PKCS#7
+func encryptForTest(t *testing.T, key, iv []byte, plain string) string {
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	n := aes.BlockSize - len(plain)%aes.BlockSize
+	data := append([]byte(plain), bytes.Repeat([]byte{byte(n)}, n)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return base64.StdEncoding.EncodeToString(data)
+}
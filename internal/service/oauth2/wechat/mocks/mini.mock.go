@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./mini.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./mini.go -package=wechatmocks -destination=mocks/mini.mock.go MiniService
+//
+
+// Package wechatmocks is a generated GoMock package.
+package wechatmocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	domain "github.com/dadaxiaoxiao/user/internal/domain"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMiniService is a mock of MiniService interface.
+type MockMiniService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMiniServiceMockRecorder
+}
+
+// MockMiniServiceMockRecorder is the mock recorder for MockMiniService.
+type MockMiniServiceMockRecorder struct {
+	mock *MockMiniService
+}
+
+// NewMockMiniService creates a new mock instance.
+func NewMockMiniService(ctrl *gomock.Controller) *MockMiniService {
+	mock := &MockMiniService{ctrl: ctrl}
+	mock.recorder = &MockMiniServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMiniService) EXPECT() *MockMiniServiceMockRecorder {
+	return m.recorder
+}
+
+// Code2Session mocks base method.
+func (m *MockMiniService) Code2Session(ctx context.Context, jsCode string) (domain.WechatMiniSession, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Code2Session", ctx, jsCode)
+	ret0, _ := ret[0].(domain.WechatMiniSession)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Code2Session indicates an expected call of Code2Session.
+func (mr *MockMiniServiceMockRecorder) Code2Session(ctx, jsCode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Code2Session", reflect.TypeOf((*MockMiniService)(nil).Code2Session), ctx, jsCode)
+}
+
+// DecryptPhone mocks base method.
+func (m *MockMiniService) DecryptPhone(sessionKey, encryptedData, iv string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecryptPhone", sessionKey, encryptedData, iv)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecryptPhone indicates an expected call of DecryptPhone.
+func (mr *MockMiniServiceMockRecorder) DecryptPhone(sessionKey, encryptedData, iv any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecryptPhone", reflect.TypeOf((*MockMiniService)(nil).DecryptPhone), sessionKey, encryptedData, iv)
+}
@@ -41,7 +41,7 @@ func NewPrometheusDecorator(svc Service,
 }
 
 // VerifyCode 因为 AuthURL 过于简单，没有监控的必要
-func (p *PrometheusDecorator) VerifyCode(ctx context.Context, code string) (domain.WechatInfo, error) {
+func (p *PrometheusDecorator) VerifyCode(ctx context.Context, code string) (domain.WechatToken, error) {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
@@ -0,0 +1,60 @@
+package wechat
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2"
+)
+
+// Provider 把 Service 适配成 oauth2.Provider，登录的时候顺便同步昵称和头像
+type Provider struct {
+	svc  Service
+	repo repository.WechatTokenRepository
+	log  accesslog.Logger
+}
+
+func NewProvider(svc Service, repo repository.WechatTokenRepository, log accesslog.Logger) oauth2.Provider {
+	return &Provider{
+		svc:  svc,
+		repo: repo,
+		log:  log,
+	}
+}
+
+func (p *Provider) Name() string {
+	return domain.ProviderWechat
+}
+
+func (p *Provider) AuthURL(ctx context.Context, state string) (string, error) {
+	return p.svc.AuthURL(ctx, state)
+}
+
+// VerifyCode 以 openid 作为 Subject，和 users 表里面已有的 wechat_open_id 保持一致
+// 登录和绑定都会走到这里，用刚换到的 token 获取个人信息，保存 token 和获取个人信息失败都不影响登录
+func (p *Provider) VerifyCode(ctx context.Context, code string) (domain.UserIdentity, error) {
+	token, err := p.svc.VerifyCode(ctx, code)
+	if err != nil {
+		return domain.UserIdentity{}, err
+	}
+	identity := domain.UserIdentity{
+		Provider: domain.ProviderWechat,
+		Subject:  token.OpenId,
+		UnionId:  token.UnionId,
+	}
+	if err = p.repo.Save(ctx, token); err != nil {
+		p.log.Error("保存微信 token 失败", accesslog.String("openid", token.OpenId), accesslog.Error(err))
+	}
+	profile, err := p.svc.UserInfo(ctx, token)
+	if err != nil {
+		p.log.Warn("获取微信个人信息失败", accesslog.String("openid", token.OpenId), accesslog.Error(err))
+		return identity, nil
+	}
+	identity.Nickname = profile.Nickname
+	identity.Avatar = profile.Avatar
+	if identity.UnionId == "" {
+		identity.UnionId = profile.UnionId
+	}
+	return identity, nil
+}
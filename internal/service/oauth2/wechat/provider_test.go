@@ -0,0 +1,103 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProvider_VerifyCode(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.WechatTokenRepository
+		// 微信的响应
+		tokenResp    string
+		userInfoResp string
+		// 输出
+		wantIdentity domain.UserIdentity
+		wantErr      bool
+	}{
+		{
+			name: "换取成功，同步昵称和头像",
+			mock: func(ctrl *gomock.Controller) repository.WechatTokenRepository {
+				repo := repomocks.NewMockWechatTokenRepository(ctrl)
+				repo.EXPECT().Save(gomock.Any(), gomock.Cond(func(x any) bool {
+					token := x.(domain.WechatToken)
+					return token.OpenId == "openid" && token.AccessToken == "token" && token.RefreshToken == "refresh"
+				})).Return(nil)
+				return repo
+			},
+			tokenResp:    `{"access_token":"token","expires_in":7200,"refresh_token":"refresh","openid":"openid","unionid":"unionid","scope":"snsapi_login"}`,
+			userInfoResp: `{"openid":"openid","nickname":"叶钦","headimgurl":"https://thirdwx.qlogo.cn/avatar","unionid":"unionid"}`,
+			wantIdentity: domain.UserIdentity{
+				Provider: domain.ProviderWechat,
+				Subject:  "openid",
+				UnionId:  "unionid",
+				Nickname: "叶钦",
+				Avatar:   "https://thirdwx.qlogo.cn/avatar",
+			},
+		},
+		{
+			name: "获取个人信息和保存 token 失败，不影响登录",
+			mock: func(ctrl *gomock.Controller) repository.WechatTokenRepository {
+				repo := repomocks.NewMockWechatTokenRepository(ctrl)
+				repo.EXPECT().Save(gomock.Any(), gomock.Any()).Return(errors.New("db 异常"))
+				return repo
+			},
+			tokenResp:    `{"access_token":"token","expires_in":7200,"refresh_token":"refresh","openid":"openid","unionid":"unionid"}`,
+			userInfoResp: `{"errcode":40003,"errmsg":"invalid openid"}`,
+			wantIdentity: domain.UserIdentity{
+				Provider: domain.ProviderWechat,
+				Subject:  "openid",
+				UnionId:  "unionid",
+			},
+		},
+		{
+			name: "code 无效",
+			mock: func(ctrl *gomock.Controller) repository.WechatTokenRepository {
+				return repomocks.NewMockWechatTokenRepository(ctrl)
+			},
+			tokenResp: `{"errcode":40029,"errmsg":"invalid code"}`,
+			wantErr:   true,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mux := http.NewServeMux()
+			mux.HandleFunc("/sns/oauth2/access_token", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "appid", r.URL.Query().Get("appid"))
+				assert.Equal(t, "secret", r.URL.Query().Get("secret"))
+				assert.Equal(t, "code", r.URL.Query().Get("code"))
+				_, _ = w.Write([]byte(tc.tokenResp))
+			})
+			mux.HandleFunc("/sns/userinfo", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "token", r.URL.Query().Get("access_token"))
+				assert.Equal(t, "openid", r.URL.Query().Get("openid"))
+				_, _ = w.Write([]byte(tc.userInfoResp))
+			})
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+			svc := Newservice("appid", "secret").(*service)
+			svc.apiBase = srv.URL
+			p := NewProvider(svc, tc.mock(ctrl), accesslog.NewNopLogger())
+			identity, err := p.VerifyCode(context.Background(), "code")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantIdentity, identity)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/dadaxiaoxiao/user/internal/domain"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 var redirectURI = url.PathEscape("https://qinyeyiyi.cn/oauth2/wechat/callback")
@@ -15,6 +16,9 @@ type service struct {
 	appId     string
 	appSecret string
 	client    *http.Client
+	// 测试的时候替换成 httptest 的地址
+	openBase string
+	apiBase  string
 }
 
 func Newservice(appid string, appSecret string) Service {
@@ -22,46 +26,81 @@ func Newservice(appid string, appSecret string) Service {
 		appId:     appid,
 		appSecret: appSecret,
 		client:    http.DefaultClient,
+		openBase:  "https://open.weixin.qq.com",
+		apiBase:   "https://api.weixin.qq.com",
 	}
 }
 
 func (s *service) AuthURL(ctx context.Context, state string) (string, error) {
-	const urlPattern = "https://open.weixin.qq.com/connect/qrconnect?appid=%s&redirect_uri=%s&response_type=code&scope=snsapi_login&state=%s#wechat_redirect"
-	return fmt.Sprintf(urlPattern, s.appId, redirectURI, state), nil
+	const urlPattern = "%s/connect/qrconnect?appid=%s&redirect_uri=%s&response_type=code&scope=snsapi_login&state=%s#wechat_redirect"
+	return fmt.Sprintf(urlPattern, s.openBase, s.appId, redirectURI, state), nil
 }
 
-func (s *service) VerifyCode(ctx context.Context, code string) (domain.WechatInfo, error) {
-	const targetPattern = "https://api.weixin.qq.com/sns/oauth2/access_token?appid=%s&secret=%s&code=%s&grant_type=authorization_code"
-	target := fmt.Sprintf(targetPattern, s.appId, s.appSecret, code)
+func (s *service) VerifyCode(ctx context.Context, code string) (domain.WechatToken, error) {
+	const targetPattern = "%s/sns/oauth2/access_token?appid=%s&secret=%s&code=%s&grant_type=authorization_code"
+	target := fmt.Sprintf(targetPattern, s.apiBase, s.appId, s.appSecret, url.QueryEscape(code))
+	return s.token(ctx, target)
+}
+
+func (s *service) RefreshToken(ctx context.Context, refreshToken string) (domain.WechatToken, error) {
+	const targetPattern = "%s/sns/oauth2/refresh_token?appid=%s&grant_type=refresh_token&refresh_token=%s"
+	target := fmt.Sprintf(targetPattern, s.apiBase, s.appId, url.QueryEscape(refreshToken))
+	return s.token(ctx, target)
+}
+
+func (s *service) UserInfo(ctx context.Context, token domain.WechatToken) (domain.WechatProfile, error) {
+	const targetPattern = "%s/sns/userinfo?access_token=%s&openid=%s&lang=zh_CN"
+	target := fmt.Sprintf(targetPattern, s.apiBase, url.QueryEscape(token.AccessToken), url.QueryEscape(token.OpenId))
+	var res UserInfoResult
+	if err := s.get(ctx, target, &res); err != nil {
+		return domain.WechatProfile{}, err
+	}
+	if res.ErrCode != 0 {
+		return domain.WechatProfile{}, fmt.Errorf("微信返回错误响应，错误码%d,错误信息%s", res.ErrCode, res.ErrMsg)
+	}
+	return domain.WechatProfile{
+		OpenId:   res.OpenId,
+		UnionId:  res.UnionId,
+		Nickname: res.Nickname,
+		Avatar:   res.HeadImgURL,
+	}, nil
+}
+
+// token 换取和刷新 token 的响应是一样的
+func (s *service) token(ctx context.Context, target string) (domain.WechatToken, error) {
+	var res Result
+	if err := s.get(ctx, target, &res); err != nil {
+		return domain.WechatToken{}, err
+	}
+	if res.ErrCode != 0 {
+		// 错误返回
+		return domain.WechatToken{}, fmt.Errorf("微信返回错误响应，错误码%d,错误信息%s", res.ErrCode, res.ErrMsg)
+	}
+	return domain.WechatToken{
+		OpenId:       res.OpenId,
+		UnionId:      res.UnionId,
+		AccessToken:  res.AccessToken,
+		RefreshToken: res.RefreshToken,
+		Scope:        res.Scope,
+		ExpiresAt:    time.Now().Add(time.Duration(res.ExpiresIn) * time.Second),
+	}, nil
+}
 
+// get 微信的接口出错的时候状态码也是 200，需要调用方检查 errcode
+func (s *service) get(ctx context.Context, target string, val any) error {
 	// 构建请求
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
-		return domain.WechatInfo{}, err
+		return err
 	}
 
 	// 发送请求
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return domain.WechatInfo{}, err
+		return err
 	}
 	defer resp.Body.Close()
-
-	var res Result
-	err = json.NewDecoder(resp.Body).Decode(&res)
-	if err != nil {
-		return domain.WechatInfo{}, err
-	}
-
-	if res.ErrCode != 0 {
-		// 错误返回
-		return domain.WechatInfo{}, fmt.Errorf("微信返回错误响应，错误码%d,错误信息%s", res.ErrCode, res.ErrMsg)
-	}
-
-	return domain.WechatInfo{
-		OpenId:  res.OpenId,
-		UnionId: res.UnionId,
-	}, err
+	return json.NewDecoder(resp.Body).Decode(val)
 }
 
 type Result struct {
@@ -77,3 +116,14 @@ type Result struct {
 	Scope   string `json:"scope"`
 	UnionId string `json:"unionid"`
 }
+
+type UserInfoResult struct {
+	// 错误返回
+	ErrCode int64  `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+
+	OpenId     string `json:"openid"`
+	UnionId    string `json:"unionid"`
+	Nickname   string `json:"nickname"`
+	HeadImgURL string `json:"headimgurl"`
+}
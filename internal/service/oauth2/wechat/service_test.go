@@ -2,7 +2,10 @@ package wechat
 
 import (
 	"context"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -22,3 +25,20 @@ func Test_service_VerifyCode(t *testing.T) {
 	require.NoError(t, err)
 	t.Log(res)
 }
+
+func Test_service_RefreshToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/sns/oauth2/refresh_token", r.URL.Path)
+		assert.Equal(t, "refresh_token", r.URL.Query().Get("grant_type"))
+		assert.Equal(t, "refresh", r.URL.Query().Get("refresh_token"))
+		_, _ = w.Write([]byte(`{"access_token":"token","expires_in":7200,"refresh_token":"refresh","openid":"openid","scope":"snsapi_login"}`))
+	}))
+	defer srv.Close()
+	svc := Newservice("appid", "secret").(*service)
+	svc.apiBase = srv.URL
+	token, err := svc.RefreshToken(context.Background(), "refresh")
+	require.NoError(t, err)
+	assert.Equal(t, "openid", token.OpenId)
+	assert.Equal(t, "token", token.AccessToken)
+	assert.Equal(t, "refresh", token.RefreshToken)
+}
@@ -7,5 +7,10 @@ import (
 
 type Service interface {
 	AuthURL(ctx context.Context, state string) (string, error)
-	VerifyCode(ctx context.Context, code string) (domain.WechatInfo, error)
+	// VerifyCode 用 code 换取 token，token 里面有 openid 和 unionid
+	VerifyCode(ctx context.Context, code string) (domain.WechatToken, error)
+	// UserInfo 调用 sns/userinfo 获取昵称和头像，需要 access_token 还没有过期
+	UserInfo(ctx context.Context, token domain.WechatToken) (domain.WechatProfile, error)
+	// RefreshToken access_token 过期之后用 refresh_token 刷新，refresh_token 过期之后只能重新授权
+	RefreshToken(ctx context.Context, refreshToken string) (domain.WechatToken, error)
 }
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OIDCError OAuth2 / OIDC 协议规定的错误，Code 会原样返回给 RP
+type OIDCError struct {
+	Code        string
+	Description string
+}
+
+func (e *OIDCError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+var (
+	ErrOIDCInvalidClient      = &OIDCError{Code: "invalid_client", Description: "应用不存在或者认证失败"}
+	ErrOIDCInvalidRedirectURI = &OIDCError{Code: "invalid_request", Description: "redirect_uri 没有注册"}
+	ErrOIDCUnsupportedType    = &OIDCError{Code: "unsupported_response_type", Description: "只支持 code"}
+	ErrOIDCInvalidScope       = &OIDCError{Code: "invalid_scope", Description: "scope 必须包含 openid"}
+	ErrOIDCPKCERequired       = &OIDCError{Code: "invalid_request", Description: "公开客户端必须使用 PKCE（S256）"}
+	ErrOIDCConsentRequired    = &OIDCError{Code: "consent_required", Description: "需要用户同意授权"}
+	ErrOIDCAccessDenied       = &OIDCError{Code: "access_denied", Description: "用户拒绝授权"}
+	ErrOIDCInvalidGrant       = &OIDCError{Code: "invalid_grant", Description: "授权码无效、已经使用或者和请求不匹配"}
+	// ErrOIDCInvalidClientMetadata 注册应用的时候参数不对，RFC 7591
+	ErrOIDCInvalidClientMetadata = &OIDCError{Code: "invalid_client_metadata", Description: "应用名字不能为空，回调地址必须是不带 fragment 的绝对地址"}
+)
+
+const (
+	ScopeOpenId  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+	ScopePhone   = "phone"
+)
+
+// SupportedScopes 支持的 scope，其它的会被忽略
+var SupportedScopes = []string{ScopeOpenId, ScopeProfile, ScopeEmail, ScopePhone}
+
+// AuthorizeRequest /authorize 的参数
+type AuthorizeRequest struct {
+	ClientId            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OIDCService OIDC 授权码模式
+// 登录本身复用现有的密码、短信、微信登录，这里只处理授权和换 token
+//
+//go:generate mockgen.exe -source=./oidc.go -package=svcmocks -destination=mocks/oidc.mock.go OIDCService
+type OIDCService interface {
+	// RegisterClient 注册应用，返回 client_secret 明文，只有这一次机会拿到
+	RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (domain.OAuthClient, string, error)
+	// ValidateAuthorize 校验授权请求，返回应用和生效的 scope
+	// 返回的错误不是 ErrOIDCInvalidClient 和 ErrOIDCInvalidRedirectURI 的时候，可以回调给 RP
+	ValidateAuthorize(ctx context.Context, req AuthorizeRequest) (domain.OAuthClient, []string, error)
+	// Authorize 用户已经登录，签发授权码。没有授权记录并且 approve 为 false 的时候返回 ErrOIDCConsentRequired
+	Authorize(ctx context.Context, uid int64, req AuthorizeRequest, approve bool) (string, error)
+	// Exchange 用授权码换 token，返回授权码里面的信息
+	Exchange(ctx context.Context, clientId, clientSecret, code, redirectURI, codeVerifier string) (domain.AuthCode, error)
+	// AuthenticateClient 校验 client_id 和 client_secret，公开客户端没有 secret，同样返回 ErrOIDCInvalidClient
+	AuthenticateClient(ctx context.Context, clientId, clientSecret string) (domain.OAuthClient, error)
+}
+
+type oidcService struct {
+	repo repository.OIDCRepository
+	now  func() time.Time
+}
+
+func NewOIDCService(repo repository.OIDCRepository) OIDCService {
+	return &oidcService{
+		repo: repo,
+		now:  time.Now,
+	}
+}
+
+func (svc *oidcService) RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (domain.OAuthClient, string, error) {
+	if !svc.validClientMetadata(name, redirectURIs) {
+		return domain.OAuthClient{}, "", ErrOIDCInvalidClientMetadata
+	}
+	clientId, err := randomString(16)
+	if err != nil {
+		return domain.OAuthClient{}, "", err
+	}
+	c := domain.OAuthClient{
+		ClientId:     clientId,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Public:       public,
+	}
+	var secret string
+	if !public {
+		secret, err = randomString(32)
+		if err != nil {
+			return domain.OAuthClient{}, "", err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			return domain.OAuthClient{}, "", err
+		}
+		c.SecretHash = string(hash)
+	}
+	return c, secret, svc.repo.CreateClient(ctx, c)
+}
+
+func (svc *oidcService) ValidateAuthorize(ctx context.Context, req AuthorizeRequest) (domain.OAuthClient, []string, error) {
+	c, err := svc.repo.FindClient(ctx, req.ClientId)
+	if err == repository.ErrOAuthClientNotFound {
+		return domain.OAuthClient{}, nil, ErrOIDCInvalidClient
+	}
+	if err != nil {
+		return domain.OAuthClient{}, nil, err
+	}
+	if !c.AllowRedirect(req.RedirectURI) {
+		return domain.OAuthClient{}, nil, ErrOIDCInvalidRedirectURI
+	}
+	if req.ResponseType != "code" {
+		return c, nil, ErrOIDCUnsupportedType
+	}
+	scopes := svc.scopes(req.Scope)
+	if !slices.Contains(scopes, ScopeOpenId) {
+		return c, nil, ErrOIDCInvalidScope
+	}
+	switch {
+	case req.CodeChallenge != "" && req.CodeChallengeMethod != "S256":
+		// 不支持 plain
+		return c, nil, ErrOIDCPKCERequired
+	case req.CodeChallenge == "" && c.Public:
+		return c, nil, ErrOIDCPKCERequired
+	}
+	return c, scopes, nil
+}
+
+func (svc *oidcService) Authorize(ctx context.Context, uid int64, req AuthorizeRequest, approve bool) (string, error) {
+	_, scopes, err := svc.ValidateAuthorize(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	consent, err := svc.repo.FindConsent(ctx, uid, req.ClientId)
+	if err != nil && err != repository.ErrOAuthConsentNotFound {
+		return "", err
+	}
+	// 没有授权记录的时候 consent 是零值，不会覆盖 openid
+	if !consent.Covers(scopes) {
+		if !approve {
+			return "", ErrOIDCConsentRequired
+		}
+		err = svc.repo.SaveConsent(ctx, domain.OAuthConsent{
+			Uid:      uid,
+			ClientId: req.ClientId,
+			Scopes:   scopes,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	code, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	return code, svc.repo.StoreCode(ctx, code, domain.AuthCode{
+		ClientId:            req.ClientId,
+		Uid:                 uid,
+		RedirectURI:         req.RedirectURI,
+		Scopes:              scopes,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            svc.now(),
+	})
+}
+
+func (svc *oidcService) Exchange(ctx context.Context, clientId, clientSecret, code, redirectURI, codeVerifier string) (domain.AuthCode, error) {
+	c, err := svc.repo.FindClient(ctx, clientId)
+	if err == repository.ErrOAuthClientNotFound {
+		return domain.AuthCode{}, ErrOIDCInvalidClient
+	}
+	if err != nil {
+		return domain.AuthCode{}, err
+	}
+	if !c.Public && bcrypt.CompareHashAndPassword([]byte(c.SecretHash), []byte(clientSecret)) != nil {
+		return domain.AuthCode{}, ErrOIDCInvalidClient
+	}
+
+	// 先删掉授权码，不管后面校验是否通过，授权码都不能再用
+	ac, err := svc.repo.TakeCode(ctx, code)
+	if err == repository.ErrAuthCodeNotFound {
+		return domain.AuthCode{}, ErrOIDCInvalidGrant
+	}
+	if err != nil {
+		return domain.AuthCode{}, err
+	}
+	if ac.ClientId != clientId || ac.RedirectURI != redirectURI {
+		return domain.AuthCode{}, ErrOIDCInvalidGrant
+	}
+	if ac.CodeChallenge != "" && !svc.verifyPKCE(ac.CodeChallenge, codeVerifier) {
+		return domain.AuthCode{}, ErrOIDCInvalidGrant
+	}
+	return ac, nil
+}
+
+func (svc *oidcService) AuthenticateClient(ctx context.Context, clientId, clientSecret string) (domain.OAuthClient, error) {
+	c, err := svc.repo.FindClient(ctx, clientId)
+	if err == repository.ErrOAuthClientNotFound {
+		return domain.OAuthClient{}, ErrOIDCInvalidClient
+	}
+	if err != nil {
+		return domain.OAuthClient{}, err
+	}
+	if c.Public || bcrypt.CompareHashAndPassword([]byte(c.SecretHash), []byte(clientSecret)) != nil {
+		return domain.OAuthClient{}, ErrOIDCInvalidClient
+	}
+	return c, nil
+}
+
+func (svc *oidcService) validClientMetadata(name string, redirectURIs []string) bool {
+	if strings.TrimSpace(name) == "" || len(redirectURIs) == 0 {
+		return false
+	}
+	for _, uri := range redirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// scopes 去掉不支持的和重复的
+func (svc *oidcService) scopes(scope string) []string {
+	res := make([]string, 0, len(SupportedScopes))
+	for _, s := range strings.Fields(scope) {
+		if slices.Contains(SupportedScopes, s) && !slices.Contains(res, s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// verifyPKCE S256: BASE64URL(SHA256(code_verifier)) == code_challenge
+func (svc *oidcService) verifyPKCE(challenge string, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// IsOIDCError 是否为协议规定的错误
+func IsOIDCError(err error) (*OIDCError, bool) {
+	var oe *OIDCError
+	ok := errors.As(err, &oe)
+	return oe, ok
+}
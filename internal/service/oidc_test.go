@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+	"time"
+)
+
+func Test_oidcService_Authorize(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	client := domain.OAuthClient{
+		ClientId:     "client",
+		Name:         "论坛",
+		RedirectURIs: []string{"https://rp.example.com/callback"},
+	}
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.OIDCRepository
+		// 输入
+		req     AuthorizeRequest
+		approve bool
+		// 输出
+		wantCode bool
+		wantErr  error
+	}{
+		{
+			name: "已经授权过，直接签发授权码",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				repo.EXPECT().FindConsent(gomock.Any(), int64(1), "client").
+					Return(domain.OAuthConsent{Uid: 1, ClientId: "client", Scopes: []string{"openid", "email"}}, nil)
+				repo.EXPECT().StoreCode(gomock.Any(), gomock.Any(), domain.AuthCode{
+					ClientId:    "client",
+					Uid:         1,
+					RedirectURI: "https://rp.example.com/callback",
+					// 不支持的 scope 被忽略
+					Scopes:   []string{"openid", "email"},
+					Nonce:    "nonce",
+					AuthTime: now,
+				}).Return(nil)
+				return repo
+			},
+			req: AuthorizeRequest{
+				ClientId:     "client",
+				RedirectURI:  "https://rp.example.com/callback",
+				ResponseType: "code",
+				Scope:        "openid email address",
+				Nonce:        "nonce",
+			},
+			wantCode: true,
+		},
+		{
+			name: "没有授权过，需要用户同意",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				repo.EXPECT().FindConsent(gomock.Any(), int64(1), "client").
+					Return(domain.OAuthConsent{}, repository.ErrOAuthConsentNotFound)
+				return repo
+			},
+			req: AuthorizeRequest{
+				ClientId:     "client",
+				RedirectURI:  "https://rp.example.com/callback",
+				ResponseType: "code",
+				Scope:        "openid",
+			},
+			wantErr: ErrOIDCConsentRequired,
+		},
+		{
+			name: "新增了 scope，用户同意之后保存",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				repo.EXPECT().FindConsent(gomock.Any(), int64(1), "client").
+					Return(domain.OAuthConsent{Uid: 1, ClientId: "client", Scopes: []string{"openid"}}, nil)
+				repo.EXPECT().SaveConsent(gomock.Any(), domain.OAuthConsent{
+					Uid: 1, ClientId: "client", Scopes: []string{"openid", "phone"},
+				}).Return(nil)
+				repo.EXPECT().StoreCode(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				return repo
+			},
+			req: AuthorizeRequest{
+				ClientId:     "client",
+				RedirectURI:  "https://rp.example.com/callback",
+				ResponseType: "code",
+				Scope:        "openid phone",
+			},
+			approve:  true,
+			wantCode: true,
+		},
+		{
+			name: "回调地址没有注册",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				return repo
+			},
+			req: AuthorizeRequest{
+				ClientId:     "client",
+				RedirectURI:  "https://evil.example.com/callback",
+				ResponseType: "code",
+				Scope:        "openid",
+			},
+			wantErr: ErrOIDCInvalidRedirectURI,
+		},
+		{
+			name: "公开客户端必须使用 PKCE",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				public := client
+				public.Public = true
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(public, nil)
+				return repo
+			},
+			req: AuthorizeRequest{
+				ClientId:     "client",
+				RedirectURI:  "https://rp.example.com/callback",
+				ResponseType: "code",
+				Scope:        "openid",
+			},
+			wantErr: ErrOIDCPKCERequired,
+		},
+		{
+			name: "没有 openid",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				return repo
+			},
+			req: AuthorizeRequest{
+				ClientId:     "client",
+				RedirectURI:  "https://rp.example.com/callback",
+				ResponseType: "code",
+				Scope:        "email",
+			},
+			wantErr: ErrOIDCInvalidScope,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewOIDCService(tc.mock(ctrl)).(*oidcService)
+			svc.now = func() time.Time { return now }
+			code, err := svc.Authorize(context.Background(), 1, tc.req, tc.approve)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCode, code != "")
+		})
+	}
+}
+
+func Test_oidcService_Exchange(t *testing.T) {
+	secretHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	client := domain.OAuthClient{
+		ClientId:     "client",
+		SecretHash:   string(secretHash),
+		RedirectURIs: []string{"https://rp.example.com/callback"},
+	}
+	// RFC 7636 附录 B 的例子
+	const (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+	ac := domain.AuthCode{
+		ClientId:            "client",
+		Uid:                 1,
+		RedirectURI:         "https://rp.example.com/callback",
+		Scopes:              []string{"openid"},
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	}
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.OIDCRepository
+		// 输入
+		secret      string
+		redirectURI string
+		verifier    string
+		// 输出
+		wantCode domain.AuthCode
+		wantErr  error
+	}{
+		{
+			name: "换取成功",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				repo.EXPECT().TakeCode(gomock.Any(), "code").Return(ac, nil)
+				return repo
+			},
+			secret:      "secret",
+			redirectURI: "https://rp.example.com/callback",
+			verifier:    verifier,
+			wantCode:    ac,
+		},
+		{
+			name: "secret 错误",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				return repo
+			},
+			secret:      "wrong",
+			redirectURI: "https://rp.example.com/callback",
+			verifier:    verifier,
+			wantErr:     ErrOIDCInvalidClient,
+		},
+		{
+			name: "code_verifier 错误",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				repo.EXPECT().TakeCode(gomock.Any(), "code").Return(ac, nil)
+				return repo
+			},
+			secret:      "secret",
+			redirectURI: "https://rp.example.com/callback",
+			verifier:    "wrong",
+			wantErr:     ErrOIDCInvalidGrant,
+		},
+		{
+			name: "redirect_uri 和授权的时候不一样",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				repo.EXPECT().TakeCode(gomock.Any(), "code").Return(ac, nil)
+				return repo
+			},
+			secret:      "secret",
+			redirectURI: "https://rp.example.com/other",
+			verifier:    verifier,
+			wantErr:     ErrOIDCInvalidGrant,
+		},
+		{
+			name: "授权码已经用过了",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "client").Return(client, nil)
+				repo.EXPECT().TakeCode(gomock.Any(), "code").
+					Return(domain.AuthCode{}, repository.ErrAuthCodeNotFound)
+				return repo
+			},
+			secret:      "secret",
+			redirectURI: "https://rp.example.com/callback",
+			verifier:    verifier,
+			wantErr:     ErrOIDCInvalidGrant,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewOIDCService(tc.mock(ctrl))
+			code, err := svc.Exchange(context.Background(), "client", tc.secret, "code", tc.redirectURI, tc.verifier)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCode, code)
+		})
+	}
+}
+
+func Test_oidcService_AuthenticateClient(t *testing.T) {
+	secretHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	client := domain.OAuthClient{
+		ClientId:   "rs",
+		SecretHash: string(secretHash),
+	}
+	testCase := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) repository.OIDCRepository
+		secret string
+
+		wantClient domain.OAuthClient
+		wantErr    error
+	}{
+		{
+			name: "认证成功",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "rs").Return(client, nil)
+				return repo
+			},
+			secret:     "secret",
+			wantClient: client,
+		},
+		{
+			name: "secret 错误",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "rs").Return(client, nil)
+				return repo
+			},
+			secret:  "wrong",
+			wantErr: ErrOIDCInvalidClient,
+		},
+		{
+			name: "公开客户端不能认证",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "rs").
+					Return(domain.OAuthClient{ClientId: "rs", Public: true}, nil)
+				return repo
+			},
+			wantErr: ErrOIDCInvalidClient,
+		},
+		{
+			name: "客户端不存在",
+			mock: func(ctrl *gomock.Controller) repository.OIDCRepository {
+				repo := repomocks.NewMockOIDCRepository(ctrl)
+				repo.EXPECT().FindClient(gomock.Any(), "rs").
+					Return(domain.OAuthClient{}, repository.ErrOAuthClientNotFound)
+				return repo
+			},
+			secret:  "secret",
+			wantErr: ErrOIDCInvalidClient,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewOIDCService(tc.mock(ctrl))
+			c, err := svc.AuthenticateClient(context.Background(), "rs", tc.secret)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantClient, c)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"time"
+)
+
+var (
+	ErrResetCodeInvalid  = errors.New("验证码错误")
+	ErrResetTokenInvalid = errors.New("重置密码的凭证无效或者已经过期")
+	ErrResetTypeInvalid  = errors.New("不支持的找回方式")
+)
+
+const (
+	ResetByEmail = "email"
+	ResetByPhone = "phone"
+
+	resetPwdBiz = "reset_pwd"
+)
+
+// PasswordResetService 找回密码
+// 1. 向邮箱或者手机号发送验证码
+// 2. 验证码校验通过之后签发一次性的重置凭证
+// 3. 使用重置凭证设置新密码
+type PasswordResetService interface {
+	// SendCode 发送验证码，用户不存在的时候什么都不做，避免泄露注册情况
+	SendCode(ctx context.Context, typ string, target string) error
+	// Verify 校验验证码，返回重置凭证
+	Verify(ctx context.Context, typ string, target string, code string) (string, error)
+	// Reset 设置新密码，返回被重置的用户 id
+	Reset(ctx context.Context, token string, password string) (int64, error)
+}
+
+type passwordResetService struct {
+	userRepo     repository.UserRepository
+	repo         repository.PasswordResetRepository
+	codeSvc      CodeService
+	emailCodeSvc EmailCodeService
+}
+
+func NewPasswordResetService(userRepo repository.UserRepository,
+	repo repository.PasswordResetRepository,
+	codeSvc CodeService,
+	emailCodeSvc EmailCodeService) PasswordResetService {
+	return &passwordResetService{
+		userRepo:     userRepo,
+		repo:         repo,
+		codeSvc:      codeSvc,
+		emailCodeSvc: emailCodeSvc,
+	}
+}
+
+func (svc *passwordResetService) SendCode(ctx context.Context, typ string, target string) error {
+	_, err := svc.findUser(ctx, typ, target)
+	if err == repository.ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if typ == ResetByEmail {
+		return svc.emailCodeSvc.Send(ctx, resetPwdBiz, target)
+	}
+	return svc.codeSvc.Send(ctx, resetPwdBiz, target)
+}
+
+func (svc *passwordResetService) Verify(ctx context.Context, typ string, target string, code string) (string, error) {
+	var (
+		ok  bool
+		err error
+	)
+	switch typ {
+	case ResetByEmail:
+		ok, err = svc.emailCodeSvc.Verify(ctx, resetPwdBiz, target, code)
+	case ResetByPhone:
+		ok, err = svc.codeSvc.Verify(ctx, resetPwdBiz, target, code)
+	default:
+		return "", ErrResetTypeInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrResetCodeInvalid
+	}
+
+	u, err := svc.findUser(ctx, typ, target)
+	if err != nil {
+		return "", err
+	}
+	// 能收到邮件，说明邮箱是本人的
+	if typ == ResetByEmail && u.VerifiedAt.IsZero() {
+		err = svc.userRepo.MarkEmailVerified(ctx, u.Id, time.Now())
+		if err != nil {
+			return "", err
+		}
+	}
+	token := uuid.New().String()
+	err = svc.repo.Store(ctx, token, u.Id)
+	return token, err
+}
+
+func (svc *passwordResetService) Reset(ctx context.Context, token string, password string) (int64, error) {
+	uid, err := svc.repo.Consume(ctx, token)
+	if err == repository.ErrResetTokenNotFound {
+		return 0, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+	return uid, svc.userRepo.UpdatePassword(ctx, uid, string(hash))
+}
+
+func (svc *passwordResetService) findUser(ctx context.Context, typ string, target string) (domain.User, error) {
+	switch typ {
+	case ResetByEmail:
+		return svc.userRepo.FindByEmail(ctx, target)
+	case ResetByPhone:
+		return svc.userRepo.FindByPhone(ctx, target)
+	default:
+		return domain.User{}, ErrResetTypeInvalid
+	}
+}
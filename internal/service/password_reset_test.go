@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/dadaxiaoxiao/user/internal/service/email/memory"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func Test_passwordResetService_Verify(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.PasswordResetRepository, repository.CodeRepository)
+		// 输入
+		code string
+		// 输出
+		wantToken bool
+		wantErr   error
+	}{
+		{
+			name: "验证成功，顺便标记邮箱已验证",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.PasswordResetRepository, repository.CodeRepository) {
+				codeRepo := repomocks.NewMockCodeRepository(ctrl)
+				codeRepo.EXPECT().Verify(gomock.Any(), "reset_pwd", "1426325504@qq.com", "123456").
+					Return(true, nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
+					Return(domain.User{Id: 1, Email: "1426325504@qq.com"}, nil)
+				userRepo.EXPECT().MarkEmailVerified(gomock.Any(), int64(1), gomock.Any()).
+					Return(nil)
+				resetRepo := repomocks.NewMockPasswordResetRepository(ctrl)
+				resetRepo.EXPECT().Store(gomock.Any(), gomock.Any(), int64(1)).
+					Return(nil)
+				return userRepo, resetRepo, codeRepo
+			},
+			code:      "123456",
+			wantToken: true,
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.PasswordResetRepository, repository.CodeRepository) {
+				codeRepo := repomocks.NewMockCodeRepository(ctrl)
+				codeRepo.EXPECT().Verify(gomock.Any(), "reset_pwd", "1426325504@qq.com", "654321").
+					Return(false, nil)
+				return repomocks.NewMockUserRepository(ctrl), repomocks.NewMockPasswordResetRepository(ctrl), codeRepo
+			},
+			code:    "654321",
+			wantErr: ErrResetCodeInvalid,
+		},
+		{
+			name: "验证次数太多",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.PasswordResetRepository, repository.CodeRepository) {
+				codeRepo := repomocks.NewMockCodeRepository(ctrl)
+				codeRepo.EXPECT().Verify(gomock.Any(), "reset_pwd", "1426325504@qq.com", "123456").
+					Return(false, ErrCodeVerifyTooManyTimes)
+				return repomocks.NewMockUserRepository(ctrl), repomocks.NewMockPasswordResetRepository(ctrl), codeRepo
+			},
+			code:    "123456",
+			wantErr: ErrCodeVerifyTooManyTimes,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, resetRepo, codeRepo := tc.mock(ctrl)
+			svc := NewPasswordResetService(userRepo, resetRepo, nil, NewEmailCodeService(codeRepo, memory.NewService()))
+			token, err := svc.Verify(context.Background(), ResetByEmail, "1426325504@qq.com", tc.code)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantToken, token != "")
+		})
+	}
+}
+
+func Test_passwordResetService_Reset(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.UserRepository, repository.PasswordResetRepository)
+		// 输入
+		token string
+		// 输出
+		wantUid int64
+		wantErr error
+	}{
+		{
+			name: "重置成功",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.PasswordResetRepository) {
+				resetRepo := repomocks.NewMockPasswordResetRepository(ctrl)
+				resetRepo.EXPECT().Consume(gomock.Any(), "token").Return(int64(1), nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, id int64, password string) error {
+						// 存的是加密之后的密码
+						return bcrypt.CompareHashAndPassword([]byte(password), []byte("hellword@123"))
+					})
+				return userRepo, resetRepo
+			},
+			token:   "token",
+			wantUid: 1,
+		},
+		{
+			name: "凭证无效",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.PasswordResetRepository) {
+				resetRepo := repomocks.NewMockPasswordResetRepository(ctrl)
+				resetRepo.EXPECT().Consume(gomock.Any(), "token").Return(int64(0), repository.ErrResetTokenNotFound)
+				return repomocks.NewMockUserRepository(ctrl), resetRepo
+			},
+			token:   "token",
+			wantErr: ErrResetTokenInvalid,
+		},
+		{
+			name: "数据库错误",
+			mock: func(ctrl *gomock.Controller) (repository.UserRepository, repository.PasswordResetRepository) {
+				resetRepo := repomocks.NewMockPasswordResetRepository(ctrl)
+				resetRepo.EXPECT().Consume(gomock.Any(), "token").Return(int64(1), nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).
+					Return(errors.New("mock db 错误"))
+				return userRepo, resetRepo
+			},
+			token:   "token",
+			wantUid: 1,
+			wantErr: errors.New("mock db 错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			userRepo, resetRepo := tc.mock(ctrl)
+			svc := NewPasswordResetService(userRepo, resetRepo, nil, nil)
+			uid, err := svc.Reset(context.Background(), tc.token, "hellword@123")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUid, uid)
+		})
+	}
+}
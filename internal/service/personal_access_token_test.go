@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_personalAccessTokenService_Create(t *testing.T) {
+	testCase := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) repository.PersonalAccessTokenRepository
+		scopes []string
+		ttl    time.Duration
+
+		wantErr error
+	}{
+		{
+			name: "创建成功",
+			mock: func(ctrl *gomock.Controller) repository.PersonalAccessTokenRepository {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).Return(nil, nil)
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, tk domain.PersonalAccessToken) (int64, error) {
+						// 只保存哈希
+						assert.Len(t, tk.TokenHash, 64)
+						assert.Equal(t, []string{domain.PATScopeRead}, tk.Scopes)
+						return 10, nil
+					})
+				return repo
+			},
+			scopes: []string{domain.PATScopeRead},
+			ttl:    time.Hour * 24 * 30,
+		},
+		{
+			name: "不支持的权限",
+			mock: func(ctrl *gomock.Controller) repository.PersonalAccessTokenRepository {
+				return repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+			},
+			scopes:  []string{"admin"},
+			ttl:     time.Hour * 24,
+			wantErr: ErrPersonalAccessTokenArgs,
+		},
+		{
+			name: "有效期太长",
+			mock: func(ctrl *gomock.Controller) repository.PersonalAccessTokenRepository {
+				return repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+			},
+			scopes:  []string{domain.PATScopeWrite},
+			ttl:     PersonalAccessTokenMaxTTL + time.Hour,
+			wantErr: ErrPersonalAccessTokenArgs,
+		},
+		{
+			name: "数量达到上限",
+			mock: func(ctrl *gomock.Controller) repository.PersonalAccessTokenRepository {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(make([]domain.PersonalAccessToken, personalAccessTokenLimit), nil)
+				return repo
+			},
+			scopes:  []string{domain.PATScopeWrite},
+			ttl:     time.Hour * 24,
+			wantErr: ErrPersonalAccessTokenTooMany,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewPersonalAccessTokenService(tc.mock(ctrl), nil)
+			tk, token, err := svc.Create(context.Background(), 1, "部署脚本", tc.scopes, tc.ttl)
+			assert.Equal(t, tc.wantErr, err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, int64(10), tk.Id)
+			assert.True(t, strings.HasPrefix(token, PersonalAccessTokenPrefix))
+			assert.True(t, strings.HasPrefix(token, tk.Prefix))
+			assert.Equal(t, hashPersonalAccessToken(token), tk.TokenHash)
+		})
+	}
+}
+
+func Test_personalAccessTokenService_Verify(t *testing.T) {
+	now := time.Now()
+	const token = "pat_abc"
+	hash := hashPersonalAccessToken(token)
+	testCase := []struct {
+		name  string
+		mock  func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository)
+		token string
+
+		wantToken domain.PersonalAccessToken
+		wantErr   error
+	}{
+		{
+			name: "校验成功，更新最近使用时间",
+			mock: func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository) {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByHash(gomock.Any(), hash).
+					Return(domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now.Add(time.Hour)}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.User{Id: 2}, nil)
+				repo.EXPECT().UpdateLastUsed(gomock.Any(), int64(1), now).Return(nil)
+				return repo, userRepo
+			},
+			token:     token,
+			wantToken: domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now.Add(time.Hour), LastUsedAt: now},
+		},
+		{
+			name: "刚刚用过，不更新最近使用时间",
+			mock: func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository) {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByHash(gomock.Any(), hash).
+					Return(domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now.Add(time.Hour),
+						LastUsedAt: now.Add(-time.Second)}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.User{Id: 2}, nil)
+				return repo, userRepo
+			},
+			token: token,
+			wantToken: domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now.Add(time.Hour),
+				LastUsedAt: now.Add(-time.Second)},
+		},
+		{
+			name: "更新最近使用时间失败不影响校验",
+			mock: func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository) {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByHash(gomock.Any(), hash).
+					Return(domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now.Add(time.Hour)}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.User{Id: 2}, nil)
+				repo.EXPECT().UpdateLastUsed(gomock.Any(), int64(1), now).Return(errors.New("db 错误"))
+				return repo, userRepo
+			},
+			token:     token,
+			wantToken: domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now.Add(time.Hour)},
+		},
+		{
+			name: "用户被冻结",
+			mock: func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository) {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByHash(gomock.Any(), hash).
+					Return(domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now.Add(time.Hour)}, nil)
+				userRepo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.User{Id: 2, FrozenAt: now}, nil)
+				return repo, userRepo
+			},
+			token:   token,
+			wantErr: ErrPersonalAccessTokenInvalid,
+		},
+		{
+			name: "用户已经合并到其它用户",
+			mock: func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository) {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByHash(gomock.Any(), hash).
+					Return(domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now.Add(time.Hour)}, nil)
+				// 墓碑按 id 查到的是 target
+				userRepo.EXPECT().FindById(gomock.Any(), int64(2)).Return(domain.User{Id: 3}, nil)
+				return repo, userRepo
+			},
+			token:   token,
+			wantErr: ErrPersonalAccessTokenInvalid,
+		},
+		{
+			name: "已经过期",
+			mock: func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository) {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByHash(gomock.Any(), hash).
+					Return(domain.PersonalAccessToken{Id: 1, Uid: 2, ExpiresAt: now}, nil)
+				return repo, userRepo
+			},
+			token:   token,
+			wantErr: ErrPersonalAccessTokenInvalid,
+		},
+		{
+			name: "已经删除",
+			mock: func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository) {
+				repo := repomocks.NewMockPersonalAccessTokenRepository(ctrl)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByHash(gomock.Any(), hash).
+					Return(domain.PersonalAccessToken{}, repository.ErrPersonalAccessTokenNotFound)
+				return repo, userRepo
+			},
+			token:   token,
+			wantErr: ErrPersonalAccessTokenInvalid,
+		},
+		{
+			name: "不是访问令牌",
+			mock: func(ctrl *gomock.Controller) (repository.PersonalAccessTokenRepository, repository.UserRepository) {
+				return repomocks.NewMockPersonalAccessTokenRepository(ctrl), nil
+			},
+			token:   "eyJhbGciOi",
+			wantErr: ErrPersonalAccessTokenInvalid,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, userRepo := tc.mock(ctrl)
+			svc := NewPersonalAccessTokenService(repo, userRepo).(*personalAccessTokenService)
+			svc.now = func() time.Time { return now }
+			tk, err := svc.Verify(context.Background(), tc.token)
+			require.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantToken, tk)
+		})
+	}
+}
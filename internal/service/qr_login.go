@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/google/uuid"
+	"time"
+)
+
+var (
+	// ErrQRLoginExpired 票据不存在或者已经过期，桌面端需要重新生成二维码
+	ErrQRLoginExpired = errors.New("二维码已经过期")
+	// ErrQRLoginStatusConflict 比如还没有扫码就确认，或者确认的不是扫码的用户
+	ErrQRLoginStatusConflict = errors.New("二维码状态已经变化")
+	// ErrQRLoginSecretInvalid 轮询的时候 secret 和票据对不上
+	ErrQRLoginSecretInvalid = errors.New("二维码无效")
+)
+
+const (
+	// 二维码的有效期
+	qrLoginPendingExpiration = time.Minute * 2
+	// 扫码之后等待用户在手机上确认
+	qrLoginScannedExpiration = time.Minute
+	// 确认之后等待桌面端轮询拿走 token
+	qrLoginConfirmedExpiration = time.Second * 30
+)
+
+// QRLoginService 已经登录的 App 扫码确认桌面端登录
+//
+//go:generate mockgen.exe -source=./qr_login.go -package=svcmocks -destination=mocks/qr_login.mock.go QRLoginService
+type QRLoginService interface {
+	// Create 桌面端生成二维码，返回的 Secret 只能给桌面端
+	Create(ctx context.Context, device domain.QRLoginDevice) (domain.QRLogin, error)
+	// Poll 桌面端长轮询，状态和 last 不一样或者超时之后返回
+	// 返回 QRLoginConfirmed 的时候票据已经删除，Uid 是要登录的用户，只会返回一次
+	Poll(ctx context.Context, ticket string, secret string, last string) (domain.QRLogin, error)
+	// Scan App 扫码，返回发起登录的设备给用户确认
+	Scan(ctx context.Context, ticket string, uid int64) (domain.QRLoginDevice, error)
+	// Confirm 只有扫码的用户可以确认
+	Confirm(ctx context.Context, ticket string, uid int64) error
+	// Cancel 扫码之后拒绝登录，票据直接作废
+	Cancel(ctx context.Context, ticket string, uid int64) error
+}
+
+type qrLoginService struct {
+	repo repository.QRLoginRepository
+	// 长轮询的最长时间和查询间隔，测试的时候改小
+	pollTimeout  time.Duration
+	pollInterval time.Duration
+}
+
+func NewQRLoginService(repo repository.QRLoginRepository) QRLoginService {
+	return &qrLoginService{
+		repo:         repo,
+		pollTimeout:  time.Second * 25,
+		pollInterval: time.Millisecond * 500,
+	}
+}
+
+func (svc *qrLoginService) Create(ctx context.Context, device domain.QRLoginDevice) (domain.QRLogin, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return domain.QRLogin{}, err
+	}
+	qr := domain.QRLogin{
+		Ticket: uuid.New().String(),
+		Secret: base64.RawURLEncoding.EncodeToString(secret),
+		Status: domain.QRLoginPending,
+		Device: device,
+		Ctime:  time.Now(),
+	}
+	return qr, svc.repo.Create(ctx, qr, qrLoginPendingExpiration)
+}
+
+func (svc *qrLoginService) Poll(ctx context.Context, ticket string, secret string, last string) (domain.QRLogin, error) {
+	timer := time.NewTimer(svc.pollTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(svc.pollInterval)
+	defer ticker.Stop()
+	for {
+		qr, err := svc.repo.FindByTicket(ctx, ticket)
+		if err == repository.ErrQRLoginNotFound {
+			return domain.QRLogin{Ticket: ticket, Status: domain.QRLoginExpired}, nil
+		}
+		if err != nil {
+			return domain.QRLogin{}, err
+		}
+		if subtle.ConstantTimeCompare([]byte(qr.Secret), []byte(secret)) != 1 {
+			return domain.QRLogin{}, ErrQRLoginSecretInvalid
+		}
+		if qr.Status == domain.QRLoginConfirmed {
+			return svc.take(ctx, qr)
+		}
+		if qr.Status != last {
+			return qr, nil
+		}
+		select {
+		case <-ctx.Done():
+			return domain.QRLogin{}, ctx.Err()
+		case <-timer.C:
+			return qr, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// take 确认之后删除票据，并发轮询的时候只有一个请求能拿到用户
+func (svc *qrLoginService) take(ctx context.Context, qr domain.QRLogin) (domain.QRLogin, error) {
+	uid, err := svc.repo.Transit(ctx, qr.Ticket, domain.QRLoginConfirmed, "", 0, 0)
+	switch err {
+	case nil:
+		qr.Uid = uid
+		return qr, nil
+	case repository.ErrQRLoginNotFound, repository.ErrQRLoginStatusConflict:
+		return domain.QRLogin{Ticket: qr.Ticket, Status: domain.QRLoginExpired}, nil
+	default:
+		return domain.QRLogin{}, err
+	}
+}
+
+func (svc *qrLoginService) Scan(ctx context.Context, ticket string, uid int64) (domain.QRLoginDevice, error) {
+	_, err := svc.repo.Transit(ctx, ticket, domain.QRLoginPending, domain.QRLoginScanned, uid, qrLoginScannedExpiration)
+	if err != nil {
+		return domain.QRLoginDevice{}, svc.transitErr(err)
+	}
+	qr, err := svc.repo.FindByTicket(ctx, ticket)
+	if err != nil {
+		return domain.QRLoginDevice{}, svc.transitErr(err)
+	}
+	return qr.Device, nil
+}
+
+func (svc *qrLoginService) Confirm(ctx context.Context, ticket string, uid int64) error {
+	_, err := svc.repo.Transit(ctx, ticket, domain.QRLoginScanned, domain.QRLoginConfirmed, uid, qrLoginConfirmedExpiration)
+	return svc.transitErr(err)
+}
+
+func (svc *qrLoginService) Cancel(ctx context.Context, ticket string, uid int64) error {
+	_, err := svc.repo.Transit(ctx, ticket, domain.QRLoginScanned, "", uid, 0)
+	return svc.transitErr(err)
+}
+
+func (svc *qrLoginService) transitErr(err error) error {
+	switch err {
+	case repository.ErrQRLoginNotFound:
+		return ErrQRLoginExpired
+	case repository.ErrQRLoginStatusConflict:
+		return ErrQRLoginStatusConflict
+	default:
+		return err
+	}
+}
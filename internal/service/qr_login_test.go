@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func Test_qrLoginService_Poll(t *testing.T) {
+	pending := domain.QRLogin{Ticket: "ticket", Secret: "secret", Status: domain.QRLoginPending}
+	scanned := domain.QRLogin{Ticket: "ticket", Secret: "secret", Status: domain.QRLoginScanned, Uid: 1}
+	confirmed := domain.QRLogin{Ticket: "ticket", Secret: "secret", Status: domain.QRLoginConfirmed, Uid: 1}
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.QRLoginRepository
+		// 输入
+		secret string
+		last   string
+		// 输出
+		wantQR  domain.QRLogin
+		wantErr error
+	}{
+		{
+			name: "状态变化之后返回",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				gomock.InOrder(
+					repo.EXPECT().FindByTicket(gomock.Any(), "ticket").Return(pending, nil),
+					repo.EXPECT().FindByTicket(gomock.Any(), "ticket").Return(scanned, nil),
+				)
+				return repo
+			},
+			secret: "secret",
+			last:   domain.QRLoginPending,
+			wantQR: scanned,
+		},
+		{
+			name: "一直没有变化，超时返回",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				repo.EXPECT().FindByTicket(gomock.Any(), "ticket").Return(pending, nil).MinTimes(1)
+				return repo
+			},
+			secret: "secret",
+			last:   domain.QRLoginPending,
+			wantQR: pending,
+		},
+		{
+			name: "已经确认，拿走票据",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				repo.EXPECT().FindByTicket(gomock.Any(), "ticket").Return(confirmed, nil)
+				repo.EXPECT().Transit(gomock.Any(), "ticket", domain.QRLoginConfirmed, "", int64(0), time.Duration(0)).
+					Return(int64(1), nil)
+				return repo
+			},
+			secret: "secret",
+			last:   domain.QRLoginScanned,
+			wantQR: confirmed,
+		},
+		{
+			name: "并发轮询，票据已经被拿走",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				repo.EXPECT().FindByTicket(gomock.Any(), "ticket").Return(confirmed, nil)
+				repo.EXPECT().Transit(gomock.Any(), "ticket", domain.QRLoginConfirmed, "", int64(0), time.Duration(0)).
+					Return(int64(0), repository.ErrQRLoginNotFound)
+				return repo
+			},
+			secret: "secret",
+			last:   domain.QRLoginScanned,
+			wantQR: domain.QRLogin{Ticket: "ticket", Status: domain.QRLoginExpired},
+		},
+		{
+			name: "票据过期",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				repo.EXPECT().FindByTicket(gomock.Any(), "ticket").Return(domain.QRLogin{}, repository.ErrQRLoginNotFound)
+				return repo
+			},
+			secret: "secret",
+			last:   domain.QRLoginPending,
+			wantQR: domain.QRLogin{Ticket: "ticket", Status: domain.QRLoginExpired},
+		},
+		{
+			name: "secret 不对",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				repo.EXPECT().FindByTicket(gomock.Any(), "ticket").Return(confirmed, nil)
+				return repo
+			},
+			secret:  "stolen",
+			last:    domain.QRLoginScanned,
+			wantErr: ErrQRLoginSecretInvalid,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewQRLoginService(tc.mock(ctrl)).(*qrLoginService)
+			svc.pollTimeout = time.Millisecond * 50
+			svc.pollInterval = time.Millisecond * 10
+			qr, err := svc.Poll(context.Background(), "ticket", tc.secret, tc.last)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantQR, qr)
+		})
+	}
+}
+
+func Test_qrLoginService_Confirm(t *testing.T) {
+	testCase := []struct {
+		name    string
+		mock    func(ctrl *gomock.Controller) repository.QRLoginRepository
+		wantErr error
+	}{
+		{
+			name: "确认成功",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				repo.EXPECT().Transit(gomock.Any(), "ticket", domain.QRLoginScanned, domain.QRLoginConfirmed, int64(1), gomock.Any()).
+					Return(int64(1), nil)
+				return repo
+			},
+		},
+		{
+			name: "还没有扫码或者不是扫码的用户",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				repo.EXPECT().Transit(gomock.Any(), "ticket", domain.QRLoginScanned, domain.QRLoginConfirmed, int64(1), gomock.Any()).
+					Return(int64(0), repository.ErrQRLoginStatusConflict)
+				return repo
+			},
+			wantErr: ErrQRLoginStatusConflict,
+		},
+		{
+			name: "二维码过期",
+			mock: func(ctrl *gomock.Controller) repository.QRLoginRepository {
+				repo := repomocks.NewMockQRLoginRepository(ctrl)
+				repo.EXPECT().Transit(gomock.Any(), "ticket", domain.QRLoginScanned, domain.QRLoginConfirmed, int64(1), gomock.Any()).
+					Return(int64(0), repository.ErrQRLoginNotFound)
+				return repo
+			},
+			wantErr: ErrQRLoginExpired,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewQRLoginService(tc.mock(ctrl))
+			err := svc.Confirm(context.Background(), "ticket", 1)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
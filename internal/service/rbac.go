@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrRoleNotFound  = repository.ErrRoleNotFound
+	ErrRoleDuplicate = repository.ErrRoleDuplicate
+	ErrRoleInvalid   = errors.New("角色名字不合法或者包含未定义的权限")
+)
+
+// RBACService 基于角色的权限控制
+// 权限在代码里面定义（domain.Permissions），角色和用户的角色保存在数据库
+//
+//go:generate mockgen.exe -source=./rbac.go -package=svcmocks -destination=mocks/rbac.mock.go RBACService
+type RBACService interface {
+	// Permissions 用户拥有的全部权限
+	Permissions(ctx context.Context, uid int64) ([]string, error)
+	HasPermission(ctx context.Context, uid int64, permission string) (bool, error)
+	Roles(ctx context.Context) ([]domain.Role, error)
+	// SaveRole Id 为 0 的时候新建，否则整体更新，返回角色 id
+	SaveRole(ctx context.Context, r domain.Role) (int64, error)
+	// DeleteRole 拥有这个角色的用户会失去对应的权限
+	DeleteRole(ctx context.Context, id int64) error
+	UserRoles(ctx context.Context, uid int64) ([]domain.Role, error)
+	// SetUserRoles 整体替换用户的角色，roleIds 为空就是去掉所有角色
+	SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error
+}
+
+type rbacService struct {
+	repo repository.RBACRepository
+	// superAdmins 拥有全部权限，不需要分配角色，用来初始化第一个管理员
+	superAdmins []int64
+}
+
+func NewRBACService(repo repository.RBACRepository, superAdmins []int64) RBACService {
+	return &rbacService{
+		repo:        repo,
+		superAdmins: superAdmins,
+	}
+}
+
+func (svc *rbacService) Permissions(ctx context.Context, uid int64) ([]string, error) {
+	if slices.Contains(svc.superAdmins, uid) {
+		res := make([]string, 0, len(domain.Permissions))
+		for _, p := range domain.Permissions {
+			res = append(res, p.Code)
+		}
+		return res, nil
+	}
+	return svc.repo.FindPermissionsByUid(ctx, uid)
+}
+
+func (svc *rbacService) HasPermission(ctx context.Context, uid int64, permission string) (bool, error) {
+	perms, err := svc.Permissions(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(perms, permission), nil
+}
+
+func (svc *rbacService) Roles(ctx context.Context) ([]domain.Role, error) {
+	return svc.repo.FindRoles(ctx)
+}
+
+func (svc *rbacService) SaveRole(ctx context.Context, r domain.Role) (int64, error) {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" || utf8.RuneCountInString(r.Name) > 64 || utf8.RuneCountInString(r.Description) > 256 {
+		return 0, ErrRoleInvalid
+	}
+	for _, p := range r.Permissions {
+		if !domain.ValidPermission(p) {
+			return 0, ErrRoleInvalid
+		}
+	}
+	slices.Sort(r.Permissions)
+	r.Permissions = slices.Compact(r.Permissions)
+	return svc.repo.SaveRole(ctx, r)
+}
+
+func (svc *rbacService) DeleteRole(ctx context.Context, id int64) error {
+	return svc.repo.DeleteRole(ctx, id)
+}
+
+func (svc *rbacService) UserRoles(ctx context.Context, uid int64) ([]domain.Role, error) {
+	return svc.repo.FindRolesByUid(ctx, uid)
+}
+
+func (svc *rbacService) SetUserRoles(ctx context.Context, uid int64, roleIds []int64) error {
+	roleIds = slices.Clone(roleIds)
+	slices.Sort(roleIds)
+	return svc.repo.SetUserRoles(ctx, uid, slices.Compact(roleIds))
+}
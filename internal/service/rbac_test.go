@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func Test_rbacService_HasPermission(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.RBACRepository
+		uid  int64
+
+		wantHas bool
+		wantErr error
+	}{
+		{
+			name: "角色里面有这个权限",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().FindPermissionsByUid(gomock.Any(), int64(2)).
+					Return([]string{domain.PermissionRBACManage}, nil)
+				return repo
+			},
+			uid:     2,
+			wantHas: true,
+		},
+		{
+			name: "没有角色",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().FindPermissionsByUid(gomock.Any(), int64(2)).Return(nil, nil)
+				return repo
+			},
+			uid: 2,
+		},
+		{
+			name: "超级管理员不查数据库",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				return repomocks.NewMockRBACRepository(ctrl)
+			},
+			uid:     1,
+			wantHas: true,
+		},
+		{
+			name: "查询失败",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().FindPermissionsByUid(gomock.Any(), int64(2)).Return(nil, errors.New("db 错误"))
+				return repo
+			},
+			uid:     2,
+			wantErr: errors.New("db 错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewRBACService(tc.mock(ctrl), []int64{1})
+			has, err := svc.HasPermission(context.Background(), tc.uid, domain.PermissionRBACManage)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantHas, has)
+		})
+	}
+}
+
+func Test_rbacService_SaveRole(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.RBACRepository
+		role domain.Role
+
+		wantId  int64
+		wantErr error
+	}{
+		{
+			name: "保存成功，权限去重",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				repo := repomocks.NewMockRBACRepository(ctrl)
+				repo.EXPECT().SaveRole(gomock.Any(), domain.Role{
+					Name:        "管理员",
+					Permissions: []string{domain.PermissionRBACManage},
+				}).Return(int64(3), nil)
+				return repo
+			},
+			role: domain.Role{
+				Name:        " 管理员 ",
+				Permissions: []string{domain.PermissionRBACManage, domain.PermissionRBACManage},
+			},
+			wantId: 3,
+		},
+		{
+			name: "未定义的权限",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				return repomocks.NewMockRBACRepository(ctrl)
+			},
+			role:    domain.Role{Name: "管理员", Permissions: []string{"root"}},
+			wantErr: ErrRoleInvalid,
+		},
+		{
+			name: "名字为空",
+			mock: func(ctrl *gomock.Controller) repository.RBACRepository {
+				return repomocks.NewMockRBACRepository(ctrl)
+			},
+			role:    domain.Role{Name: " "},
+			wantErr: ErrRoleInvalid,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewRBACService(tc.mock(ctrl), nil)
+			id, err := svc.SaveRole(context.Background(), tc.role)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantId, id)
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/pkg/totp"
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTwoFactorNoPassword       = errors.New("没有设置密码的账号不能开启两步验证")
+	ErrTwoFactorEnabled          = errors.New("两步验证已经开启")
+	ErrTwoFactorNotEnabled       = errors.New("两步验证没有开启")
+	ErrTwoFactorCodeInvalid      = errors.New("两步验证码错误")
+	ErrTwoFactorChallengeInvalid = errors.New("两步验证已经过期，请重新登录")
+)
+
+const recoveryCodeCount = 10
+
+// TwoFactorService TOTP 两步验证
+// 1. Setup 生成密钥，前端把 otpauth 链接渲染成二维码
+// 2. Enable 用验证器 App 上的验证码确认，返回恢复码，恢复码只展示这一次
+// 3. 登录的时候，密码校验通过之后调用 Challenge，再用 VerifyChallenge 完成登录
+//
+// 第二步验证错误和密码错误一样由 LoginLimitService 按用户统计，
+// 不然知道密码的人可以反复登录拿新的挑战，无限次猜验证码
+type TwoFactorService interface {
+	// Setup 返回密钥和 otpauth 链接
+	Setup(ctx context.Context, uid int64) (secret string, uri string, err error)
+	// Enable 确认开启，返回恢复码明文
+	Enable(ctx context.Context, uid int64, code string) ([]string, error)
+	// Disable 关闭，需要验证码或者恢复码
+	Disable(ctx context.Context, uid int64, code string) error
+	Enabled(ctx context.Context, uid int64) (bool, error)
+	// Challenge 密码校验通过之后，生成第二步验证的凭证
+	Challenge(ctx context.Context, c domain.TwoFactorChallenge) (string, error)
+	// VerifyChallenge 校验验证码或者恢复码，返回挑战的内容
+	// 错误次数太多的时候返回等待时间和 ErrLoginLocked 或者 ErrLoginTooFrequent
+	// 通过之后才清掉第一步登录账号的密码错误次数
+	VerifyChallenge(ctx context.Context, token string, code string, ip string) (domain.TwoFactorChallenge, time.Duration, error)
+}
+
+type twoFactorService struct {
+	userRepo repository.UserRepository
+	repo     repository.TOTPRepository
+	limitSvc LoginLimitService
+	// issuer 验证器 App 上显示的名字
+	issuer string
+	now    func() time.Time
+}
+
+func NewTwoFactorService(userRepo repository.UserRepository, repo repository.TOTPRepository,
+	limitSvc LoginLimitService, issuer string) TwoFactorService {
+	return &twoFactorService{
+		userRepo: userRepo,
+		repo:     repo,
+		limitSvc: limitSvc,
+		issuer:   issuer,
+		now:      time.Now,
+	}
+}
+
+func (svc *twoFactorService) Setup(ctx context.Context, uid int64) (string, string, error) {
+	u, err := svc.userRepo.FindById(ctx, uid)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Password == "" {
+		return "", "", ErrTwoFactorNoPassword
+	}
+	t, err := svc.repo.FindByUid(ctx, uid)
+	switch {
+	case err == nil && t.Enabled():
+		return "", "", ErrTwoFactorEnabled
+	case err != nil && err != repository.ErrTOTPNotFound:
+		return "", "", err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	err = svc.repo.Save(ctx, domain.TOTP{
+		Uid:    uid,
+		Secret: secret,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return secret, totp.ProvisioningURI(svc.issuer, svc.account(u), secret), nil
+}
+
+func (svc *twoFactorService) Enable(ctx context.Context, uid int64, code string) ([]string, error) {
+	t, err := svc.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	counter, ok := totp.Validate(t.Secret, code, svc.now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		c, err := svc.generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, svc.hashRecoveryCode(c))
+	}
+	err = svc.repo.Enable(ctx, uid, hashes)
+	if err != nil {
+		return nil, err
+	}
+	// 确认用的验证码不能再拿去登录
+	err = svc.repo.UseCounter(ctx, uid, counter)
+	if err != nil && err != repository.ErrTOTPConflict {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (svc *twoFactorService) Disable(ctx context.Context, uid int64, code string) error {
+	err := svc.verify(ctx, uid, code)
+	if err != nil {
+		return err
+	}
+	return svc.repo.Delete(ctx, uid)
+}
+
+func (svc *twoFactorService) Enabled(ctx context.Context, uid int64) (bool, error) {
+	t, err := svc.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return t.Enabled(), nil
+}
+
+func (svc *twoFactorService) Challenge(ctx context.Context, c domain.TwoFactorChallenge) (string, error) {
+	token := uuid.New().String()
+	return token, svc.repo.SetChallenge(ctx, token, c)
+}
+
+func (svc *twoFactorService) VerifyChallenge(ctx context.Context, token string, code string,
+	ip string) (domain.TwoFactorChallenge, time.Duration, error) {
+	c, err := svc.repo.GetChallenge(ctx, token)
+	switch err {
+	case nil:
+	case repository.ErrChallengeNotFound, repository.ErrChallengeTooManyAttempts:
+		return domain.TwoFactorChallenge{}, 0, ErrTwoFactorChallengeInvalid
+	default:
+		return domain.TwoFactorChallenge{}, 0, err
+	}
+	// 错误次数按用户统计，换一个挑战也不会清掉
+	account := svc.limitAccount(c.Uid)
+	wait, err := svc.limitSvc.Allow(ctx, account, ip)
+	if err != nil {
+		return domain.TwoFactorChallenge{}, wait, err
+	}
+	err = svc.verify(ctx, c.Uid, code)
+	if err == ErrTwoFactorCodeInvalid {
+		wait, ferr := svc.limitSvc.Fail(ctx, account, ip)
+		if ferr == ErrLoginLocked {
+			return domain.TwoFactorChallenge{}, wait, ferr
+		}
+		return domain.TwoFactorChallenge{}, 0, err
+	}
+	if err != nil {
+		return domain.TwoFactorChallenge{}, 0, err
+	}
+	// 挑战只能用一次
+	err = svc.repo.DelChallenge(ctx, token)
+	if err != nil {
+		return domain.TwoFactorChallenge{}, 0, err
+	}
+	// 两步都通过了才清掉失败次数，这里出错不影响登录
+	_ = svc.limitSvc.Succeed(ctx, account)
+	if c.Account != "" {
+		_ = svc.limitSvc.Succeed(ctx, c.Account)
+	}
+	return c, 0, nil
+}
+
+// limitAccount 第二步验证错误次数的统计账号，和邮箱区分开
+func (svc *twoFactorService) limitAccount(uid int64) string {
+	return "2fa:" + strconv.FormatInt(uid, 10)
+}
+
+// verify 校验验证码或者恢复码
+func (svc *twoFactorService) verify(ctx context.Context, uid int64, code string) error {
+	t, err := svc.repo.FindByUid(ctx, uid)
+	if err == repository.ErrTOTPNotFound {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if !t.Enabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if len(code) == totp.Digits {
+		counter, ok := totp.Validate(t.Secret, code, svc.now())
+		// 同一个验证码不能用两次
+		if !ok || counter <= t.LastCounter {
+			return ErrTwoFactorCodeInvalid
+		}
+		err = svc.repo.UseCounter(ctx, uid, counter)
+		if err == repository.ErrTOTPConflict {
+			return ErrTwoFactorCodeInvalid
+		}
+		return err
+	}
+
+	hash := svc.hashRecoveryCode(code)
+	remain := make([]string, 0, len(t.RecoveryCodes))
+	for _, h := range t.RecoveryCodes {
+		if h != hash {
+			remain = append(remain, h)
+		}
+	}
+	if len(remain) == len(t.RecoveryCodes) {
+		return ErrTwoFactorCodeInvalid
+	}
+	err = svc.repo.UpdateRecoveryCodes(ctx, uid, t.RecoveryCodes, remain)
+	if err == repository.ErrTOTPConflict {
+		// 并发使用了同一个恢复码
+		return ErrTwoFactorCodeInvalid
+	}
+	return err
+}
+
+// generateRecoveryCode 生成 xxxxx-xxxxx 格式的恢复码
+func (svc *twoFactorService) generateRecoveryCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(buf)
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hashRecoveryCode 恢复码是随机生成的，sha256 就足够了
+// 忽略大小写和中间的 -，方便用户输入
+func (svc *twoFactorService) hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// account 验证器 App 上显示的账号
+func (svc *twoFactorService) account(u domain.User) string {
+	switch {
+	case u.Email != "":
+		return u.Email
+	case u.Phone != "":
+		return u.Phone
+	default:
+		return strconv.FormatInt(u.Id, 10)
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/dadaxiaoxiao/user/pkg/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+func Test_twoFactorService_Enable(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	code, err := totp.Code(testTOTPSecret, totp.Counter(now))
+	require.NoError(t, err)
+
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.TOTPRepository
+		// 输入
+		code string
+		// 输出
+		wantCodes int
+		wantErr   error
+	}{
+		{
+			name: "开启成功",
+			mock: func(ctrl *gomock.Controller) repository.TOTPRepository {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{Uid: 1, Secret: testTOTPSecret}, nil)
+				repo.EXPECT().Enable(gomock.Any(), int64(1), gomock.Len(recoveryCodeCount)).
+					Return(nil)
+				repo.EXPECT().UseCounter(gomock.Any(), int64(1), totp.Counter(now)).
+					Return(nil)
+				return repo
+			},
+			code:      code,
+			wantCodes: recoveryCodeCount,
+		},
+		{
+			name: "验证码错误",
+			mock: func(ctrl *gomock.Controller) repository.TOTPRepository {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{Uid: 1, Secret: testTOTPSecret}, nil)
+				return repo
+			},
+			code:    "000000",
+			wantErr: ErrTwoFactorCodeInvalid,
+		},
+		{
+			name: "已经开启了",
+			mock: func(ctrl *gomock.Controller) repository.TOTPRepository {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{Uid: 1, Secret: testTOTPSecret, EnabledAt: now}, nil)
+				return repo
+			},
+			code:    code,
+			wantErr: ErrTwoFactorEnabled,
+		},
+		{
+			name: "还没有绑定",
+			mock: func(ctrl *gomock.Controller) repository.TOTPRepository {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{}, repository.ErrTOTPNotFound)
+				return repo
+			},
+			code:    code,
+			wantErr: ErrTwoFactorNotEnabled,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewTwoFactorService(nil, tc.mock(ctrl), nil, "用户中心").(*twoFactorService)
+			svc.now = func() time.Time { return now }
+			codes, err := svc.Enable(context.Background(), 1, tc.code)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Len(t, codes, tc.wantCodes)
+		})
+	}
+}
+
+func Test_twoFactorService_VerifyChallenge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	code, err := totp.Code(testTOTPSecret, totp.Counter(now))
+	require.NoError(t, err)
+	svc := &twoFactorService{}
+	recoveryHash := svc.hashRecoveryCode("abcde-12345")
+	otherHash := svc.hashRecoveryCode("fghij-67890")
+	challenge := domain.TwoFactorChallenge{Uid: 1, Account: "1426325504@qq.com"}
+	rule := domain.LoginLimitRule{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute,
+		Window: time.Minute * 15, LockThreshold: 10, LockDuration: time.Minute * 30}
+
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (repository.TOTPRepository, repository.LoginLimitRepository)
+		// 输入
+		code string
+		// 输出
+		wantChallenge domain.TwoFactorChallenge
+		wantWait      time.Duration
+		wantErr       error
+	}{
+		{
+			name: "验证码正确",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, repository.LoginLimitRepository) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().GetChallenge(gomock.Any(), "challenge").Return(challenge, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{Uid: 1, Secret: testTOTPSecret, EnabledAt: now}, nil)
+				repo.EXPECT().UseCounter(gomock.Any(), int64(1), totp.Counter(now)).Return(nil)
+				repo.EXPECT().DelChallenge(gomock.Any(), "challenge").Return(nil)
+				limitRepo := repomocks.NewMockLoginLimitRepository(ctrl)
+				limitRepo.EXPECT().Blocked(gomock.Any(), "2fa:1", "10.0.0.1").Return(time.Duration(0), false, nil)
+				// 两步都通过了才清掉第二步和密码的失败次数
+				limitRepo.EXPECT().ResetAccount(gomock.Any(), "2fa:1").Return(nil)
+				limitRepo.EXPECT().ResetAccount(gomock.Any(), "1426325504@qq.com").Return(nil)
+				return repo, limitRepo
+			},
+			code:          code,
+			wantChallenge: challenge,
+		},
+		{
+			name: "验证码已经用过了",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, repository.LoginLimitRepository) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().GetChallenge(gomock.Any(), "challenge").Return(challenge, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{Uid: 1, Secret: testTOTPSecret, EnabledAt: now, LastCounter: totp.Counter(now)}, nil)
+				limitRepo := repomocks.NewMockLoginLimitRepository(ctrl)
+				limitRepo.EXPECT().Blocked(gomock.Any(), "2fa:1", "10.0.0.1").Return(time.Duration(0), false, nil)
+				limitRepo.EXPECT().FailAccount(gomock.Any(), "2fa:1", rule).Return(time.Duration(0), false, nil)
+				limitRepo.EXPECT().FailIP(gomock.Any(), "10.0.0.1", rule).Return(time.Duration(0), false, nil)
+				return repo, limitRepo
+			},
+			code:    code,
+			wantErr: ErrTwoFactorCodeInvalid,
+		},
+		{
+			name: "恢复码正确",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, repository.LoginLimitRepository) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().GetChallenge(gomock.Any(), "challenge").Return(challenge, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{
+						Uid:           1,
+						Secret:        testTOTPSecret,
+						EnabledAt:     now,
+						RecoveryCodes: []string{recoveryHash, otherHash},
+					}, nil)
+				// 用过的恢复码会被删掉
+				repo.EXPECT().UpdateRecoveryCodes(gomock.Any(), int64(1),
+					[]string{recoveryHash, otherHash}, []string{otherHash}).Return(nil)
+				repo.EXPECT().DelChallenge(gomock.Any(), "challenge").Return(nil)
+				limitRepo := repomocks.NewMockLoginLimitRepository(ctrl)
+				limitRepo.EXPECT().Blocked(gomock.Any(), "2fa:1", "10.0.0.1").Return(time.Duration(0), false, nil)
+				limitRepo.EXPECT().ResetAccount(gomock.Any(), "2fa:1").Return(nil)
+				limitRepo.EXPECT().ResetAccount(gomock.Any(), "1426325504@qq.com").Return(nil)
+				return repo, limitRepo
+			},
+			code:          "ABCDE-12345",
+			wantChallenge: challenge,
+		},
+		{
+			name: "恢复码错误",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, repository.LoginLimitRepository) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().GetChallenge(gomock.Any(), "challenge").Return(challenge, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{
+						Uid:           1,
+						Secret:        testTOTPSecret,
+						EnabledAt:     now,
+						RecoveryCodes: []string{otherHash},
+					}, nil)
+				limitRepo := repomocks.NewMockLoginLimitRepository(ctrl)
+				limitRepo.EXPECT().Blocked(gomock.Any(), "2fa:1", "10.0.0.1").Return(time.Duration(0), false, nil)
+				limitRepo.EXPECT().FailAccount(gomock.Any(), "2fa:1", rule).Return(time.Second, false, nil)
+				limitRepo.EXPECT().FailIP(gomock.Any(), "10.0.0.1", rule).Return(time.Duration(0), false, nil)
+				return repo, limitRepo
+			},
+			code:    "abcde-12345",
+			wantErr: ErrTwoFactorCodeInvalid,
+		},
+		{
+			name: "错误次数太多锁定",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, repository.LoginLimitRepository) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().GetChallenge(gomock.Any(), "challenge").Return(challenge, nil)
+				repo.EXPECT().FindByUid(gomock.Any(), int64(1)).
+					Return(domain.TOTP{Uid: 1, Secret: testTOTPSecret, EnabledAt: now}, nil)
+				limitRepo := repomocks.NewMockLoginLimitRepository(ctrl)
+				limitRepo.EXPECT().Blocked(gomock.Any(), "2fa:1", "10.0.0.1").Return(time.Duration(0), false, nil)
+				limitRepo.EXPECT().FailAccount(gomock.Any(), "2fa:1", rule).Return(time.Minute*30, true, nil)
+				limitRepo.EXPECT().FailIP(gomock.Any(), "10.0.0.1", rule).Return(time.Duration(0), false, nil)
+				return repo, limitRepo
+			},
+			code:     "000000",
+			wantWait: time.Minute * 30,
+			wantErr:  ErrLoginLocked,
+		},
+		{
+			name: "换一个挑战也还在锁定",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, repository.LoginLimitRepository) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().GetChallenge(gomock.Any(), "challenge").Return(challenge, nil)
+				limitRepo := repomocks.NewMockLoginLimitRepository(ctrl)
+				limitRepo.EXPECT().Blocked(gomock.Any(), "2fa:1", "10.0.0.1").Return(time.Minute, true, nil)
+				return repo, limitRepo
+			},
+			code:     code,
+			wantWait: time.Minute,
+			wantErr:  ErrLoginLocked,
+		},
+		{
+			name: "挑战过期",
+			mock: func(ctrl *gomock.Controller) (repository.TOTPRepository, repository.LoginLimitRepository) {
+				repo := repomocks.NewMockTOTPRepository(ctrl)
+				repo.EXPECT().GetChallenge(gomock.Any(), "challenge").
+					Return(domain.TwoFactorChallenge{}, repository.ErrChallengeNotFound)
+				return repo, repomocks.NewMockLoginLimitRepository(ctrl)
+			},
+			code:    code,
+			wantErr: ErrTwoFactorChallengeInvalid,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			repo, limitRepo := tc.mock(ctrl)
+			limitSvc := NewLoginLimitService(limitRepo, rule, rule)
+			svc := NewTwoFactorService(nil, repo, limitSvc, "用户中心").(*twoFactorService)
+			svc.now = func() time.Time { return now }
+			c, wait, err := svc.VerifyChallenge(context.Background(), "challenge", tc.code, "10.0.0.1")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantWait, wait)
+			assert.Equal(t, tc.wantChallenge, c)
+		})
+	}
+}
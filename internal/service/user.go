@@ -7,21 +7,37 @@ import (
 	"github.com/dadaxiaoxiao/user/internal/domain"
 	"github.com/dadaxiaoxiao/user/internal/repository"
 	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 var (
 	ErrUserDuplicateEmail    = repository.ErrUserDuplicateEmail
 	ErrUserNotFound          = repository.ErrUserNotFound
 	ErrInvalidUserOrPassword = errors.New("账号/邮箱或密码不对")
+	ErrEmailNotVerified      = errors.New("邮箱还没有验证")
+	// ErrUserFrozen 账号被管理员冻结，不能登录
+	ErrUserFrozen = errors.New("账号已经被冻结")
 )
 
+//go:generate mockgen.exe -source=./user.go -package=svcmocks -destination=mocks/user.mock.go UserService
 type UserService interface {
 	Signup(ctx context.Context, user domain.User) error
+	// FindOrCreate 短信登录，用户被冻结返回 ErrUserFrozen，Login 和 FindOrCreateByEmail 也一样
 	FindOrCreate(ctx context.Context, phone string) (user domain.User, err error)
-	FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (user domain.User, err error)
+	// FindOrCreateByEmail 邮箱免密登录，没有注册过的邮箱新建一个没有密码的用户
+	// 和 Login 一样不取消注销，可能还要两步验证
+	FindOrCreateByEmail(ctx context.Context, email string) (domain.User, error)
 	Login(ctx context.Context, email, password string) (domain.User, error)
 	UpdateNonSensitiveInfo(ctx context.Context, user domain.User) error
 	Profile(ctx context.Context, id int64) (domain.User, error)
+	// EmailVerified 邮箱是否已经验证，用户不存在返回 ErrUserNotFound
+	EmailVerified(ctx context.Context, email string) (bool, error)
+	// VerifyEmail 验证码校验通过之后，标记邮箱已经验证
+	VerifyEmail(ctx context.Context, email string) error
+	// VerifyEmailByLink 邮箱免密登录第一次验证邮箱，注册时设置的密码不一定是邮箱主人设置的，一起清掉
+	VerifyEmailByLink(ctx context.Context, email string) error
+	// ChangePassword 校验旧密码之后修改密码
+	ChangePassword(ctx context.Context, id int64, oldPassword, newPassword string) error
 }
 
 type userService struct {
@@ -51,7 +67,7 @@ func (svc *userService) FindOrCreate(ctx context.Context, phone string) (user do
 	// 1.先查询，如果存在，直接返回
 	u, err := svc.repo.FindByPhone(ctx, phone)
 	if err != repository.ErrUserNotFound {
-		return u, err
+		return checkLogin(ctx, svc.repo, u, err)
 	}
 
 	// 2. 注册一个用户
@@ -69,32 +85,19 @@ func (svc *userService) FindOrCreate(ctx context.Context, phone string) (user do
 	return svc.repo.FindByPhone(ctx, phone)
 }
 
-// FindOrCreateByWechat 根据微信查询新建用户
-func (svc *userService) FindOrCreateByWechat(ctx context.Context, info domain.WechatInfo) (user domain.User, err error) {
-	u, err := svc.repo.FindByWechat(ctx, info.OpenId)
+func (svc *userService) FindOrCreateByEmail(ctx context.Context, email string) (domain.User, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
 	if err != repository.ErrUserNotFound {
-		return u, err
-	}
-
-	// 直接使用包变量
-	//zap.L().Info("微信用户未注册，注册新用户",
-	//	zap.Any("wechat_info", info))
-
-	// 使用依赖注入的logger
-	svc.log.Info("微信用户未注册，注册新用户",
-		accesslog.Any("wechat_info", info))
-
-	// 2. 注册一个用户
-	u = domain.User{
-		WechatInfo: info,
+		return checkFrozen(u, err)
 	}
-	// 注册一个用户
-	err = svc.repo.Create(ctx, u)
-	if err != nil {
-		return u, err
+	err = svc.repo.Create(ctx, domain.User{
+		Email: email,
+	})
+	// 并发登录，另外一个请求已经注册了
+	if err != nil && err != repository.ErrUserDuplicateEmail {
+		return domain.User{}, err
 	}
-	// 会遇到主从延迟问题
-	return svc.repo.FindByWechat(ctx, info.OpenId)
+	return svc.repo.FindByEmail(ctx, email)
 }
 
 // Login 用户登录，返回domain.User ,error
@@ -115,6 +118,37 @@ func (svc *userService) Login(ctx context.Context, email, password string) (doma
 	if err != nil {
 		return domain.User{}, ErrInvalidUserOrPassword // 密码不对
 	}
+	// 密码对了才告诉前端邮箱没有验证，避免泄露邮箱的注册情况
+	if u.VerifiedAt.IsZero() {
+		return domain.User{}, ErrEmailNotVerified
+	}
+	return checkFrozen(u, nil)
+}
+
+// checkLogin 登录的时候查到用户之后调用，冻结的用户返回 ErrUserFrozen，注销冷静期内登录会取消注销
+// 可能还要两步验证的登录方式（密码、邮箱链接）用 checkFrozen，通过两步验证之后再由 AccountDeletionService.Cancel 取消注销
+func checkLogin(ctx context.Context, repo repository.UserRepository, u domain.User, err error) (domain.User, error) {
+	u, err = checkFrozen(u, err)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !u.DeleteAt.IsZero() {
+		if err = repo.UpdateDeleteAt(ctx, u.Id, time.Time{}); err != nil {
+			return domain.User{}, err
+		}
+		u.DeleteAt = time.Time{}
+	}
+	return u, nil
+}
+
+// checkFrozen 冻结的用户返回 ErrUserFrozen
+func checkFrozen(u domain.User, err error) (domain.User, error) {
+	if err != nil {
+		return domain.User{}, err
+	}
+	if u.Frozen() {
+		return domain.User{}, ErrUserFrozen
+	}
 	return u, nil
 }
 
@@ -134,6 +168,7 @@ func (svc *userService) UpdateNonSensitiveInfo(ctx context.Context, user domain.
 	user.Phone = ""
 	user.Password = ""
 	user.WechatInfo = domain.WechatInfo{}
+	user.VerifiedAt = time.Time{}
 	return svc.repo.Update(ctx, user)
 }
 
@@ -145,3 +180,63 @@ func (svc *userService) Profile(ctx context.Context, id int64) (domain.User, err
 	}
 	return u, nil
 }
+
+// EmailVerified 邮箱是否已经验证
+func (svc *userService) EmailVerified(ctx context.Context, email string) (bool, error) {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return false, err
+	}
+	return !u.VerifiedAt.IsZero(), nil
+}
+
+// VerifyEmail 标记邮箱已经验证，重复验证不会修改验证时间
+func (svc *userService) VerifyEmail(ctx context.Context, email string) error {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !u.VerifiedAt.IsZero() {
+		return nil
+	}
+	return svc.repo.MarkEmailVerified(ctx, u.Id, time.Now())
+}
+
+func (svc *userService) VerifyEmailByLink(ctx context.Context, email string) error {
+	u, err := svc.repo.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !u.VerifiedAt.IsZero() {
+		return nil
+	}
+	// 别人可能抢先用这个邮箱注册并设置了密码，先清掉密码再标记验证，中途失败也不会留下别人的密码
+	if u.Password != "" {
+		err = svc.repo.UpdatePassword(ctx, u.Id, "")
+		if err != nil {
+			return err
+		}
+	}
+	return svc.repo.MarkEmailVerified(ctx, u.Id, time.Now())
+}
+
+// ChangePassword 修改密码，旧密码不对返回 ErrInvalidUserOrPassword
+// 手机号、微信注册的用户没有密码，同样返回 ErrInvalidUserOrPassword
+func (svc *userService) ChangePassword(ctx context.Context, id int64, oldPassword, newPassword string) error {
+	u, err := svc.repo.FindById(ctx, id)
+	if err == repository.ErrUserNotFound {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(oldPassword))
+	if err != nil {
+		return ErrInvalidUserOrPassword
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return svc.repo.UpdatePassword(ctx, id, string(hash))
+}
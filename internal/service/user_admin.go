@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrUserQueryInvalid = errors.New("查询条件不合法")
+
+const (
+	userSearchDefaultLimit = 20
+	userSearchMaxLimit     = 100
+)
+
+// UserAdminService 给客服和管理员查询、冻结用户，权限由 RBAC 中间件校验
+//
+//go:generate mockgen.exe -source=./user_admin.go -package=svcmocks -destination=mocks/user_admin.mock.go UserAdminService
+type UserAdminService interface {
+	// Search 返回这一页的用户和下一页的游标，没有下一页的时候游标为 0
+	Search(ctx context.Context, q domain.UserQuery) ([]domain.User, int64, error)
+	// Detail 用户的完整信息和绑定的第三方账号
+	Detail(ctx context.Context, uid int64) (domain.User, []domain.UserIdentity, error)
+	// Freeze 冻结之后不能再登录，已经登录的设备需要调用方踢掉
+	Freeze(ctx context.Context, uid int64, reason string) error
+	Unfreeze(ctx context.Context, uid int64) error
+}
+
+type userAdminService struct {
+	repo         repository.UserRepository
+	identityRepo repository.IdentityRepository
+	now          func() time.Time
+}
+
+func NewUserAdminService(repo repository.UserRepository, identityRepo repository.IdentityRepository) UserAdminService {
+	return &userAdminService{
+		repo:         repo,
+		identityRepo: identityRepo,
+		now:          time.Now,
+	}
+}
+
+func (svc *userAdminService) Search(ctx context.Context, q domain.UserQuery) ([]domain.User, int64, error) {
+	if q.Limit <= 0 {
+		q.Limit = userSearchDefaultLimit
+	}
+	if q.Limit > userSearchMaxLimit || q.Cursor < 0 ||
+		(q.MaxId > 0 && q.MinId > q.MaxId) ||
+		(!q.CtimeEnd.IsZero() && q.CtimeStart.After(q.CtimeEnd)) {
+		return nil, 0, ErrUserQueryInvalid
+	}
+	users, err := svc.repo.Search(ctx, q)
+	if err != nil {
+		return nil, 0, err
+	}
+	var next int64
+	if len(users) == q.Limit {
+		next = users[len(users)-1].Id
+	}
+	return users, next, nil
+}
+
+func (svc *userAdminService) Detail(ctx context.Context, uid int64) (domain.User, []domain.UserIdentity, error) {
+	u, err := svc.repo.FindById(ctx, uid)
+	if err != nil {
+		return domain.User{}, nil, err
+	}
+	identities, err := svc.identityRepo.FindByUid(ctx, u.Id)
+	if err != nil {
+		return domain.User{}, nil, err
+	}
+	return u, identities, nil
+}
+
+func (svc *userAdminService) Freeze(ctx context.Context, uid int64, reason string) error {
+	if utf8.RuneCountInString(reason) > 256 {
+		return ErrUserQueryInvalid
+	}
+	return svc.repo.UpdateFrozen(ctx, uid, svc.now(), reason)
+}
+
+func (svc *userAdminService) Unfreeze(ctx context.Context, uid int64) error {
+	return svc.repo.UpdateFrozen(ctx, uid, time.Time{}, "")
+}
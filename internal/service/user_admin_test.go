@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func Test_userAdminService_Search(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+		q    domain.UserQuery
+
+		wantUsers  []domain.User
+		wantCursor int64
+		wantErr    error
+	}{
+		{
+			name: "默认每页 20 个，满一页返回下一页游标",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				users := make([]domain.User, 20)
+				for i := range users {
+					users[i].Id = int64(i + 11)
+				}
+				repo.EXPECT().Search(gomock.Any(), domain.UserQuery{Phone: "13800000000", Cursor: 10, Limit: 20}).
+					Return(users, nil)
+				return repo
+			},
+			q:          domain.UserQuery{Phone: "13800000000", Cursor: 10},
+			wantCursor: 30,
+		},
+		{
+			name: "最后一页",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().Search(gomock.Any(), domain.UserQuery{MinId: 1, MaxId: 100, Limit: 2}).
+					Return([]domain.User{{Id: 3}}, nil)
+				return repo
+			},
+			q:         domain.UserQuery{MinId: 1, MaxId: 100, Limit: 2},
+			wantUsers: []domain.User{{Id: 3}},
+		},
+		{
+			name: "每页太多",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			q:       domain.UserQuery{Limit: 101},
+			wantErr: ErrUserQueryInvalid,
+		},
+		{
+			name: "id 范围不对",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			q:       domain.UserQuery{MinId: 10, MaxId: 1},
+			wantErr: ErrUserQueryInvalid,
+		},
+		{
+			name: "时间范围不对",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			q: domain.UserQuery{
+				CtimeStart: time.UnixMilli(2000),
+				CtimeEnd:   time.UnixMilli(1000),
+			},
+			wantErr: ErrUserQueryInvalid,
+		},
+		{
+			name: "查询失败",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().Search(gomock.Any(), gomock.Any()).Return(nil, errors.New("db 错误"))
+				return repo
+			},
+			wantErr: errors.New("db 错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserAdminService(tc.mock(ctrl), nil)
+			users, cursor, err := svc.Search(context.Background(), tc.q)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCursor, cursor)
+			if tc.wantUsers != nil {
+				assert.Equal(t, tc.wantUsers, users)
+			}
+		})
+	}
+}
+
+func Test_userAdminService_Freeze(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	testCase := []struct {
+		name   string
+		mock   func(ctrl *gomock.Controller) repository.UserRepository
+		uid    int64
+		reason string
+
+		wantErr error
+	}{
+		{
+			name: "冻结成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().UpdateFrozen(gomock.Any(), int64(1), now, "发垃圾广告").Return(nil)
+				return repo
+			},
+			uid:    1,
+			reason: "发垃圾广告",
+		},
+		{
+			name: "用户不存在",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().UpdateFrozen(gomock.Any(), int64(1), now, "").Return(repository.ErrUserNotFound)
+				return repo
+			},
+			uid:     1,
+			wantErr: ErrUserNotFound,
+		},
+		{
+			name: "原因太长",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				return repomocks.NewMockUserRepository(ctrl)
+			},
+			uid:     1,
+			reason:  string(make([]rune, 257)),
+			wantErr: ErrUserQueryInvalid,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc := NewUserAdminService(tc.mock(ctrl), nil).(*userAdminService)
+			svc.now = func() time.Time {
+				return now
+			}
+			err := svc.Freeze(context.Background(), tc.uid, tc.reason)
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
@@ -13,7 +13,6 @@ import (
 	"time"
 )
 
-
 func Test_userService_Login(t *testing.T) {
 	now := time.Now()
 	testCase := []struct {
@@ -33,14 +32,15 @@ func Test_userService_Login(t *testing.T) {
 				repo := repomocks.NewMockUserRepository(ctrl)
 				repo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
 					Return(domain.User{
-						Id:       1,
-						Email:    "1426325504@qq.com",
-						Phone:    "178xxxxxxx3",
-						Password: "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
-						Nickname: "yeqin",
-						AboutMe:  "小胖子",
-						Birthday: now,
-						Ctime:    now,
+						Id:         1,
+						Email:      "1426325504@qq.com",
+						Phone:      "178xxxxxxx3",
+						Password:   "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+						Nickname:   "yeqin",
+						AboutMe:    "小胖子",
+						Birthday:   now,
+						VerifiedAt: now,
+						Ctime:      now,
 					}, nil)
 				return repo
 			},
@@ -48,14 +48,15 @@ func Test_userService_Login(t *testing.T) {
 			email:    "1426325504@qq.com",
 			password: "hellword@123",
 			wantUser: domain.User{
-				Id:       1,
-				Email:    "1426325504@qq.com",
-				Phone:    "178xxxxxxx3",
-				Password: "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
-				Nickname: "yeqin",
-				AboutMe:  "小胖子",
-				Birthday: now,
-				Ctime:    now,
+				Id:         1,
+				Email:      "1426325504@qq.com",
+				Phone:      "178xxxxxxx3",
+				Password:   "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+				Nickname:   "yeqin",
+				AboutMe:    "小胖子",
+				Birthday:   now,
+				VerifiedAt: now,
+				Ctime:      now,
 			},
 			wantErr: nil,
 		},
@@ -112,6 +113,73 @@ func Test_userService_Login(t *testing.T) {
 			wantUser: domain.User{},
 			wantErr:  ErrInvalidUserOrPassword,
 		},
+		{
+			name: "邮箱没有验证",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
+					Return(domain.User{
+						Id:       1,
+						Email:    "1426325504@qq.com",
+						Password: "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+						Ctime:    now,
+					}, nil)
+				return repo
+			},
+			ctx:      context.Background(),
+			email:    "1426325504@qq.com",
+			password: "hellword@123",
+			wantUser: domain.User{},
+			wantErr:  ErrEmailNotVerified,
+		},
+		{
+			name: "账号被冻结",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
+					Return(domain.User{
+						Id:         1,
+						Email:      "1426325504@qq.com",
+						Password:   "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+						Ctime:      now,
+						VerifiedAt: now,
+						FrozenAt:   now,
+					}, nil)
+				return repo
+			},
+			ctx:      context.Background(),
+			email:    "1426325504@qq.com",
+			password: "hellword@123",
+			wantUser: domain.User{},
+			wantErr:  ErrUserFrozen,
+		},
+		{
+			name: "注销冷静期内登录，还可能要两步验证，不在这里取消注销",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindByEmail(gomock.Any(), "1426325504@qq.com").
+					Return(domain.User{
+						Id:         1,
+						Email:      "1426325504@qq.com",
+						Password:   "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+						Ctime:      now,
+						VerifiedAt: now,
+						DeleteAt:   now,
+					}, nil)
+				return repo
+			},
+			ctx:      context.Background(),
+			email:    "1426325504@qq.com",
+			password: "hellword@123",
+			wantUser: domain.User{
+				Id:         1,
+				Email:      "1426325504@qq.com",
+				Password:   "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+				Ctime:      now,
+				VerifiedAt: now,
+				DeleteAt:   now,
+			},
+		},
 	}
 	for _, tc := range testCase {
 		t.Run(tc.name, func(t *testing.T) {
@@ -125,6 +193,69 @@ func Test_userService_Login(t *testing.T) {
 	}
 }
 
+func Test_userService_ChangePassword(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) repository.UserRepository
+		// 输入
+		oldPassword string
+		// 输出
+		wantErr error
+	}{
+		{
+			name: "修改成功",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{
+						Id:       1,
+						Password: "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+					}, nil)
+				repo.EXPECT().UpdatePassword(gomock.Any(), int64(1), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, id int64, password string) error {
+						return bcrypt.CompareHashAndPassword([]byte(password), []byte("hellword@456"))
+					})
+				return repo
+			},
+			oldPassword: "hellword@123",
+		},
+		{
+			name: "旧密码不对",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{
+						Id:       1,
+						Password: "$2a$10$mb97OEV00ZcyUl8ablHht.eJOKyMgOY/XcNLrBKzQGvTJDwJEb1Eq",
+					}, nil)
+				return repo
+			},
+			oldPassword: "hellword@12",
+			wantErr:     ErrInvalidUserOrPassword,
+		},
+		{
+			name: "没有设置过密码",
+			mock: func(ctrl *gomock.Controller) repository.UserRepository {
+				repo := repomocks.NewMockUserRepository(ctrl)
+				repo.EXPECT().FindById(gomock.Any(), int64(1)).
+					Return(domain.User{Id: 1, Phone: "178xxxxxxx3"}, nil)
+				return repo
+			},
+			oldPassword: "",
+			wantErr:     ErrInvalidUserOrPassword,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			service := NewUserService(tc.mock(ctrl), nil)
+			err := service.ChangePassword(context.Background(), 1, tc.oldPassword, "hellword@456")
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
 func TestEncrypted(t *testing.T) {
 	hash, err := bcrypt.GenerateFromPassword([]byte("hellword@123"), bcrypt.DefaultCost)
 	if err == nil {
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2/wechat"
+)
+
+var (
+	// ErrWechatMiniSessionExpired session_key 过期了，小程序需要重新 wx.login
+	ErrWechatMiniSessionExpired = errors.New("小程序登录已经过期，请重新登录")
+	ErrWechatMiniDecrypt        = wechat.ErrMiniDecrypt
+)
+
+// WechatMiniService 微信小程序登录，按 unionid 和网站应用的微信登录是同一个用户
+//
+//go:generate mockgen.exe -source=./wechat_mini.go -package=svcmocks -destination=mocks/wechat_mini.mock.go WechatMiniService
+type WechatMiniService interface {
+	// Login 用 wx.login 拿到的 code 登录，没有注册过的新建用户
+	Login(ctx context.Context, jsCode string) (domain.User, error)
+	// BindPhone 解密 getPhoneNumber 的数据并绑定手机号，手机号已经被其它用户绑定返回 ErrBindConflict
+	BindPhone(ctx context.Context, uid int64, encryptedData string, iv string) (string, error)
+}
+
+type wechatMiniService struct {
+	svc         wechat.MiniService
+	identitySvc IdentityService
+	sessionRepo repository.WechatMiniSessionRepository
+	userRepo    repository.UserRepository
+	log         accesslog.Logger
+}
+
+func NewWechatMiniService(svc wechat.MiniService, identitySvc IdentityService,
+	sessionRepo repository.WechatMiniSessionRepository, userRepo repository.UserRepository,
+	log accesslog.Logger) WechatMiniService {
+	return &wechatMiniService{
+		svc:         svc,
+		identitySvc: identitySvc,
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		log:         log,
+	}
+}
+
+func (s *wechatMiniService) Login(ctx context.Context, jsCode string) (domain.User, error) {
+	session, err := s.svc.Code2Session(ctx, jsCode)
+	if err != nil {
+		return domain.User{}, err
+	}
+	u, err := s.identitySvc.FindOrCreateByIdentity(ctx, domain.UserIdentity{
+		Provider: domain.ProviderWechatMini,
+		Subject:  session.OpenId,
+		UnionId:  session.UnionId,
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+	// 保存失败只影响绑定手机号，不影响登录
+	if err = s.sessionRepo.SaveSessionKey(ctx, u.Id, session.SessionKey); err != nil {
+		s.log.Error("保存小程序 session_key 失败", accesslog.Int64("uid", u.Id), accesslog.Error(err))
+	}
+	return u, nil
+}
+
+func (s *wechatMiniService) BindPhone(ctx context.Context, uid int64, encryptedData string, iv string) (string, error) {
+	sessionKey, err := s.sessionRepo.FindSessionKey(ctx, uid)
+	if err == repository.ErrWechatMiniSessionNotFound {
+		return "", ErrWechatMiniSessionExpired
+	}
+	if err != nil {
+		return "", err
+	}
+	phone, err := s.svc.DecryptPhone(sessionKey, encryptedData, iv)
+	if err != nil {
+		return "", err
+	}
+	err = s.userRepo.UpdatePhone(ctx, uid, phone)
+	if err == repository.ErrUserDuplicatePhone {
+		return "", ErrBindConflict
+	}
+	return phone, err
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	repomocks "github.com/dadaxiaoxiao/user/internal/repository/mocks"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2/wechat"
+	wechatmocks "github.com/dadaxiaoxiao/user/internal/service/oauth2/wechat/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func Test_wechatMiniService_BindPhone(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (wechat.MiniService, repository.WechatMiniSessionRepository, repository.UserRepository)
+		// 输出
+		wantPhone string
+		wantErr   error
+	}{
+		{
+			name: "绑定成功",
+			mock: func(ctrl *gomock.Controller) (wechat.MiniService, repository.WechatMiniSessionRepository, repository.UserRepository) {
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				sessionRepo.EXPECT().FindSessionKey(gomock.Any(), int64(1)).Return("session_key", nil)
+				svc := wechatmocks.NewMockMiniService(ctrl)
+				svc.EXPECT().DecryptPhone("session_key", "data", "iv").Return("13800138000", nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().UpdatePhone(gomock.Any(), int64(1), "13800138000").Return(nil)
+				return svc, sessionRepo, userRepo
+			},
+			wantPhone: "13800138000",
+		},
+		{
+			name: "session_key 过期了",
+			mock: func(ctrl *gomock.Controller) (wechat.MiniService, repository.WechatMiniSessionRepository, repository.UserRepository) {
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				sessionRepo.EXPECT().FindSessionKey(gomock.Any(), int64(1)).Return("", repository.ErrWechatMiniSessionNotFound)
+				return wechatmocks.NewMockMiniService(ctrl), sessionRepo, repomocks.NewMockUserRepository(ctrl)
+			},
+			wantErr: ErrWechatMiniSessionExpired,
+		},
+		{
+			name: "解密失败",
+			mock: func(ctrl *gomock.Controller) (wechat.MiniService, repository.WechatMiniSessionRepository, repository.UserRepository) {
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				sessionRepo.EXPECT().FindSessionKey(gomock.Any(), int64(1)).Return("session_key", nil)
+				svc := wechatmocks.NewMockMiniService(ctrl)
+				svc.EXPECT().DecryptPhone("session_key", "data", "iv").Return("", wechat.ErrMiniDecrypt)
+				return svc, sessionRepo, repomocks.NewMockUserRepository(ctrl)
+			},
+			wantErr: ErrWechatMiniDecrypt,
+		},
+		{
+			name: "手机号已经绑定了其它用户",
+			mock: func(ctrl *gomock.Controller) (wechat.MiniService, repository.WechatMiniSessionRepository, repository.UserRepository) {
+				sessionRepo := repomocks.NewMockWechatMiniSessionRepository(ctrl)
+				sessionRepo.EXPECT().FindSessionKey(gomock.Any(), int64(1)).Return("session_key", nil)
+				svc := wechatmocks.NewMockMiniService(ctrl)
+				svc.EXPECT().DecryptPhone("session_key", "data", "iv").Return("13800138000", nil)
+				userRepo := repomocks.NewMockUserRepository(ctrl)
+				userRepo.EXPECT().UpdatePhone(gomock.Any(), int64(1), "13800138000").Return(repository.ErrUserDuplicatePhone)
+				return svc, sessionRepo, userRepo
+			},
+			wantErr: ErrBindConflict,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, sessionRepo, userRepo := tc.mock(ctrl)
+			miniSvc := NewWechatMiniService(svc, nil, sessionRepo, userRepo, accesslog.NewNopLogger())
+			phone, err := miniSvc.BindPhone(context.Background(), 1, "data", "iv")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantPhone, phone)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// AccountDeletionHandler 注销账号
+// 申请之后退出所有设备，冷静期内重新登录就会取消注销，所以不需要单独的取消接口
+// 不能用个人访问令牌申请，在 ioc 里面配置中间件拒绝
+type AccountDeletionHandler struct {
+	svc   service.AccountDeletionService
+	wtHdl myjwt.Handler
+	log   accesslog.Logger
+}
+
+func NewAccountDeletionHandler(svc service.AccountDeletionService, wtHdl myjwt.Handler,
+	log accesslog.Logger) *AccountDeletionHandler {
+	return &AccountDeletionHandler{
+		svc:   svc,
+		wtHdl: wtHdl,
+		log:   log,
+	}
+}
+
+func (h *AccountDeletionHandler) RegisterRoutes(server *gin.Engine) {
+	server.POST("/users/deletion/request", h.Request)
+}
+
+// Request 申请注销，返回删除个人信息的时间
+func (h *AccountDeletionHandler) Request(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	deleteAt, err := h.svc.Request(ctx.Request.Context(), uc.Uid)
+	if err == nil {
+		err = h.wtHdl.RevokeSessions(ctx.Request.Context(), uc.Uid, "")
+	}
+	if err != nil {
+		h.log.Error("申请注销失败", accesslog.Error(err), accesslog.Int64("uid", uc.Uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	h.log.Info("申请注销", accesslog.Int64("uid", uc.Uid), accesslog.Int64("deleteAt", deleteAt.UnixMilli()))
+	ctx.JSONP(http.StatusOK, Result{
+		Msg:  "已经申请注销，在这之前重新登录可以取消",
+		Data: deleteAt.Format(time.DateTime),
+	})
+}
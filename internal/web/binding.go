@@ -0,0 +1,217 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	regexp "github.com/dlclark/regexp2"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// BindingHandler 已经登录的用户绑定和解绑手机号、邮箱和第三方账号
+// 第三方账号的绑定走 OAuth2Handler.BindURL
+type BindingHandler struct {
+	svc           service.BindingService
+	emailRegexExp *regexp.Regexp
+	phoneRegexExp *regexp.Regexp
+	log           accesslog.Logger
+}
+
+func NewBindingHandler(svc service.BindingService, log accesslog.Logger) *BindingHandler {
+	return &BindingHandler{
+		svc:           svc,
+		emailRegexExp: regexp.MustCompile(emailRegexPattern, regexp.None),
+		phoneRegexExp: regexp.MustCompile(phoneRegexPattern, regexp.None),
+		log:           log,
+	}
+}
+
+func (h *BindingHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users")
+	ug.GET("/bindings", h.Bindings)
+	ug.POST("/bind/code/send", h.SendCode)
+	ug.POST("/bind", h.Bind)
+	ug.POST("/unbind", h.Unbind)
+}
+
+// Bindings 已经绑定的登录方式
+func (h *BindingHandler) Bindings(ctx *gin.Context) {
+	type Identity struct {
+		Provider string `json:"provider"`
+		Nickname string `json:"nickname"`
+	}
+	type Resp struct {
+		Phone      string     `json:"phone"`
+		Email      string     `json:"email"`
+		Identities []Identity `json:"identities"`
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	b, err := h.svc.Bindings(ctx.Request.Context(), uc.Uid)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	resp := Resp{
+		Phone:      b.Phone,
+		Email:      b.Email,
+		Identities: make([]Identity, 0, len(b.Identities)),
+	}
+	for _, i := range b.Identities {
+		resp.Identities = append(resp.Identities, Identity{
+			Provider: i.Provider,
+			Nickname: i.Nickname,
+		})
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: resp,
+	})
+}
+
+// SendCode 向要绑定的手机号或者邮箱发送验证码
+func (h *BindingHandler) SendCode(ctx *gin.Context) {
+	type Req struct {
+		// Type email 或者 phone
+		Type   string `json:"type"`
+		Target string `json:"target"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if !h.checkTarget(ctx, req.Type, req.Target) {
+		return
+	}
+
+	err := h.svc.SendCode(ctx.Request.Context(), req.Type, req.Target)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case service.ErrCodeSendTooMany:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "发送太频繁，请稍后再试",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Warn("发送绑定验证码失败", accesslog.Error(err))
+	}
+}
+
+// Bind 校验验证码之后绑定手机号或者邮箱，已经绑定过的会被替换
+func (h *BindingHandler) Bind(ctx *gin.Context) {
+	type Req struct {
+		Type   string `json:"type"`
+		Target string `json:"target"`
+		Code   string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if !h.checkTarget(ctx, req.Type, req.Target) {
+		return
+	}
+
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := h.svc.BindWithCode(ctx.Request.Context(), uc.Uid, req.Type, req.Target, req.Code)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "绑定成功",
+		})
+	case service.ErrBindCodeInvalid:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "验证码错误",
+		})
+	case service.ErrCodeVerifyTooManyTimes:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "验证次数太多，请重新发送验证码",
+		})
+	case service.ErrBindConflict:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserBindConflict,
+			Msg:  "该手机号或者邮箱已经绑定了其它账号",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("绑定手机号或者邮箱失败", accesslog.Int64("uid", uc.Uid), accesslog.Error(err))
+	}
+}
+
+// Unbind 解绑，method 是 phone、email 或者第三方提供方的名字
+func (h *BindingHandler) Unbind(ctx *gin.Context) {
+	type Req struct {
+		Method string `json:"method"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := h.svc.Unbind(ctx.Request.Context(), uc.Uid, req.Method)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "解绑成功",
+		})
+	case service.ErrUnbindNotBound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "没有绑定该登录方式",
+		})
+	case service.ErrUnbindLastMethod:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserUnbindLastMethod,
+			Msg:  "至少需要保留一种登录方式",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("解绑失败", accesslog.Int64("uid", uc.Uid), accesslog.Error(err))
+	}
+}
+
+// checkTarget 校验手机号或者邮箱的格式，不通过的时候已经写好了响应
+func (h *BindingHandler) checkTarget(ctx *gin.Context, typ string, target string) bool {
+	var (
+		ok  bool
+		err error
+	)
+	switch typ {
+	case service.BindByEmail:
+		ok, err = h.emailRegexExp.MatchString(target)
+	case service.BindByPhone:
+		ok, err = h.phoneRegexExp.MatchString(target)
+	}
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return false
+	}
+	if !ok {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "邮箱或者手机号不正确",
+		})
+	}
+	return ok
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"fmt"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// DataExportHandler 导出个人数据
+// 导出的是全部的个人数据，创建和查询不能用个人访问令牌，在 ioc 里面配置中间件拒绝
+// 创建之后轮询状态，成功之后状态里面带上下载链接，下载接口靠链接里面的签名校验，不需要登录
+type DataExportHandler struct {
+	svc service.DataExportService
+	log accesslog.Logger
+}
+
+func NewDataExportHandler(svc service.DataExportService, log accesslog.Logger) *DataExportHandler {
+	return &DataExportHandler{
+		svc: svc,
+		log: log,
+	}
+}
+
+func (h *DataExportHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/users/exports")
+	g.POST("/create", h.Create)
+	g.GET("/status", h.Status)
+	g.GET("/download", h.Download)
+}
+
+type dataExportVo struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	Size   int64  `json:"size"`
+	Ctime  string `json:"ctime"`
+	// DownloadURL 导出成功之后才有
+	DownloadURL string `json:"downloadUrl,omitempty"`
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+}
+
+// Create 创建导出任务，已经有进行中的任务的时候返回那个任务
+func (h *DataExportHandler) Create(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	e, err := h.svc.Create(ctx.Request.Context(), uc.Uid)
+	if err != nil {
+		h.log.Error("创建导出任务失败", accesslog.Error(err), accesslog.Int64("uid", uc.Uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: h.toVo(e),
+	})
+}
+
+// Status 查询导出任务，GET /users/exports/status?id=xxx
+func (h *DataExportHandler) Status(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	e, err := h.svc.Find(ctx.Request.Context(), uc.Uid, ctx.Query("id"))
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Data: h.toVo(e),
+		})
+	case service.ErrDataExportNotFound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "导出任务不存在或者已经过期",
+		})
+	default:
+		h.log.Error("查询导出任务失败", accesslog.Error(err), accesslog.Int64("uid", uc.Uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// Download 下载压缩包，GET /users/exports/download?token=xxx
+func (h *DataExportHandler) Download(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	e, r, err := h.svc.Open(ctx.Request.Context(), ctx.Query("token"))
+	switch err {
+	case nil:
+	case service.ErrDataExportLinkInvalid:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "下载链接无效或者已经过期，请重新导出",
+		})
+		return
+	default:
+		h.log.Error("打开导出文件失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	defer r.Close()
+	ctx.DataFromReader(http.StatusOK, e.Size, "application/zip", r, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="user-data-%s.zip"`, e.Id),
+	})
+}
+
+func (h *DataExportHandler) toVo(e domain.DataExport) dataExportVo {
+	vo := dataExportVo{
+		Id:     e.Id,
+		Status: e.Status,
+		Size:   e.Size,
+		Ctime:  e.Ctime.Format(time.DateTime),
+	}
+	if e.Status == domain.DataExportSucceeded {
+		link, expiresAt := h.svc.DownloadURL(e)
+		vo.DownloadURL = link
+		vo.ExpiresAt = expiresAt.Format(time.DateTime)
+	}
+	return vo
+}
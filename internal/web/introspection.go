@@ -0,0 +1,85 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// IntrospectionHandler 资源服务器校验 token，RFC 7662
+// 资源服务器自己用 JWKS 验签的话感知不到退出登录，需要实时结果的时候调用这里
+type IntrospectionHandler struct {
+	oidcSvc service.OIDCService
+	wtHdl   myjwt.Handler
+	log     accesslog.Logger
+}
+
+func NewIntrospectionHandler(oidcSvc service.OIDCService, wtHdl myjwt.Handler, log accesslog.Logger) *IntrospectionHandler {
+	return &IntrospectionHandler{
+		oidcSvc: oidcSvc,
+		wtHdl:   wtHdl,
+		log:     log,
+	}
+}
+
+func (h *IntrospectionHandler) RegisterRoutes(server *gin.Engine) {
+	server.POST("/oidc/introspect", h.Introspect)
+}
+
+// Introspect 调用方用 client 凭证认证，响应不包在 Result 里面
+func (h *IntrospectionHandler) Introspect(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	clientId, secret := clientCredentials(ctx)
+	_, err := h.oidcSvc.AuthenticateClient(ctx.Request.Context(), clientId, secret)
+	switch err {
+	case nil:
+	case service.ErrOIDCInvalidClient:
+		ctx.Header("WWW-Authenticate", `Basic realm="introspect"`)
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	default:
+		h.log.Error("认证 client 失败", accesslog.Error(err), accesslog.String("client_id", clientId))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	token := ctx.PostForm("token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+	res, err := h.wtHdl.Introspect(ctx.Request.Context(), token)
+	if err != nil {
+		h.log.Error("token 自省失败", accesslog.Error(err), accesslog.String("client_id", clientId))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	if !res.Active {
+		ctx.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+	resp := gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+		"sub":        strconv.FormatInt(res.Uid, 10),
+		"uid":        res.Uid,
+		"exp":        res.ExpiresAt.Unix(),
+	}
+	if res.Ssid != "" {
+		resp["ssid"] = res.Ssid
+	}
+	if !res.IssuedAt.IsZero() {
+		resp["iat"] = res.IssuedAt.Unix()
+	}
+	if res.ClientId != "" {
+		resp["client_id"] = res.ClientId
+	}
+	if len(res.Scopes) > 0 {
+		resp["scope"] = strings.Join(res.Scopes, " ")
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,137 @@
+package web
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache/redismocks"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	svcmocks "github.com/dadaxiaoxiao/user/internal/service/mocks"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntrospectionHandler_Introspect(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ks, err := myjwt.NewKeySet("k1", myjwt.Key{Kid: "k1", Private: priv, Public: pub})
+	require.NoError(t, err)
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	accessToken, err := ks.Sign(myjwt.TypeAccessToken, OIDCAccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "123",
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+		ClientId: "rp",
+		Scope:    "openid email",
+	})
+	require.NoError(t, err)
+	cacheMiss := func(ctrl *gomock.Controller) redis.Cmdable {
+		cmd := redismocks.NewMockCmdable(ctrl)
+		get := redis.NewStringCmd(context.Background())
+		get.SetErr(redis.Nil)
+		cmd.EXPECT().Get(gomock.Any(), gomock.Any()).Return(get)
+		cmd.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(redis.NewStatusCmd(context.Background()))
+		return cmd
+	}
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (service.OIDCService, redis.Cmdable)
+		form url.Values
+
+		wantCode int
+		wantBody map[string]any
+	}{
+		{
+			name: "token 有效",
+			mock: func(ctrl *gomock.Controller) (service.OIDCService, redis.Cmdable) {
+				svc := svcmocks.NewMockOIDCService(ctrl)
+				svc.EXPECT().AuthenticateClient(gomock.Any(), "rs", "secret").
+					Return(domain.OAuthClient{ClientId: "rs"}, nil)
+				return svc, cacheMiss(ctrl)
+			},
+			form:     url.Values{"client_id": {"rs"}, "client_secret": {"secret"}, "token": {accessToken}},
+			wantCode: http.StatusOK,
+			wantBody: map[string]any{
+				"active":     true,
+				"token_type": "Bearer",
+				"sub":        "123",
+				"uid":        float64(123),
+				"exp":        float64(exp.Unix()),
+				"client_id":  "rp",
+				"scope":      "openid email",
+			},
+		},
+		{
+			name: "token 无效",
+			mock: func(ctrl *gomock.Controller) (service.OIDCService, redis.Cmdable) {
+				svc := svcmocks.NewMockOIDCService(ctrl)
+				svc.EXPECT().AuthenticateClient(gomock.Any(), "rs", "secret").
+					Return(domain.OAuthClient{ClientId: "rs"}, nil)
+				return svc, cacheMiss(ctrl)
+			},
+			form:     url.Values{"client_id": {"rs"}, "client_secret": {"secret"}, "token": {"abc"}},
+			wantCode: http.StatusOK,
+			wantBody: map[string]any{"active": false},
+		},
+		{
+			name: "client 认证失败",
+			mock: func(ctrl *gomock.Controller) (service.OIDCService, redis.Cmdable) {
+				svc := svcmocks.NewMockOIDCService(ctrl)
+				svc.EXPECT().AuthenticateClient(gomock.Any(), "rs", "wrong").
+					Return(domain.OAuthClient{}, service.ErrOIDCInvalidClient)
+				return svc, nil
+			},
+			form:     url.Values{"client_id": {"rs"}, "client_secret": {"wrong"}, "token": {accessToken}},
+			wantCode: http.StatusUnauthorized,
+			wantBody: map[string]any{"error": "invalid_client"},
+		},
+		{
+			name: "没有 token",
+			mock: func(ctrl *gomock.Controller) (service.OIDCService, redis.Cmdable) {
+				svc := svcmocks.NewMockOIDCService(ctrl)
+				svc.EXPECT().AuthenticateClient(gomock.Any(), "rs", "secret").
+					Return(domain.OAuthClient{ClientId: "rs"}, nil)
+				return svc, nil
+			},
+			form:     url.Values{"client_id": {"rs"}, "client_secret": {"secret"}},
+			wantCode: http.StatusBadRequest,
+			wantBody: map[string]any{"error": "invalid_request"},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			svc, cmd := tc.mock(ctrl)
+			server := gin.New()
+			NewIntrospectionHandler(svc, myjwt.NewRedisJWTHandler(cmd, ks, myjwt.SessionConfig{}),
+				accesslog.NewNopLogger()).RegisterRoutes(server)
+
+			req := httptest.NewRequest(http.MethodPost, "/oidc/introspect", strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+			assert.Equal(t, tc.wantBody, body)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package web
+
+import (
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// JWKSHandler 公开验证 token 用的公钥，下游服务不需要持有私钥
+type JWKSHandler struct {
+	myjwt.Handler
+}
+
+func NewJWKSHandler(wtHdl myjwt.Handler) *JWKSHandler {
+	return &JWKSHandler{
+		Handler: wtHdl,
+	}
+}
+
+func (h *JWKSHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+// JWKS 按照 RFC 7517 的格式返回，不包在 Result 里面
+func (h *JWKSHandler) JWKS(ctx *gin.Context) {
+	// 轮换的时候新公钥要尽快被拿到，缓存时间不要太长
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.Handler.JWKS())
+}
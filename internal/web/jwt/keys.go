@@ -0,0 +1,212 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrKeyNotFound = errors.New("kid 对应的密钥不存在或者已经过期")
+	ErrTokenType   = errors.New("token 类型不对")
+)
+
+// token 类型，放在 header 的 typ 里面，避免 refresh token 被当成 access token 使用
+const (
+	TypeAccessToken  = "at+jwt"
+	TypeRefreshToken = "rt+jwt"
+)
+
+// Key 签名用的密钥
+type Key struct {
+	Kid string
+	// Private 私钥，只用来验证的旧密钥可以没有私钥
+	Private crypto.Signer
+	Public  crypto.PublicKey
+	// ExpireAt 过期之后不再用来验证，也不再出现在 JWKS 里面，零值代表不过期
+	ExpireAt time.Time
+}
+
+// KeySet 非对称签名的密钥集合
+// 轮换的时候，新密钥用来签名，旧密钥继续留在集合里面验证已经签发的 token，
+// 等 token 都过期之后再从配置里面删掉
+type KeySet struct {
+	signing Key
+	keys    map[string]Key
+	now     func() time.Time
+}
+
+// NewKeySet signingKid 对应的密钥必须有私钥
+func NewKeySet(signingKid string, keys ...Key) (*KeySet, error) {
+	ks := &KeySet{
+		keys: make(map[string]Key, len(keys)),
+		now:  time.Now,
+	}
+	for _, k := range keys {
+		if _, err := signingMethod(k.Public); err != nil {
+			return nil, fmt.Errorf("kid %s: %w", k.Kid, err)
+		}
+		if _, ok := ks.keys[k.Kid]; ok {
+			return nil, fmt.Errorf("kid %s 重复", k.Kid)
+		}
+		ks.keys[k.Kid] = k
+	}
+	signing, ok := ks.keys[signingKid]
+	if !ok || signing.Private == nil {
+		return nil, fmt.Errorf("签名密钥 %s 不存在或者没有私钥", signingKid)
+	}
+	ks.signing = signing
+	return ks, nil
+}
+
+// Sign 使用当前的签名密钥签名
+func (ks *KeySet) Sign(typ string, claims jwt.Claims) (string, error) {
+	method, err := signingMethod(ks.signing.Public)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = ks.signing.Kid
+	token.Header["typ"] = typ
+	return token.SignedString(ks.signing.Private)
+}
+
+// Parse 根据 kid 找到公钥验证签名
+func (ks *KeySet) Parse(tokenStr string, typ string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		if t, _ := token.Header["typ"].(string); t != typ {
+			return nil, ErrTokenType
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := ks.lookup(kid)
+		if !ok {
+			return nil, ErrKeyNotFound
+		}
+		method, err := signingMethod(key.Public)
+		if err != nil {
+			return nil, err
+		}
+		// 防止 alg 被篡改
+		if token.Method.Alg() != method.Alg() {
+			return nil, fmt.Errorf("不支持的签名算法 %s", token.Method.Alg())
+		}
+		return key.Public, nil
+	})
+}
+
+// JWKS 公开的公钥，RFC 7517
+func (ks *KeySet) JWKS() JWKS {
+	res := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for kid := range ks.keys {
+		key, ok := ks.lookup(kid)
+		if !ok {
+			continue
+		}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			res.Keys = append(res.Keys, JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: jwt.SigningMethodRS256.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			res.Keys = append(res.Keys, JWK{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Alg: jwt.SigningMethodEdDSA.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return res
+}
+
+func (ks *KeySet) lookup(kid string) (Key, bool) {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return Key{}, false
+	}
+	if !key.ExpireAt.IsZero() && ks.now().After(key.ExpireAt) {
+		return Key{}, false
+	}
+	return key, true
+}
+
+// signingMethod 只支持 RS256 和 EdDSA
+func signingMethod(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型 %T", pub)
+	}
+}
+
+// JWKS JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK JSON Web Key
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// ParseKey 解析 PEM 格式的密钥
+// 私钥支持 PKCS#8 和 PKCS#1（RSA），公钥支持 PKIX，只有公钥的密钥只能用来验证
+func ParseKey(kid string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("kid %s: 不是 PEM 格式", kid)
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("kid %s: %w", kid, err)
+		}
+		return Key{Kid: kid, Public: pub}, nil
+	case "RSA PRIVATE KEY":
+		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("kid %s: %w", kid, err)
+		}
+		return Key{Kid: kid, Private: priv, Public: priv.Public()}, nil
+	case "PRIVATE KEY":
+		priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("kid %s: %w", kid, err)
+		}
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return Key{}, fmt.Errorf("kid %s: 不支持的私钥类型 %T", kid, priv)
+		}
+		return Key{Kid: kid, Private: signer, Public: signer.Public()}, nil
+	default:
+		return Key{}, fmt.Errorf("kid %s: 不支持的 PEM 类型 %s", kid, block.Type)
+	}
+}
@@ -0,0 +1,130 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestKeySet_Rotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	now := time.Now()
+
+	// 旧的 RSA 密钥签发的 token
+	oldKs, err := NewKeySet("old", Key{Kid: "old", Private: rsaKey, Public: rsaKey.Public()})
+	require.NoError(t, err)
+	claims := UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Uid:  123,
+		Ssid: "ssid",
+	}
+	oldToken, err := oldKs.Sign(TypeAccessToken, claims)
+	require.NoError(t, err)
+
+	// 轮换之后用 Ed25519 签名，旧密钥只保留公钥
+	ks, err := NewKeySet("new",
+		Key{Kid: "new", Private: edPriv, Public: edPub},
+		Key{Kid: "old", Public: rsaKey.Public(), ExpireAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	newToken, err := ks.Sign(TypeAccessToken, claims)
+	require.NoError(t, err)
+
+	for _, tokenStr := range []string{oldToken, newToken} {
+		var uc UserClaims
+		token, err := ks.Parse(tokenStr, TypeAccessToken, &uc)
+		require.NoError(t, err)
+		assert.True(t, token.Valid)
+		assert.Equal(t, int64(123), uc.Uid)
+	}
+	var uc UserClaims
+	token, _ := ks.Parse(newToken, TypeAccessToken, &uc)
+	assert.Equal(t, "new", token.Header["kid"])
+	assert.Equal(t, "EdDSA", token.Method.Alg())
+
+	// 旧密钥过期之后，旧 token 不能再用
+	ks.now = func() time.Time { return now.Add(time.Hour * 2) }
+	_, err = ks.Parse(oldToken, TypeAccessToken, &UserClaims{})
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Len(t, ks.JWKS().Keys, 1)
+}
+
+func TestKeySet_TokenType(t *testing.T) {
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ks, err := NewKeySet("k1", Key{Kid: "k1", Private: edPriv, Public: edPub})
+	require.NoError(t, err)
+
+	refreshToken, err := ks.Sign(TypeRefreshToken, RefreshClaims{Uid: 123, Ssid: "ssid"})
+	require.NoError(t, err)
+	// refresh token 不能当 access token 用
+	_, err = ks.Parse(refreshToken, TypeAccessToken, &UserClaims{})
+	assert.ErrorIs(t, err, ErrTokenType)
+
+	// 以前 HS512 签发的 token 不再被接受
+	hsToken, err := jwt.NewWithClaims(jwt.SigningMethodHS512, UserClaims{Uid: 123}).
+		SignedString([]byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0"))
+	require.NoError(t, err)
+	_, err = ks.Parse(hsToken, TypeAccessToken, &UserClaims{})
+	assert.Error(t, err)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ks, err := NewKeySet("ed",
+		Key{Kid: "ed", Private: edPriv, Public: edPub},
+		Key{Kid: "rsa", Public: rsaKey.Public()})
+	require.NoError(t, err)
+
+	jwks := ks.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	for _, k := range jwks.Keys {
+		switch k.Kid {
+		case "ed":
+			assert.Equal(t, "OKP", k.Kty)
+			assert.Equal(t, "Ed25519", k.Crv)
+			assert.Equal(t, "EdDSA", k.Alg)
+		case "rsa":
+			assert.Equal(t, "RSA", k.Kty)
+			assert.Equal(t, "RS256", k.Alg)
+			assert.Equal(t, "AQAB", k.E)
+		default:
+			t.Fatalf("未知的 kid %s", k.Kid)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edPriv)
+	require.NoError(t, err)
+	key, err := ParseKey("k1", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.NotNil(t, key.Private)
+
+	der, err = x509.MarshalPKIXPublicKey(key.Public)
+	require.NoError(t, err)
+	pub, err := ParseKey("k1", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.Nil(t, pub.Private)
+	assert.Equal(t, key.Public, pub.Public)
+
+	// 只有公钥的密钥不能用来签名
+	_, err = NewKeySet("k1", pub)
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+-- users:session:xxx
+local key = KEYS[1]
+-- users:sessions:123
+local sessionsKey = KEYS[2]
+-- users:ssid:xxx，撤销登录的标记
+local revokedKey = KEYS[3]
+-- 客户端带上来的 refresh token 的 jti
+local jti = ARGV[1]
+local newJti = ARGV[2]
+local now = tonumber(ARGV[3])
+-- 滑动过期时间，秒
+local sliding = tonumber(ARGV[4])
+-- 绝对过期时间，毫秒
+local absolute = tonumber(ARGV[5])
+local ssid = ARGV[6]
+-- 轮换之后旧的 jti 还能用多久，毫秒。新的 token 可能没有送到客户端，或者客户端同时发起了两次刷新
+local grace = tonumber(ARGV[7])
+-- 撤销标记保留多久，秒，滑动周期和 access token 有效期里面长的那个
+local revokedTTL = tonumber(ARGV[8])
+
+-- revoke 和 RedisJWTHandler.revoke 一样，撤销整个登录
+local function revoke()
+    redis.call("set", revokedKey, "", "EX", revokedTTL)
+    redis.call("srem", sessionsKey, ssid)
+    redis.call("del", key)
+end
+
+-- 返回 {登录时间, 要签发的 jti}，失败的时候登录时间是负数
+if redis.call("exists", key) == 0 then
+    -- 登录已经过期或者被踢下线
+    return {-1, ""}
+end
+local vals = redis.call("hmget", key, "rt_jti", "prev_jti", "prev_until", "ctime")
+-- 升级之前签发的 refresh token 没有 jti
+local cur = vals[1] or ""
+local prev = vals[2] or ""
+local prevUntil = tonumber(vals[3] or "0")
+local ctime = tonumber(vals[4])
+local issueJti = newJti
+if cur ~= jti then
+    if jti == "" or jti ~= prev or now >= prevUntil then
+        -- 已经轮换过的 refresh token 又被使用了
+        revoke()
+        return {-2, ""}
+    end
+    -- 宽限期内用上一个 jti 刷新，不再轮换，签发和当前 jti 一样的 token
+    issueJti = cur
+end
+local deadline = ctime + absolute
+if now >= deadline then
+    revoke()
+    return {-3, ""}
+end
+local ttl = math.min(sliding, math.ceil((deadline - now) / 1000))
+if issueJti == newJti then
+    redis.call("hset", key, "rt_jti", newJti, "prev_jti", jti, "prev_until", now + grace)
+end
+redis.call("hset", key, "utime", now)
+redis.call("expire", key, ttl)
+redis.call("expire", sessionsKey, sliding)
+return {ctime, issueJti}
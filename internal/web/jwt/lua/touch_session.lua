@@ -0,0 +1,6 @@
+-- 设备信息存在才更新，避免留下没有过期时间的 key
+local key = KEYS[1]
+if redis.call("exists", key) == 1 then
+    return redis.call("hset", key, "utime", ARGV[1])
+end
+return 0
@@ -1,77 +1,163 @@
 package jwt
 
 import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-var (
-	AccessTokenKey  = []byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0")
-	RefreshTokenKey = []byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0")
+// luaTouchSession 更新最近一次刷新 token 的时间
+//
+//go:embed lua/touch_session.lua
+var luaTouchSession string
+
+// luaRotateRefresh 校验并轮换 refresh token 的 jti
+//
+//go:embed lua/rotate_refresh.lua
+var luaRotateRefresh string
+
+// introspectCacheTTL token 自省结果的缓存时间
+const introspectCacheTTL = 10 * time.Second
+
+// accessTokenTTL access token 的有效期
+const accessTokenTTL = time.Hour
+
+const (
+	// loginHistoryLimit 每个用户保留的登录记录条数
+	loginHistoryLimit = 100
+	// loginHistoryTTL 一直没有登录的话，登录记录保留的时间
+	loginHistoryTTL = time.Hour * 24 * 180
 )
 
+var errSessionRevoked = errors.New("session 已经无效了")
+
 type RedisJWTHandler struct {
-	cmd          redis.Cmdable
-	rcExpiration time.Duration
+	cmd  redis.Cmdable
+	keys *KeySet
+	cfg  SessionConfig
 }
 
-func NewRedisJWTHandler(cmd redis.Cmdable) Handler {
+func NewRedisJWTHandler(cmd redis.Cmdable, keys *KeySet, cfg SessionConfig) Handler {
 	return &RedisJWTHandler{
-		cmd:          cmd,
-		rcExpiration: time.Hour * 24 * 7,
+		cmd:  cmd,
+		keys: keys,
+		cfg:  cfg,
 	}
 }
 
-func (r *RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64) error {
+func (r *RedisJWTHandler) SetLoginToken(ctx *gin.Context, uid int64, method string) error {
 	ssid := uuid.New().String()
+	jti := uuid.New().String()
+	now := time.Now()
+	// 记录登录设备，方便用户查看和踢下线
+	err := r.addSession(ctx, uid, Session{
+		Ssid:      ssid,
+		UserAgent: ctx.Request.UserAgent(),
+		IP:        ctx.ClientIP(),
+		Method:    method,
+		Ctime:     now,
+	}, jti)
+	if err != nil {
+		return err
+	}
 	// 设置 jwt token
-	err := r.SetJWTToken(ctx, uid, ssid)
+	err = r.SetJWTToken(ctx, uid, ssid)
 	if err != nil {
 		return err
 	}
 	// 设置 refreshtoken
-	err = r.setRefreshToken(ctx, uid, ssid)
+	err = r.setRefreshToken(ctx, uid, ssid, jti, r.refreshExpiresAt(now, now))
 	return err
 }
 
+// RefreshLoginToken 每次刷新都签发新的 refresh token，旧的在 SessionConfig.ReuseGrace 之后作废
+// 宽限期内再用旧的刷新，拿到的是和当前 jti 一样的 token，不算重放
+func (r *RedisJWTHandler) RefreshLoginToken(ctx *gin.Context, claims RefreshClaims) error {
+	newJti := uuid.New().String()
+	now := time.Now()
+	vals, err := r.cmd.Eval(ctx, luaRotateRefresh,
+		[]string{r.sessionKey(claims.Ssid), r.sessionsKey(claims.Uid), r.revokedKey(claims.Ssid)},
+		claims.ID, newJti, now.UnixMilli(), int64(r.cfg.Sliding.Seconds()), r.cfg.Absolute.Milliseconds(), claims.Ssid,
+		r.cfg.ReuseGrace.Milliseconds(), int64(r.revokedTTL().Seconds())).Slice()
+	if err != nil {
+		return err
+	}
+	if len(vals) != 2 {
+		return fmt.Errorf("轮换 refresh token 的脚本返回值不对 %v", vals)
+	}
+	// 宽限期内 jti 是当前的，不是 newJti
+	res, ok1 := vals[0].(int64)
+	jti, ok2 := vals[1].(string)
+	if !ok1 || !ok2 {
+		return fmt.Errorf("轮换 refresh token 的脚本返回值不对 %v", vals)
+	}
+	switch res {
+	case -1:
+		return ErrSessionNotFound
+	case -2:
+		// 旧的 refresh token 被重放，无法分辨哪边是攻击者，脚本里面已经撤销了整个登录
+		return ErrRefreshTokenReused
+	case -3:
+		// 超过了绝对有效期，同样已经撤销
+		return ErrSessionNotFound
+	}
+	err = r.SetJWTToken(ctx, claims.Uid, claims.Ssid)
+	if err != nil {
+		return err
+	}
+	return r.setRefreshToken(ctx, claims.Uid, claims.Ssid, jti, r.refreshExpiresAt(time.UnixMilli(res), now))
+}
+
+// refreshExpiresAt 滑动过期，但是不能超过登录时间加上绝对有效期
+func (r *RedisJWTHandler) refreshExpiresAt(ctime time.Time, now time.Time) time.Time {
+	exp := now.Add(r.cfg.Sliding)
+	if deadline := ctime.Add(r.cfg.Absolute); deadline.Before(exp) {
+		return deadline
+	}
+	return exp
+}
+
 func (r *RedisJWTHandler) SetJWTToken(ctx *gin.Context, uid int64, ssid string) error {
 	claims := UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
 		},
 		Uid:       uid,
 		UserAgent: ctx.Request.UserAgent(),
 		Ssid:      ssid,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims) // token 携带用户信息
-
-	tokenStr, err := token.SignedString(AccessTokenKey)
+	tokenStr, err := r.keys.Sign(TypeAccessToken, claims) // token 携带用户信息
 	if err != nil {
 		return err
 	}
 	ctx.Header("x-jwt-token", tokenStr)
-	return nil
+	// 记录最近一次刷新的时间
+	return r.touchSession(ctx, ssid)
 }
 
-// setRefreshToken 设置refresh
-func (r *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid string) error {
+// setRefreshToken 设置refresh，jti 和 Redis 里面记录的一致才能使用
+func (r *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid string, jti string, expiresAt time.Time) error {
 	claims := RefreshClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			// 有效期7 天
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(r.rcExpiration)),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 		Uid:  uid,
 		Ssid: ssid,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims) // token 携带用户信息
-
-	tokenStr, err := token.SignedString(RefreshTokenKey)
+	tokenStr, err := r.keys.Sign(TypeRefreshToken, claims) // token 携带用户信息
 	if err != nil {
 		return err
 	}
@@ -79,6 +165,16 @@ func (r *RedisJWTHandler) setRefreshToken(ctx *gin.Context, uid int64, ssid stri
 	return nil
 }
 
+// ParseToken 校验签名，typ 是 TypeAccessToken 或者 TypeRefreshToken
+func (r *RedisJWTHandler) ParseToken(tokenStr string, typ string, claims jwt.Claims) (*jwt.Token, error) {
+	return r.keys.Parse(tokenStr, typ, claims)
+}
+
+// JWKS 公钥集合，下游服务用来验证 access token
+func (r *RedisJWTHandler) JWKS() JWKS {
+	return r.keys.JWKS()
+}
+
 // ExtractToken 提取jwt token
 func (r *RedisJWTHandler) ExtractToken(ctx *gin.Context) string {
 	tokenHeader := ctx.GetHeader("Authorization") // 	Bearer token
@@ -91,7 +187,11 @@ func (r *RedisJWTHandler) ExtractToken(ctx *gin.Context) string {
 }
 
 func (r *RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) error {
-	cnt, err := r.cmd.Exists(ctx, fmt.Sprintf("users:ssid:%s", ssid)).Result()
+	return r.checkSession(ctx, ssid)
+}
+
+func (r *RedisJWTHandler) checkSession(ctx context.Context, ssid string) error {
+	cnt, err := r.cmd.Exists(ctx, r.revokedKey(ssid)).Result()
 	switch err {
 	case redis.Nil: // key 不存在
 		return nil
@@ -100,13 +200,88 @@ func (r *RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) error {
 			return nil
 		}
 		// 存在key
-		return errors.New("session 已经无效了")
+		return errSessionRevoked
 	default:
 		return err
 	}
 	return nil
 }
 
+// introspectClaims 同时兼容 UserClaims 和 /oidc/token 签发的 token
+type introspectClaims struct {
+	jwt.RegisteredClaims
+	Uid      int64
+	Ssid     string
+	ClientId string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// Introspect 结果缓存一小段时间，资源服务器每个请求都来问也扛得住
+// 代价是登录态失效之后，最多 introspectCacheTTL 之内还会返回 active
+func (r *RedisJWTHandler) Introspect(ctx context.Context, token string) (Introspection, error) {
+	key := r.introspectKey(token)
+	data, err := r.cmd.Get(ctx, key).Bytes()
+	if err == nil {
+		var res Introspection
+		if err = json.Unmarshal(data, &res); err == nil {
+			return res, nil
+		}
+	}
+	res, err := r.introspect(ctx, token)
+	if err != nil {
+		return Introspection{}, err
+	}
+	ttl := introspectCacheTTL
+	if res.Active {
+		ttl = min(ttl, time.Until(res.ExpiresAt))
+	}
+	if ttl > 0 {
+		data, _ = json.Marshal(res)
+		// 缓存失败不影响结果
+		_ = r.cmd.Set(ctx, key, data, ttl).Err()
+	}
+	return res, nil
+}
+
+func (r *RedisJWTHandler) introspect(ctx context.Context, tokenStr string) (Introspection, error) {
+	var claims introspectClaims
+	token, err := r.keys.Parse(tokenStr, TypeAccessToken, &claims)
+	if err != nil || !token.Valid || claims.ExpiresAt == nil {
+		return Introspection{}, nil
+	}
+	uid := claims.Uid
+	if uid == 0 {
+		// OIDC 的 access token 用 sub 表示用户
+		uid, _ = strconv.ParseInt(claims.Subject, 10, 64)
+	}
+	if uid == 0 {
+		return Introspection{}, nil
+	}
+	if claims.Ssid != "" {
+		switch err = r.checkSession(ctx, claims.Ssid); err {
+		case nil:
+		case errSessionRevoked:
+			return Introspection{}, nil
+		default:
+			return Introspection{}, err
+		}
+	}
+	res := Introspection{
+		Active:    true,
+		Uid:       uid,
+		Ssid:      claims.Ssid,
+		ClientId:  claims.ClientId,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}
+	if claims.Scope != "" {
+		res.Scopes = strings.Fields(claims.Scope)
+	}
+	if claims.IssuedAt != nil {
+		res.IssuedAt = claims.IssuedAt.Time
+	}
+	return res, nil
+}
+
 func (r *RedisJWTHandler) ClearToken(ctx *gin.Context) error {
 	// 前端用户 会把两个token 更新
 	// 这样 登录校验里面，走不到查询redis
@@ -115,10 +290,212 @@ func (r *RedisJWTHandler) ClearToken(ctx *gin.Context) error {
 	// 获取 jwt token 中间件解析的  UserClaims
 
 	c, _ := ctx.Get("user")
-	uc, ok := c.(*UserClaims)
+	uc, ok := c.(UserClaims)
 	if !ok {
 		return errors.New("解析UserClaims 错误")
 	}
 
-	return r.cmd.Set(ctx, fmt.Sprintf("users:ssid:%s", uc.Ssid), "", r.rcExpiration).Err()
+	return r.revoke(ctx, uc.Uid, uc.Ssid)
+}
+
+// ListSessions 查询用户的登录设备
+func (r *RedisJWTHandler) ListSessions(ctx context.Context, uid int64) ([]Session, error) {
+	key := r.sessionsKey(uid)
+	ssids, err := r.cmd.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	pipe := r.cmd.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, 0, len(ssids))
+	for _, ssid := range ssids {
+		cmds = append(cmds, pipe.HGetAll(ctx, r.sessionKey(ssid)))
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Session, 0, len(ssids))
+	expired := make([]any, 0)
+	for i, cmd := range cmds {
+		vals := cmd.Val()
+		if len(vals) == 0 {
+			// 设备信息已经过期了，顺手清理掉
+			expired = append(expired, ssids[i])
+			continue
+		}
+		res = append(res, r.toSession(ssids[i], vals))
+	}
+	if len(expired) > 0 {
+		r.cmd.SRem(ctx, key, expired...)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Ctime.After(res[j].Ctime)
+	})
+	return res, nil
+}
+
+// RevokeSession 踢掉用户的一个登录设备
+func (r *RedisJWTHandler) RevokeSession(ctx context.Context, uid int64, ssid string) error {
+	ok, err := r.cmd.SIsMember(ctx, r.sessionsKey(uid), ssid).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return r.revoke(ctx, uid, ssid)
+}
+
+// RevokeSessions 把用户的 ssid 都加入 users:ssid:%s，CheckSession 就会拒绝
+func (r *RedisJWTHandler) RevokeSessions(ctx context.Context, uid int64, exceptSsid string) error {
+	key := r.sessionsKey(uid)
+	ssids, err := r.cmd.SMembers(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	pipe := r.cmd.TxPipeline()
+	for _, ssid := range ssids {
+		if ssid == exceptSsid {
+			continue
+		}
+		r.revokeCmds(ctx, pipe, uid, ssid)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// revoke 让一个 ssid 失效，并且从登录设备里面移除
+func (r *RedisJWTHandler) revoke(ctx context.Context, uid int64, ssid string) error {
+	pipe := r.cmd.TxPipeline()
+	r.revokeCmds(ctx, pipe, uid, ssid)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisJWTHandler) revokeCmds(ctx context.Context, pipe redis.Pipeliner, uid int64, ssid string) {
+	pipe.Set(ctx, r.revokedKey(ssid), "", r.revokedTTL())
+	pipe.SRem(ctx, r.sessionsKey(uid), ssid)
+	pipe.Del(ctx, r.sessionKey(ssid))
+}
+
+// revokedTTL 撤销标记保留的时间，已经签发的 access token 和 refresh token 都过期之前不能删
+func (r *RedisJWTHandler) revokedTTL() time.Duration {
+	return max(r.cfg.Sliding, accessTokenTTL)
+}
+
+// addSession 记录登录设备和当前 refresh token 的 jti
+// users:sessions:%d 记录用户有哪些 ssid，users:session:%s 记录设备信息
+// 两者的过期时间都跟 refresh token 一致，每次刷新都会延长
+func (r *RedisJWTHandler) addSession(ctx context.Context, uid int64, sess Session, jti string) error {
+	key := r.sessionsKey(uid)
+	sessKey := r.sessionKey(sess.Ssid)
+	now := sess.Ctime.UnixMilli()
+	pipe := r.cmd.TxPipeline()
+	pipe.HSet(ctx, sessKey,
+		"uid", uid,
+		"user_agent", sess.UserAgent,
+		"ip", sess.IP,
+		"method", sess.Method,
+		"ctime", now,
+		"utime", now,
+		"rt_jti", jti)
+	pipe.Expire(ctx, sessKey, r.cfg.Sliding)
+	pipe.SAdd(ctx, key, sess.Ssid)
+	pipe.Expire(ctx, key, r.cfg.Sliding)
+	// 登录设备退出之后就删掉了，登录记录单独保存
+	record, err := json.Marshal(loginRecord{
+		Ssid:      sess.Ssid,
+		UserAgent: sess.UserAgent,
+		IP:        sess.IP,
+		Method:    sess.Method,
+		Ctime:     now,
+	})
+	if err != nil {
+		return err
+	}
+	historyKey := r.loginHistoryKey(uid)
+	pipe.LPush(ctx, historyKey, record)
+	pipe.LTrim(ctx, historyKey, 0, loginHistoryLimit-1)
+	pipe.Expire(ctx, historyKey, loginHistoryTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// loginRecord 登录记录在 Redis 里面的格式
+type loginRecord struct {
+	Ssid      string `json:"ssid"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+	Method    string `json:"method"`
+	Ctime     int64  `json:"ctime"`
+}
+
+// LoginHistory 最近的登录记录，Utime 没有意义，和 Ctime 一样
+func (r *RedisJWTHandler) LoginHistory(ctx context.Context, uid int64) ([]Session, error) {
+	vals, err := r.cmd.LRange(ctx, r.loginHistoryKey(uid), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Session, 0, len(vals))
+	for _, val := range vals {
+		var record loginRecord
+		if err = json.Unmarshal([]byte(val), &record); err != nil {
+			return nil, err
+		}
+		ctime := time.UnixMilli(record.Ctime)
+		res = append(res, Session{
+			Ssid:      record.Ssid,
+			UserAgent: record.UserAgent,
+			IP:        record.IP,
+			Method:    record.Method,
+			Ctime:     ctime,
+			Utime:     ctime,
+		})
+	}
+	return res, nil
+}
+
+func (r *RedisJWTHandler) DeleteLoginHistory(ctx context.Context, uid int64) error {
+	return r.cmd.Del(ctx, r.loginHistoryKey(uid)).Err()
+}
+
+// touchSession 更新最近一次刷新的时间，设备信息已经过期的话什么都不做
+func (r *RedisJWTHandler) touchSession(ctx context.Context, ssid string) error {
+	return r.cmd.Eval(ctx, luaTouchSession, []string{r.sessionKey(ssid)}, time.Now().UnixMilli()).Err()
+}
+
+func (r *RedisJWTHandler) toSession(ssid string, vals map[string]string) Session {
+	ctime, _ := strconv.ParseInt(vals["ctime"], 10, 64)
+	utime, _ := strconv.ParseInt(vals["utime"], 10, 64)
+	return Session{
+		Ssid:      ssid,
+		UserAgent: vals["user_agent"],
+		IP:        vals["ip"],
+		Method:    vals["method"],
+		Ctime:     time.UnixMilli(ctime),
+		Utime:     time.UnixMilli(utime),
+	}
+}
+
+func (r *RedisJWTHandler) sessionsKey(uid int64) string {
+	return fmt.Sprintf("users:sessions:%d", uid)
+}
+
+func (r *RedisJWTHandler) loginHistoryKey(uid int64) string {
+	return fmt.Sprintf("users:login_history:%d", uid)
+}
+
+func (r *RedisJWTHandler) sessionKey(ssid string) string {
+	return fmt.Sprintf("users:session:%s", ssid)
+}
+
+// introspectKey 不直接用 token 做 key，太长了
+func (r *RedisJWTHandler) introspectKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "users:introspect:" + hex.EncodeToString(sum[:])
+}
+
+func (r *RedisJWTHandler) revokedKey(ssid string) string {
+	return fmt.Sprintf("users:ssid:%s", ssid)
 }
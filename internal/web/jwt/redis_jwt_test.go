@@ -0,0 +1,573 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache/redismocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRedisJWTHandler_RefreshLoginToken(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ks, err := NewKeySet("k1", Key{Kid: "k1", Private: priv, Public: pub})
+	require.NoError(t, err)
+	cfg := SessionConfig{Sliding: time.Hour * 24 * 7, Absolute: time.Hour * 24 * 30}
+	now := time.Now()
+	testCase := []struct {
+		name string
+		// 脚本的返回值，第一个大于 0 的时候是登录时间，第二个是要签发的 jti
+		evalRes []any
+		// 输出
+		wantErr error
+		// 新的 refresh token 的过期时间
+		wantExpiresAt time.Time
+	}{
+		{
+			name:          "轮换成功，滑动过期",
+			evalRes:       []any{now.Add(-time.Hour).UnixMilli(), "new_jti"},
+			wantExpiresAt: now.Add(cfg.Sliding),
+		},
+		{
+			name:          "轮换成功，快到绝对有效期了",
+			evalRes:       []any{now.Add(-time.Hour * 24 * 29).UnixMilli(), "new_jti"},
+			wantExpiresAt: now.Add(-time.Hour * 24 * 29).Add(cfg.Absolute),
+		},
+		{
+			name:    "旧的 refresh token 被重放",
+			evalRes: []any{int64(-2), ""},
+			wantErr: ErrRefreshTokenReused,
+		},
+		{
+			name:    "登录已经失效",
+			evalRes: []any{int64(-1), ""},
+			wantErr: ErrSessionNotFound,
+		},
+		{
+			name:    "超过绝对有效期",
+			evalRes: []any{int64(-3), ""},
+			wantErr: ErrSessionNotFound,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			cmd := redismocks.NewMockCmdable(ctrl)
+			rotate := redis.NewCmd(context.Background())
+			rotate.SetVal(tc.evalRes)
+			cmd.EXPECT().Eval(gomock.Any(), luaRotateRefresh,
+				[]string{"users:session:ssid", "users:sessions:123", "users:ssid:ssid"},
+				"old_jti", gomock.Any(), gomock.Any(), int64(cfg.Sliding.Seconds()), cfg.Absolute.Milliseconds(), "ssid",
+				cfg.ReuseGrace.Milliseconds(), int64(cfg.Sliding.Seconds())).
+				Return(rotate)
+			if tc.wantErr == nil {
+				// 签发新的 access token 的时候更新最近刷新的时间
+				cmd.EXPECT().Eval(gomock.Any(), luaTouchSession, []string{"users:session:ssid"}, gomock.Any()).
+					Return(redis.NewCmd(context.Background()))
+			}
+			hdl := NewRedisJWTHandler(cmd, ks, cfg)
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/users/refresh_token", nil)
+
+			err := hdl.RefreshLoginToken(ctx, RefreshClaims{Uid: 123, Ssid: "ssid", RegisteredClaims: jwt.RegisteredClaims{ID: "old_jti"}})
+			assert.Equal(t, tc.wantErr, err)
+			if tc.wantErr != nil {
+				assert.Empty(t, recorder.Header().Get("x-refresh-token"))
+				return
+			}
+			assert.NotEmpty(t, recorder.Header().Get("x-jwt-token"))
+			var claims RefreshClaims
+			_, err = hdl.ParseToken(recorder.Header().Get("x-refresh-token"), TypeRefreshToken, &claims)
+			require.NoError(t, err)
+			assert.Equal(t, "new_jti", claims.ID)
+			assert.Equal(t, int64(123), claims.Uid)
+			assert.WithinDuration(t, tc.wantExpiresAt, claims.ExpiresAt.Time, time.Second*2)
+		})
+	}
+}
+
+func TestRedisJWTHandler_Introspect(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ks, err := NewKeySet("k1", Key{Kid: "k1", Private: priv, Public: pub})
+	require.NoError(t, err)
+	now := time.Now().Truncate(time.Second)
+	expiresAt := now.Add(time.Hour)
+	userToken, err := ks.Sign(TypeAccessToken, UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+		Uid:              123,
+		Ssid:             "ssid",
+	})
+	require.NoError(t, err)
+	oidcToken, err := ks.Sign(TypeAccessToken, introspectClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "123",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		ClientId: "rp",
+		Scope:    "openid profile",
+	})
+	require.NoError(t, err)
+	refreshToken, err := ks.Sign(TypeRefreshToken, RefreshClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(expiresAt)},
+		Uid:              123,
+		Ssid:             "ssid",
+	})
+	require.NoError(t, err)
+	cacheMiss := func(cmd *redismocks.MockCmdable, token string) {
+		get := redis.NewStringCmd(context.Background())
+		get.SetErr(redis.Nil)
+		cmd.EXPECT().Get(gomock.Any(), introspectKeyOf(token)).Return(get)
+	}
+	testCase := []struct {
+		name  string
+		token string
+		mock  func(ctrl *gomock.Controller) redis.Cmdable
+
+		wantRes Introspection
+		wantErr error
+	}{
+		{
+			name:  "用户中心的 access token",
+			token: userToken,
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cacheMiss(cmd, userToken)
+				cmd.EXPECT().Exists(gomock.Any(), "users:ssid:ssid").Return(redis.NewIntResult(0, nil))
+				cmd.EXPECT().Set(gomock.Any(), introspectKeyOf(userToken), gomock.Any(), introspectCacheTTL).
+					Return(redis.NewStatusCmd(context.Background()))
+				return cmd
+			},
+			wantRes: Introspection{Active: true, Uid: 123, Ssid: "ssid", ExpiresAt: expiresAt},
+		},
+		{
+			name:  "OIDC 的 access token",
+			token: oidcToken,
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cacheMiss(cmd, oidcToken)
+				cmd.EXPECT().Set(gomock.Any(), introspectKeyOf(oidcToken), gomock.Any(), introspectCacheTTL).
+					Return(redis.NewStatusCmd(context.Background()))
+				return cmd
+			},
+			wantRes: Introspection{Active: true, Uid: 123, ClientId: "rp",
+				Scopes: []string{"openid", "profile"}, ExpiresAt: expiresAt, IssuedAt: now},
+		},
+		{
+			name:  "登录已经退出",
+			token: userToken,
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cacheMiss(cmd, userToken)
+				cmd.EXPECT().Exists(gomock.Any(), "users:ssid:ssid").Return(redis.NewIntResult(1, nil))
+				cmd.EXPECT().Set(gomock.Any(), introspectKeyOf(userToken), gomock.Any(), introspectCacheTTL).
+					Return(redis.NewStatusCmd(context.Background()))
+				return cmd
+			},
+			wantRes: Introspection{},
+		},
+		{
+			name:  "refresh token 不能自省",
+			token: refreshToken,
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cacheMiss(cmd, refreshToken)
+				cmd.EXPECT().Set(gomock.Any(), introspectKeyOf(refreshToken), gomock.Any(), introspectCacheTTL).
+					Return(redis.NewStatusCmd(context.Background()))
+				return cmd
+			},
+			wantRes: Introspection{},
+		},
+		{
+			name:  "命中缓存",
+			token: userToken,
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				get := redis.NewStringCmd(context.Background())
+				get.SetVal(`{"Active":true,"Uid":123,"Ssid":"ssid"}`)
+				cmd.EXPECT().Get(gomock.Any(), introspectKeyOf(userToken)).Return(get)
+				return cmd
+			},
+			wantRes: Introspection{Active: true, Uid: 123, Ssid: "ssid"},
+		},
+		{
+			name:  "redis 出错",
+			token: userToken,
+			mock: func(ctrl *gomock.Controller) redis.Cmdable {
+				cmd := redismocks.NewMockCmdable(ctrl)
+				cacheMiss(cmd, userToken)
+				cmd.EXPECT().Exists(gomock.Any(), "users:ssid:ssid").Return(redis.NewIntResult(0, errors.New("redis 错误")))
+				return cmd
+			},
+			wantErr: errors.New("redis 错误"),
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			hdl := NewRedisJWTHandler(tc.mock(ctrl), ks, SessionConfig{})
+			res, err := hdl.Introspect(context.Background(), tc.token)
+			assert.Equal(t, tc.wantErr, err)
+			assert.True(t, tc.wantRes.ExpiresAt.Equal(res.ExpiresAt))
+			assert.True(t, tc.wantRes.IssuedAt.Equal(res.IssuedAt))
+			tc.wantRes.ExpiresAt, res.ExpiresAt = time.Time{}, time.Time{}
+			tc.wantRes.IssuedAt, res.IssuedAt = time.Time{}, time.Time{}
+			assert.Equal(t, tc.wantRes, res)
+		})
+	}
+}
+
+func introspectKeyOf(token string) string {
+	return (&RedisJWTHandler{}).introspectKey(token)
+}
+
+func TestRedisJWTHandler_LoginHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cmd := redismocks.NewMockCmdable(ctrl)
+	cmd.EXPECT().LRange(gomock.Any(), "users:login_history:123", int64(0), int64(-1)).
+		Return(redis.NewStringSliceResult([]string{
+			`{"ssid":"s2","user_agent":"chrome","ip":"127.0.0.1","method":"sms","ctime":1700000060000}`,
+			`{"ssid":"s1","user_agent":"safari","ip":"127.0.0.1","method":"password","ctime":1700000000000}`,
+		}, nil))
+	hdl := NewRedisJWTHandler(cmd, nil, SessionConfig{})
+	res, err := hdl.LoginHistory(context.Background(), 123)
+	require.NoError(t, err)
+	assert.Equal(t, []Session{
+		{Ssid: "s2", UserAgent: "chrome", IP: "127.0.0.1", Method: "sms",
+			Ctime: time.UnixMilli(1700000060000), Utime: time.UnixMilli(1700000060000)},
+		{Ssid: "s1", UserAgent: "safari", IP: "127.0.0.1", Method: "password",
+			Ctime: time.UnixMilli(1700000000000), Utime: time.UnixMilli(1700000000000)},
+	}, res)
+}
+
+// newMiniredisHandler 登录设备的读写都在 pipeline 里面，直接用 miniredis 测
+func newMiniredisHandler(t *testing.T, cfg SessionConfig) (*miniredis.Miniredis, *RedisJWTHandler) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ks, err := NewKeySet("k1", Key{Kid: "k1", Private: priv, Public: pub})
+	require.NoError(t, err)
+	mr := miniredis.RunT(t)
+	cmd := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = cmd.Close()
+	})
+	return mr, NewRedisJWTHandler(cmd, ks, cfg).(*RedisJWTHandler)
+}
+
+func TestRedisJWTHandler_SetLoginToken(t *testing.T) {
+	cfg := SessionConfig{Sliding: time.Hour * 24 * 7, Absolute: time.Hour * 24 * 30}
+	mr, hdl := newMiniredisHandler(t, cfg)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/users/login", nil)
+	ctx.Request.Header.Set("User-Agent", "chrome")
+	ctx.Request.RemoteAddr = "10.0.0.1:1234"
+
+	err := hdl.SetLoginToken(ctx, 123, LoginMethodPassword)
+	require.NoError(t, err)
+
+	var claims RefreshClaims
+	_, err = hdl.ParseToken(recorder.Header().Get("x-refresh-token"), TypeRefreshToken, &claims)
+	require.NoError(t, err)
+	assert.NotEmpty(t, recorder.Header().Get("x-jwt-token"))
+
+	// 登录设备
+	members, err := mr.SMembers("users:sessions:123")
+	require.NoError(t, err)
+	assert.Equal(t, []string{claims.Ssid}, members)
+	sessKey := "users:session:" + claims.Ssid
+	assert.Equal(t, "123", mr.HGet(sessKey, "uid"))
+	assert.Equal(t, "chrome", mr.HGet(sessKey, "user_agent"))
+	assert.Equal(t, "10.0.0.1", mr.HGet(sessKey, "ip"))
+	assert.Equal(t, LoginMethodPassword, mr.HGet(sessKey, "method"))
+	// refresh token 的 jti 和 Redis 里面记录的一致
+	assert.Equal(t, claims.ID, mr.HGet(sessKey, "rt_jti"))
+	assert.Equal(t, cfg.Sliding, mr.TTL(sessKey))
+	assert.Equal(t, cfg.Sliding, mr.TTL("users:sessions:123"))
+
+	// 登录记录
+	history, err := hdl.LoginHistory(context.Background(), 123)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, claims.Ssid, history[0].Ssid)
+	assert.Equal(t, LoginMethodPassword, history[0].Method)
+	assert.Equal(t, loginHistoryTTL, mr.TTL("users:login_history:123"))
+
+	// 注销账号的时候删除登录记录
+	require.NoError(t, hdl.DeleteLoginHistory(context.Background(), 123))
+	assert.False(t, mr.Exists("users:login_history:123"))
+}
+
+func TestRedisJWTHandler_ListSessions(t *testing.T) {
+	testCase := []struct {
+		name   string
+		before func(t *testing.T, mr *miniredis.Miniredis)
+
+		wantRes []Session
+		// 查询之后 users:sessions:123 里面剩下的 ssid
+		wantSsids []string
+	}{
+		{
+			name: "最近登录的在前面",
+			before: func(t *testing.T, mr *miniredis.Miniredis) {
+				mr.SAdd("users:sessions:123", "s1", "s2")
+				mr.HSet("users:session:s1", "user_agent", "safari", "ip", "127.0.0.1", "method", "sms",
+					"ctime", "1700000000000", "utime", "1700000030000")
+				mr.HSet("users:session:s2", "user_agent", "chrome", "ip", "127.0.0.2", "method", "password",
+					"ctime", "1700000060000", "utime", "1700000060000")
+			},
+			wantRes: []Session{
+				{Ssid: "s2", UserAgent: "chrome", IP: "127.0.0.2", Method: "password",
+					Ctime: time.UnixMilli(1700000060000), Utime: time.UnixMilli(1700000060000)},
+				{Ssid: "s1", UserAgent: "safari", IP: "127.0.0.1", Method: "sms",
+					Ctime: time.UnixMilli(1700000000000), Utime: time.UnixMilli(1700000030000)},
+			},
+			wantSsids: []string{"s1", "s2"},
+		},
+		{
+			name: "设备信息已经过期，顺手清理",
+			before: func(t *testing.T, mr *miniredis.Miniredis) {
+				mr.SAdd("users:sessions:123", "s1", "expired")
+				mr.HSet("users:session:s1", "user_agent", "safari", "ip", "127.0.0.1", "method", "sms",
+					"ctime", "1700000000000", "utime", "1700000000000")
+			},
+			wantRes: []Session{
+				{Ssid: "s1", UserAgent: "safari", IP: "127.0.0.1", Method: "sms",
+					Ctime: time.UnixMilli(1700000000000), Utime: time.UnixMilli(1700000000000)},
+			},
+			wantSsids: []string{"s1"},
+		},
+		{
+			name:      "没有登录设备",
+			before:    func(t *testing.T, mr *miniredis.Miniredis) {},
+			wantRes:   []Session{},
+			wantSsids: nil,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mr, hdl := newMiniredisHandler(t, SessionConfig{Sliding: time.Hour})
+			tc.before(t, mr)
+			res, err := hdl.ListSessions(context.Background(), 123)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRes, res)
+			ssids, _ := mr.SMembers("users:sessions:123")
+			assert.Equal(t, tc.wantSsids, ssids)
+		})
+	}
+}
+
+func TestRedisJWTHandler_RevokeSession(t *testing.T) {
+	testCase := []struct {
+		name string
+		ssid string
+
+		wantErr error
+		// 期望被撤销的 ssid
+		wantRevoked []string
+		wantSsids   []string
+	}{
+		{
+			name:        "踢掉自己的设备",
+			ssid:        "s1",
+			wantRevoked: []string{"s1"},
+			wantSsids:   []string{"s2"},
+		},
+		{
+			name:      "不是自己的设备",
+			ssid:      "other",
+			wantErr:   ErrSessionNotFound,
+			wantSsids: []string{"s1", "s2"},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mr, hdl := newMiniredisHandler(t, SessionConfig{Sliding: time.Hour})
+			mr.SAdd("users:sessions:123", "s1", "s2")
+			mr.HSet("users:session:s1", "ctime", "1700000000000")
+			mr.HSet("users:session:s2", "ctime", "1700000000000")
+			mr.HSet("users:session:other", "ctime", "1700000000000")
+
+			err := hdl.RevokeSession(context.Background(), 123, tc.ssid)
+			assert.Equal(t, tc.wantErr, err)
+			ssids, _ := mr.SMembers("users:sessions:123")
+			assert.Equal(t, tc.wantSsids, ssids)
+			for _, ssid := range []string{"s1", "s2", "other"} {
+				revoked := slices.Contains(tc.wantRevoked, ssid)
+				assert.Equal(t, revoked, mr.Exists("users:ssid:"+ssid), ssid)
+				assert.Equal(t, !revoked, mr.Exists("users:session:"+ssid), ssid)
+			}
+		})
+	}
+}
+
+func TestRedisJWTHandler_RevokeSessions(t *testing.T) {
+	testCase := []struct {
+		name       string
+		exceptSsid string
+
+		wantRevoked []string
+		wantSsids   []string
+	}{
+		{
+			name:        "保留当前设备",
+			exceptSsid:  "s2",
+			wantRevoked: []string{"s1", "s3"},
+			wantSsids:   []string{"s2"},
+		},
+		{
+			name:        "全部下线",
+			wantRevoked: []string{"s1", "s2", "s3"},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			// 滑动周期比 access token 的有效期短
+			cfg := SessionConfig{Sliding: time.Minute * 10}
+			mr, hdl := newMiniredisHandler(t, cfg)
+			mr.SAdd("users:sessions:123", "s1", "s2", "s3")
+			for _, ssid := range []string{"s1", "s2", "s3"} {
+				mr.HSet("users:session:"+ssid, "ctime", "1700000000000")
+			}
+
+			err := hdl.RevokeSessions(context.Background(), 123, tc.exceptSsid)
+			require.NoError(t, err)
+			ssids, _ := mr.SMembers("users:sessions:123")
+			assert.Equal(t, tc.wantSsids, ssids)
+			for _, ssid := range []string{"s1", "s2", "s3"} {
+				revoked := slices.Contains(tc.wantRevoked, ssid)
+				assert.Equal(t, revoked, mr.Exists("users:ssid:"+ssid), ssid)
+				assert.Equal(t, !revoked, mr.Exists("users:session:"+ssid), ssid)
+				if revoked {
+					// 撤销标记保留到已经签发的 access token 过期
+					assert.Equal(t, accessTokenTTL, mr.TTL("users:ssid:"+ssid))
+				}
+			}
+		})
+	}
+}
+
+// TestRedisJWTHandler_RotateRefresh 在 miniredis 上执行真正的 rotate_refresh.lua
+func TestRedisJWTHandler_RotateRefresh(t *testing.T) {
+	cfg := SessionConfig{Sliding: time.Hour * 24 * 7, Absolute: time.Hour * 24 * 30, ReuseGrace: time.Second * 30}
+	now := time.Now()
+	const sessKey = "users:session:ssid"
+	testCase := []struct {
+		name string
+		// session 里面除了 ctime 之外的字段
+		session map[string]string
+		ctime   time.Time
+		// 输入
+		jti string
+		// 输出
+		wantErr error
+		// wantJti 为空代表签发一个新的
+		wantJti     string
+		wantPrevJti string
+		wantRevoked bool
+	}{
+		{
+			name:        "用当前的 jti 刷新，轮换，旧的进入宽限期",
+			session:     map[string]string{"rt_jti": "jti_1"},
+			ctime:       now.Add(-time.Hour),
+			jti:         "jti_1",
+			wantPrevJti: "jti_1",
+		},
+		{
+			name: "宽限期内再用上一个 jti，拿到当前的 jti，不算重放",
+			session: map[string]string{"rt_jti": "jti_2", "prev_jti": "jti_1",
+				"prev_until": strconv.FormatInt(now.Add(time.Second*10).UnixMilli(), 10)},
+			ctime:       now.Add(-time.Hour),
+			jti:         "jti_1",
+			wantJti:     "jti_2",
+			wantPrevJti: "jti_1",
+		},
+		{
+			name: "宽限期过了再用上一个 jti，撤销整个登录",
+			session: map[string]string{"rt_jti": "jti_2", "prev_jti": "jti_1",
+				"prev_until": strconv.FormatInt(now.Add(-time.Second).UnixMilli(), 10)},
+			ctime:       now.Add(-time.Hour),
+			jti:         "jti_1",
+			wantErr:     ErrRefreshTokenReused,
+			wantRevoked: true,
+		},
+		{
+			name: "更早的 jti，撤销整个登录",
+			session: map[string]string{"rt_jti": "jti_3", "prev_jti": "jti_2",
+				"prev_until": strconv.FormatInt(now.Add(time.Second*10).UnixMilli(), 10)},
+			ctime:       now.Add(-time.Hour),
+			jti:         "jti_1",
+			wantErr:     ErrRefreshTokenReused,
+			wantRevoked: true,
+		},
+		{
+			name:        "超过绝对有效期",
+			session:     map[string]string{"rt_jti": "jti_1"},
+			ctime:       now.Add(-cfg.Absolute - time.Minute),
+			jti:         "jti_1",
+			wantErr:     ErrSessionNotFound,
+			wantRevoked: true,
+		},
+		{
+			name:    "登录已经失效",
+			jti:     "jti_1",
+			wantErr: ErrSessionNotFound,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			mr, hdl := newMiniredisHandler(t, cfg)
+			if tc.session != nil {
+				mr.HSet(sessKey, "uid", "123", "ctime", strconv.FormatInt(tc.ctime.UnixMilli(), 10))
+				for field, val := range tc.session {
+					mr.HSet(sessKey, field, val)
+				}
+				mr.SAdd("users:sessions:123", "ssid")
+			}
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+			ctx.Request = httptest.NewRequest(http.MethodPost, "/users/refresh_token", nil)
+
+			err := hdl.RefreshLoginToken(ctx, RefreshClaims{Uid: 123, Ssid: "ssid", RegisteredClaims: jwt.RegisteredClaims{ID: tc.jti}})
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantRevoked, mr.Exists("users:ssid:ssid"))
+			if tc.wantRevoked {
+				// 滑动周期比 access token 的有效期长，标记保留一个滑动周期
+				assert.Equal(t, cfg.Sliding, mr.TTL("users:ssid:ssid"))
+			}
+			if tc.wantErr != nil {
+				return
+			}
+			var claims RefreshClaims
+			_, err = hdl.ParseToken(recorder.Header().Get("x-refresh-token"), TypeRefreshToken, &claims)
+			require.NoError(t, err)
+			if tc.wantJti != "" {
+				assert.Equal(t, tc.wantJti, claims.ID)
+			} else {
+				assert.NotEqual(t, tc.jti, claims.ID)
+			}
+			// 签发出去的 jti 就是 Redis 里面当前的 jti
+			assert.Equal(t, claims.ID, mr.HGet(sessKey, "rt_jti"))
+			assert.Equal(t, tc.wantPrevJti, mr.HGet(sessKey, "prev_jti"))
+		})
+	}
+}
@@ -1,18 +1,93 @@
 package jwt
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"time"
 )
 
 // Handler
 // Token 的相关操作·
 type Handler interface {
 	SetJWTToken(ctx *gin.Context, uid int64, ssid string) error
-	SetLoginToken(ctx *gin.Context, uid int64) error
+	// SetLoginToken 登录成功之后设置 token，并记录登录设备
+	// method 是登录方式，比如 LoginMethodPassword
+	SetLoginToken(ctx *gin.Context, uid int64, method string) error
 	ExtractToken(ctx *gin.Context) string
+	// ParseToken 校验 token 的签名和类型，typ 是 TypeAccessToken 或者 TypeRefreshToken
+	ParseToken(tokenStr string, typ string, claims jwt.Claims) (*jwt.Token, error)
+	// JWKS 验证 token 用的公钥
+	JWKS() JWKS
 	CheckSession(ctx *gin.Context, ssid string) error
+	// Introspect 给资源服务器校验 access token，无效的 token 返回 Active 为 false，不返回 error
+	Introspect(ctx context.Context, token string) (Introspection, error)
+	// RefreshLoginToken 轮换 refresh token，同时签发新的 access token
+	// 已经轮换过的 refresh token 再次使用的时候撤销整个登录，返回 ErrRefreshTokenReused
+	RefreshLoginToken(ctx *gin.Context, claims RefreshClaims) error
 	ClearToken(ctx *gin.Context) error
+	// ListSessions 查询用户所有的登录设备，最近登录的在前面
+	ListSessions(ctx context.Context, uid int64) ([]Session, error)
+	// LoginHistory 最近的登录记录，包括已经退出的，最近登录的在前面
+	LoginHistory(ctx context.Context, uid int64) ([]Session, error)
+	// DeleteLoginHistory 注销账号的时候删除登录记录
+	DeleteLoginHistory(ctx context.Context, uid int64) error
+	// RevokeSession 让用户的某一个登录态失效，ssid 不属于该用户返回 ErrSessionNotFound
+	RevokeSession(ctx context.Context, uid int64, ssid string) error
+	// RevokeSessions 让用户的登录态全部失效，exceptSsid 不为空时保留该 session
+	RevokeSessions(ctx context.Context, uid int64, exceptSsid string) error
+}
+
+// 登录方式，第三方登录直接使用提供方的名字，比如 wechat、github
+const (
+	LoginMethodPassword = "password"
+	LoginMethodSMS      = "sms"
+	// LoginMethodEmail 邮箱免密登录
+	LoginMethodEmail = "email"
+	// LoginMethodQRCode App 扫码确认登录
+	LoginMethodQRCode = "qrcode"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session 不存在")
+	// ErrRefreshTokenReused refresh token 可能被盗用了
+	ErrRefreshTokenReused = errors.New("refresh token 重复使用")
+)
+
+// SessionConfig 登录态的有效期
+type SessionConfig struct {
+	// Sliding 多久没有刷新 token 之后需要重新登录
+	Sliding time.Duration
+	// Absolute 从登录开始算，最长多久之后必须重新登录
+	Absolute time.Duration
+	// ReuseGrace 轮换之后旧的 refresh token 还能用多久，容忍响应丢失和并发刷新，为 0 的时候立即作废
+	ReuseGrace time.Duration
+}
+
+// Introspection token 自省的结果，RFC 7662
+type Introspection struct {
+	Active bool
+	Uid    int64
+	// Ssid 用户中心自己签发的 token 才有
+	Ssid string
+	// ClientId 和 Scopes 是 /oidc/token 签发的 token 才有
+	ClientId  string
+	Scopes    []string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+}
+
+// Session 一次登录，也就是一台登录设备
+type Session struct {
+	Ssid      string
+	UserAgent string
+	IP        string
+	Method    string
+	// Ctime 登录时间
+	Ctime time.Time
+	// Utime 最近一次刷新 token 的时间
+	Utime time.Time
 }
 
 type UserClaims struct {
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	regexp "github.com/dlclark/regexp2"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// MagicLinkHandler 邮箱免密登录，邮件里面的链接打开前端页面，前端再用 token 换取登录态
+// 开启了两步验证的用户和密码登录一样，还要调用 /users/login/2fa
+type MagicLinkHandler struct {
+	svc           service.MagicLinkService
+	twoFactorSvc  service.TwoFactorService
+	deletionSvc   service.AccountDeletionService
+	emailRegexExp *regexp.Regexp
+	myjwt.Handler
+	log accesslog.Logger
+}
+
+func NewMagicLinkHandler(svc service.MagicLinkService, twoFactorSvc service.TwoFactorService,
+	deletionSvc service.AccountDeletionService, wtHdl myjwt.Handler, log accesslog.Logger) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		svc:           svc,
+		twoFactorSvc:  twoFactorSvc,
+		deletionSvc:   deletionSvc,
+		emailRegexExp: regexp.MustCompile(emailRegexPattern, regexp.None),
+		Handler:       wtHdl,
+		log:           log,
+	}
+}
+
+func (h *MagicLinkHandler) RegisterRoutes(server *gin.Engine) {
+	ug := server.Group("/users")
+	ug.POST("/login_email/send", h.Send)
+	ug.POST("/login_email", h.Login)
+}
+
+// Send 发送登录链接
+func (h *MagicLinkHandler) Send(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	ok, err := h.emailRegexExp.MatchString(req.Email)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "非法邮箱格式",
+		})
+		return
+	}
+	err = h.svc.Send(ctx.Request.Context(), req.Email)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case service.ErrCodeSendTooMany:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "邮件发送太频繁，请稍后再试",
+		})
+	default:
+		h.log.Error("发送登录链接失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// Login 用登录链接里面的 token 换取登录态
+func (h *MagicLinkHandler) Login(ctx *gin.Context) {
+	type Req struct {
+		Token string `json:"token"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	u, err := h.svc.Login(ctx.Request.Context(), req.Token)
+	if err == service.ErrMagicLinkInvalid {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "登录链接无效或者已经过期，请重新发送",
+		})
+		return
+	}
+	if err == service.ErrUserFrozen {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserFrozen,
+			Msg:  "账号已经被冻结",
+		})
+		return
+	}
+	if err != nil {
+		h.log.Error("邮箱免密登录失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 链接只能证明邮箱是本人的，开启了两步验证还要校验第二步
+	if twoFactorRequired(ctx, h.twoFactorSvc, domain.TwoFactorChallenge{Uid: u.Id, Method: myjwt.LoginMethodEmail}) {
+		return
+	}
+	err = h.deletionSvc.Cancel(ctx.Request.Context(), u.Id)
+	if err != nil {
+		h.log.Error("邮箱免密登录取消注销失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	err = h.SetLoginToken(ctx, u.Id, myjwt.LoginMethodEmail)
+	if err != nil {
+		h.log.Error("邮箱免密登录失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "登录成功",
+	})
+}
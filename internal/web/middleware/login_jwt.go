@@ -3,15 +3,26 @@ package middleware
 import (
 	"encoding/gob"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"net/http"
+	"strings"
 	"time"
+	"github.com/dadaxiaoxiao/user/internal/service"
 	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
 )
 
+// PersonalAccessTokenKey 使用个人访问令牌的请求，会把 domain.PersonalAccessToken 放在 ctx 的这个 key 里面
+const PersonalAccessTokenKey = "pat"
+
+// IsPersonalAccessToken 当前请求是不是用个人访问令牌认证的
+func IsPersonalAccessToken(ctx *gin.Context) bool {
+	_, ok := ctx.Get(PersonalAccessTokenKey)
+	return ok
+}
+
 type LoginJWTMiddlewareBuilder struct {
 	paths []string
 	myjwt.Handler
+	patSvc service.PersonalAccessTokenService
 }
 
 // IgnorePaths 忽略路径
@@ -20,6 +31,13 @@ func (l *LoginJWTMiddlewareBuilder) IgnorePaths(path string) *LoginJWTMiddleware
 	return l
 }
 
+// PersonalAccessTokens 同时接受个人访问令牌，令牌不校验 UserAgent，也没有 ssid
+// 依赖 ssid 或者会创建登录态的接口要用 SessionOnlyMiddlewareBuilder 拒绝令牌
+func (l *LoginJWTMiddlewareBuilder) PersonalAccessTokens(svc service.PersonalAccessTokenService) *LoginJWTMiddlewareBuilder {
+	l.patSvc = svc
+	return l
+}
+
 // NewLoginJWTMiddlewareBuilder 返回实例
 func NewLoginJWTMiddlewareBuilder(wtHdl myjwt.Handler) *LoginJWTMiddlewareBuilder {
 	return &LoginJWTMiddlewareBuilder{
@@ -43,11 +61,13 @@ func (l *LoginJWTMiddlewareBuilder) Build() gin.HandlerFunc {
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		if l.patSvc != nil && strings.HasPrefix(tokenStr, service.PersonalAccessTokenPrefix) {
+			l.checkPersonalAccessToken(ctx, tokenStr)
+			return
+		}
 
 		claims := myjwt.UserClaims{}
-		token, err := jwt.ParseWithClaims(tokenStr, &claims, func(token *jwt.Token) (interface{}, error) {
-			return myjwt.AccessTokenKey, nil
-		})
+		token, err := l.Handler.ParseToken(tokenStr, myjwt.TypeAccessToken, &claims)
 		if err != nil {
 			// 没登录
 			ctx.AbortWithStatus(http.StatusUnauthorized)
@@ -74,3 +94,23 @@ func (l *LoginJWTMiddlewareBuilder) Build() gin.HandlerFunc {
 		ctx.Set("user", claims)
 	}
 }
+
+func (l *LoginJWTMiddlewareBuilder) checkPersonalAccessToken(ctx *gin.Context, tokenStr string) {
+	t, err := l.patSvc.Verify(ctx.Request.Context(), tokenStr)
+	switch err {
+	case nil:
+	case service.ErrPersonalAccessTokenInvalid:
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	default:
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !t.Allow(ctx.Request.Method) {
+		// 权限不够
+		ctx.AbortWithStatus(http.StatusForbidden)
+		return
+	}
+	ctx.Set("user", myjwt.UserClaims{Uid: t.Uid})
+	ctx.Set(PersonalAccessTokenKey, t)
+}
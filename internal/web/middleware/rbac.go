@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// RBACMiddlewareBuilder 按照路径校验权限，要放在 LoginJWTMiddlewareBuilder 后面
+type RBACMiddlewareBuilder struct {
+	rules []permissionRule
+	svc   service.RBACService
+}
+
+type permissionRule struct {
+	path       string
+	permission string
+}
+
+// NewRBACMiddlewareBuilder 返回实例
+func NewRBACMiddlewareBuilder(svc service.RBACService) *RBACMiddlewareBuilder {
+	return &RBACMiddlewareBuilder{
+		svc: svc,
+	}
+}
+
+// Require path 和它下面的路径都需要 permission，同一个路径匹配多条规则的时候需要全部满足
+func (b *RBACMiddlewareBuilder) Require(path string, permission string) *RBACMiddlewareBuilder {
+	b.rules = append(b.rules, permissionRule{
+		path:       strings.TrimSuffix(path, "/"),
+		permission: permission,
+	})
+	return b
+}
+
+// Build 生成中间件
+func (b *RBACMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		required := b.required(ctx.Request.URL.Path)
+		if len(required) == 0 {
+			return
+		}
+		// 路径被 IgnorePaths 忽略了的话，这里拿不到用户
+		val, _ := ctx.Get("user")
+		claims, ok := val.(myjwt.UserClaims)
+		if !ok || claims.Uid == 0 {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if IsPersonalAccessToken(ctx) {
+			// 需要权限的接口不能用访问令牌调用
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		perms, err := b.svc.Permissions(ctx.Request.Context(), claims.Uid)
+		if err != nil {
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		for _, p := range required {
+			if !slices.Contains(perms, p) {
+				ctx.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+	}
+}
+
+func (b *RBACMiddlewareBuilder) required(path string) []string {
+	var res []string
+	for _, r := range b.rules {
+		if path == r.path || strings.HasPrefix(path, r.path+"/") {
+			res = append(res, r.permission)
+		}
+	}
+	return res
+}
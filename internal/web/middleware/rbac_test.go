@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	svcmocks "github.com/dadaxiaoxiao/user/internal/service/mocks"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRBACMiddlewareBuilder_Build(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) service.RBACService
+		path string
+		// 模拟登录中间件放进去的数据
+		user any
+		pat  bool
+
+		wantCode int
+	}{
+		{
+			name: "有权限",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				svc := svcmocks.NewMockRBACService(ctrl)
+				svc.EXPECT().Permissions(gomock.Any(), int64(1)).Return([]string{domain.PermissionRBACManage}, nil)
+				return svc
+			},
+			path:     "/admin/rbac/roles",
+			user:     myjwt.UserClaims{Uid: 1},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "没有权限",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				svc := svcmocks.NewMockRBACService(ctrl)
+				svc.EXPECT().Permissions(gomock.Any(), int64(1)).Return([]string{}, nil)
+				return svc
+			},
+			path:     "/admin/rbac/roles",
+			user:     myjwt.UserClaims{Uid: 1},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "不需要权限的路径",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				return svcmocks.NewMockRBACService(ctrl)
+			},
+			path:     "/admin/rbacx",
+			user:     myjwt.UserClaims{Uid: 1},
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "没有登录",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				return svcmocks.NewMockRBACService(ctrl)
+			},
+			path:     "/admin/rbac/roles",
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name: "使用访问令牌",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				return svcmocks.NewMockRBACService(ctrl)
+			},
+			path:     "/admin/rbac/roles",
+			user:     myjwt.UserClaims{Uid: 1},
+			pat:      true,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name: "查询权限失败",
+			mock: func(ctrl *gomock.Controller) service.RBACService {
+				svc := svcmocks.NewMockRBACService(ctrl)
+				svc.EXPECT().Permissions(gomock.Any(), int64(1)).Return(nil, errors.New("redis 错误"))
+				return svc
+			},
+			path:     "/admin/rbac/roles",
+			user:     myjwt.UserClaims{Uid: 1},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				if tc.user != nil {
+					ctx.Set("user", tc.user)
+				}
+				if tc.pat {
+					ctx.Set(PersonalAccessTokenKey, domain.PersonalAccessToken{})
+				}
+			})
+			server.Use(NewRBACMiddlewareBuilder(tc.mock(ctrl)).
+				Require("/admin/rbac", domain.PermissionRBACManage).Build())
+			server.GET(tc.path, func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// SessionOnlyMiddlewareBuilder 按照路径拒绝个人访问令牌，要放在 LoginJWTMiddlewareBuilder 后面
+// 创建登录态、修改登录凭证和依赖 ssid 的接口都只能用登录之后的 token 调用，
+// 不然令牌泄露之后可以换成完整的登录态，令牌也没有 ssid，会误伤其它登录设备
+type SessionOnlyMiddlewareBuilder struct {
+	paths []string
+}
+
+// NewSessionOnlyMiddlewareBuilder 返回实例
+func NewSessionOnlyMiddlewareBuilder() *SessionOnlyMiddlewareBuilder {
+	return &SessionOnlyMiddlewareBuilder{}
+}
+
+// Path path 和它下面的路径都不能用个人访问令牌调用
+func (b *SessionOnlyMiddlewareBuilder) Path(path string) *SessionOnlyMiddlewareBuilder {
+	b.paths = append(b.paths, strings.TrimSuffix(path, "/"))
+	return b
+}
+
+// Build 生成中间件
+func (b *SessionOnlyMiddlewareBuilder) Build() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !IsPersonalAccessToken(ctx) {
+			return
+		}
+		path := ctx.Request.URL.Path
+		for _, p := range b.paths {
+			if path == p || strings.HasPrefix(path, p+"/") {
+				ctx.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionOnlyMiddlewareBuilder_Build(t *testing.T) {
+	testCase := []struct {
+		name string
+		path string
+		// 模拟登录中间件放进去的访问令牌
+		pat bool
+
+		wantCode int
+	}{
+		{
+			name:     "用访问令牌确认扫码登录",
+			path:     "/users/qr_login/confirm",
+			pat:      true,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "用访问令牌修改密码",
+			path:     "/users/password",
+			pat:      true,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "登录之后的 token 可以调用",
+			path:     "/users/qr_login/confirm",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "其它接口可以用访问令牌",
+			path:     "/users/profile",
+			pat:      true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "前缀相同但是不是子路径",
+			path:     "/users/passwordx",
+			pat:      true,
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			server := gin.New()
+			server.Use(func(ctx *gin.Context) {
+				ctx.Set("user", myjwt.UserClaims{Uid: 1})
+				if tc.pat {
+					ctx.Set(PersonalAccessTokenKey, domain.PersonalAccessToken{})
+				}
+			})
+			server.Use(NewSessionOnlyMiddlewareBuilder().
+				Path("/users/qr_login").
+				Path("/users/password/").
+				Build())
+			server.POST(tc.path, func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, tc.path, nil))
+			assert.Equal(t, tc.wantCode, resp.Code)
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package web
+
+import (
+	"crypto/subtle"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+const (
+	// oauth2NonceCookie 记录发起授权的浏览器，回调的时候和 state 里面的 Nonce 比较
+	oauth2NonceCookie = "oauth2_nonce"
+	// oauth2NonceMaxAge 和 state 的过期时间一致，单位秒
+	oauth2NonceMaxAge = 600
+)
+
+// OAuth2Config 第三方登录的配置
+type OAuth2Config struct {
+	// RedirectWhitelist 登录成功之后允许跳转回去的前端地址，只比较 scheme 和 host，
+	// 比如 https://qinyeyiyi.cn
+	RedirectWhitelist []string
+}
+
+// OAuth2Handler 第三方登录，所有提供方共用 /oauth2/:provider 的路由
+// state 保存在 Redis 里面，只能用一次，并且要和发起授权的浏览器的 cookie 对得上
+type OAuth2Handler struct {
+	providers  *oauth2.Registry
+	svc        service.IdentityService
+	bindingSvc service.BindingService
+	myjwt.Handler
+	cfg OAuth2Config
+	log accesslog.Logger
+}
+
+func NewOAuth2Handler(providers *oauth2.Registry, svc service.IdentityService, bindingSvc service.BindingService,
+	wtHdl myjwt.Handler, cfg OAuth2Config, log accesslog.Logger) *OAuth2Handler {
+	return &OAuth2Handler{
+		providers:  providers,
+		svc:        svc,
+		bindingSvc: bindingSvc,
+		Handler:    wtHdl,
+		cfg:        cfg,
+		log:        log,
+	}
+}
+
+func (h *OAuth2Handler) RegisterRoutes(s *gin.Engine) {
+	g := s.Group("/oauth2/:provider")
+	g.GET("/authurl", h.OAuth2URL)
+	g.GET("/callback", h.Callback)
+	// 回调跳转回前端之后，前端用票据换取 token
+	g.POST("/ticket", h.Ticket)
+	// 已经登录的用户绑定第三方账号，需要登录态
+	g.GET("/bind_authurl", h.BindURL)
+}
+
+// OAuth2URL 登录的授权地址，redirect_uri 可选，必须在白名单里面
+func (h *OAuth2Handler) OAuth2URL(ctx *gin.Context) {
+	p, ok := h.provider(ctx)
+	if !ok {
+		return
+	}
+	h.authURL(ctx, p, domain.OAuth2State{
+		Provider: p.Name(),
+		Intent:   domain.OAuth2IntentLogin,
+	})
+}
+
+// BindURL 绑定第三方账号的授权地址，回调的时候根据 state 找回发起绑定的用户
+func (h *OAuth2Handler) BindURL(ctx *gin.Context) {
+	p, ok := h.provider(ctx)
+	if !ok {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	h.authURL(ctx, p, domain.OAuth2State{
+		Provider: p.Name(),
+		Intent:   domain.OAuth2IntentBind,
+		Uid:      uc.Uid,
+		Ssid:     uc.Ssid,
+	})
+}
+
+func (h *OAuth2Handler) authURL(ctx *gin.Context, p oauth2.Provider, st domain.OAuth2State) {
+	st.RedirectURI = ctx.Query("redirect_uri")
+	if st.RedirectURI != "" && !h.allowRedirect(st.RedirectURI) {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "不允许跳转的地址",
+		})
+		return
+	}
+	if _, ok := p.(oauth2.PKCEProvider); ok {
+		verifier, err := oauth2.NewCodeVerifier()
+		if err != nil {
+			ctx.JSONP(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+			return
+		}
+		st.CodeVerifier = verifier
+	}
+	st.Nonce = uuid.New().String()
+	state, err := h.svc.CreateState(ctx.Request.Context(), st)
+	if err != nil {
+		h.log.Error("保存第三方登录 state 失败", accesslog.String("provider", p.Name()), accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	authURL, err := oauth2.AuthURL(ctx, p, state, st.CodeVerifier)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "构造授权URL失败",
+		})
+		return
+	}
+	// 回调是从提供方跳转过来的顶级导航，SameSite=Lax 的 cookie 会带上
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oauth2NonceCookie, st.Nonce, oauth2NonceMaxAge, "/oauth2", "", true, true)
+	ctx.JSONP(http.StatusOK, Result{
+		Data: authURL,
+	})
+}
+
+func (h *OAuth2Handler) Callback(ctx *gin.Context) {
+	p, ok := h.provider(ctx)
+	if !ok {
+		return
+	}
+	// 先校验 state，防止 CSRF
+	st, err := h.svc.TakeState(ctx.Request.Context(), ctx.Query("state"))
+	if err == service.ErrOAuth2StateInvalid || (err == nil && st.Provider != p.Name()) ||
+		(err == nil && st.Intent != domain.OAuth2IntentLogin && st.Intent != domain.OAuth2IntentBind) {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "授权已经过期，请重新发起",
+		})
+		return
+	}
+	if err != nil {
+		h.log.Error("查询第三方登录 state 失败", accesslog.String("provider", p.Name()), accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	// 回调必须发生在发起授权的浏览器上，否则攻击者可以把自己的 code 和 state 发给别人，
+	// 让别人登录到攻击者的账号，或者把攻击者的第三方账号绑到别人身上
+	if !h.sameBrowser(ctx, st) {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "授权已经过期，请重新发起",
+		})
+		return
+	}
+
+	identity, err := oauth2.VerifyCode(ctx, p, ctx.Query("code"), st.CodeVerifier)
+	if err != nil {
+		h.log.Warn("第三方登录校验 code 失败", accesslog.String("provider", p.Name()), accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	if st.Intent == domain.OAuth2IntentBind {
+		h.bind(ctx, p, st, identity)
+		return
+	}
+
+	// 查找或新创建用户
+	user, err := h.svc.FindOrCreateByIdentity(ctx, identity)
+	if err == service.ErrUserFrozen {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserFrozen,
+			Msg:  "账号已经被冻结",
+		})
+		return
+	}
+	if err != nil {
+		h.log.Error("第三方登录查找或新建用户失败", accesslog.String("provider", p.Name()), accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	if st.RedirectURI != "" {
+		// 跳转的时候响应头里面的 token 前端拿不到，改成一次性票据
+		ticket, err := h.svc.CreateState(ctx.Request.Context(), domain.OAuth2State{
+			Provider: p.Name(),
+			Intent:   domain.OAuth2IntentTicket,
+			Uid:      user.Id,
+		})
+		if err != nil {
+			h.log.Error("保存登录票据失败", accesslog.Int64("uid", user.Id), accesslog.Error(err))
+			ctx.JSONP(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+			return
+		}
+		ctx.Redirect(http.StatusFound, withQuery(st.RedirectURI, "ticket", ticket))
+		return
+	}
+
+	// 设置token，登录方式就是提供方的名字
+	if err = h.SetLoginToken(ctx, user.Id, p.Name()); err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	//登录成功
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "登录成功",
+	})
+}
+
+// Ticket 用回调跳转带上的票据换取 token，票据只能用一次
+func (h *OAuth2Handler) Ticket(ctx *gin.Context) {
+	type Req struct {
+		Ticket string `json:"ticket"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	p, ok := h.provider(ctx)
+	if !ok {
+		return
+	}
+	st, err := h.svc.TakeState(ctx.Request.Context(), req.Ticket)
+	if err == service.ErrOAuth2StateInvalid ||
+		(err == nil && (st.Intent != domain.OAuth2IntentTicket || st.Provider != p.Name())) {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "登录已经过期，请重新登录",
+		})
+		return
+	}
+	if err == nil {
+		err = h.SetLoginToken(ctx, st.Uid, p.Name())
+	}
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "登录成功",
+	})
+}
+
+// bind 回调的时候不依赖浏览器带上的登录态，而是用 state 里面记录的用户
+func (h *OAuth2Handler) bind(ctx *gin.Context, p oauth2.Provider, st domain.OAuth2State, identity domain.UserIdentity) {
+	// 发起绑定之后退出登录了，就不能再绑定
+	err := h.CheckSession(ctx, st.Ssid)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "登录已经失效，请重新登录",
+		})
+		return
+	}
+	err = h.bindingSvc.BindIdentity(ctx.Request.Context(), st.Uid, identity)
+	switch err {
+	case nil:
+		if st.RedirectURI != "" {
+			ctx.Redirect(http.StatusFound, st.RedirectURI)
+			return
+		}
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "绑定成功",
+		})
+	case service.ErrBindConflict:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserBindConflict,
+			Msg:  "该账号已经绑定了其它用户",
+		})
+	case service.ErrBindProviderBound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserBindConflict,
+			Msg:  "已经绑定了该平台的其它账号，请先解绑",
+		})
+	default:
+		h.log.Error("绑定第三方账号失败", accesslog.String("provider", p.Name()), accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// sameBrowser 比较 cookie 和 state 里面的 Nonce，比较完就清掉 cookie
+func (h *OAuth2Handler) sameBrowser(ctx *gin.Context, st domain.OAuth2State) bool {
+	nonce, err := ctx.Cookie(oauth2NonceCookie)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oauth2NonceCookie, "", -1, "/oauth2", "", true, true)
+	return err == nil && st.Nonce != "" &&
+		subtle.ConstantTimeCompare([]byte(nonce), []byte(st.Nonce)) == 1
+}
+
+// allowRedirect 只允许跳转到白名单里面的 scheme + host
+func (h *OAuth2Handler) allowRedirect(redirectURI string) bool {
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return false
+	}
+	return slices.Contains(h.cfg.RedirectWhitelist, u.Scheme+"://"+u.Host)
+}
+
+func (h *OAuth2Handler) provider(ctx *gin.Context) (oauth2.Provider, bool) {
+	p, ok := h.providers.Get(ctx.Param("provider"))
+	if !ok {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "不支持的登录方式",
+		})
+	}
+	return p, ok
+}
+
+// withQuery 在跳转地址上追加参数，地址已经校验过可以解析
+func withQuery(rawURL string, key string, val string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	q.Set(key, val)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,180 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	svcmocks "github.com/dadaxiaoxiao/user/internal/service/mocks"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2"
+	oauth2mocks "github.com/dadaxiaoxiao/user/internal/service/oauth2/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuth2Handler(t *testing.T) {
+	testCase := []struct {
+		name string
+		mock func(ctrl *gomock.Controller) (oauth2.Provider, service.IdentityService)
+		// 输入
+		url    string
+		cookie string
+		// 输出
+		wantCode     int
+		wantLocation string
+		wantBizCode  int
+	}{
+		{
+			name: "跳转地址不在白名单",
+			mock: func(ctrl *gomock.Controller) (oauth2.Provider, service.IdentityService) {
+				return githubMock(ctrl), svcmocks.NewMockIdentityService(ctrl)
+			},
+			url:         "/oauth2/github/authurl?redirect_uri=https%3A%2F%2Fevil.com%2Fdone",
+			wantCode:    http.StatusOK,
+			wantBizCode: errs.UserInvalidInput,
+		},
+		{
+			name: "state 无效，不会去换 token",
+			mock: func(ctrl *gomock.Controller) (oauth2.Provider, service.IdentityService) {
+				svc := svcmocks.NewMockIdentityService(ctrl)
+				svc.EXPECT().TakeState(gomock.Any(), "forged").
+					Return(domain.OAuth2State{}, service.ErrOAuth2StateInvalid)
+				return githubMock(ctrl), svc
+			},
+			url:         "/oauth2/github/callback?code=code&state=forged",
+			wantCode:    http.StatusOK,
+			wantBizCode: errs.UserInvalidInput,
+		},
+		{
+			name: "state 是其它提供方的",
+			mock: func(ctrl *gomock.Controller) (oauth2.Provider, service.IdentityService) {
+				svc := svcmocks.NewMockIdentityService(ctrl)
+				svc.EXPECT().TakeState(gomock.Any(), "login_1").Return(domain.OAuth2State{
+					Provider: domain.ProviderWechat,
+					Intent:   domain.OAuth2IntentLogin,
+				}, nil)
+				return githubMock(ctrl), svc
+			},
+			url:         "/oauth2/github/callback?code=code&state=login_1",
+			wantCode:    http.StatusOK,
+			wantBizCode: errs.UserInvalidInput,
+		},
+		{
+			name: "登录成功，带着票据跳转回去",
+			mock: func(ctrl *gomock.Controller) (oauth2.Provider, service.IdentityService) {
+				p := githubMock(ctrl)
+				p.EXPECT().VerifyCode(gomock.Any(), "code").
+					Return(domain.UserIdentity{Provider: domain.ProviderGithub, Subject: "583231"}, nil)
+				svc := svcmocks.NewMockIdentityService(ctrl)
+				svc.EXPECT().TakeState(gomock.Any(), "login_1").Return(domain.OAuth2State{
+					Provider:    domain.ProviderGithub,
+					Intent:      domain.OAuth2IntentLogin,
+					RedirectURI: "https://qinyeyiyi.cn/done?from=github",
+					Nonce:       "nonce",
+				}, nil)
+				svc.EXPECT().FindOrCreateByIdentity(gomock.Any(), gomock.Any()).Return(domain.User{Id: 123}, nil)
+				svc.EXPECT().CreateState(gomock.Any(), domain.OAuth2State{
+					Provider: domain.ProviderGithub,
+					Intent:   domain.OAuth2IntentTicket,
+					Uid:      123,
+				}).Return("ticket_1", nil)
+				return p, svc
+			},
+			url:          "/oauth2/github/callback?code=code&state=login_1",
+			cookie:       "nonce",
+			wantCode:     http.StatusFound,
+			wantLocation: "https://qinyeyiyi.cn/done?from=github&ticket=ticket_1",
+		},
+		{
+			name: "别人发来的登录回调，浏览器没有对应的 cookie，不会去换 token",
+			mock: func(ctrl *gomock.Controller) (oauth2.Provider, service.IdentityService) {
+				svc := svcmocks.NewMockIdentityService(ctrl)
+				svc.EXPECT().TakeState(gomock.Any(), "login_1").Return(domain.OAuth2State{
+					Provider: domain.ProviderGithub,
+					Intent:   domain.OAuth2IntentLogin,
+					Nonce:    "attacker",
+				}, nil)
+				return githubMock(ctrl), svc
+			},
+			url:         "/oauth2/github/callback?code=code&state=login_1",
+			cookie:      "victim",
+			wantCode:    http.StatusOK,
+			wantBizCode: errs.UserInvalidInput,
+		},
+		{
+			name: "绑定回调没有带上发起绑定的浏览器的 cookie",
+			mock: func(ctrl *gomock.Controller) (oauth2.Provider, service.IdentityService) {
+				svc := svcmocks.NewMockIdentityService(ctrl)
+				svc.EXPECT().TakeState(gomock.Any(), "bind_1").Return(domain.OAuth2State{
+					Provider: domain.ProviderGithub,
+					Intent:   domain.OAuth2IntentBind,
+					Uid:      123,
+					Ssid:     "ssid",
+					Nonce:    "nonce",
+				}, nil)
+				return githubMock(ctrl), svc
+			},
+			url:         "/oauth2/github/callback?code=code&state=bind_1",
+			wantCode:    http.StatusOK,
+			wantBizCode: errs.UserInvalidInput,
+		},
+		{
+			name: "绑定回调的 cookie 和 state 对不上",
+			mock: func(ctrl *gomock.Controller) (oauth2.Provider, service.IdentityService) {
+				svc := svcmocks.NewMockIdentityService(ctrl)
+				svc.EXPECT().TakeState(gomock.Any(), "bind_1").Return(domain.OAuth2State{
+					Provider: domain.ProviderGithub,
+					Intent:   domain.OAuth2IntentBind,
+					Uid:      123,
+					Ssid:     "ssid",
+					Nonce:    "nonce",
+				}, nil)
+				return githubMock(ctrl), svc
+			},
+			url:         "/oauth2/github/callback?code=code&state=bind_1",
+			cookie:      "attacker",
+			wantCode:    http.StatusOK,
+			wantBizCode: errs.UserInvalidInput,
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			p, svc := tc.mock(ctrl)
+			hdl := NewOAuth2Handler(oauth2.NewRegistry(p), svc, nil, nil, OAuth2Config{
+				RedirectWhitelist: []string{"https://qinyeyiyi.cn"},
+			}, accesslog.NewNopLogger())
+			server := gin.New()
+			hdl.RegisterRoutes(server)
+
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: oauth2NonceCookie, Value: tc.cookie})
+			}
+			resp := httptest.NewRecorder()
+			server.ServeHTTP(resp, req)
+
+			assert.Equal(t, tc.wantCode, resp.Code)
+			if tc.wantCode == http.StatusFound {
+				assert.Equal(t, tc.wantLocation, resp.Header().Get("Location"))
+				return
+			}
+			var res Result
+			require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &res))
+			assert.Equal(t, tc.wantBizCode, res.Code)
+		})
+	}
+}
+
+func githubMock(ctrl *gomock.Controller) *oauth2mocks.MockProvider {
+	p := oauth2mocks.NewMockProvider(ctrl)
+	p.EXPECT().Name().Return(domain.ProviderGithub).AnyTimes()
+	return p
+}
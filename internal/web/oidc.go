@@ -0,0 +1,379 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	oidcAccessTokenExpire = time.Hour
+	oidcIdTokenExpire     = time.Hour
+)
+
+// OIDCConfig OIDC 提供方的配置
+type OIDCConfig struct {
+	// Issuer 对外的地址，比如 https://sso.your-company.com，会出现在 token 的 iss 里面
+	Issuer string
+	// LoginURL 前端的登录页，/oidc/authorize 会带着原来的参数跳转过去，
+	// 用户登录之后前端调用 /oidc/authorize/confirm
+	LoginURL string
+}
+
+// OIDCAccessClaims 签发给 RP 的 access token，只能用来调用 /oidc/userinfo
+// 没有 Uid，所以不能当作用户中心自己的 access token 使用
+type OIDCAccessClaims struct {
+	jwt.RegisteredClaims
+	ClientId string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// OIDCHandler 把用户中心作为 OpenID Connect 提供方，只支持授权码模式
+type OIDCHandler struct {
+	svc     service.OIDCService
+	userSvc service.UserService
+	keys    *myjwt.KeySet
+	cfg     OIDCConfig
+	log     accesslog.Logger
+	now     func() time.Time
+}
+
+func NewOIDCHandler(svc service.OIDCService, userSvc service.UserService, keys *myjwt.KeySet,
+	cfg OIDCConfig, log accesslog.Logger) *OIDCHandler {
+	return &OIDCHandler{
+		svc:     svc,
+		userSvc: userSvc,
+		keys:    keys,
+		cfg:     cfg,
+		log:     log,
+		now:     time.Now,
+	}
+}
+
+func (h *OIDCHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/.well-known/openid-configuration", h.Discovery)
+	g := server.Group("/oidc")
+	g.GET("/authorize", h.Authorize)
+	g.POST("/authorize/confirm", h.Confirm)
+	g.POST("/token", h.Token)
+	g.GET("/userinfo", h.UserInfo)
+}
+
+// Discovery OpenID Connect Discovery 1.0
+func (h *OIDCHandler) Discovery(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"issuer":                                h.cfg.Issuer,
+		"authorization_endpoint":                h.cfg.Issuer + "/oidc/authorize",
+		"token_endpoint":                        h.cfg.Issuer + "/oidc/token",
+		"userinfo_endpoint":                     h.cfg.Issuer + "/oidc/userinfo",
+		"introspection_endpoint":                h.cfg.Issuer + "/oidc/introspect",
+		"jwks_uri":                              h.cfg.Issuer + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256", "EdDSA"},
+		"scopes_supported":                      service.SupportedScopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported": []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+			"nickname", "email", "email_verified", "phone_number"},
+	})
+}
+
+type authorizeReq struct {
+	ClientId            string `json:"client_id" form:"client_id"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	ResponseType        string `json:"response_type" form:"response_type"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	Nonce               string `json:"nonce" form:"nonce"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+}
+
+func (r authorizeReq) toService() service.AuthorizeRequest {
+	return service.AuthorizeRequest{
+		ClientId:            r.ClientId,
+		RedirectURI:         r.RedirectURI,
+		ResponseType:        r.ResponseType,
+		Scope:               r.Scope,
+		Nonce:               r.Nonce,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+	}
+}
+
+// Authorize 校验参数之后跳转到登录页
+func (h *OIDCHandler) Authorize(ctx *gin.Context) {
+	var req authorizeReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+	_, _, err := h.svc.ValidateAuthorize(ctx.Request.Context(), req.toService())
+	switch err {
+	case nil:
+		ctx.Redirect(http.StatusFound, h.cfg.LoginURL+"?"+ctx.Request.URL.RawQuery)
+	case service.ErrOIDCInvalidClient, service.ErrOIDCInvalidRedirectURI:
+		// redirect_uri 不可信，不能跳回去
+		h.oauthError(ctx, http.StatusBadRequest, err)
+	default:
+		if oe, ok := service.IsOIDCError(err); ok {
+			ctx.Redirect(http.StatusFound, h.errorRedirect(req, oe))
+			return
+		}
+		h.log.Error("校验授权请求失败", accesslog.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+	}
+}
+
+// Confirm 用户登录之后由前端调用，返回要跳转的地址
+// 第一次授权的时候返回应用名字和 scope，用户同意之后带上 approve 再调用一次
+func (h *OIDCHandler) Confirm(ctx *gin.Context) {
+	type Req struct {
+		authorizeReq
+		Approve bool `json:"approve"`
+		// Deny 用户拒绝授权，带着 access_denied 跳回 RP
+		Deny bool `json:"deny"`
+	}
+	type Resp struct {
+		// RedirectURL 不为空的时候前端直接跳转
+		RedirectURL     string   `json:"redirect_url,omitempty"`
+		ConsentRequired bool     `json:"consent_required,omitempty"`
+		ClientName      string   `json:"client_name,omitempty"`
+		Scopes          []string `json:"scopes,omitempty"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	if req.Deny {
+		_, _, err := h.svc.ValidateAuthorize(ctx.Request.Context(), req.toService())
+		if err == service.ErrOIDCInvalidClient || err == service.ErrOIDCInvalidRedirectURI {
+			ctx.JSONP(http.StatusOK, Result{
+				Code: 4,
+				Msg:  "应用不存在或者回调地址不对",
+			})
+			return
+		}
+		ctx.JSONP(http.StatusOK, Result{
+			Data: Resp{RedirectURL: h.errorRedirect(req.authorizeReq, service.ErrOIDCAccessDenied)},
+		})
+		return
+	}
+	code, err := h.svc.Authorize(ctx.Request.Context(), uc.Uid, req.toService(), req.Approve)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Data: Resp{RedirectURL: h.redirect(req.RedirectURI, url.Values{
+				"code":  {code},
+				"state": {req.State},
+			})},
+		})
+	case service.ErrOIDCInvalidClient, service.ErrOIDCInvalidRedirectURI:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "应用不存在或者回调地址不对",
+		})
+	case service.ErrOIDCConsentRequired:
+		client, scopes, err := h.svc.ValidateAuthorize(ctx.Request.Context(), req.toService())
+		if err != nil {
+			ctx.JSONP(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+			return
+		}
+		ctx.JSONP(http.StatusOK, Result{
+			Data: Resp{ConsentRequired: true, ClientName: client.Name, Scopes: scopes},
+		})
+	default:
+		oe, ok := service.IsOIDCError(err)
+		if !ok {
+			h.log.Error("签发授权码失败", accesslog.Error(err))
+			ctx.JSONP(http.StatusOK, Result{
+				Code: 5,
+				Msg:  "系统错误",
+			})
+			return
+		}
+		ctx.JSONP(http.StatusOK, Result{
+			Data: Resp{RedirectURL: h.errorRedirect(req.authorizeReq, oe)},
+		})
+	}
+}
+
+// Token 授权码换 token，RFC 6749 4.1.3
+func (h *OIDCHandler) Token(ctx *gin.Context) {
+	// token 不能被缓存
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+	if ctx.PostForm("grant_type") != "authorization_code" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+	clientId, secret := clientCredentials(ctx)
+	ac, err := h.svc.Exchange(ctx.Request.Context(), clientId, secret, ctx.PostForm("code"),
+		ctx.PostForm("redirect_uri"), ctx.PostForm("code_verifier"))
+	switch err {
+	case nil:
+	case service.ErrOIDCInvalidClient:
+		h.oauthError(ctx, http.StatusUnauthorized, err)
+		return
+	default:
+		if _, ok := service.IsOIDCError(err); ok {
+			h.oauthError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		h.log.Error("授权码换 token 失败", accesslog.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	u, err := h.userSvc.Profile(ctx.Request.Context(), ac.Uid)
+	if err != nil {
+		h.log.Error("查询用户信息失败", accesslog.Error(err), accesslog.Int64("uid", ac.Uid))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	now := h.now()
+	sub := strconv.FormatInt(ac.Uid, 10)
+	scope := strings.Join(ac.Scopes, " ")
+	accessToken, err := h.keys.Sign(myjwt.TypeAccessToken, OIDCAccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    h.cfg.Issuer,
+			Subject:   sub,
+			Audience:  jwt.ClaimStrings{clientId},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(oidcAccessTokenExpire)),
+		},
+		ClientId: clientId,
+		Scope:    scope,
+	})
+	if err != nil {
+		h.log.Error("签发 access token 失败", accesslog.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	idClaims := h.userClaims(u, ac.Scopes)
+	idClaims["iss"] = h.cfg.Issuer
+	idClaims["aud"] = clientId
+	idClaims["iat"] = now.Unix()
+	idClaims["exp"] = now.Add(oidcIdTokenExpire).Unix()
+	idClaims["auth_time"] = ac.AuthTime.Unix()
+	if ac.Nonce != "" {
+		idClaims["nonce"] = ac.Nonce
+	}
+	idToken, err := h.keys.Sign("JWT", idClaims)
+	if err != nil {
+		h.log.Error("签发 id token 失败", accesslog.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int64(oidcAccessTokenExpire / time.Second),
+		"id_token":     idToken,
+		"scope":        scope,
+	})
+}
+
+// UserInfo 使用 /oidc/token 签发的 access token 查询用户信息
+func (h *OIDCHandler) UserInfo(ctx *gin.Context) {
+	tokenStr, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var claims OIDCAccessClaims
+	token, err := h.keys.Parse(tokenStr, myjwt.TypeAccessToken, &claims)
+	uid, _ := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || !token.Valid || uid == 0 || claims.ClientId == "" {
+		ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	u, err := h.userSvc.Profile(ctx.Request.Context(), uid)
+	if err != nil {
+		h.log.Error("查询用户信息失败", accesslog.Error(err), accesslog.Int64("uid", uid))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	ctx.JSON(http.StatusOK, h.userClaims(u, strings.Fields(claims.Scope)))
+}
+
+// userClaims 根据 scope 返回用户信息，OIDC Core 5.4
+func (h *OIDCHandler) userClaims(u domain.User, scopes []string) jwt.MapClaims {
+	res := jwt.MapClaims{
+		"sub": strconv.FormatInt(u.Id, 10),
+	}
+	if slices.Contains(scopes, service.ScopeProfile) {
+		res["nickname"] = u.Nickname
+	}
+	if slices.Contains(scopes, service.ScopeEmail) && u.Email != "" {
+		res["email"] = u.Email
+		res["email_verified"] = !u.VerifiedAt.IsZero()
+	}
+	if slices.Contains(scopes, service.ScopePhone) && u.Phone != "" {
+		res["phone_number"] = u.Phone
+	}
+	return res
+}
+
+// clientCredentials 支持 client_secret_basic 和 client_secret_post
+func clientCredentials(ctx *gin.Context) (string, string) {
+	clientId, secret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		return ctx.PostForm("client_id"), ctx.PostForm("client_secret")
+	}
+	// client_secret_basic 需要先 URL 编码
+	clientId, _ = url.QueryUnescape(clientId)
+	secret, _ = url.QueryUnescape(secret)
+	return clientId, secret
+}
+
+func (h *OIDCHandler) oauthError(ctx *gin.Context, status int, err error) {
+	oe, _ := service.IsOIDCError(err)
+	ctx.JSON(status, gin.H{
+		"error":             oe.Code,
+		"error_description": oe.Description,
+	})
+}
+
+// errorRedirect 把错误带回 RP，RFC 6749 4.1.2.1
+func (h *OIDCHandler) errorRedirect(req authorizeReq, oe *service.OIDCError) string {
+	return h.redirect(req.RedirectURI, url.Values{
+		"error":             {oe.Code},
+		"error_description": {oe.Description},
+		"state":             {req.State},
+	})
+}
+
+func (h *OIDCHandler) redirect(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		// 注册的时候已经校验过了
+		return redirectURI
+	}
+	q := u.Query()
+	for k, v := range params {
+		if len(v) > 0 && v[0] != "" {
+			q.Set(k, v[0])
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
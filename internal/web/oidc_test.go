@@ -0,0 +1,337 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	svcmocks "github.com/dadaxiaoxiao/user/internal/service/mocks"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestOIDC_AuthorizationCodeFlow 在进程内模拟一个 RP 走完授权码 + PKCE 的流程
+func TestOIDC_AuthorizationCodeFlow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	userSvc := svcmocks.NewMockUserService(ctrl)
+	userSvc.EXPECT().Profile(gomock.Any(), int64(123)).Return(domain.User{
+		Id:         123,
+		Email:      "1426325504@qq.com",
+		Nickname:   "yeqin",
+		Phone:      "15212345678",
+		VerifiedAt: time.Now(),
+	}, nil).AnyTimes()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ks, err := myjwt.NewKeySet("k1", myjwt.Key{Kid: "k1", Private: priv, Public: pub})
+	require.NoError(t, err)
+	oidcSvc := service.NewOIDCService(newMemoryOIDCRepository())
+
+	server := gin.New()
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+	// 模拟登录中间件，/oidc/authorize/confirm 需要登录
+	server.Use(func(ctx *gin.Context) {
+		if ctx.Request.URL.Path == "/oidc/authorize/confirm" {
+			ctx.Set("user", myjwt.UserClaims{Uid: 123})
+		}
+	})
+	NewJWKSHandler(myjwt.NewRedisJWTHandler(nil, ks, myjwt.SessionConfig{})).RegisterRoutes(server)
+	NewOIDCHandler(oidcSvc, userSvc, ks, OIDCConfig{
+		Issuer:   srv.URL,
+		LoginURL: "https://sso.example.com/login",
+	}, accesslog.NewNopLogger()).RegisterRoutes(server)
+
+	const redirectURI = "https://rp.example.com/callback"
+	client, secret, err := oidcSvc.RegisterClient(context.Background(), "论坛", []string{redirectURI}, false)
+	require.NoError(t, err)
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// 1. RP 通过 discovery 拿到各个地址
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+		JwksURI               string `json:"jwks_uri"`
+	}
+	getJSON(t, httpClient, srv.URL+"/.well-known/openid-configuration", &discovery)
+	assert.Equal(t, srv.URL, discovery.Issuer)
+
+	// 2. 浏览器跳转到 /oidc/authorize，校验通过之后跳到登录页
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"client_id":             {client.ClientId},
+		"redirect_uri":          {redirectURI},
+		"response_type":         {"code"},
+		"scope":                 {"openid profile email"},
+		"state":                 {"state"},
+		"nonce":                 {"nonce"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	resp, err := httpClient.Get(discovery.AuthorizationEndpoint + "?" + params.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), "https://sso.example.com/login?"))
+
+	// 3. 用户登录之后，第一次需要同意授权
+	confirm := map[string]any{}
+	for k := range params {
+		confirm[k] = params.Get(k)
+	}
+	type confirmResult struct {
+		Code int `json:"code"`
+		Data struct {
+			RedirectURL     string   `json:"redirect_url"`
+			ConsentRequired bool     `json:"consent_required"`
+			ClientName      string   `json:"client_name"`
+			Scopes          []string `json:"scopes"`
+		} `json:"data"`
+	}
+	var res confirmResult
+	postJSON(t, httpClient, srv.URL+"/oidc/authorize/confirm", confirm, &res)
+	assert.True(t, res.Data.ConsentRequired)
+	assert.Equal(t, "论坛", res.Data.ClientName)
+	assert.Equal(t, []string{"openid", "profile", "email"}, res.Data.Scopes)
+
+	confirm["approve"] = true
+	res = confirmResult{}
+	postJSON(t, httpClient, srv.URL+"/oidc/authorize/confirm", confirm, &res)
+	cb, err := url.Parse(res.Data.RedirectURL)
+	require.NoError(t, err)
+	assert.Equal(t, "state", cb.Query().Get("state"))
+	code := cb.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	// 4. RP 后端用授权码换 token
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(client.ClientId), url.QueryEscape(secret))
+	resp, err = httpClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		IdToken     string `json:"id_token"`
+		Scope       string `json:"scope"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tokenResp))
+	resp.Body.Close()
+	assert.Equal(t, "Bearer", tokenResp.TokenType)
+	assert.Equal(t, "openid profile email", tokenResp.Scope)
+
+	// 5. RP 只用 JWKS 里面的公钥验证 id token
+	var jwks myjwt.JWKS
+	getJSON(t, httpClient, discovery.JwksURI, &jwks)
+	idClaims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(tokenResp.IdToken, idClaims, func(token *jwt.Token) (interface{}, error) {
+		for _, k := range jwks.Keys {
+			if k.Kid == token.Header["kid"] && k.Kty == "OKP" {
+				x, err := base64.RawURLEncoding.DecodeString(k.X)
+				return ed25519.PublicKey(x), err
+			}
+		}
+		return nil, myjwt.ErrKeyNotFound
+	}, jwt.WithIssuer(discovery.Issuer), jwt.WithAudience(client.ClientId), jwt.WithValidMethods([]string{"EdDSA"}))
+	require.NoError(t, err)
+	assert.Equal(t, "123", idClaims["sub"])
+	assert.Equal(t, "nonce", idClaims["nonce"])
+	assert.Equal(t, "1426325504@qq.com", idClaims["email"])
+	assert.Equal(t, true, idClaims["email_verified"])
+	assert.Equal(t, "yeqin", idClaims["nickname"])
+	// 没有申请 phone
+	assert.NotContains(t, idClaims, "phone_number")
+
+	// 6. 用 access token 查询用户信息
+	req, err = http.NewRequest(http.MethodGet, discovery.UserinfoEndpoint, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+tokenResp.AccessToken)
+	resp, err = httpClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var userInfo map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&userInfo))
+	resp.Body.Close()
+	assert.Equal(t, "123", userInfo["sub"])
+	assert.Equal(t, "1426325504@qq.com", userInfo["email"])
+
+	// 7. 授权码只能用一次
+	req, err = http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(client.ClientId), url.QueryEscape(secret))
+	resp, err = httpClient.Do(req)
+	require.NoError(t, err)
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "invalid_grant", errResp.Error)
+
+	// 8. 已经同意过，再次登录不需要确认
+	delete(confirm, "approve")
+	res = confirmResult{}
+	postJSON(t, httpClient, srv.URL+"/oidc/authorize/confirm", confirm, &res)
+	assert.False(t, res.Data.ConsentRequired)
+	assert.NotEmpty(t, res.Data.RedirectURL)
+}
+
+func TestOIDC_AuthorizeInvalidRedirect(t *testing.T) {
+	oidcSvc := service.NewOIDCService(newMemoryOIDCRepository())
+	client, _, err := oidcSvc.RegisterClient(context.Background(), "论坛",
+		[]string{"https://rp.example.com/callback"}, true)
+	require.NoError(t, err)
+	server := gin.New()
+	NewOIDCHandler(oidcSvc, nil, nil, OIDCConfig{
+		Issuer:   "https://sso.example.com",
+		LoginURL: "https://sso.example.com/login",
+	}, accesslog.NewNopLogger()).RegisterRoutes(server)
+
+	// 回调地址没有注册，不能跳回去
+	params := url.Values{
+		"client_id":     {client.ClientId},
+		"redirect_uri":  {"https://evil.example.com/callback"},
+		"response_type": {"code"},
+		"scope":         {"openid"},
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/oidc/authorize?"+params.Encode(), nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Location"))
+
+	// 公开客户端没有使用 PKCE，错误带回 RP
+	params.Set("redirect_uri", "https://rp.example.com/callback")
+	params.Set("state", "state")
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/oidc/authorize?"+params.Encode(), nil))
+	assert.Equal(t, http.StatusFound, recorder.Code)
+	loc, err := url.Parse(recorder.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "rp.example.com", loc.Host)
+	assert.Equal(t, "invalid_request", loc.Query().Get("error"))
+	assert.Equal(t, "state", loc.Query().Get("state"))
+}
+
+func getJSON(t *testing.T, client *http.Client, u string, val any) {
+	resp, err := client.Get(u)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(val))
+}
+
+func postJSON(t *testing.T, client *http.Client, u string, body any, val any) {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	resp, err := client.Post(u, "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(val))
+}
+
+// memoryOIDCRepository 测试用的内存实现
+type memoryOIDCRepository struct {
+	mu       sync.Mutex
+	clients  map[string]domain.OAuthClient
+	consents map[string]domain.OAuthConsent
+	codes    map[string]domain.AuthCode
+}
+
+func newMemoryOIDCRepository() *memoryOIDCRepository {
+	return &memoryOIDCRepository{
+		clients:  map[string]domain.OAuthClient{},
+		consents: map[string]domain.OAuthConsent{},
+		codes:    map[string]domain.AuthCode{},
+	}
+}
+
+func (r *memoryOIDCRepository) CreateClient(ctx context.Context, c domain.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clients[c.ClientId] = c
+	return nil
+}
+
+func (r *memoryOIDCRepository) FindClient(ctx context.Context, clientId string) (domain.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.clients[clientId]
+	if !ok {
+		return domain.OAuthClient{}, repository.ErrOAuthClientNotFound
+	}
+	return c, nil
+}
+
+func (r *memoryOIDCRepository) FindConsent(ctx context.Context, uid int64, clientId string) (domain.OAuthConsent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.consents[fmt.Sprintf("%d/%s", uid, clientId)]
+	if !ok {
+		return domain.OAuthConsent{}, repository.ErrOAuthConsentNotFound
+	}
+	return c, nil
+}
+
+func (r *memoryOIDCRepository) SaveConsent(ctx context.Context, c domain.OAuthConsent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consents[fmt.Sprintf("%d/%s", c.Uid, c.ClientId)] = c
+	return nil
+}
+
+func (r *memoryOIDCRepository) StoreCode(ctx context.Context, code string, val domain.AuthCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code] = val
+	return nil
+}
+
+func (r *memoryOIDCRepository) TakeCode(ctx context.Context, code string) (domain.AuthCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ac, ok := r.codes[code]
+	if !ok {
+		return domain.AuthCode{}, repository.ErrAuthCodeNotFound
+	}
+	delete(r.codes, code)
+	return ac, nil
+}
@@ -0,0 +1,154 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// PersonalAccessTokenHandler 用户管理自己的个人访问令牌
+// 创建和删除不能用令牌本身调用，不然令牌泄露之后可以用它创建新的令牌，在 ioc 里面配置中间件拒绝
+type PersonalAccessTokenHandler struct {
+	svc service.PersonalAccessTokenService
+	log accesslog.Logger
+}
+
+func NewPersonalAccessTokenHandler(svc service.PersonalAccessTokenService, log accesslog.Logger) *PersonalAccessTokenHandler {
+	return &PersonalAccessTokenHandler{
+		svc: svc,
+		log: log,
+	}
+}
+
+func (h *PersonalAccessTokenHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/users/tokens")
+	g.GET("", h.List)
+	g.POST("/create", h.Create)
+	g.POST("/revoke", h.Revoke)
+}
+
+type personalAccessTokenVo struct {
+	Id         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expiresAt"`
+	LastUsedAt string   `json:"lastUsedAt"`
+	Ctime      string   `json:"ctime"`
+}
+
+// Create 令牌明文只在这里返回一次
+func (h *PersonalAccessTokenHandler) Create(ctx *gin.Context) {
+	type Req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+		// ExpireDays 有效期，单位天
+		ExpireDays int `json:"expireDays"`
+	}
+	type Resp struct {
+		personalAccessTokenVo
+		Token string `json:"token"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	t, token, err := h.svc.Create(ctx.Request.Context(), uc.Uid, req.Name, req.Scopes,
+		time.Duration(req.ExpireDays)*time.Hour*24)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Data: Resp{
+				personalAccessTokenVo: h.toVo(t),
+				Token:                 token,
+			},
+		})
+	case service.ErrPersonalAccessTokenArgs:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "名字不能为空，权限只能是 read 或者 write，有效期 1 到 365 天",
+		})
+	case service.ErrPersonalAccessTokenTooMany:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "访问令牌太多了，请先删除不用的",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("创建访问令牌失败", accesslog.Error(err), accesslog.Int64("uid", uc.Uid))
+	}
+}
+
+// List 不会返回令牌明文
+func (h *PersonalAccessTokenHandler) List(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	tokens, err := h.svc.List(ctx.Request.Context(), uc.Uid)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	res := make([]personalAccessTokenVo, 0, len(tokens))
+	for _, t := range tokens {
+		res = append(res, h.toVo(t))
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// Revoke 删除之后立即失效
+func (h *PersonalAccessTokenHandler) Revoke(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := h.svc.Revoke(ctx.Request.Context(), uc.Uid, req.Id)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "删除成功",
+		})
+	case service.ErrPersonalAccessTokenNotFound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "访问令牌不存在",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("删除访问令牌失败", accesslog.Error(err), accesslog.Int64("uid", uc.Uid))
+	}
+}
+
+func (h *PersonalAccessTokenHandler) toVo(t domain.PersonalAccessToken) personalAccessTokenVo {
+	vo := personalAccessTokenVo{
+		Id:        t.Id,
+		Name:      t.Name,
+		Prefix:    t.Prefix,
+		Scopes:    t.Scopes,
+		ExpiresAt: t.ExpiresAt.Format(time.DateTime),
+		Ctime:     t.Ctime.Format(time.DateTime),
+	}
+	if !t.LastUsedAt.IsZero() {
+		vo.LastUsedAt = t.LastUsedAt.Format(time.DateTime)
+	}
+	return vo
+}
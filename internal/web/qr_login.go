@@ -0,0 +1,190 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// QRLoginHandler 扫码登录，桌面端生成二维码并长轮询状态，已经登录的 App 扫码确认
+type QRLoginHandler struct {
+	svc service.QRLoginService
+	myjwt.Handler
+	log accesslog.Logger
+}
+
+func NewQRLoginHandler(svc service.QRLoginService, wtHdl myjwt.Handler, log accesslog.Logger) *QRLoginHandler {
+	return &QRLoginHandler{
+		svc:     svc,
+		Handler: wtHdl,
+		log:     log,
+	}
+}
+
+func (h *QRLoginHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/users/qr_login")
+	// 桌面端，不需要登录态
+	g.POST("/ticket", h.Ticket)
+	g.POST("/poll", h.Poll)
+	// App 端，需要登录态
+	g.POST("/scan", h.Scan)
+	g.POST("/confirm", h.Confirm)
+	g.POST("/cancel", h.Cancel)
+}
+
+// Ticket 桌面端生成二维码，ticket 放在二维码里面，secret 留在桌面端轮询的时候用
+func (h *QRLoginHandler) Ticket(ctx *gin.Context) {
+	type Resp struct {
+		Ticket string `json:"ticket"`
+		Secret string `json:"secret"`
+	}
+	qr, err := h.svc.Create(ctx.Request.Context(), domain.QRLoginDevice{
+		UserAgent: ctx.Request.UserAgent(),
+		IP:        ctx.ClientIP(),
+	})
+	if err != nil {
+		h.log.Error("生成扫码登录票据失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: Resp{
+			Ticket: qr.Ticket,
+			Secret: qr.Secret,
+		},
+	})
+}
+
+// Poll 桌面端长轮询，status 是上一次拿到的状态，状态变化或者超时之后返回
+// 状态是 confirmed 的时候 token 放在响应头里面，和其它登录方式一样
+func (h *QRLoginHandler) Poll(ctx *gin.Context) {
+	type Req struct {
+		Ticket string `json:"ticket"`
+		Secret string `json:"secret"`
+		Status string `json:"status"`
+	}
+	type Resp struct {
+		Status string `json:"status"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	qr, err := h.svc.Poll(ctx.Request.Context(), req.Ticket, req.Secret, req.Status)
+	if err == service.ErrQRLoginSecretInvalid {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "二维码无效",
+		})
+		return
+	}
+	if err == nil && qr.Status == domain.QRLoginConfirmed {
+		err = h.SetLoginToken(ctx, qr.Uid, myjwt.LoginMethodQRCode)
+	}
+	if err != nil {
+		h.log.Error("扫码登录轮询失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: Resp{
+			Status: qr.Status,
+		},
+	})
+}
+
+// Scan App 扫码之后展示发起登录的设备，让用户确认
+func (h *QRLoginHandler) Scan(ctx *gin.Context) {
+	type Resp struct {
+		UserAgent string `json:"user_agent"`
+		IP        string `json:"ip"`
+	}
+	ticket, ok := h.ticket(ctx)
+	if !ok {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	device, err := h.svc.Scan(ctx.Request.Context(), ticket, uc.Uid)
+	if err != nil {
+		h.handleErr(ctx, err)
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: Resp{
+			UserAgent: device.UserAgent,
+			IP:        device.IP,
+		},
+	})
+}
+
+func (h *QRLoginHandler) Confirm(ctx *gin.Context) {
+	ticket, ok := h.ticket(ctx)
+	if !ok {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	if err := h.svc.Confirm(ctx.Request.Context(), ticket, uc.Uid); err != nil {
+		h.handleErr(ctx, err)
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "已确认登录",
+	})
+}
+
+func (h *QRLoginHandler) Cancel(ctx *gin.Context) {
+	ticket, ok := h.ticket(ctx)
+	if !ok {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	if err := h.svc.Cancel(ctx.Request.Context(), ticket, uc.Uid); err != nil {
+		h.handleErr(ctx, err)
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "已取消登录",
+	})
+}
+
+func (h *QRLoginHandler) ticket(ctx *gin.Context) (string, bool) {
+	type Req struct {
+		Ticket string `json:"ticket"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return "", false
+	}
+	return req.Ticket, true
+}
+
+func (h *QRLoginHandler) handleErr(ctx *gin.Context, err error) {
+	switch err {
+	case service.ErrQRLoginExpired:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "二维码已经过期，请刷新",
+		})
+	case service.ErrQRLoginStatusConflict:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "二维码已经被扫描，请刷新",
+		})
+	default:
+		h.log.Error("扫码登录失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
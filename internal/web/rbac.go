@@ -0,0 +1,242 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+// RBACHandler 角色和权限的管理接口
+// /admin/rbac 下面的接口需要 domain.PermissionRBACManage，在 ioc 里面配置中间件校验
+type RBACHandler struct {
+	svc service.RBACService
+	log accesslog.Logger
+}
+
+func NewRBACHandler(svc service.RBACService, log accesslog.Logger) *RBACHandler {
+	return &RBACHandler{
+		svc: svc,
+		log: log,
+	}
+}
+
+func (h *RBACHandler) RegisterRoutes(server *gin.Engine) {
+	server.GET("/users/permissions", h.MyPermissions)
+	g := server.Group("/admin/rbac")
+	g.GET("/permissions", h.Permissions)
+	g.GET("/roles", h.Roles)
+	g.POST("/roles/save", h.SaveRole)
+	g.POST("/roles/delete", h.DeleteRole)
+	g.GET("/user_roles", h.UserRoles)
+	g.POST("/user_roles/set", h.SetUserRoles)
+}
+
+type roleVo struct {
+	Id          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// MyPermissions 当前用户的权限，前端用来决定展示哪些菜单
+func (h *RBACHandler) MyPermissions(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	perms, err := h.svc.Permissions(ctx.Request.Context(), uc.Uid)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if perms == nil {
+		perms = []string{}
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: perms,
+	})
+}
+
+// Permissions 所有可以分配的权限
+func (h *RBACHandler) Permissions(ctx *gin.Context) {
+	type Permission struct {
+		Code        string `json:"code"`
+		Description string `json:"description"`
+	}
+	res := make([]Permission, 0, len(domain.Permissions))
+	for _, p := range domain.Permissions {
+		res = append(res, Permission{
+			Code:        p.Code,
+			Description: p.Description,
+		})
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+func (h *RBACHandler) Roles(ctx *gin.Context) {
+	roles, err := h.svc.Roles(ctx.Request.Context())
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: h.toVos(roles),
+	})
+}
+
+// SaveRole id 为 0 的时候新建，否则更新，权限整体替换
+func (h *RBACHandler) SaveRole(ctx *gin.Context) {
+	var req roleVo
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	id, err := h.svc.SaveRole(ctx.Request.Context(), domain.Role{
+		Id:          req.Id,
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	switch err {
+	case nil:
+		h.log.Info("保存角色", accesslog.Int64("operator", uc.Uid), accesslog.Int64("role", id),
+			accesslog.Any("permissions", req.Permissions))
+		ctx.JSONP(http.StatusOK, Result{
+			Data: id,
+		})
+	case service.ErrRoleInvalid:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "角色名字不合法或者包含未定义的权限",
+		})
+	case service.ErrRoleDuplicate:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "角色名字已经存在",
+		})
+	case service.ErrRoleNotFound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "角色不存在",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("保存角色失败", accesslog.Error(err), accesslog.Int64("operator", uc.Uid))
+	}
+}
+
+func (h *RBACHandler) DeleteRole(ctx *gin.Context) {
+	type Req struct {
+		Id int64 `json:"id"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := h.svc.DeleteRole(ctx.Request.Context(), req.Id)
+	switch err {
+	case nil:
+		h.log.Info("删除角色", accesslog.Int64("operator", uc.Uid), accesslog.Int64("role", req.Id))
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "删除成功",
+		})
+	case service.ErrRoleNotFound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "角色不存在",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("删除角色失败", accesslog.Error(err), accesslog.Int64("operator", uc.Uid))
+	}
+}
+
+// UserRoles 查询某个用户的角色，GET /admin/rbac/user_roles?uid=123
+func (h *RBACHandler) UserRoles(ctx *gin.Context) {
+	uid, err := strconv.ParseInt(ctx.Query("uid"), 10, 64)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	roles, err := h.svc.UserRoles(ctx.Request.Context(), uid)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: h.toVos(roles),
+	})
+}
+
+// SetUserRoles 整体替换用户的角色
+func (h *RBACHandler) SetUserRoles(ctx *gin.Context) {
+	type Req struct {
+		Uid     int64   `json:"uid"`
+		RoleIds []int64 `json:"roleIds"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := h.svc.SetUserRoles(ctx.Request.Context(), req.Uid, req.RoleIds)
+	switch err {
+	case nil:
+		h.log.Info("修改用户角色", accesslog.Int64("operator", uc.Uid), accesslog.Int64("uid", req.Uid),
+			accesslog.Any("roles", req.RoleIds))
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "修改成功",
+		})
+	case service.ErrRoleNotFound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "角色不存在",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("修改用户角色失败", accesslog.Error(err), accesslog.Int64("operator", uc.Uid))
+	}
+}
+
+func (h *RBACHandler) toVos(roles []domain.Role) []roleVo {
+	res := make([]roleVo, 0, len(roles))
+	for _, r := range roles {
+		perms := r.Permissions
+		if perms == nil {
+			perms = []string{}
+		}
+		res = append(res, roleVo{
+			Id:          r.Id,
+			Name:        r.Name,
+			Description: r.Description,
+			Permissions: perms,
+		})
+	}
+	return res
+}
@@ -0,0 +1,139 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// TwoFactorHandler 两步验证的开启和关闭
+// 登录时候的第二步验证在 UserHandler.LoginTwoFactor
+type TwoFactorHandler struct {
+	svc service.TwoFactorService
+	log accesslog.Logger
+}
+
+func NewTwoFactorHandler(svc service.TwoFactorService, log accesslog.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		svc: svc,
+		log: log,
+	}
+}
+
+func (h *TwoFactorHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/users/2fa")
+	g.GET("", h.Status)
+	g.POST("/setup", h.Setup)
+	g.POST("/enable", h.Enable)
+	g.POST("/disable", h.Disable)
+}
+
+// Status 是否已经开启两步验证
+func (h *TwoFactorHandler) Status(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	enabled, err := h.svc.Enabled(ctx.Request.Context(), uc.Uid)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: enabled,
+	})
+}
+
+// Setup 生成密钥，前端把 uri 渲染成二维码
+func (h *TwoFactorHandler) Setup(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	secret, uri, err := h.svc.Setup(ctx.Request.Context(), uc.Uid)
+	switch err {
+	case nil:
+		type Resp struct {
+			Secret string `json:"secret"`
+			URI    string `json:"uri"`
+		}
+		ctx.JSONP(http.StatusOK, Result{
+			Data: Resp{
+				Secret: secret,
+				URI:    uri,
+			},
+		})
+	case service.ErrTwoFactorNoPassword, service.ErrTwoFactorEnabled:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("生成两步验证密钥失败", accesslog.Int64("uid", uc.Uid), accesslog.Error(err))
+	}
+}
+
+// Enable 用验证码确认开启，返回恢复码
+func (h *TwoFactorHandler) Enable(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	codes, err := h.svc.Enable(ctx.Request.Context(), uc.Uid, req.Code)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg:  "开启成功，请妥善保存恢复码，恢复码只显示这一次",
+			Data: codes,
+		})
+	case service.ErrTwoFactorCodeInvalid, service.ErrTwoFactorEnabled, service.ErrTwoFactorNotEnabled:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("开启两步验证失败", accesslog.Int64("uid", uc.Uid), accesslog.Error(err))
+	}
+}
+
+// Disable 关闭，需要验证码或者恢复码
+func (h *TwoFactorHandler) Disable(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := h.svc.Disable(ctx.Request.Context(), uc.Uid, req.Code)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "已经关闭两步验证",
+		})
+	case service.ErrTwoFactorCodeInvalid, service.ErrTwoFactorNotEnabled:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  err.Error(),
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		h.log.Error("关闭两步验证失败", accesslog.Int64("uid", uc.Uid), accesslog.Error(err))
+	}
+}
@@ -8,8 +8,8 @@ import (
 	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
 	regexp "github.com/dlclark/regexp2"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"go.opentelemetry.io/otel/trace"
+	"math"
 	"net/http"
 	"time"
 	"unicode/utf8"
@@ -27,6 +27,11 @@ const (
 type UserHandler struct {
 	userSvc          service.UserService
 	codeSvc          service.CodeService
+	emailCodeSvc     service.EmailCodeService
+	resetSvc         service.PasswordResetService
+	twoFactorSvc     service.TwoFactorService
+	limitSvc         service.LoginLimitService
+	deletionSvc      service.AccountDeletionService
 	emailRegexExp    *regexp.Regexp
 	passwordRegexExp *regexp.Regexp
 	birthdayRegexExp *regexp.Regexp
@@ -36,10 +41,15 @@ type UserHandler struct {
 }
 
 // NewUserHandler 返回 UserHandler 类的指针
-func NewUserHandler(svc service.UserService, codeSvc service.CodeService, wtHdl myjwt.Handler, log accesslog.Logger) *UserHandler {
+func NewUserHandler(svc service.UserService, codeSvc service.CodeService, emailCodeSvc service.EmailCodeService, resetSvc service.PasswordResetService, twoFactorSvc service.TwoFactorService, limitSvc service.LoginLimitService, deletionSvc service.AccountDeletionService, wtHdl myjwt.Handler, log accesslog.Logger) *UserHandler {
 	return &UserHandler{
 		userSvc:          svc,
 		codeSvc:          codeSvc,
+		emailCodeSvc:     emailCodeSvc,
+		resetSvc:         resetSvc,
+		twoFactorSvc:     twoFactorSvc,
+		limitSvc:         limitSvc,
+		deletionSvc:      deletionSvc,
 		emailRegexExp:    regexp.MustCompile(emailRegexPattern, regexp.None),
 		passwordRegexExp: regexp.MustCompile(passwordRegexPattern, regexp.None),
 		birthdayRegexExp: regexp.MustCompile(birthdayRegexPattern, regexp.None),
@@ -57,11 +67,21 @@ func (u *UserHandler) RegisterRoutes(server *gin.Engine) {
 	ug.POST("/signup", u.Signup)
 	ug.POST("/edit", u.Edit)
 	ug.POST("/login", u.LoginJWT)
+	ug.POST("/login/2fa", u.LoginTwoFactor)
 	ug.GET("/profile", u.Profile)
 	ug.POST("/logout", u.Logout)
 	ug.POST("/login_sms/code/send", u.SendSMSLoginCode)
 	ug.POST("/login_sms", u.LoginSMS)
 	ug.POST("/refresh_token", u.RefreshToken)
+	ug.POST("/email/verify/send", u.SendEmailVerifyCode)
+	ug.POST("/email/verify", u.VerifyEmail)
+	ug.POST("/password", u.ChangePassword)
+	ug.GET("/sessions", u.Sessions)
+	ug.POST("/sessions/revoke", u.RevokeSession)
+	ug.POST("/sessions/revoke_all", u.RevokeAllSessions)
+	ug.POST("/password/reset/send", u.SendPasswordResetCode)
+	ug.POST("/password/reset/verify", u.VerifyPasswordResetCode)
+	ug.POST("/password/reset", u.ResetPassword)
 
 }
 
@@ -140,9 +160,340 @@ func (u *UserHandler) Signup(ctx *gin.Context) {
 		ctx.JSON(http.StatusOK, Result{Code: 4, Msg: "系统异常"})
 		return
 	}
-	ctx.JSON(http.StatusOK, Result{Msg: "注册成功"})
+
+	// 发送邮箱验证码，验证之前不能登录
+	err = u.emailCodeSvc.Send(ctx.Request.Context(), service.EmailVerifyBiz, req.Email)
+	if err != nil {
+		// 账号已经创建了，用户可以重新发送验证码
+		u.log.Warn("发送邮箱验证码失败", accesslog.Error(err))
+		ctx.JSON(http.StatusOK, Result{Msg: "注册成功，验证邮件发送失败，请重新发送"})
+		return
+	}
+	ctx.JSON(http.StatusOK, Result{Msg: "注册成功，请查收验证邮件"})
+}
+
+// SendEmailVerifyCode 重新发送邮箱验证码
+func (u *UserHandler) SendEmailVerifyCode(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	verified, err := u.userSvc.EmailVerified(ctx.Request.Context(), req.Email)
+	switch err {
+	case nil:
+		if verified {
+			ctx.JSONP(http.StatusOK, Result{
+				Code: 4,
+				Msg:  "邮箱已经验证过了",
+			})
+			return
+		}
+	case service.ErrUserNotFound:
+		// 不告诉前端邮箱有没有注册，也不给没有注册的邮箱发邮件
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+		return
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	err = u.emailCodeSvc.Send(ctx.Request.Context(), service.EmailVerifyBiz, req.Email)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case service.ErrCodeSendTooMany:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "邮件发送太频繁，请稍后再试",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		u.log.Warn("发送邮箱验证码失败", accesslog.Error(err))
+	}
+}
+
+// VerifyEmail 校验邮箱验证码
+func (u *UserHandler) VerifyEmail(ctx *gin.Context) {
+	type Req struct {
+		Email string `json:"email"`
+		Code  string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	ok, err := u.emailCodeSvc.Verify(ctx.Request.Context(), service.EmailVerifyBiz, req.Email, req.Code)
+	if err == service.ErrCodeVerifyTooManyTimes {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证次数太多，请重新发送验证码",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		u.log.Error("校验邮箱验证码出错", accesslog.Error(err))
+		return
+	}
+	if !ok {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "验证码错误",
+		})
+		return
+	}
+
+	err = u.userSvc.VerifyEmail(ctx.Request.Context(), req.Email)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "验证成功",
+	})
 }
 
+// SendPasswordResetCode 找回密码，发送验证码
+func (u *UserHandler) SendPasswordResetCode(ctx *gin.Context) {
+	type Req struct {
+		// Type email 或者 phone
+		Type   string `json:"type"`
+		Target string `json:"target"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	ok, err := u.checkResetTarget(req.Type, req.Target)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !ok {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "邮箱或者手机号不正确",
+		})
+		return
+	}
+
+	err = u.resetSvc.SendCode(ctx.Request.Context(), req.Type, req.Target)
+	switch err {
+	case nil:
+		// 不管有没有注册都返回发送成功
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "发送成功",
+		})
+	case service.ErrCodeSendTooMany:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "发送太频繁，请稍后再试",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		u.log.Warn("发送找回密码验证码失败", accesslog.Error(err))
+	}
+}
+
+// VerifyPasswordResetCode 找回密码，校验验证码，返回重置凭证
+func (u *UserHandler) VerifyPasswordResetCode(ctx *gin.Context) {
+	type Req struct {
+		Type   string `json:"type"`
+		Target string `json:"target"`
+		Code   string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	token, err := u.resetSvc.Verify(ctx.Request.Context(), req.Type, req.Target, req.Code)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg:  "验证成功",
+			Data: token,
+		})
+	case service.ErrResetCodeInvalid, service.ErrResetTypeInvalid:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "验证码错误",
+		})
+	case service.ErrCodeVerifyTooManyTimes:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "验证次数太多，请重新发送验证码",
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		u.log.Error("校验找回密码验证码出错", accesslog.Error(err))
+	}
+}
+
+// ResetPassword 找回密码，使用重置凭证设置新密码
+// 成功之后该用户所有的登录态都会失效
+func (u *UserHandler) ResetPassword(ctx *gin.Context) {
+	type Req struct {
+		Token           string `json:"token"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	if req.Password != req.ConfirmPassword {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "两次输入的密码不相同",
+		})
+		return
+	}
+	isPassword, err := u.passwordRegexExp.MatchString(req.Password)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !isPassword {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "密码必须包含数字、特殊字符，并且长度不能小于 8 位",
+		})
+		return
+	}
+
+	uid, err := u.resetSvc.Reset(ctx.Request.Context(), req.Token, req.Password)
+	if err == service.ErrResetTokenInvalid {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "重置凭证无效或者已经过期，请重新找回密码",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	err = u.RevokeSessions(ctx.Request.Context(), uid, "")
+	if err != nil {
+		// 密码已经改了，这里只记录日志
+		u.log.Error("重置密码之后注销登录态失败", accesslog.Int64("uid", uid), accesslog.Error(err))
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "密码重置成功，请重新登录",
+	})
+}
+
+// ChangePassword 登录之后修改密码
+// 成功之后除了当前登录态，其它登录态都会失效
+func (u *UserHandler) ChangePassword(ctx *gin.Context) {
+	type Req struct {
+		OldPassword     string `json:"oldPassword"`
+		Password        string `json:"password"`
+		ConfirmPassword string `json:"confirmPassword"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	if req.Password != req.ConfirmPassword {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "两次输入的密码不相同",
+		})
+		return
+	}
+	isPassword, err := u.passwordRegexExp.MatchString(req.Password)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if !isPassword {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "密码必须包含数字、特殊字符，并且长度不能小于 8 位",
+		})
+		return
+	}
+
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err = u.userSvc.ChangePassword(ctx.Request.Context(), uc.Uid, req.OldPassword, req.Password)
+	if err == service.ErrInvalidUserOrPassword {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidOrPassword,
+			Msg:  "旧密码不对",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	err = u.RevokeSessions(ctx.Request.Context(), uc.Uid, uc.Ssid)
+	if err != nil {
+		u.log.Error("修改密码之后注销其它登录态失败", accesslog.Int64("uid", uc.Uid), accesslog.Error(err))
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "修改密码成功",
+	})
+}
+
+// checkResetTarget 校验找回密码的邮箱或者手机号格式
+func (u *UserHandler) checkResetTarget(typ string, target string) (bool, error) {
+	switch typ {
+	case service.ResetByEmail:
+		return u.emailRegexExp.MatchString(target)
+	case service.ResetByPhone:
+		return u.phoneRegexExp.MatchString(target)
+	default:
+		return false, nil
+	}
+}
 
 // LoginJWT 登录 得到jwt token
 func (u *UserHandler) LoginJWT(ctx *gin.Context) {
@@ -155,14 +506,42 @@ func (u *UserHandler) LoginJWT(ctx *gin.Context) {
 		return
 	}
 
+	ip := ctx.ClientIP()
+	wait, err := u.limitSvc.Allow(ctx.Request.Context(), req.Email, ip)
+	if err != nil {
+		u.loginLimited(ctx, wait, err)
+		return
+	}
+
 	user, err := u.userSvc.Login(ctx, req.Email, req.Password)
 	if err == service.ErrInvalidUserOrPassword {
+		wait, err = u.limitSvc.Fail(ctx.Request.Context(), req.Email, ip)
+		if err == service.ErrLoginLocked {
+			u.loginLimited(ctx, wait, err)
+			return
+		}
+		if err != nil && err != service.ErrLoginTooFrequent {
+			u.log.Error("记录登录失败次数出错", accesslog.Error(err))
+		}
 		ctx.JSONP(http.StatusOK, Result{
 			Code: errs.UserInvalidOrPassword,
 			Msg:  "用户名或密码不对",
 		})
 		return
 	}
+	if err == service.ErrUserFrozen {
+		u.loginSucceed(ctx, req.Email)
+		u.frozen(ctx)
+		return
+	}
+	if err == service.ErrEmailNotVerified {
+		u.loginSucceed(ctx, req.Email)
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserEmailNotVerified,
+			Msg:  "邮箱还没有验证，请先验证邮箱",
+		})
+		return
+	}
 	if err != nil {
 		ctx.JSONP(http.StatusOK, Result{
 			Code: 4,
@@ -171,7 +550,25 @@ func (u *UserHandler) LoginJWT(ctx *gin.Context) {
 		return
 	}
 
-	err = u.SetLoginToken(ctx, user.Id)
+	// 开启了两步验证，先不设置 token，第二步通过之后才清掉失败次数
+	if twoFactorRequired(ctx, u.twoFactorSvc, domain.TwoFactorChallenge{
+		Uid:     user.Id,
+		Method:  myjwt.LoginMethodPassword,
+		Account: req.Email,
+	}) {
+		return
+	}
+	u.loginSucceed(ctx, req.Email)
+	// 完成登录之后才取消注销，只知道密码不能取消别人的注销
+	if err = u.deletionSvc.Cancel(ctx.Request.Context(), user.Id); err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "系统异常",
+		})
+		return
+	}
+
+	err = u.SetLoginToken(ctx, user.Id, myjwt.LoginMethodPassword)
 	if err != nil {
 		ctx.JSONP(http.StatusOK, Result{
 			Code: 4,
@@ -185,14 +582,146 @@ func (u *UserHandler) LoginJWT(ctx *gin.Context) {
 	return
 }
 
+// twoFactorRequired 开启了两步验证的时候返回挑战，由 /users/login/2fa 完成登录
+// 返回 true 代表已经写了响应，调用方不能再设置 token
+func twoFactorRequired(ctx *gin.Context, svc service.TwoFactorService, c domain.TwoFactorChallenge) bool {
+	enabled, err := svc.Enabled(ctx.Request.Context(), c.Uid)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "系统异常",
+		})
+		return true
+	}
+	if !enabled {
+		return false
+	}
+	challenge, err := svc.Challenge(ctx.Request.Context(), c)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 4,
+			Msg:  "系统异常",
+		})
+		return true
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Code: errs.UserSecondFactorRequired,
+		Msg:  "请输入两步验证码",
+		Data: challenge,
+	})
+	return true
+}
+
+// loginSucceed 密码是对的，清掉失败次数
+func (u *UserHandler) loginSucceed(ctx *gin.Context, email string) {
+	if err := u.limitSvc.Succeed(ctx.Request.Context(), email); err != nil {
+		u.log.Warn("清除登录失败次数出错", accesslog.Error(err))
+	}
+}
+
+// frozen 账号被冻结，不允许登录
+func (u *UserHandler) frozen(ctx *gin.Context) {
+	ctx.JSONP(http.StatusOK, Result{
+		Code: errs.UserFrozen,
+		Msg:  "账号已经被冻结",
+	})
+}
+
+// loginLimited 密码登录被限制，返回剩余的等待秒数
+func (u *UserHandler) loginLimited(ctx *gin.Context, wait time.Duration, err error) {
+	seconds := int64(math.Ceil(wait.Seconds()))
+	switch err {
+	case service.ErrLoginLocked:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserLoginLocked,
+			Msg:  "密码错误次数太多，账号已经被临时锁定",
+			Data: seconds,
+		})
+	case service.ErrLoginTooFrequent:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserLoginTooFrequent,
+			Msg:  "登录太频繁，请稍后再试",
+			Data: seconds,
+		})
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// LoginTwoFactor 两步验证，校验通过之后才设置 token
+func (u *UserHandler) LoginTwoFactor(ctx *gin.Context) {
+	type Req struct {
+		// Challenge LoginJWT 返回的凭证
+		Challenge string `json:"challenge"`
+		// Code 验证器 App 上的验证码，或者恢复码
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+
+	c, wait, err := u.twoFactorSvc.VerifyChallenge(ctx.Request.Context(), req.Challenge, req.Code, ctx.ClientIP())
+	switch err {
+	case nil:
+	case service.ErrLoginLocked, service.ErrLoginTooFrequent:
+		u.loginLimited(ctx, wait, err)
+		return
+	case service.ErrTwoFactorCodeInvalid:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "验证码错误",
+		})
+		return
+	case service.ErrTwoFactorChallengeInvalid, service.ErrTwoFactorNotEnabled:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "两步验证已经过期，请重新登录",
+		})
+		return
+	default:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if err = u.deletionSvc.Cancel(ctx.Request.Context(), c.Uid); err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	// 按第一步的登录方式记录，邮箱免密登录的也会走到这里
+	// 升级之前创建的挑战没有登录方式，按密码登录记录
+	method := c.Method
+	if method == "" {
+		method = myjwt.LoginMethodPassword
+	}
+	err = u.SetLoginToken(ctx, c.Uid, method)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "登录成功",
+	})
+}
+
 // RefreshToken 刷新token
 func (u *UserHandler) RefreshToken(ctx *gin.Context) {
 	// 要求前端 请求刷新token 接口时候，一样通过 Authorization 传值
 	refreshToken := u.Handler.ExtractToken(ctx)
 	var claims myjwt.RefreshClaims
-	token, err := jwt.ParseWithClaims(refreshToken, &claims, func(token *jwt.Token) (interface{}, error) {
-		return myjwt.RefreshTokenKey, nil
-	})
+	token, err := u.Handler.ParseToken(refreshToken, myjwt.TypeRefreshToken, &claims)
 	if err != nil || !token.Valid {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
@@ -205,8 +734,29 @@ func (u *UserHandler) RefreshToken(ctx *gin.Context) {
 		return
 	}
 
-	// 重新生成token
-	err = u.Handler.SetJWTToken(ctx, claims.Uid, claims.Ssid)
+	// 冻结的时候会撤销登录，这里再查一次，防止冻结之后撤销之前的窗口期
+	user, err := u.userSvc.Profile(ctx, claims.Uid)
+	if err == nil && user.Frozen() {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, Result{
+			Code: errs.UserFrozen,
+			Msg:  "账号已经被冻结",
+		})
+		return
+	}
+	if err != nil {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	// 重新生成 token，refresh token 也一起轮换
+	err = u.Handler.RefreshLoginToken(ctx, claims)
+	if err == myjwt.ErrRefreshTokenReused {
+		u.log.Warn("安全事件：refresh token 重复使用，已撤销整个登录",
+			accesslog.Int64("uid", claims.Uid),
+			accesslog.String("ssid", claims.Ssid),
+			accesslog.String("ip", ctx.ClientIP()),
+			accesslog.String("user_agent", ctx.Request.UserAgent()))
+	}
 	if err != nil {
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
@@ -231,6 +781,96 @@ func (u *UserHandler) Logout(ctx *gin.Context) {
 	})
 }
 
+// Sessions 查询自己的登录设备
+func (u *UserHandler) Sessions(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	sessions, err := u.ListSessions(ctx.Request.Context(), uc.Uid)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	type Session struct {
+		Ssid      string `json:"ssid"`
+		UserAgent string `json:"userAgent"`
+		IP        string `json:"ip"`
+		Method    string `json:"method"`
+		Ctime     string `json:"ctime"`
+		Utime     string `json:"utime"`
+		// Current 是否为当前登录的设备
+		Current bool `json:"current"`
+	}
+	res := make([]Session, 0, len(sessions))
+	for _, sess := range sessions {
+		res = append(res, Session{
+			Ssid:      sess.Ssid,
+			UserAgent: sess.UserAgent,
+			IP:        sess.IP,
+			Method:    sess.Method,
+			Ctime:     sess.Ctime.Format(time.DateTime),
+			Utime:     sess.Utime.Format(time.DateTime),
+			Current:   sess.Ssid == uc.Ssid,
+		})
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: res,
+	})
+}
+
+// RevokeSession 踢掉一个登录设备
+func (u *UserHandler) RevokeSession(ctx *gin.Context) {
+	type Req struct {
+		Ssid string `json:"ssid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := u.Handler.RevokeSession(ctx.Request.Context(), uc.Uid, req.Ssid)
+	if err == myjwt.ErrSessionNotFound {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "登录设备不存在",
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if req.Ssid == uc.Ssid {
+		ctx.Header("x-jwt-token", "")
+		ctx.Header("x-refresh-token", "")
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "操作成功",
+	})
+}
+
+// RevokeAllSessions 踢掉所有的登录设备，包括当前设备
+func (u *UserHandler) RevokeAllSessions(ctx *gin.Context) {
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := u.RevokeSessions(ctx.Request.Context(), uc.Uid, "")
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.Header("x-jwt-token", "")
+	ctx.Header("x-refresh-token", "")
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "操作成功",
+	})
+}
+
 // Edit 编辑
 func (u *UserHandler) Edit(ctx *gin.Context) {
 	type EditReq struct {
@@ -311,6 +951,7 @@ func (u *UserHandler) Profile(ctx *gin.Context) {
 		Nickname string
 		Birthday string
 		AboutMe  string
+		Avatar   string
 	}
 
 	ctx.JSONP(http.StatusOK, Result{Data: rep{
@@ -319,6 +960,7 @@ func (u *UserHandler) Profile(ctx *gin.Context) {
 		Nickname: user.Nickname,
 		Birthday: user.Birthday.Format(time.DateOnly),
 		AboutMe:  user.AboutMe,
+		Avatar:   user.Avatar,
 	}})
 }
 
@@ -438,6 +1080,10 @@ func (u *UserHandler) LoginSMS(ctx *gin.Context) {
 
 	// 查找或新创建用户
 	user, err := u.userSvc.FindOrCreate(ctx, req.Phone)
+	if err == service.ErrUserFrozen {
+		u.frozen(ctx)
+		return
+	}
 	if err != nil {
 		ctx.JSONP(http.StatusOK, Result{
 			Code: 5,
@@ -447,7 +1093,7 @@ func (u *UserHandler) LoginSMS(ctx *gin.Context) {
 	}
 
 	// 设置token
-	if err = u.SetLoginToken(ctx, user.Id); err != nil {
+	if err = u.SetLoginToken(ctx, user.Id, myjwt.LoginMethodSMS); err != nil {
 		ctx.JSON(http.StatusOK, Result{
 			Code: 5,
 			Msg:  "系统错误",
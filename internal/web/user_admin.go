@@ -0,0 +1,341 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// UserAdminHandler 管理后台的用户管理接口
+// 查询需要 domain.PermissionUserRead，冻结、解冻和强制下线需要 domain.PermissionUserManage，在 ioc 里面配置中间件校验
+type UserAdminHandler struct {
+	svc   service.UserAdminService
+	wtHdl myjwt.Handler
+	log   accesslog.Logger
+}
+
+func NewUserAdminHandler(svc service.UserAdminService, wtHdl myjwt.Handler, log accesslog.Logger) *UserAdminHandler {
+	return &UserAdminHandler{
+		svc:   svc,
+		wtHdl: wtHdl,
+		log:   log,
+	}
+}
+
+func (h *UserAdminHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/admin/users")
+	g.POST("/search", h.Search)
+	g.GET("/detail", h.Detail)
+	g.POST("/freeze", h.Freeze)
+	g.POST("/unfreeze", h.Unfreeze)
+	g.POST("/logout", h.Logout)
+}
+
+// userAdminVo 不返回密码，只返回是否设置了密码
+type userAdminVo struct {
+	Id           int64  `json:"id"`
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Nickname     string `json:"nickname"`
+	Avatar       string `json:"avatar"`
+	AboutMe      string `json:"aboutMe"`
+	Birthday     string `json:"birthday"`
+	WechatOpenId string `json:"wechatOpenId"`
+	HasPassword  bool   `json:"hasPassword"`
+	Verified     bool   `json:"verified"`
+	Frozen       bool   `json:"frozen"`
+	FrozenAt     string `json:"frozenAt"`
+	FrozenReason string `json:"frozenReason"`
+	Ctime        string `json:"ctime"`
+}
+
+// Search 按邮箱、手机号、微信 openid、id 范围和注册时间查询，时间格式为 2006-01-02 15:04:05
+func (h *UserAdminHandler) Search(ctx *gin.Context) {
+	type Req struct {
+		Email        string `json:"email"`
+		Phone        string `json:"phone"`
+		WechatOpenId string `json:"wechatOpenId"`
+		MinId        int64  `json:"minId"`
+		MaxId        int64  `json:"maxId"`
+		CtimeStart   string `json:"ctimeStart"`
+		CtimeEnd     string `json:"ctimeEnd"`
+		// Cursor 上一页返回的 cursor，第一页不传
+		Cursor int64 `json:"cursor"`
+		Limit  int   `json:"limit"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	start, err1 := h.parseTime(req.CtimeStart)
+	end, err2 := h.parseTime(req.CtimeEnd)
+	if err1 != nil || err2 != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "时间格式不对",
+		})
+		return
+	}
+	users, next, err := h.svc.Search(ctx.Request.Context(), domain.UserQuery{
+		Email:        req.Email,
+		Phone:        req.Phone,
+		WechatOpenId: req.WechatOpenId,
+		MinId:        req.MinId,
+		MaxId:        req.MaxId,
+		CtimeStart:   start,
+		CtimeEnd:     end,
+		Cursor:       req.Cursor,
+		Limit:        req.Limit,
+	})
+	switch err {
+	case nil:
+	case service.ErrUserQueryInvalid:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "查询条件不合法",
+		})
+		return
+	default:
+		h.log.Error("查询用户失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	type Resp struct {
+		Users []userAdminVo `json:"users"`
+		// Cursor 下一页的游标，为 0 代表没有下一页
+		Cursor int64 `json:"cursor"`
+	}
+	vos := make([]userAdminVo, 0, len(users))
+	for _, u := range users {
+		vos = append(vos, h.toVo(u))
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: Resp{Users: vos, Cursor: next},
+	})
+}
+
+// Detail 用户的完整信息、绑定的第三方账号和登录设备，GET /admin/users/detail?uid=123
+func (h *UserAdminHandler) Detail(ctx *gin.Context) {
+	uid, err := strconv.ParseInt(ctx.Query("uid"), 10, 64)
+	if err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "参数错误",
+		})
+		return
+	}
+	u, identities, err := h.svc.Detail(ctx.Request.Context(), uid)
+	if err == service.ErrUserNotFound {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "用户不存在",
+		})
+		return
+	}
+	if err != nil {
+		h.log.Error("查询用户详情失败", accesslog.Error(err), accesslog.Int64("uid", uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	sessions, err := h.wtHdl.ListSessions(ctx.Request.Context(), u.Id)
+	if err != nil {
+		h.log.Error("查询用户登录设备失败", accesslog.Error(err), accesslog.Int64("uid", uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+
+	type Identity struct {
+		Provider string `json:"provider"`
+		Subject  string `json:"subject"`
+		UnionId  string `json:"unionId"`
+		Nickname string `json:"nickname"`
+		Email    string `json:"email"`
+		Ctime    string `json:"ctime"`
+	}
+	type Session struct {
+		Ssid      string `json:"ssid"`
+		UserAgent string `json:"userAgent"`
+		IP        string `json:"ip"`
+		Method    string `json:"method"`
+		Ctime     string `json:"ctime"`
+		Utime     string `json:"utime"`
+	}
+	type Resp struct {
+		User       userAdminVo `json:"user"`
+		Identities []Identity  `json:"identities"`
+		Sessions   []Session   `json:"sessions"`
+	}
+	resp := Resp{
+		User:       h.toVo(u),
+		Identities: make([]Identity, 0, len(identities)),
+		Sessions:   make([]Session, 0, len(sessions)),
+	}
+	for _, i := range identities {
+		resp.Identities = append(resp.Identities, Identity{
+			Provider: i.Provider,
+			Subject:  i.Subject,
+			UnionId:  i.UnionId,
+			Nickname: i.Nickname,
+			Email:    i.Email,
+			Ctime:    i.Ctime.Format(time.DateTime),
+		})
+	}
+	for _, sess := range sessions {
+		resp.Sessions = append(resp.Sessions, Session{
+			Ssid:      sess.Ssid,
+			UserAgent: sess.UserAgent,
+			IP:        sess.IP,
+			Method:    sess.Method,
+			Ctime:     sess.Ctime.Format(time.DateTime),
+			Utime:     sess.Utime.Format(time.DateTime),
+		})
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Data: resp,
+	})
+}
+
+// Freeze 冻结用户，同时踢掉所有登录设备
+func (h *UserAdminHandler) Freeze(ctx *gin.Context) {
+	type Req struct {
+		Uid    int64  `json:"uid"`
+		Reason string `json:"reason"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := h.svc.Freeze(ctx.Request.Context(), req.Uid, req.Reason)
+	if err == nil {
+		err = h.wtHdl.RevokeSessions(ctx.Request.Context(), req.Uid, "")
+	}
+	switch err {
+	case nil:
+		h.log.Info("冻结用户", accesslog.Int64("operator", uc.Uid), accesslog.Int64("uid", req.Uid),
+			accesslog.String("reason", req.Reason))
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "冻结成功",
+		})
+	case service.ErrUserQueryInvalid:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "冻结原因太长",
+		})
+	case service.ErrUserNotFound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "用户不存在",
+		})
+	default:
+		h.log.Error("冻结用户失败", accesslog.Error(err), accesslog.Int64("operator", uc.Uid),
+			accesslog.Int64("uid", req.Uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+func (h *UserAdminHandler) Unfreeze(ctx *gin.Context) {
+	type Req struct {
+		Uid int64 `json:"uid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	err := h.svc.Unfreeze(ctx.Request.Context(), req.Uid)
+	switch err {
+	case nil:
+		h.log.Info("解冻用户", accesslog.Int64("operator", uc.Uid), accesslog.Int64("uid", req.Uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Msg: "解冻成功",
+		})
+	case service.ErrUserNotFound:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "用户不存在",
+		})
+	default:
+		h.log.Error("解冻用户失败", accesslog.Error(err), accesslog.Int64("operator", uc.Uid),
+			accesslog.Int64("uid", req.Uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
+
+// Logout 强制下线，让用户所有的登录设备失效
+func (h *UserAdminHandler) Logout(ctx *gin.Context) {
+	type Req struct {
+		Uid int64 `json:"uid"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	if err := h.wtHdl.RevokeSessions(ctx.Request.Context(), req.Uid, ""); err != nil {
+		h.log.Error("强制下线失败", accesslog.Error(err), accesslog.Int64("operator", uc.Uid),
+			accesslog.Int64("uid", req.Uid))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	h.log.Info("强制下线", accesslog.Int64("operator", uc.Uid), accesslog.Int64("uid", req.Uid))
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "下线成功",
+	})
+}
+
+// parseTime 空字符串代表不限制
+func (h *UserAdminHandler) parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(time.DateTime, s, time.Local)
+}
+
+func (h *UserAdminHandler) toVo(u domain.User) userAdminVo {
+	vo := userAdminVo{
+		Id:           u.Id,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		Nickname:     u.Nickname,
+		Avatar:       u.Avatar,
+		AboutMe:      u.AboutMe,
+		WechatOpenId: u.WechatInfo.OpenId,
+		HasPassword:  u.Password != "",
+		Verified:     !u.VerifiedAt.IsZero(),
+		Frozen:       u.Frozen(),
+		FrozenReason: u.FrozenReason,
+		Ctime:        u.Ctime.Format(time.DateTime),
+	}
+	if !u.Birthday.IsZero() {
+		vo.Birthday = u.Birthday.Format(time.DateOnly)
+	}
+	if u.Frozen() {
+		vo.FrozenAt = u.FrozenAt.Format(time.DateTime)
+	}
+	return vo
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/errs"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// WechatMiniHandler 微信小程序登录，token 和其它登录方式一样放在响应头里面
+type WechatMiniHandler struct {
+	svc service.WechatMiniService
+	myjwt.Handler
+	log accesslog.Logger
+}
+
+func NewWechatMiniHandler(svc service.WechatMiniService, wtHdl myjwt.Handler, log accesslog.Logger) *WechatMiniHandler {
+	return &WechatMiniHandler{
+		svc:     svc,
+		Handler: wtHdl,
+		log:     log,
+	}
+}
+
+func (h *WechatMiniHandler) RegisterRoutes(server *gin.Engine) {
+	g := server.Group("/wechat/mini")
+	g.POST("/login", h.Login)
+	// 绑定手机号需要登录态
+	g.POST("/phone", h.BindPhone)
+}
+
+// Login 小程序 wx.login 拿到 code 之后调用
+func (h *WechatMiniHandler) Login(ctx *gin.Context) {
+	type Req struct {
+		Code string `json:"code"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	if req.Code == "" {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "code 不能为空",
+		})
+		return
+	}
+	u, err := h.svc.Login(ctx.Request.Context(), req.Code)
+	if err == service.ErrUserFrozen {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserFrozen,
+			Msg:  "账号已经被冻结",
+		})
+		return
+	}
+	if err != nil {
+		h.log.Warn("小程序登录失败", accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	if err = h.SetLoginToken(ctx, u.Id, domain.ProviderWechatMini); err != nil {
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+		return
+	}
+	ctx.JSONP(http.StatusOK, Result{
+		Msg: "登录成功",
+	})
+}
+
+// BindPhone 小程序 getPhoneNumber 拿到的加密数据，解密之后绑定到当前用户
+func (h *WechatMiniHandler) BindPhone(ctx *gin.Context) {
+	type Req struct {
+		EncryptedData string `json:"encrypted_data"`
+		Iv            string `json:"iv"`
+	}
+	var req Req
+	if err := ctx.Bind(&req); err != nil {
+		return
+	}
+	uc := ctx.MustGet("user").(myjwt.UserClaims)
+	phone, err := h.svc.BindPhone(ctx.Request.Context(), uc.Uid, req.EncryptedData, req.Iv)
+	switch err {
+	case nil:
+		ctx.JSONP(http.StatusOK, Result{
+			Msg:  "绑定成功",
+			Data: phone,
+		})
+	case service.ErrWechatMiniSessionExpired:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "小程序登录已经过期，请重新登录",
+		})
+	case service.ErrWechatMiniDecrypt:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserInvalidInput,
+			Msg:  "手机号数据无效，请重试",
+		})
+	case service.ErrBindConflict:
+		ctx.JSONP(http.StatusOK, Result{
+			Code: errs.UserBindConflict,
+			Msg:  "该手机号已经绑定了其它用户",
+		})
+	default:
+		h.log.Error("小程序绑定手机号失败", accesslog.Int64("uid", uc.Uid), accesslog.Error(err))
+		ctx.JSONP(http.StatusOK, Result{
+			Code: 5,
+			Msg:  "系统错误",
+		})
+	}
+}
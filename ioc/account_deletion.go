@@ -0,0 +1,54 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/events"
+	"github.com/dadaxiaoxiao/user/internal/job"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	rlock "github.com/gotomicro/redis-lock"
+	"github.com/spf13/viper"
+	"time"
+)
+
+// InitAccountDeletionService 初始化注销账号，冷静期从配置文件读取
+// 导出的压缩包和 jwt 里面的登录记录也是个人数据，注销的时候一起删除
+func InitAccountDeletionService(repo repository.UserRepository, sessionRepo repository.WechatMiniSessionRepository,
+	producer events.Producer, exportSvc service.DataExportService, wtHdl myjwt.Handler,
+	log accesslog.Logger) service.AccountDeletionService {
+	type Config struct {
+		CoolingOff time.Duration `yaml:"coolingOff"`
+	}
+	// 默认值，配置文件里面有的会覆盖
+	cfg := Config{
+		CoolingOff: time.Hour * 24 * 15,
+	}
+	err := viper.UnmarshalKey("accountDeletion", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	erasers := []service.AccountEraser{
+		exportSvc.Erase,
+		wtHdl.DeleteLoginHistory,
+	}
+	return service.NewAccountDeletionService(repo, sessionRepo, producer, erasers, cfg.CoolingOff, log)
+}
+
+// InitAccountDeletionJob 初始化删除注销用户的定时任务
+func InitAccountDeletionJob(svc service.AccountDeletionService, client *rlock.Client,
+	log accesslog.Logger) *job.AccountDeletionJob {
+	type Config struct {
+		Interval time.Duration `yaml:"interval"`
+		Batch    int           `yaml:"batch"`
+	}
+	cfg := Config{
+		Interval: time.Minute * 10,
+		Batch:    100,
+	}
+	err := viper.UnmarshalKey("accountDeletion.job", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return job.NewAccountDeletionJob(svc, client, log, cfg.Interval, cfg.Batch)
+}
@@ -0,0 +1,158 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/job"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/dadaxiaoxiao/user/pkg/blobstore"
+	"github.com/dadaxiaoxiao/user/pkg/blobstore/local"
+	"github.com/dadaxiaoxiao/user/pkg/registry"
+	"github.com/spf13/viper"
+	"os"
+	"time"
+)
+
+// blobStoreConfig 本地磁盘的配置
+type blobStoreConfig struct {
+	Dir string `yaml:"dir"`
+	// Shared 目录是所有实例共享挂载的（比如 NFS），为 false 的时候只有一个实例在运行才执行导出
+	Shared bool `yaml:"shared"`
+}
+
+// InitBlobStore 初始化文件存储，目前只支持本地磁盘
+func InitBlobStore() blobstore.Store {
+	cfg := initBlobStoreConfig()
+	return local.NewStore(cfg.Dir)
+}
+
+func initBlobStoreConfig() blobStoreConfig {
+	cfg := blobStoreConfig{
+		Dir: "./data/blobs",
+	}
+	err := viper.UnmarshalKey("blobStore.local", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// InitDataExportService 初始化个人数据导出，下载链接签名的密钥从环境变量读取
+// 登录设备和登录记录在 jwt 里面，在这里转换成导出的格式
+func InitDataExportService(repo repository.DataExportRepository, userRepo repository.UserRepository,
+	identityRepo repository.IdentityRepository, store blobstore.Store, wtHdl myjwt.Handler,
+	log accesslog.Logger) service.DataExportService {
+	type Config struct {
+		Retention   time.Duration `yaml:"retention"`
+		LinkTTL     time.Duration `yaml:"linkTTL"`
+		DownloadURL string        `yaml:"downloadURL"`
+	}
+	cfg := Config{
+		Retention: time.Hour * 24 * 7,
+		LinkTTL:   time.Hour,
+	}
+	err := viper.UnmarshalKey("dataExport", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.DownloadURL == "" {
+		panic("dataExport.downloadURL 不能为空")
+	}
+	// 下载链接只靠签名认证
+	key := signKeyFromEnv("DATA_EXPORT_SIGN_KEY")
+	sessions := func(list func(ctx context.Context, uid int64) ([]myjwt.Session, error)) service.DataExportSource {
+		type Session struct {
+			UserAgent string `json:"userAgent"`
+			IP        string `json:"ip"`
+			Method    string `json:"method"`
+			Ctime     string `json:"ctime"`
+			Utime     string `json:"utime"`
+		}
+		return func(ctx context.Context, uid int64) (any, error) {
+			ss, err := list(ctx, uid)
+			if err != nil {
+				return nil, err
+			}
+			res := make([]Session, 0, len(ss))
+			for _, s := range ss {
+				res = append(res, Session{
+					UserAgent: s.UserAgent,
+					IP:        s.IP,
+					Method:    s.Method,
+					Ctime:     s.Ctime.Format(time.DateTime),
+					Utime:     s.Utime.Format(time.DateTime),
+				})
+			}
+			return res, nil
+		}
+	}
+	sources := map[string]service.DataExportSource{
+		"sessions.json":      sessions(wtHdl.ListSessions),
+		"login_history.json": sessions(wtHdl.LoginHistory),
+	}
+	return service.NewDataExportService(repo, userRepo, identityRepo, store, sources,
+		cfg.Retention, cfg.LinkTTL, key, cfg.DownloadURL, log)
+}
+
+// InitDataExportJob 初始化执行导出队列的任务
+// 本地磁盘不是共享的时候，别的实例读不到压缩包，etcd 里面有其它实例就暂停执行
+func InitDataExportJob(svc service.DataExportService, reg registry.Registry, instances []registry.ServiceInstance,
+	log accesslog.Logger) *job.DataExportJob {
+	type Config struct {
+		PollInterval    time.Duration `yaml:"pollInterval"`
+		CleanupInterval time.Duration `yaml:"cleanupInterval"`
+	}
+	cfg := Config{
+		PollInterval:    time.Second * 5,
+		CleanupInterval: time.Hour,
+	}
+	err := viper.UnmarshalKey("dataExport.job", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	var allowed func(ctx context.Context) (bool, error)
+	if !initBlobStoreConfig().Shared {
+		allowed = onlyInstance(reg, instances)
+	}
+	return job.NewDataExportJob(svc, log, cfg.PollInterval, cfg.CleanupInterval, allowed)
+}
+
+// onlyInstance etcd 里面没有当前实例以外的 HTTP 实例，启动的时候当前实例可能还没有注册
+func onlyInstance(reg registry.Registry, instances []registry.ServiceInstance) func(ctx context.Context) (bool, error) {
+	var self registry.ServiceInstance
+	for _, si := range instances {
+		if si.Protocol == registry.ProtocolHTTP {
+			self = si
+		}
+	}
+	return func(ctx context.Context) (bool, error) {
+		sis, err := reg.ListServices(ctx, self.Name, registry.ProtocolHTTP)
+		if err != nil {
+			return false, err
+		}
+		for _, si := range sis {
+			if si.Addr != self.Addr {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// minSignKeyLen HMAC 签名密钥的最小长度，太短的密钥可以被猜出来，签名就能伪造
+const minSignKeyLen = 32
+
+// signKeyFromEnv 从环境变量读取 HMAC 签名密钥，没有配置或者太短直接启动失败
+func signKeyFromEnv(name string) []byte {
+	key, ok := os.LookupEnv(name)
+	if !ok {
+		panic("获取系统环境变量 " + name + " 失败 ")
+	}
+	if len(key) < minSignKeyLen {
+		panic(fmt.Sprintf("环境变量 %s 至少 %d 字节", name, minSignKeyLen))
+	}
+	return []byte(key)
+}
@@ -0,0 +1,41 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/service/email"
+	"github.com/dadaxiaoxiao/user/internal/service/email/memory"
+	"github.com/dadaxiaoxiao/user/internal/service/email/smtp"
+	"github.com/spf13/viper"
+	"os"
+)
+
+// InitEmailService 初始化邮件服务
+func InitEmailService(l accesslog.Logger) email.Service {
+	type Config struct {
+		// UseMemory 本地开发的时候打开，使用本地邮件服务，邮件内容只记在 debug 日志里面
+		UseMemory bool   `yaml:"useMemory"`
+		Host      string `yaml:"host"`
+		Port      int    `yaml:"port"`
+		Username  string `yaml:"username"`
+		From      string `yaml:"from"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("email", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.UseMemory {
+		l.Warn("使用本地邮件服务，邮件不会真的发出去")
+		return memory.NewService(memory.WithLogger(l))
+	}
+	// 没有配置 smtp 直接启动失败，不能悄悄地换成本地邮件服务
+	if cfg.Host == "" {
+		panic("没有配置 email.host")
+	}
+	// 密码和短信的密钥一样，从环境变量读取
+	password, ok := os.LookupEnv("EMAIL_PASSWORD")
+	if !ok {
+		panic("获取系统环境变量 EMAIL_PASSWORD 失败 ")
+	}
+	return smtp.NewService(cfg.Host, cfg.Port, cfg.Username, password, cfg.From)
+}
@@ -1,8 +1,11 @@
 package ioc
 
 import (
+	"github.com/dadaxiaoxiao/user/pkg/registry"
+	"github.com/dadaxiaoxiao/user/pkg/registry/etcd"
 	"github.com/spf13/viper"
 	etcdv3 "go.etcd.io/etcd/client/v3"
+	"net"
 )
 
 func InitEtcd() *etcdv3.Client {
@@ -17,3 +20,75 @@ func InitEtcd() *etcdv3.Client {
 	}
 	return cli
 }
+
+// InitRegistry 初始化服务注册
+func InitRegistry(client *etcdv3.Client) registry.Registry {
+	type Config struct {
+		// 租约过期时间，单位秒
+		TTL int64 `yaml:"ttl"`
+	}
+	var cfg = Config{
+		TTL: 10,
+	}
+	err := viper.UnmarshalKey("registry", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return etcd.NewRegistry(client, etcd.WithTTL(cfg.TTL))
+}
+
+// InitServiceInstances 本进程需要注册的 HTTP 和 gRPC 实例
+func InitServiceInstances() []registry.ServiceInstance {
+	type Config struct {
+		Name string `yaml:"name"`
+		// 注册到 etcd 的 IP，为空的时候使用本机 IP
+		Host string `yaml:"host"`
+	}
+	var cfg = Config{
+		Name: "user",
+	}
+	err := viper.UnmarshalKey("registry", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	host := cfg.Host
+	if host == "" {
+		host = localIP()
+	}
+	return []registry.ServiceInstance{
+		{
+			Name:     cfg.Name,
+			Protocol: registry.ProtocolHTTP,
+			Addr:     advertiseAddr(host, viper.GetString("http.addr")),
+		},
+		{
+			Name:     cfg.Name,
+			Protocol: registry.ProtocolGRPC,
+			Addr:     advertiseAddr(host, viper.GetString("grpc.server.addr")),
+		},
+	}
+}
+
+// advertiseAddr 监听地址一般是 :8089 这种，要换成其它服务可以访问的地址
+func advertiseAddr(host string, listenAddr string) string {
+	_, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		panic(err)
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// localIP 本机第一个非回环的 IPv4 地址
+func localIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		panic(err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
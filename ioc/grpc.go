@@ -0,0 +1,44 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/grpcx"
+	userv1 "github.com/dadaxiaoxiao/user/api/proto/gen/user/v1"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	igrpc "github.com/dadaxiaoxiao/user/internal/grpc"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+)
+
+// InitGRPCxServer 初始化 gRPC 服务
+func InitGRPCxServer(userServer *igrpc.UserServiceServer, oidcClientServer *igrpc.OIDCClientServiceServer,
+	tokenServer *igrpc.TokenServiceServer, wtHdl myjwt.Handler, rbacSvc service.RBACService) *grpcx.Server {
+	type Config struct {
+		Addr string `yaml:"addr"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("grpc.server", &cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(authInterceptor(wtHdl, rbacSvc)))
+	// 注册服务
+	userServer.Register(server)
+	oidcClientServer.Register(server)
+	tokenServer.Register(server)
+	return &grpcx.Server{
+		Server: server,
+		Addr:   cfg.Addr,
+	}
+}
+
+// authInterceptor 管理类的 RPC 需要管理员的 access token
+func authInterceptor(wtHdl myjwt.Handler, rbacSvc service.RBACService) grpc.UnaryServerInterceptor {
+	return igrpc.NewAuthInterceptorBuilder(wtHdl, rbacSvc).
+		Require(userv1.UserService_UnlockLogin_FullMethodName, domain.PermissionUserManage).
+		Require(userv1.UserService_MergeUsers_FullMethodName, domain.PermissionUserManage).
+		Require(userv1.OIDCClientService_RegisterOIDCClient_FullMethodName, domain.PermissionOIDCManage).
+		Build()
+}
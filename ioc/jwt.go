@@ -0,0 +1,89 @@
+package ioc
+
+import (
+	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
+	"github.com/spf13/viper"
+	"os"
+	"time"
+)
+
+// InitJWTKeySet 初始化签名 token 的密钥
+// 轮换密钥：先把新密钥加到 keys 里面，再把 signingKid 改成新密钥，
+// 旧密钥设置 expireAt（不早于最后一个旧 token 过期的时间），过期之后从配置里面删掉
+func InitJWTKeySet() *myjwt.KeySet {
+	type KeyConfig struct {
+		Kid string `yaml:"kid"`
+		// File PEM 文件，私钥或者公钥
+		File string `yaml:"file"`
+		// ExpireAt RFC3339 格式，为空代表不过期
+		ExpireAt string `yaml:"expireAt"`
+	}
+	type Config struct {
+		SigningKid string      `yaml:"signingKid"`
+		Keys       []KeyConfig `yaml:"keys"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("jwt", &cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	if len(cfg.Keys) == 0 {
+		// 不能临时生成密钥，否则重启之后、多个实例之间的 token 互相不认
+		panic("没有配置 jwt.keys")
+	}
+
+	keys := make([]myjwt.Key, 0, len(cfg.Keys))
+	for _, kc := range cfg.Keys {
+		data, err := os.ReadFile(kc.File)
+		if err != nil {
+			panic(err)
+		}
+		key, err := myjwt.ParseKey(kc.Kid, data)
+		if err != nil {
+			panic(err)
+		}
+		if kc.ExpireAt != "" {
+			key.ExpireAt, err = time.Parse(time.RFC3339, kc.ExpireAt)
+			if err != nil {
+				panic(err)
+			}
+		}
+		keys = append(keys, key)
+	}
+	ks, err := myjwt.NewKeySet(cfg.SigningKid, keys...)
+	if err != nil {
+		panic(err)
+	}
+	return ks
+}
+
+// InitJWTSessionConfig 登录态的有效期，没有配置的时候滑动 7 天、最长 30 天
+// 旧的 refresh token 默认还能用 30 秒
+func InitJWTSessionConfig() myjwt.SessionConfig {
+	type Config struct {
+		Sliding    time.Duration `yaml:"sliding"`
+		Absolute   time.Duration `yaml:"absolute"`
+		ReuseGrace time.Duration `yaml:"reuseGrace"`
+	}
+	cfg := Config{
+		Sliding:    time.Hour * 24 * 7,
+		Absolute:   time.Hour * 24 * 30,
+		ReuseGrace: time.Second * 30,
+	}
+	err := viper.UnmarshalKey("jwt.session", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Sliding <= 0 || cfg.Absolute < cfg.Sliding {
+		panic("jwt.session.sliding 必须大于 0，并且不能超过 jwt.session.absolute")
+	}
+	if cfg.ReuseGrace < 0 || cfg.ReuseGrace > time.Minute {
+		panic("jwt.session.reuseGrace 不能小于 0，也不能超过 1 分钟")
+	}
+	return myjwt.SessionConfig{
+		Sliding:    cfg.Sliding,
+		Absolute:   cfg.Absolute,
+		ReuseGrace: cfg.ReuseGrace,
+	}
+}
@@ -0,0 +1,58 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	"github.com/spf13/viper"
+	"time"
+)
+
+// InitLoginLimitService 初始化密码登录失败的限制
+func InitLoginLimitService(repo repository.LoginLimitRepository) service.LoginLimitService {
+	type Rule struct {
+		FreeAttempts  int           `yaml:"freeAttempts"`
+		BaseDelay     time.Duration `yaml:"baseDelay"`
+		MaxDelay      time.Duration `yaml:"maxDelay"`
+		Window        time.Duration `yaml:"window"`
+		LockThreshold int           `yaml:"lockThreshold"`
+		LockDuration  time.Duration `yaml:"lockDuration"`
+	}
+	type Config struct {
+		Account Rule `yaml:"account"`
+		IP      Rule `yaml:"ip"`
+	}
+	// 默认值，配置文件里面有的会覆盖
+	cfg := Config{
+		Account: Rule{
+			FreeAttempts:  3,
+			BaseDelay:     time.Second,
+			MaxDelay:      time.Minute,
+			Window:        time.Minute * 15,
+			LockThreshold: 10,
+			LockDuration:  time.Minute * 30,
+		},
+		IP: Rule{
+			// 同一个出口 IP 后面可能有很多用户，放宽一些，也不锁定
+			FreeAttempts: 20,
+			BaseDelay:    time.Second,
+			MaxDelay:     time.Minute * 5,
+			Window:       time.Hour,
+		},
+	}
+	err := viper.UnmarshalKey("loginLimit", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	toRule := func(r Rule) domain.LoginLimitRule {
+		return domain.LoginLimitRule{
+			FreeAttempts:  r.FreeAttempts,
+			BaseDelay:     r.BaseDelay,
+			MaxDelay:      r.MaxDelay,
+			Window:        r.Window,
+			LockThreshold: r.LockThreshold,
+			LockDuration:  r.LockDuration,
+		}
+	}
+	return service.NewLoginLimitService(repo, toRule(cfg.Account), toRule(cfg.IP))
+}
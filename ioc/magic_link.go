@@ -0,0 +1,25 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	"github.com/dadaxiaoxiao/user/internal/service/email"
+	"github.com/spf13/viper"
+)
+
+// InitMagicLinkService 初始化邮箱免密登录，签名的密钥从环境变量读取
+func InitMagicLinkService(repo repository.CodeRepository, emailSvc email.Service, userSvc service.UserService) service.MagicLinkService {
+	type Config struct {
+		LoginURL string `yaml:"loginURL"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("magicLink", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.LoginURL == "" {
+		panic("magicLink.loginURL 不能为空")
+	}
+	key := signKeyFromEnv("MAGIC_LINK_SIGN_KEY")
+	return service.NewMagicLinkService(repo, emailSvc, userSvc, key, cfg.LoginURL)
+}
@@ -0,0 +1,49 @@
+package ioc
+
+import (
+	"fmt"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/events"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	"github.com/spf13/viper"
+)
+
+// InitMergeService 初始化合并用户，资料字段的合并规则从配置文件读取，没有配置的字段保留 target 的值
+func InitMergeService(repo repository.UserRepository, producer events.Producer) service.MergeService {
+	type Config struct {
+		Nickname string `yaml:"nickname"`
+		Birthday string `yaml:"birthday"`
+		AboutMe  string `yaml:"aboutMe"`
+		Avatar   string `yaml:"avatar"`
+	}
+	keep := string(domain.MergeKeepTarget)
+	cfg := Config{
+		Nickname: keep,
+		Birthday: keep,
+		AboutMe:  keep,
+		Avatar:   keep,
+	}
+	err := viper.UnmarshalKey("merge", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return service.NewMergeService(repo, producer, domain.MergeRules{
+		Nickname: mergePolicy("nickname", cfg.Nickname),
+		Birthday: mergePolicy("birthday", cfg.Birthday),
+		AboutMe:  mergePolicy("aboutMe", cfg.AboutMe),
+		Avatar:   mergePolicy("avatar", cfg.Avatar),
+	})
+}
+
+// mergePolicy 写错了不能悄悄地当成保留 target，直接启动失败
+func mergePolicy(field string, val string) domain.MergePolicy {
+	policy := domain.MergePolicy(val)
+	switch policy {
+	case domain.MergeKeepTarget, domain.MergePreferSource:
+		return policy
+	default:
+		panic(fmt.Sprintf("merge.%s 只能是 %s 或者 %s，配置的是 %q", field,
+			domain.MergeKeepTarget, domain.MergePreferSource, val))
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/dadaxiaoxiao/go-pkg/ginx/middlerwares/metric"
 	midratelimit "github.com/dadaxiaoxiao/go-pkg/ginx/middlerwares/ratelimitx"
 	"github.com/dadaxiaoxiao/go-pkg/ratelimit"
+	"github.com/dadaxiaoxiao/user/internal/domain"
+	"github.com/dadaxiaoxiao/user/internal/service"
 	myjwt "github.com/dadaxiaoxiao/user/internal/web/jwt"
 	"github.com/dadaxiaoxiao/user/internal/web/middleware"
 	"github.com/gin-contrib/cors"
@@ -21,11 +23,14 @@ import (
 )
 
 // InitGinMiddlewares 初始化中间件
-func InitGinMiddlewares(redisClient redis.Cmdable, wtHdl myjwt.Handler, log accesslog.Logger) []gin.HandlerFunc {
+func InitGinMiddlewares(redisClient redis.Cmdable, wtHdl myjwt.Handler, patSvc service.PersonalAccessTokenService,
+	rbacSvc service.RBACService, log accesslog.Logger) []gin.HandlerFunc {
 	initCodeCounter()
 	return []gin.HandlerFunc{
 		corsMiddleware(),
-		jwtTokenMiddleware(wtHdl),
+		jwtTokenMiddleware(wtHdl, patSvc),
+		sessionOnlyMiddleware(),
+		rbacMiddleware(rbacSvc),
 		rateLimitMiddleware(redisClient),
 		loggerMiddleware(log),
 		metricMiddleware(),
@@ -58,19 +63,80 @@ func rateLimitMiddleware(redisClient redis.Cmdable) gin.HandlerFunc {
 }
 
 // jwtTokenMiddleware JWT token 中间件
-func jwtTokenMiddleware(wtHdl myjwt.Handler) gin.HandlerFunc {
+func jwtTokenMiddleware(wtHdl myjwt.Handler, patSvc service.PersonalAccessTokenService) gin.HandlerFunc {
 	return middleware.NewLoginJWTMiddlewareBuilder(wtHdl).
+		PersonalAccessTokens(patSvc).
 		IgnorePaths("/users/signup").
 		IgnorePaths("/users/login").
+		IgnorePaths("/users/login/2fa").
 		IgnorePaths("/users/login_sms/code/send").
 		IgnorePaths("/users/login_sms").
+		IgnorePaths("/users/login_email/send").
+		IgnorePaths("/users/login_email").
 		IgnorePaths("/oauth2/wechat/authurl").
 		IgnorePaths("/oauth2/wechat/callback").
+		IgnorePaths("/oauth2/wechat/ticket").
+		IgnorePaths("/oauth2/github/authurl").
+		IgnorePaths("/oauth2/github/callback").
+		IgnorePaths("/oauth2/github/ticket").
+		IgnorePaths("/oauth2/dingtalk/authurl").
+		IgnorePaths("/oauth2/dingtalk/callback").
+		IgnorePaths("/oauth2/dingtalk/ticket").
+		IgnorePaths("/wechat/mini/login").
+		IgnorePaths("/users/qr_login/ticket").
+		IgnorePaths("/users/qr_login/poll").
 		IgnorePaths("/users/refresh_token").
+		IgnorePaths("/users/email/verify/send").
+		IgnorePaths("/users/email/verify").
+		IgnorePaths("/users/password/reset/send").
+		IgnorePaths("/users/password/reset/verify").
+		IgnorePaths("/users/password/reset").
+		IgnorePaths("/.well-known/jwks.json").
+		IgnorePaths("/.well-known/openid-configuration").
+		IgnorePaths("/oidc/authorize").
+		IgnorePaths("/oidc/token").
+		IgnorePaths("/oidc/userinfo").
+		IgnorePaths("/oidc/introspect").
+		IgnorePaths("/users/exports/download").
 		IgnorePaths("/test/metric").
 		Build()
 }
 
+// sessionOnlyMiddleware 创建登录态、修改登录凭证、依赖 ssid 和导出全部个人数据的接口不能用个人访问令牌调用
+// 新增这类接口的时候要加到这里
+func sessionOnlyMiddleware() gin.HandlerFunc {
+	return middleware.NewSessionOnlyMiddlewareBuilder().
+		Path("/users/qr_login").
+		Path("/users/bind").
+		Path("/users/unbind").
+		Path("/oauth2/wechat/bind_authurl").
+		Path("/oauth2/github/bind_authurl").
+		Path("/oauth2/dingtalk/bind_authurl").
+		Path("/wechat/mini/phone").
+		Path("/oidc/authorize/confirm").
+		Path("/users/password").
+		Path("/users/2fa").
+		Path("/users/sessions").
+		Path("/users/logout").
+		Path("/users/tokens/create").
+		Path("/users/tokens/revoke").
+		Path("/users/deletion/request").
+		Path("/users/exports/create").
+		Path("/users/exports/status").
+		Build()
+}
+
+// rbacMiddleware 管理接口的权限校验
+func rbacMiddleware(svc service.RBACService) gin.HandlerFunc {
+	return middleware.NewRBACMiddlewareBuilder(svc).
+		Require("/admin/rbac", domain.PermissionRBACManage).
+		Require("/admin/users", domain.PermissionUserRead).
+		Require("/admin/users/freeze", domain.PermissionUserManage).
+		Require("/admin/users/unfreeze", domain.PermissionUserManage).
+		Require("/admin/users/logout", domain.PermissionUserManage).
+		Build()
+}
+
 // loggerMiddleware 初始化log 中间件
 func loggerMiddleware(log accesslog.Logger) gin.HandlerFunc {
 	ml := midlogger.NewBuilder(func(ctx context.Context, al *midlogger.AccessLog) {
@@ -0,0 +1,66 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2/dingtalk"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2/github"
+	"github.com/dadaxiaoxiao/user/internal/service/oauth2/wechat"
+	"github.com/dadaxiaoxiao/user/internal/web"
+	"github.com/spf13/viper"
+	"os"
+	"strings"
+)
+
+// InitOAuth2Registry 初始化第三方登录
+// 微信一直启用，GitHub 和钉钉配置了 clientId 才启用，secret 从环境变量读取
+func InitOAuth2Registry(wechatSvc wechat.Service, wechatTokenRepo repository.WechatTokenRepository,
+	log accesslog.Logger) *oauth2.Registry {
+	type ProviderConfig struct {
+		ClientId    string `yaml:"clientId"`
+		RedirectURI string `yaml:"redirectURI"`
+	}
+	type Config struct {
+		Github   ProviderConfig `yaml:"github"`
+		DingTalk ProviderConfig `yaml:"dingtalk"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("oauth2", &cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	providers := []oauth2.Provider{wechat.NewProvider(wechatSvc, wechatTokenRepo, log)}
+	if cfg.Github.ClientId != "" {
+		secret, ok := os.LookupEnv("GITHUB_CLIENT_SECRET")
+		if !ok {
+			panic("获取系统环境变量 GITHUB_CLIENT_SECRET 失败 ")
+		}
+		providers = append(providers, github.NewProvider(cfg.Github.ClientId, secret, cfg.Github.RedirectURI))
+	}
+	if cfg.DingTalk.ClientId != "" {
+		secret, ok := os.LookupEnv("DINGTALK_CLIENT_SECRET")
+		if !ok {
+			panic("获取系统环境变量 DINGTALK_CLIENT_SECRET 失败 ")
+		}
+		providers = append(providers, dingtalk.NewProvider(cfg.DingTalk.ClientId, secret, cfg.DingTalk.RedirectURI))
+	}
+	return oauth2.NewRegistry(providers...)
+}
+
+// InitOAuth2Config 第三方登录成功之后允许跳转的前端地址
+func InitOAuth2Config() web.OAuth2Config {
+	var whitelist []string
+	err := viper.UnmarshalKey("oauth2.redirectWhitelist", &whitelist)
+	if err != nil {
+		panic(err)
+	}
+	for i, origin := range whitelist {
+		// 白名单只比较 scheme + host
+		whitelist[i] = strings.TrimSuffix(origin, "/")
+	}
+	return web.OAuth2Config{
+		RedirectWhitelist: whitelist,
+	}
+}
@@ -0,0 +1,28 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/user/internal/web"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// InitOIDCConfig 初始化 OIDC 提供方的配置
+func InitOIDCConfig() web.OIDCConfig {
+	type Config struct {
+		Issuer   string `yaml:"issuer"`
+		LoginURL string `yaml:"loginURL"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("oidc", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	if cfg.Issuer == "" || cfg.LoginURL == "" {
+		panic("oidc.issuer 和 oidc.loginURL 不能为空")
+	}
+	return web.OIDCConfig{
+		// iss 必须和 discovery 里面的完全一致，去掉末尾的 /
+		Issuer:   strings.TrimSuffix(cfg.Issuer, "/"),
+		LoginURL: cfg.LoginURL,
+	}
+}
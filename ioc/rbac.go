@@ -0,0 +1,20 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	"github.com/spf13/viper"
+)
+
+// InitRBACService 初始化权限控制，rbac.superAdmins 里面的用户拥有全部权限
+func InitRBACService(repo repository.RBACRepository) service.RBACService {
+	type Config struct {
+		SuperAdmins []int64 `yaml:"superAdmins"`
+	}
+	var cfg Config
+	err := viper.UnmarshalKey("rbac", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return service.NewRBACService(repo, cfg.SuperAdmins)
+}
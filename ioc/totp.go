@@ -0,0 +1,40 @@
+package ioc
+
+import (
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/service"
+	"github.com/dadaxiaoxiao/user/pkg/cryptox"
+	"github.com/spf13/viper"
+	"os"
+)
+
+// InitTOTPEncrypter 加密两步验证的密钥
+// 密钥从环境变量读取，长度必须是 16、24 或者 32 字节，换了之后已经绑定的用户都要重新绑定
+func InitTOTPEncrypter() cryptox.Encrypter {
+	key, ok := os.LookupEnv("TOTP_ENCRYPT_KEY")
+	if !ok {
+		panic("获取系统环境变量 TOTP_ENCRYPT_KEY 失败 ")
+	}
+	enc, err := cryptox.NewAESGCM([]byte(key))
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// InitTwoFactorService 初始化两步验证
+func InitTwoFactorService(userRepo repository.UserRepository, repo repository.TOTPRepository,
+	limitSvc service.LoginLimitService) service.TwoFactorService {
+	type Config struct {
+		// 验证器 App 上显示的名字
+		Issuer string `yaml:"issuer"`
+	}
+	cfg := Config{
+		Issuer: "用户中心",
+	}
+	err := viper.UnmarshalKey("totp", &cfg)
+	if err != nil {
+		panic(err)
+	}
+	return service.NewTwoFactorService(userRepo, repo, limitSvc, cfg.Issuer)
+}
@@ -10,7 +10,20 @@ import (
 // InitWebServer 初始化 web 服务
 func InitWebServer(mdls []gin.HandlerFunc,
 	userHdl *web.UserHandler,
-	oauth2WechatHdl *web.OAuth2WechatHandler) *ginx.Server {
+	twoFactorHdl *web.TwoFactorHandler,
+	jwksHdl *web.JWKSHandler,
+	oidcHdl *web.OIDCHandler,
+	introspectionHdl *web.IntrospectionHandler,
+	oauth2Hdl *web.OAuth2Handler,
+	bindingHdl *web.BindingHandler,
+	wechatMiniHdl *web.WechatMiniHandler,
+	qrLoginHdl *web.QRLoginHandler,
+	magicLinkHdl *web.MagicLinkHandler,
+	patHdl *web.PersonalAccessTokenHandler,
+	rbacHdl *web.RBACHandler,
+	userAdminHdl *web.UserAdminHandler,
+	accountDeletionHdl *web.AccountDeletionHandler,
+	dataExportHdl *web.DataExportHandler) *ginx.Server {
 
 	type Config struct {
 		Addr string `yaml:"addr"`
@@ -26,7 +39,20 @@ func InitWebServer(mdls []gin.HandlerFunc,
 	server.Use(mdls...)
 	// 注册路由
 	userHdl.RegisterRoutes(server)
-	oauth2WechatHdl.RegisterRoutes(server)
+	twoFactorHdl.RegisterRoutes(server)
+	jwksHdl.RegisterRoutes(server)
+	oidcHdl.RegisterRoutes(server)
+	introspectionHdl.RegisterRoutes(server)
+	oauth2Hdl.RegisterRoutes(server)
+	bindingHdl.RegisterRoutes(server)
+	wechatMiniHdl.RegisterRoutes(server)
+	qrLoginHdl.RegisterRoutes(server)
+	magicLinkHdl.RegisterRoutes(server)
+	patHdl.RegisterRoutes(server)
+	rbacHdl.RegisterRoutes(server)
+	userAdminHdl.RegisterRoutes(server)
+	accountDeletionHdl.RegisterRoutes(server)
+	dataExportHdl.RegisterRoutes(server)
 	return &ginx.Server{
 		Engine: server,
 		Addr:   cfg.Addr,
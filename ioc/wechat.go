@@ -1,8 +1,11 @@
 package ioc
 
 import (
+	"github.com/dadaxiaoxiao/user/internal/repository"
+	"github.com/dadaxiaoxiao/user/internal/repository/cache"
+	"github.com/dadaxiaoxiao/user/internal/repository/dao"
 	"github.com/dadaxiaoxiao/user/internal/service/oauth2/wechat"
-	"github.com/dadaxiaoxiao/user/internal/web"
+	"github.com/dadaxiaoxiao/user/pkg/cryptox"
 	"os"
 )
 
@@ -18,8 +21,37 @@ func InitWechatService() wechat.Service {
 	return wechat.Newservice(appId, appSecret)
 }
 
-func InitWechatHandlerConfig() web.WechatHandlerConfig {
-	return web.WechatHandlerConfig{
-		Secure: false,
+// InitWechatTokenRepository 微信的 token 加密保存，密钥和两步验证的分开
+// 密钥从环境变量读取，长度必须是 16、24 或者 32 字节
+func InitWechatTokenRepository(d dao.WechatTokenDAO) repository.WechatTokenRepository {
+	return repository.NewEncryptedWechatTokenRepository(d, initWechatEncrypter())
+}
+
+func InitWechatMiniService() wechat.MiniService {
+	appId, ok := os.LookupEnv("WECHAT_MINI_APP_ID")
+	if !ok {
+		panic("获取系统环境变量 WECHAT_MINI_APP_ID 失败 ")
+	}
+	appSecret, ok := os.LookupEnv("WECHAT_MINI_APP_SECRET")
+	if !ok {
+		panic("获取系统环境变量 WECHAT_MINI_APP_SECRET 失败 ")
+	}
+	return wechat.NewMiniService(appId, appSecret)
+}
+
+// InitWechatMiniSessionRepository 小程序的 session_key 和微信的 token 用同一个密钥加密
+func InitWechatMiniSessionRepository(c cache.WechatMiniSessionCache) repository.WechatMiniSessionRepository {
+	return repository.NewEncryptedWechatMiniSessionRepository(c, initWechatEncrypter())
+}
+
+func initWechatEncrypter() cryptox.Encrypter {
+	key, ok := os.LookupEnv("WECHAT_TOKEN_ENCRYPT_KEY")
+	if !ok {
+		panic("获取系统环境变量 WECHAT_TOKEN_ENCRYPT_KEY 失败 ")
+	}
+	enc, err := cryptox.NewAESGCM([]byte(key))
+	if err != nil {
+		panic(err)
 	}
+	return enc
 }
@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
+	"github.com/dadaxiaoxiao/go-pkg/accesslog"
 	"github.com/dadaxiaoxiao/user/ioc"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -16,16 +21,80 @@ func main() {
 	closeFunc := ioc.InitOTEL()
 
 	app := InitApp()
-	server := app.GinServer
-	server.Start()
+	// gRPC 服务和 gin 服务一起启动
+	go func() {
+		err := app.GRPCServer.Serve()
+		if err != nil {
+			panic(err)
+		}
+	}()
+	// 不用 GinServer.Start，自己持有 http.Server 才能优雅退出
+	webServer := &http.Server{
+		Addr:    app.GinServer.Addr,
+		Handler: app.GinServer.Engine,
+	}
+	go func() {
+		err := webServer.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+
+	// 服务启动之后再注册到 etcd
+	register(app)
+
+	jobCtx, jobCancel := context.WithCancel(context.Background())
+	go app.DeletionJob.Start(jobCtx)
+	go app.ExportJob.Start(jobCtx)
+
+	// 等待退出信号
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
 
 	// 下面这些是正常退出
+	jobCancel()
 	// 一分钟内要关完，且退出
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
+	// 先从 etcd 摘掉，客户端就不会再把新请求发过来
+	unregister(ctx, app)
+	// 不再接收新请求，等待处理中的请求结束
+	if err := webServer.Shutdown(ctx); err != nil {
+		app.Logger.Error("关闭 HTTP 服务失败", accesslog.Error(err))
+	}
+	app.GRPCServer.GracefulStop()
 	closeFunc(ctx)
 }
 
+// register 注册本进程的全部实例
+func register(app *App) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	for _, si := range app.Instances {
+		err := app.Registry.Register(ctx, si)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// unregister 注销本进程的全部实例
+func unregister(ctx context.Context, app *App) {
+	for _, si := range app.Instances {
+		err := app.Registry.UnRegister(ctx, si)
+		if err != nil {
+			app.Logger.Error("注销服务实例失败", accesslog.String("protocol", si.Protocol),
+				accesslog.String("addr", si.Addr), accesslog.Error(err))
+		}
+	}
+	// 撤销租约
+	err := app.Registry.Close()
+	if err != nil {
+		app.Logger.Error("关闭服务注册失败", accesslog.Error(err))
+	}
+}
+
 func initViper() {
 	cfile := pflag.String("config", "config/config.yaml", "配置文件路径")
 	pflag.Parse()
@@ -0,0 +1,37 @@
+package local
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/pkg/blobstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(t.TempDir())
+
+	require.NoError(t, s.Put(ctx, "exports/1/a.zip", strings.NewReader("hello")))
+	// 覆盖
+	require.NoError(t, s.Put(ctx, "exports/1/a.zip", strings.NewReader("world")))
+	r, err := s.Get(ctx, "exports/1/a.zip")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "world", string(data))
+
+	require.NoError(t, s.Delete(ctx, "exports/1/a.zip"))
+	_, err = s.Get(ctx, "exports/1/a.zip")
+	assert.Equal(t, blobstore.ErrNotFound, err)
+	// 删除不存在的文件
+	assert.NoError(t, s.Delete(ctx, "exports/1/a.zip"))
+
+	// 不能跳出目录
+	for _, key := range []string{"", "../a.zip", "/etc/passwd", "exports/../../a.zip"} {
+		assert.Equal(t, ErrInvalidKey, s.Put(ctx, key, strings.NewReader("x")), key)
+	}
+}
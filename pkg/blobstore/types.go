@@ -0,0 +1,20 @@
+// Package blobstore 保存文件，比如导出的个人数据，可以换成本地磁盘或者对象存储
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("文件不存在")
+
+// Store 按 key 保存文件，key 用 / 分隔，例如 exports/1/xxx.zip
+type Store interface {
+	// Put 写入文件，已经存在的会被覆盖
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get 读取文件，不存在返回 ErrNotFound，调用方负责关闭
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，不存在的时候不返回错误
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,60 @@
+// Package cryptox 加密需要落库的敏感数据
+package cryptox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+var ErrInvalidCiphertext = errors.New("密文格式不对")
+
+// Encrypter 对称加密
+type Encrypter interface {
+	// Encrypt 加密，返回 base64 编码的密文，方便存到 varchar 字段
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
+
+// AESGCM AES-GCM 加密，密文格式为 nonce + 密文
+type AESGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM key 的长度必须是 16、24 或者 32 字节
+func NewAESGCM(key []byte) (*AESGCM, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCM{
+		aead: aead,
+	}, nil
+}
+
+func (a *AESGCM) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	res := a.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(res), nil
+}
+
+func (a *AESGCM) Decrypt(ciphertext string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	size := a.aead.NonceSize()
+	if len(data) < size {
+		return nil, ErrInvalidCiphertext
+	}
+	return a.aead.Open(nil, data[:size], data[size:], nil)
+}
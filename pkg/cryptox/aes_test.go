@@ -0,0 +1,29 @@
+package cryptox
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAESGCM(t *testing.T) {
+	enc, err := NewAESGCM([]byte("95osj3fUD7fo0mlYdDbncXz4VD2igvf0"))
+	require.NoError(t, err)
+
+	ciphertext, err := enc.Encrypt([]byte("JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+	assert.NotContains(t, ciphertext, "JBSWY3DPEHPK3PXP")
+
+	plaintext, err := enc.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", string(plaintext))
+
+	// 换一个 key 解不出来
+	other, err := NewAESGCM([]byte("00000000000000000000000000000000"))
+	require.NoError(t, err)
+	_, err = other.Decrypt(ciphertext)
+	assert.Error(t, err)
+
+	_, err = enc.Decrypt("YWJj")
+	assert.Equal(t, ErrInvalidCiphertext, err)
+}
@@ -0,0 +1,231 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"github.com/dadaxiaoxiao/user/pkg/registry"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/naming/endpoints"
+	"sync"
+	"time"
+)
+
+var _ registry.Registry = &Registry{}
+
+// Registry 基于 etcd 的服务注册
+// 所有实例挂在同一个租约上，进程存活期间自动续约，
+// 进程异常退出的时候，租约过期，实例会被 etcd 自动删除
+type Registry struct {
+	client *etcdv3.Client
+	// 租约的过期时间，单位秒
+	ttl int64
+	// 续约失败之后，重新注册的间隔
+	retryInterval time.Duration
+
+	mutex     sync.Mutex
+	leaseId   etcdv3.LeaseID
+	kaCancel  context.CancelFunc
+	instances map[string]registry.ServiceInstance
+}
+
+type Option func(r *Registry)
+
+// WithTTL 租约过期时间，单位秒
+func WithTTL(ttl int64) Option {
+	return func(r *Registry) {
+		r.ttl = ttl
+	}
+}
+
+// WithRetryInterval 续约失败之后的重试间隔
+func WithRetryInterval(interval time.Duration) Option {
+	return func(r *Registry) {
+		r.retryInterval = interval
+	}
+}
+
+func NewRegistry(client *etcdv3.Client, opts ...Option) *Registry {
+	r := &Registry{
+		client:        client,
+		ttl:           10,
+		retryInterval: time.Second,
+		instances:     make(map[string]registry.ServiceInstance),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register 注册实例
+func (r *Registry) Register(ctx context.Context, si registry.ServiceInstance) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.kaCancel == nil {
+		err := r.grant(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err := r.addEndpoint(ctx, si)
+	if err != nil {
+		return err
+	}
+	r.instances[instanceKey(si)] = si
+	return nil
+}
+
+// UnRegister 注销实例
+func (r *Registry) UnRegister(ctx context.Context, si registry.ServiceInstance) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	em, err := endpoints.NewManager(r.client, serviceKey(si.Name, si.Protocol))
+	if err != nil {
+		return err
+	}
+	delete(r.instances, instanceKey(si))
+	return em.DeleteEndpoint(ctx, instanceKey(si))
+}
+
+// ListServices 查询存活的实例
+func (r *Registry) ListServices(ctx context.Context, name string, protocol string) ([]registry.ServiceInstance, error) {
+	em, err := endpoints.NewManager(r.client, serviceKey(name, protocol))
+	if err != nil {
+		return nil, err
+	}
+	eps, err := em.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]registry.ServiceInstance, 0, len(eps))
+	for _, ep := range eps {
+		si := registry.ServiceInstance{
+			Name:     name,
+			Protocol: protocol,
+			Addr:     ep.Addr,
+		}
+		// endpoints 用 JSON 存储，反序列化出来是 map[string]any
+		if md, ok := ep.Metadata.(map[string]any); ok {
+			si.Metadata = md
+		}
+		res = append(res, si)
+	}
+	return res, nil
+}
+
+// Close 停止续约，并且撤销租约，挂在租约上的实例会一起被删除
+func (r *Registry) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.kaCancel != nil {
+		r.kaCancel()
+		r.kaCancel = nil
+	}
+	r.instances = make(map[string]registry.ServiceInstance)
+	if r.leaseId == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	_, err := r.client.Revoke(ctx, r.leaseId)
+	r.leaseId = 0
+	return err
+}
+
+// grant 第一次注册的时候申请租约并且开始续约，调用方需要持有锁
+func (r *Registry) grant(ctx context.Context) error {
+	// 续约不能用调用方的 ctx，调用方返回之后 ctx 就失效了
+	kaCtx, cancel := context.WithCancel(context.Background())
+	ch, err := r.newLease(ctx, kaCtx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	r.kaCancel = cancel
+	go r.keepAlive(kaCtx, ch)
+	return nil
+}
+
+// newLease 申请租约并且续约，调用方需要持有锁
+func (r *Registry) newLease(ctx context.Context, kaCtx context.Context) (<-chan *etcdv3.LeaseKeepAliveResponse, error) {
+	lease, err := r.client.Grant(ctx, r.ttl)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := r.client.KeepAlive(kaCtx, lease.ID)
+	if err != nil {
+		return nil, err
+	}
+	r.leaseId = lease.ID
+	return ch, nil
+}
+
+// keepAlive 消费续约的响应，直到 Close
+// channel 被关闭并且不是主动关闭，说明租约已经丢了（例如 etcd 长时间不可用），
+// 这时候重新申请租约，把实例重新注册上去
+func (r *Registry) keepAlive(ctx context.Context, ch <-chan *etcdv3.LeaseKeepAliveResponse) {
+	for {
+		for range ch {
+		}
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+			newCh, err := r.reRegister(ctx)
+			if err == nil {
+				ch = newCh
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(r.retryInterval):
+			}
+		}
+	}
+}
+
+func (r *Registry) reRegister(kaCtx context.Context) (<-chan *etcdv3.LeaseKeepAliveResponse, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if kaCtx.Err() != nil {
+		// 在等待锁的过程中被关闭了
+		return nil, kaCtx.Err()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	ch, err := r.newLease(ctx, kaCtx)
+	if err != nil {
+		return nil, err
+	}
+	for _, si := range r.instances {
+		err = r.addEndpoint(ctx, si)
+		if err != nil {
+			// 撤销这次的租约，下一次重试重新申请
+			_, _ = r.client.Revoke(ctx, r.leaseId)
+			return nil, err
+		}
+	}
+	return ch, nil
+}
+
+func (r *Registry) addEndpoint(ctx context.Context, si registry.ServiceInstance) error {
+	em, err := endpoints.NewManager(r.client, serviceKey(si.Name, si.Protocol))
+	if err != nil {
+		return err
+	}
+	return em.AddEndpoint(ctx, instanceKey(si), endpoints.Endpoint{
+		Addr:     si.Addr,
+		Metadata: si.Metadata,
+	}, etcdv3.WithLease(r.leaseId))
+}
+
+// serviceKey 服务的 key 前缀，例如 service/user/grpc
+func serviceKey(name, protocol string) string {
+	return fmt.Sprintf("service/%s/%s", name, protocol)
+}
+
+// instanceKey 实例的 key，例如 service/user/grpc/192.168.1.2:8090
+func instanceKey(si registry.ServiceInstance) string {
+	return fmt.Sprintf("%s/%s", serviceKey(si.Name, si.Protocol), si.Addr)
+}
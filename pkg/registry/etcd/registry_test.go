@@ -0,0 +1,137 @@
+//go:build e2e
+
+// 依赖本地的 etcd，地址通过环境变量 ETCD_ENDPOINT 指定，默认 127.0.0.1:2379
+// docker run -d -p 2379:2379 -e ALLOW_NONE_AUTHENTICATION=yes bitnami/etcd:3.5
+// go test -tags=e2e ./pkg/registry/...
+package etcd
+
+import (
+	"context"
+	"github.com/dadaxiaoxiao/user/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+type RegistryTestSuite struct {
+	suite.Suite
+	client *etcdv3.Client
+}
+
+func (s *RegistryTestSuite) SetupSuite() {
+	endpoint := os.Getenv("ETCD_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "127.0.0.1:2379"
+	}
+	client, err := etcdv3.New(etcdv3.Config{
+		Endpoints:   []string{endpoint},
+		DialTimeout: time.Second * 3,
+	})
+	require.NoError(s.T(), err)
+	s.client = client
+
+	// 确认 etcd 可用，同时清掉上一次测试留下的实例
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	_, err = client.Delete(ctx, "service/user/", etcdv3.WithPrefix())
+	require.NoError(s.T(), err)
+	_, err = client.Delete(ctx, "service/resolver-test/", etcdv3.WithPrefix())
+	require.NoError(s.T(), err)
+}
+
+func (s *RegistryTestSuite) TearDownSuite() {
+	_ = s.client.Close()
+}
+
+func (s *RegistryTestSuite) TestRegisterAndUnRegister() {
+	t := s.T()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	r := NewRegistry(s.client, WithTTL(5))
+	grpcIns := registry.ServiceInstance{
+		Name:     "user",
+		Protocol: registry.ProtocolGRPC,
+		Addr:     "127.0.0.1:8090",
+	}
+	httpIns := registry.ServiceInstance{
+		Name:     "user",
+		Protocol: registry.ProtocolHTTP,
+		Addr:     "127.0.0.1:8089",
+		Metadata: map[string]any{"weight": "100"},
+	}
+	require.NoError(t, r.Register(ctx, grpcIns))
+	require.NoError(t, r.Register(ctx, httpIns))
+
+	// HTTP 和 gRPC 互相不影响
+	res, err := r.ListServices(ctx, "user", registry.ProtocolHTTP)
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{httpIns}, res)
+	res, err = r.ListServices(ctx, "user", registry.ProtocolGRPC)
+	require.NoError(t, err)
+	assert.Equal(t, []registry.ServiceInstance{grpcIns}, res)
+
+	// 超过 TTL 之后，因为有续约，实例还在
+	time.Sleep(time.Second * 7)
+	res, err = r.ListServices(ctx, "user", registry.ProtocolGRPC)
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	require.NoError(t, r.UnRegister(ctx, grpcIns))
+	res, err = r.ListServices(ctx, "user", registry.ProtocolGRPC)
+	require.NoError(t, err)
+	assert.Len(t, res, 0)
+
+	// 关闭之后，剩下的实例也会被删除
+	require.NoError(t, r.Close())
+	res, err = r.ListServices(ctx, "user", registry.ProtocolHTTP)
+	require.NoError(t, err)
+	assert.Len(t, res, 0)
+}
+
+func (s *RegistryTestSuite) TestResolver() {
+	t := s.T()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	r := NewRegistry(s.client)
+	defer r.Close()
+	require.NoError(t, r.Register(ctx, registry.ServiceInstance{
+		Name:     "resolver-test",
+		Protocol: registry.ProtocolGRPC,
+		Addr:     lis.Addr().String(),
+	}))
+
+	bd, err := NewResolverBuilder(s.client)
+	require.NoError(t, err)
+	cc, err := grpc.Dial(Target("resolver-test"),
+		grpc.WithResolvers(bd),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer cc.Close()
+
+	resp, err := grpc_health_v1.NewHealthClient(cc).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func TestRegistry(t *testing.T) {
+	suite.Run(t, new(RegistryTestSuite))
+}
@@ -0,0 +1,24 @@
+package etcd
+
+import (
+	"github.com/dadaxiaoxiao/user/pkg/registry"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+	etcdresolver "go.etcd.io/etcd/client/v3/naming/resolver"
+	"google.golang.org/grpc/resolver"
+)
+
+// NewResolverBuilder gRPC 客户端的服务发现
+// 用法：
+//
+//	bd, err := etcd.NewResolverBuilder(client)
+//	cc, err := grpc.Dial(etcd.Target("user"), grpc.WithResolvers(bd), ...)
+//
+// 只会拿到还在续约的实例，实例下线或者租约过期之后会自动从连接池里面摘掉
+func NewResolverBuilder(client *etcdv3.Client) (resolver.Builder, error) {
+	return etcdresolver.NewBuilder(client)
+}
+
+// Target 服务在 etcd 里面的 gRPC 地址，例如 etcd:///service/user/grpc
+func Target(name string) string {
+	return "etcd:///" + serviceKey(name, registry.ProtocolGRPC)
+}
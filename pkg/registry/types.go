@@ -0,0 +1,34 @@
+package registry
+
+import (
+	"context"
+	"io"
+)
+
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// ServiceInstance 一个服务实例，同一个进程的 HTTP 和 gRPC 各算一个实例
+type ServiceInstance struct {
+	// Name 服务名，例如 user
+	Name string
+	// Protocol grpc 或者 http
+	Protocol string
+	// Addr 其它服务可以访问的地址，ip:port
+	Addr string
+	// Metadata 元数据，例如权重、分组，可以用于负载均衡
+	Metadata map[string]any
+}
+
+// Registry 服务注册与发现
+type Registry interface {
+	// Register 注册实例，实例会一直保持到 UnRegister 或者 Close
+	Register(ctx context.Context, si ServiceInstance) error
+	UnRegister(ctx context.Context, si ServiceInstance) error
+	// ListServices 查询某个服务当前存活的实例
+	ListServices(ctx context.Context, name string, protocol string) ([]ServiceInstance, error)
+	// Close 关闭的时候会注销全部实例
+	io.Closer
+}
@@ -0,0 +1,58 @@
+package totp
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量，取后 6 位
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	testCase := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "59", unix: 59, want: "287082"},
+		{name: "1111111109", unix: 1111111109, want: "081804"},
+		{name: "1111111111", unix: 1111111111, want: "050471"},
+		{name: "1234567890", unix: 1234567890, want: "005924"},
+		{name: "2000000000", unix: 2000000000, want: "279037"},
+		{name: "20000000000", unix: 20000000000, want: "353130"},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := Code(secret, Counter(time.Unix(tc.unix, 0)))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, code)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, Counter(now))
+	require.NoError(t, err)
+
+	counter, ok := Validate(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, Counter(now), counter)
+
+	// 允许一个周期的时钟误差
+	_, ok = Validate(secret, code, now.Add(Period))
+	assert.True(t, ok)
+	_, ok = Validate(secret, code, now.Add(2*Period))
+	assert.False(t, ok)
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("用户中心", "1426325504@qq.com", "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, "otpauth://totp/%E7%94%A8%E6%88%B7%E4%B8%AD%E5%BF%83:1426325504@qq.com?algorithm=SHA1&digits=6&issuer=%E7%94%A8%E6%88%B7%E4%B8%AD%E5%BF%83&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+}
@@ -4,6 +4,8 @@ package main
 
 import (
 	"github.com/dadaxiaoxiao/go-pkg/customserver"
+	"github.com/dadaxiaoxiao/user/internal/events"
+	"github.com/dadaxiaoxiao/user/internal/grpc"
 	"github.com/dadaxiaoxiao/user/internal/repository"
 	"github.com/dadaxiaoxiao/user/internal/repository/cache"
 	"github.com/dadaxiaoxiao/user/internal/repository/dao"
@@ -19,36 +21,155 @@ var thirdProvider = wire.NewSet(
 	ioc.InitEtcd,
 	ioc.InitLogger,
 	ioc.InitRedis,
+	ioc.InitJWTKeySet,
+	ioc.InitJWTSessionConfig,
 	myjwt.NewRedisJWTHandler,
 )
 
 var userHdlProvider = wire.NewSet(
 	dao.NewGORMUserDAO,
+	dao.NewGORMTOTPDAO,
 	cache.NewRedisUserCache,
 	cache.NewRedisCodeCache,
+	cache.NewRedisPasswordResetCache,
+	cache.NewRedisTwoFactorCache,
+	cache.NewRedisLoginLimitCache,
 	repository.NewCachedUserRepository,
 	repository.NewCachedCodeRepository,
+	repository.NewCachedPasswordResetRepository,
+	repository.NewCachedTOTPRepository,
+	repository.NewCachedLoginLimitRepository,
 	ioc.InitSmsService,
 	service.NewUserService,
 	service.NewSMSCodeService,
+	ioc.InitEmailService,
+	service.NewEmailCodeService,
+	service.NewPasswordResetService,
+	ioc.InitTOTPEncrypter,
+	ioc.InitTwoFactorService,
+	ioc.InitLoginLimitService,
 	web.NewUserHandler,
+	web.NewTwoFactorHandler,
+	web.NewJWKSHandler,
 )
 
-var oauth2WechatHdlProvider = wire.NewSet(
+var mergeProvider = wire.NewSet(
+	events.NewRedisStreamProducer,
+	ioc.InitMergeService,
+)
+
+var oidcHdlProvider = wire.NewSet(
+	dao.NewGORMOIDCDAO,
+	cache.NewRedisOIDCCache,
+	repository.NewCachedOIDCRepository,
+	service.NewOIDCService,
+	ioc.InitOIDCConfig,
+	web.NewOIDCHandler,
+	web.NewIntrospectionHandler,
+)
+
+var registryProvider = wire.NewSet(
+	ioc.InitRegistry,
+	ioc.InitServiceInstances,
+)
+
+var oauth2HdlProvider = wire.NewSet(
+	dao.NewGORMIdentityDAO,
+	cache.NewRedisOAuth2StateCache,
+	repository.NewCachedIdentityRepository,
+	repository.NewCachedOAuth2StateRepository,
+	service.NewIdentityService,
+	service.NewBindingService,
 	ioc.InitWechatService,
-	ioc.InitWechatHandlerConfig,
-	web.NewOAuth2WechatHandler,
+	dao.NewGORMWechatTokenDAO,
+	ioc.InitWechatTokenRepository,
+	ioc.InitOAuth2Registry,
+	ioc.InitOAuth2Config,
+	web.NewOAuth2Handler,
+	web.NewBindingHandler,
+)
+
+var wechatMiniHdlProvider = wire.NewSet(
+	ioc.InitWechatMiniService,
+	cache.NewRedisWechatMiniSessionCache,
+	ioc.InitWechatMiniSessionRepository,
+	service.NewWechatMiniService,
+	web.NewWechatMiniHandler,
+)
+
+var qrLoginHdlProvider = wire.NewSet(
+	cache.NewRedisQRLoginCache,
+	repository.NewCachedQRLoginRepository,
+	service.NewQRLoginService,
+	web.NewQRLoginHandler,
+)
+
+var magicLinkHdlProvider = wire.NewSet(
+	ioc.InitMagicLinkService,
+	web.NewMagicLinkHandler,
+)
+
+var patHdlProvider = wire.NewSet(
+	dao.NewGORMPersonalAccessTokenDAO,
+	repository.NewCachedPersonalAccessTokenRepository,
+	service.NewPersonalAccessTokenService,
+	web.NewPersonalAccessTokenHandler,
+)
+
+var rbacHdlProvider = wire.NewSet(
+	dao.NewGORMRBACDAO,
+	cache.NewRedisRBACCache,
+	repository.NewCachedRBACRepository,
+	ioc.InitRBACService,
+	web.NewRBACHandler,
+)
+
+var userAdminHdlProvider = wire.NewSet(
+	service.NewUserAdminService,
+	web.NewUserAdminHandler,
+)
+
+var accountDeletionProvider = wire.NewSet(
+	ioc.InitRlockClient,
+	ioc.InitAccountDeletionService,
+	ioc.InitAccountDeletionJob,
+	web.NewAccountDeletionHandler,
+)
+
+var dataExportProvider = wire.NewSet(
+	ioc.InitBlobStore,
+	cache.NewRedisDataExportCache,
+	repository.NewCachedDataExportRepository,
+	ioc.InitDataExportService,
+	ioc.InitDataExportJob,
+	web.NewDataExportHandler,
 )
 
-func InitApp() *customserver.App {
+func InitApp() *App {
 	wire.Build(
 		thirdProvider,
 		ioc.InitGinMiddlewares,
 		userHdlProvider,
-		oauth2WechatHdlProvider,
+		oauth2HdlProvider,
+		wechatMiniHdlProvider,
+		qrLoginHdlProvider,
+		magicLinkHdlProvider,
+		patHdlProvider,
+		rbacHdlProvider,
+		userAdminHdlProvider,
+		accountDeletionProvider,
+		dataExportProvider,
+		oidcHdlProvider,
+		mergeProvider,
 		ioc.InitWebServer,
+		grpc.NewUserServiceServer,
+		grpc.NewOIDCClientServiceServer,
+		grpc.NewTokenServiceServer,
+		ioc.InitGRPCxServer,
+		registryProvider,
 		// 组装 *App
-		wire.Struct(new(customserver.App), "GinServer"),
+		wire.Struct(new(customserver.App), "GinServer", "GRPCServer"),
+		wire.Struct(new(App), "*"),
 	)
-	return new(customserver.App)
+	return new(App)
 }
@@ -8,6 +8,8 @@ package main
 
 import (
 	"github.com/dadaxiaoxiao/go-pkg/customserver"
+	"github.com/dadaxiaoxiao/user/internal/events"
+	"github.com/dadaxiaoxiao/user/internal/grpc"
 	"github.com/dadaxiaoxiao/user/internal/repository"
 	"github.com/dadaxiaoxiao/user/internal/repository/cache"
 	"github.com/dadaxiaoxiao/user/internal/repository/dao"
@@ -20,35 +22,141 @@ import (
 
 // Injectors from wire.go:
 
-func InitApp() *customserver.App {
+func InitApp() *App {
 	cmdable := ioc.InitRedis()
-	handler := jwt.NewRedisJWTHandler(cmdable)
 	logger := ioc.InitLogger()
-	v := ioc.InitGinMiddlewares(cmdable, handler, logger)
+	keySet := ioc.InitJWTKeySet()
+	sessionConfig := ioc.InitJWTSessionConfig()
+	handler := jwt.NewRedisJWTHandler(cmdable, keySet, sessionConfig)
 	db := ioc.InitDB(logger)
 	userDao := dao.NewGORMUserDAO(db)
 	userCache := cache.NewRedisUserCache(cmdable)
 	userRepository := repository.NewCachedUserRepository(userDao, userCache)
+	personalAccessTokenDAO := dao.NewGORMPersonalAccessTokenDAO(db)
+	personalAccessTokenRepository := repository.NewCachedPersonalAccessTokenRepository(personalAccessTokenDAO)
+	personalAccessTokenService := service.NewPersonalAccessTokenService(personalAccessTokenRepository, userRepository)
+	rbacdao := dao.NewGORMRBACDAO(db)
+	rbacCache := cache.NewRedisRBACCache(cmdable)
+	rbacRepository := repository.NewCachedRBACRepository(rbacdao, rbacCache)
+	rbacService := ioc.InitRBACService(rbacRepository)
+	v := ioc.InitGinMiddlewares(cmdable, handler, personalAccessTokenService, rbacService, logger)
 	userService := service.NewUserService(userRepository, logger)
 	codeCache := cache.NewRedisCodeCache(cmdable)
 	codeRepository := repository.NewCachedCodeRepository(codeCache)
 	smsService := ioc.InitSmsService(cmdable)
 	codeService := service.NewSMSCodeService(codeRepository, smsService)
-	userHandler := web.NewUserHandler(userService, codeService, handler, logger)
+	emailService := ioc.InitEmailService(logger)
+	emailCodeService := service.NewEmailCodeService(codeRepository, emailService)
+	passwordResetCache := cache.NewRedisPasswordResetCache(cmdable)
+	passwordResetRepository := repository.NewCachedPasswordResetRepository(passwordResetCache)
+	passwordResetService := service.NewPasswordResetService(userRepository, passwordResetRepository, codeService, emailCodeService)
+	totpdao := dao.NewGORMTOTPDAO(db)
+	twoFactorCache := cache.NewRedisTwoFactorCache(cmdable)
+	encrypter := ioc.InitTOTPEncrypter()
+	totpRepository := repository.NewCachedTOTPRepository(totpdao, twoFactorCache, encrypter)
+	loginLimitCache := cache.NewRedisLoginLimitCache(cmdable)
+	loginLimitRepository := repository.NewCachedLoginLimitRepository(loginLimitCache)
+	loginLimitService := ioc.InitLoginLimitService(loginLimitRepository)
+	twoFactorService := ioc.InitTwoFactorService(userRepository, totpRepository, loginLimitService)
+	wechatMiniSessionCache := cache.NewRedisWechatMiniSessionCache(cmdable)
+	wechatMiniSessionRepository := ioc.InitWechatMiniSessionRepository(wechatMiniSessionCache)
+	producer := events.NewRedisStreamProducer(cmdable)
+	dataExportCache := cache.NewRedisDataExportCache(cmdable)
+	dataExportRepository := repository.NewCachedDataExportRepository(dataExportCache)
+	store := ioc.InitBlobStore()
+	identityDAO := dao.NewGORMIdentityDAO(db)
+	identityRepository := repository.NewCachedIdentityRepository(identityDAO)
+	dataExportService := ioc.InitDataExportService(dataExportRepository, userRepository, identityRepository, store, handler, logger)
+	accountDeletionService := ioc.InitAccountDeletionService(userRepository, wechatMiniSessionRepository, producer, dataExportService, handler, logger)
+	userHandler := web.NewUserHandler(userService, codeService, emailCodeService, passwordResetService, twoFactorService, loginLimitService, accountDeletionService, handler, logger)
+	twoFactorHandler := web.NewTwoFactorHandler(twoFactorService, logger)
+	jwksHandler := web.NewJWKSHandler(handler)
+	oidcdao := dao.NewGORMOIDCDAO(db)
+	oidcCache := cache.NewRedisOIDCCache(cmdable)
+	oidcRepository := repository.NewCachedOIDCRepository(oidcdao, oidcCache)
+	oidcService := service.NewOIDCService(oidcRepository)
+	oidcConfig := ioc.InitOIDCConfig()
+	oidcHandler := web.NewOIDCHandler(oidcService, userService, keySet, oidcConfig, logger)
+	introspectionHandler := web.NewIntrospectionHandler(oidcService, handler, logger)
 	wechatService := ioc.InitWechatService()
-	wechatHandlerConfig := ioc.InitWechatHandlerConfig()
-	oAuth2WechatHandler := web.NewOAuth2WechatHandler(wechatService, userService, wechatHandlerConfig, handler)
-	server := ioc.InitWebServer(v, userHandler, oAuth2WechatHandler)
+	wechatTokenDAO := dao.NewGORMWechatTokenDAO(db)
+	wechatTokenRepository := ioc.InitWechatTokenRepository(wechatTokenDAO)
+	registry := ioc.InitOAuth2Registry(wechatService, wechatTokenRepository, logger)
+	oAuth2StateCache := cache.NewRedisOAuth2StateCache(cmdable)
+	oAuth2StateRepository := repository.NewCachedOAuth2StateRepository(oAuth2StateCache)
+	identityService := service.NewIdentityService(identityRepository, oAuth2StateRepository, userRepository, logger)
+	bindingService := service.NewBindingService(userRepository, identityRepository, codeService, emailCodeService)
+	oAuth2Config := ioc.InitOAuth2Config()
+	oAuth2Handler := web.NewOAuth2Handler(registry, identityService, bindingService, handler, oAuth2Config, logger)
+	bindingHandler := web.NewBindingHandler(bindingService, logger)
+	miniService := ioc.InitWechatMiniService()
+	wechatMiniService := service.NewWechatMiniService(miniService, identityService, wechatMiniSessionRepository, userRepository, logger)
+	wechatMiniHandler := web.NewWechatMiniHandler(wechatMiniService, handler, logger)
+	qrLoginCache := cache.NewRedisQRLoginCache(cmdable)
+	qrLoginRepository := repository.NewCachedQRLoginRepository(qrLoginCache)
+	qrLoginService := service.NewQRLoginService(qrLoginRepository)
+	qrLoginHandler := web.NewQRLoginHandler(qrLoginService, handler, logger)
+	magicLinkService := ioc.InitMagicLinkService(codeRepository, emailService, userService)
+	magicLinkHandler := web.NewMagicLinkHandler(magicLinkService, twoFactorService, accountDeletionService, handler, logger)
+	personalAccessTokenHandler := web.NewPersonalAccessTokenHandler(personalAccessTokenService, logger)
+	rbacHandler := web.NewRBACHandler(rbacService, logger)
+	userAdminService := service.NewUserAdminService(userRepository, identityRepository)
+	userAdminHandler := web.NewUserAdminHandler(userAdminService, handler, logger)
+	accountDeletionHandler := web.NewAccountDeletionHandler(accountDeletionService, handler, logger)
+	dataExportHandler := web.NewDataExportHandler(dataExportService, logger)
+	server := ioc.InitWebServer(v, userHandler, twoFactorHandler, jwksHandler, oidcHandler, introspectionHandler, oAuth2Handler, bindingHandler, wechatMiniHandler, qrLoginHandler, magicLinkHandler, personalAccessTokenHandler, rbacHandler, userAdminHandler, accountDeletionHandler, dataExportHandler)
+	mergeService := ioc.InitMergeService(userRepository, producer)
+	userServiceServer := grpc.NewUserServiceServer(userService, emailCodeService, loginLimitService, mergeService, handler)
+	oidcClientServiceServer := grpc.NewOIDCClientServiceServer(oidcService)
+	tokenServiceServer := grpc.NewTokenServiceServer(oidcService, handler)
+	grpcxServer := ioc.InitGRPCxServer(userServiceServer, oidcClientServiceServer, tokenServiceServer, handler, rbacService)
 	app := &customserver.App{
-		GinServer: server,
+		GinServer:  server,
+		GRPCServer: grpcxServer,
 	}
-	return app
+	client := ioc.InitEtcd()
+	registryRegistry := ioc.InitRegistry(client)
+	v2 := ioc.InitServiceInstances()
+	rlockClient := ioc.InitRlockClient(cmdable)
+	accountDeletionJob := ioc.InitAccountDeletionJob(accountDeletionService, rlockClient, logger)
+	dataExportJob := ioc.InitDataExportJob(dataExportService, registryRegistry, v2, logger)
+	mainApp := &App{
+		App:         app,
+		Registry:    registryRegistry,
+		Instances:   v2,
+		DeletionJob: accountDeletionJob,
+		ExportJob:   dataExportJob,
+		Logger:      logger,
+	}
+	return mainApp
 }
 
 // wire.go:
 
-var thirdProvider = wire.NewSet(ioc.InitDB, ioc.InitEtcd, ioc.InitLogger, ioc.InitRedis, jwt.NewRedisJWTHandler)
+var thirdProvider = wire.NewSet(ioc.InitDB, ioc.InitEtcd, ioc.InitLogger, ioc.InitRedis, ioc.InitJWTKeySet, ioc.InitJWTSessionConfig, jwt.NewRedisJWTHandler)
+
+var userHdlProvider = wire.NewSet(dao.NewGORMUserDAO, dao.NewGORMTOTPDAO, cache.NewRedisUserCache, cache.NewRedisCodeCache, cache.NewRedisPasswordResetCache, cache.NewRedisTwoFactorCache, cache.NewRedisLoginLimitCache, repository.NewCachedUserRepository, repository.NewCachedCodeRepository, repository.NewCachedPasswordResetRepository, repository.NewCachedTOTPRepository, repository.NewCachedLoginLimitRepository, ioc.InitSmsService, service.NewUserService, service.NewSMSCodeService, ioc.InitEmailService, service.NewEmailCodeService, service.NewPasswordResetService, ioc.InitTOTPEncrypter, ioc.InitTwoFactorService, ioc.InitLoginLimitService, web.NewUserHandler, web.NewTwoFactorHandler, web.NewJWKSHandler)
+
+var mergeProvider = wire.NewSet(events.NewRedisStreamProducer, ioc.InitMergeService)
+
+var oidcHdlProvider = wire.NewSet(dao.NewGORMOIDCDAO, cache.NewRedisOIDCCache, repository.NewCachedOIDCRepository, service.NewOIDCService, ioc.InitOIDCConfig, web.NewOIDCHandler, web.NewIntrospectionHandler)
+
+var registryProvider = wire.NewSet(ioc.InitRegistry, ioc.InitServiceInstances)
+
+var oauth2HdlProvider = wire.NewSet(dao.NewGORMIdentityDAO, cache.NewRedisOAuth2StateCache, repository.NewCachedIdentityRepository, repository.NewCachedOAuth2StateRepository, service.NewIdentityService, service.NewBindingService, ioc.InitWechatService, dao.NewGORMWechatTokenDAO, ioc.InitWechatTokenRepository, ioc.InitOAuth2Registry, ioc.InitOAuth2Config, web.NewOAuth2Handler, web.NewBindingHandler)
+
+var wechatMiniHdlProvider = wire.NewSet(ioc.InitWechatMiniService, cache.NewRedisWechatMiniSessionCache, ioc.InitWechatMiniSessionRepository, service.NewWechatMiniService, web.NewWechatMiniHandler)
+
+var qrLoginHdlProvider = wire.NewSet(cache.NewRedisQRLoginCache, repository.NewCachedQRLoginRepository, service.NewQRLoginService, web.NewQRLoginHandler)
+
+var magicLinkHdlProvider = wire.NewSet(ioc.InitMagicLinkService, web.NewMagicLinkHandler)
+
+var patHdlProvider = wire.NewSet(dao.NewGORMPersonalAccessTokenDAO, repository.NewCachedPersonalAccessTokenRepository, service.NewPersonalAccessTokenService, web.NewPersonalAccessTokenHandler)
+
+var rbacHdlProvider = wire.NewSet(dao.NewGORMRBACDAO, cache.NewRedisRBACCache, repository.NewCachedRBACRepository, ioc.InitRBACService, web.NewRBACHandler)
+
+var userAdminHdlProvider = wire.NewSet(service.NewUserAdminService, web.NewUserAdminHandler)
 
-var userHdlProvider = wire.NewSet(dao.NewGORMUserDAO, cache.NewRedisUserCache, cache.NewRedisCodeCache, repository.NewCachedUserRepository, repository.NewCachedCodeRepository, ioc.InitSmsService, service.NewUserService, service.NewSMSCodeService, web.NewUserHandler)
+var accountDeletionProvider = wire.NewSet(ioc.InitRlockClient, ioc.InitAccountDeletionService, ioc.InitAccountDeletionJob, web.NewAccountDeletionHandler)
 
-var oauth2WechatHdlProvider = wire.NewSet(ioc.InitWechatService, ioc.InitWechatHandlerConfig, web.NewOAuth2WechatHandler)
+var dataExportProvider = wire.NewSet(ioc.InitBlobStore, cache.NewRedisDataExportCache, repository.NewCachedDataExportRepository, ioc.InitDataExportService, ioc.InitDataExportJob, web.NewDataExportHandler)